@@ -11,7 +11,7 @@ import "unicode/utf8"
 // - Cursor moves to the end of the last applied (effective) edit.
 // - Selection is cleared if any edit applies.
 func (b *Buffer) Apply(edits ...TextEdit) {
-	if len(edits) == 0 {
+	if len(edits) == 0 || b.lazy != nil {
 		return
 	}
 
@@ -45,7 +45,7 @@ func (b *Buffer) Apply(edits ...TextEdit) {
 // ApplyRemote applies remote edits in order and returns a change payload with
 // deterministic cursor/selection remap details.
 func (b *Buffer) ApplyRemote(edits []RemoteEdit, opts ApplyRemoteOptions) (ApplyRemoteResult, bool) {
-	if len(edits) == 0 {
+	if len(edits) == 0 || b.lazy != nil {
 		return ApplyRemoteResult{}, false
 	}
 	if !validVersionMismatchMode(opts.VersionMismatchMode) {
@@ -0,0 +1,212 @@
+package buffer
+
+// BracketPair is an opener/closer grapheme pair used for bracket matching.
+// Pairs with equal Open and Close (quotes) cannot be matched and are ignored.
+type BracketPair struct {
+	Open  string
+	Close string
+}
+
+// DefaultBracketPairs returns (), [] and {}.
+func DefaultBracketPairs() []BracketPair {
+	return []BracketPair{
+		{Open: "(", Close: ")"},
+		{Open: "[", Close: "]"},
+		{Open: "{", Close: "}"},
+	}
+}
+
+// BracketOptions configures bracket matching.
+type BracketOptions struct {
+	// Pairs lists matchable pairs. When nil, DefaultBracketPairs() is used.
+	Pairs []BracketPair
+	// Skip, if set, reports whether the grapheme at p must be ignored, e.g.
+	// because it is inside a string or comment.
+	Skip func(p Pos) bool
+	// MaxRows limits how many rows away from the start bracket the scan may
+	// go. Values <= 0 scan the whole document.
+	MaxRows int
+}
+
+// MatchBracket finds the bracket matching the one adjacent to p.
+//
+// The grapheme at p is tried first, then the one before p. at is the
+// position of that bracket and match the position of its partner. Nested
+// brackets of the same pair are balanced; other pairs are not considered.
+func (b *Buffer) MatchBracket(p Pos, opt BracketOptions) (at, match Pos, ok bool) {
+	pairs := bracketPairs(opt.Pairs)
+	p = b.clampPos(p)
+	for _, col := range [2]int{p.GraphemeCol, p.GraphemeCol - 1} {
+		cand := Pos{Row: p.Row, GraphemeCol: col}
+		g, gOK := b.graphemeAt(cand)
+		if !gOK || (opt.Skip != nil && opt.Skip(cand)) {
+			continue
+		}
+		for _, pair := range pairs {
+			switch g {
+			case pair.Open:
+				if m, ok := b.scanBracket(cand, pair, true, opt); ok {
+					return cand, m, true
+				}
+			case pair.Close:
+				if m, ok := b.scanBracket(cand, pair, false, opt); ok {
+					return cand, m, true
+				}
+			}
+		}
+	}
+	return Pos{}, Pos{}, false
+}
+
+// EnclosingBrackets finds the innermost bracket pair that encloses p, i.e.
+// open is before p and close is at or after p.
+func (b *Buffer) EnclosingBrackets(p Pos, opt BracketOptions) (open, close Pos, ok bool) {
+	pairs := bracketPairs(opt.Pairs)
+	p = b.clampPos(p)
+
+	// The first unbalanced closer of each pair at or after p closes the
+	// nearest unbalanced opener of that pair before p.
+	closers := make([]Pos, len(pairs))
+	closed := make([]bool, len(pairs))
+	depth := make([]int, len(pairs))
+	left := len(pairs)
+	b.walkGraphemes(p, true, opt.MaxRows, func(q Pos, g string) bool {
+		for i, pair := range pairs {
+			if closed[i] || (g != pair.Open && g != pair.Close) {
+				continue
+			}
+			if opt.Skip != nil && opt.Skip(q) {
+				return true
+			}
+			if g == pair.Open {
+				depth[i]++
+				continue
+			}
+			if depth[i] > 0 {
+				depth[i]--
+				continue
+			}
+			closers[i], closed[i] = q, true
+			left--
+		}
+		return left > 0
+	})
+	if left == len(pairs) {
+		return Pos{}, Pos{}, false
+	}
+
+	clear(depth)
+	found := false
+	b.walkGraphemes(p, false, opt.MaxRows, func(q Pos, g string) bool {
+		for i, pair := range pairs {
+			if !closed[i] || (g != pair.Open && g != pair.Close) {
+				continue
+			}
+			if opt.Skip != nil && opt.Skip(q) {
+				return true
+			}
+			if g == pair.Close {
+				depth[i]++
+				continue
+			}
+			if depth[i] > 0 {
+				depth[i]--
+				continue
+			}
+			open, close, found = q, closers[i], true
+			return false
+		}
+		return true
+	})
+	return open, close, found
+}
+
+// scanBracket returns the partner of the bracket at from. forward is true when
+// from holds pair.Open.
+func (b *Buffer) scanBracket(from Pos, pair BracketPair, forward bool, opt BracketOptions) (Pos, bool) {
+	depth := 1
+	var match Pos
+	found := false
+	start := from
+	if forward {
+		start.GraphemeCol++
+	}
+	b.walkGraphemes(start, forward, opt.MaxRows, func(q Pos, g string) bool {
+		if g != pair.Open && g != pair.Close {
+			return true
+		}
+		if opt.Skip != nil && opt.Skip(q) {
+			return true
+		}
+		if (g == pair.Open) == forward {
+			depth++
+			return true
+		}
+		depth--
+		if depth == 0 {
+			match, found = q, true
+			return false
+		}
+		return true
+	})
+	return match, found
+}
+
+// walkGraphemes visits graphemes starting at from (forward) or before from
+// (backward) until fn returns false or maxRows rows have been passed.
+func (b *Buffer) walkGraphemes(from Pos, forward bool, maxRows int, fn func(Pos, string) bool) {
+	for row := from.Row; b.hasRow(row); {
+		if maxRows > 0 && (row-from.Row > maxRows || from.Row-row > maxRows) {
+			return
+		}
+		line := b.line(row)
+		if forward {
+			col := 0
+			if row == from.Row {
+				col = from.GraphemeCol
+			}
+			for ; col < len(line); col++ {
+				if !fn(Pos{Row: row, GraphemeCol: col}, line[col]) {
+					return
+				}
+			}
+			row++
+			continue
+		}
+		col := len(line) - 1
+		if row == from.Row {
+			col = min(from.GraphemeCol, len(line)) - 1
+		}
+		for ; col >= 0; col-- {
+			if !fn(Pos{Row: row, GraphemeCol: col}, line[col]) {
+				return
+			}
+		}
+		row--
+	}
+}
+
+func (b *Buffer) graphemeAt(p Pos) (string, bool) {
+	if !b.hasRow(p.Row) {
+		return "", false
+	}
+	line := b.line(p.Row)
+	if p.GraphemeCol < 0 || p.GraphemeCol >= len(line) {
+		return "", false
+	}
+	return line[p.GraphemeCol], true
+}
+
+func bracketPairs(pairs []BracketPair) []BracketPair {
+	if pairs == nil {
+		return DefaultBracketPairs()
+	}
+	out := make([]BracketPair, 0, len(pairs))
+	for _, p := range pairs {
+		if p.Open == "" || p.Close == "" || p.Open == p.Close {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchBracket_AdjacentAndNested(t *testing.T) {
+	b := New("f(a[(b)], c)\n{\n  x\n}", Options{})
+
+	cases := []struct {
+		name      string
+		p         Pos
+		wantAt    Pos
+		wantMatch Pos
+	}{
+		{"opener at cursor", Pos{0, 1}, Pos{0, 1}, Pos{0, 11}},
+		{"closer before cursor", Pos{0, 12}, Pos{0, 11}, Pos{0, 1}},
+		{"nested same pair", Pos{0, 4}, Pos{0, 4}, Pos{0, 6}},
+		{"other pair inside", Pos{0, 3}, Pos{0, 3}, Pos{0, 7}},
+		{"across rows", Pos{1, 0}, Pos{1, 0}, Pos{3, 0}},
+		{"across rows backward", Pos{3, 1}, Pos{3, 0}, Pos{1, 0}},
+	}
+	for _, tc := range cases {
+		at, match, ok := b.MatchBracket(tc.p, BracketOptions{})
+		if !ok || at != tc.wantAt || match != tc.wantMatch {
+			t.Fatalf("%s: got at=%v match=%v ok=%v, want %v %v", tc.name, at, match, ok, tc.wantAt, tc.wantMatch)
+		}
+	}
+
+	if _, _, ok := b.MatchBracket(Pos{2, 2}, BracketOptions{}); ok {
+		t.Fatalf("expected no bracket next to plain text")
+	}
+	if _, _, ok := New("(a", Options{}).MatchBracket(Pos{0, 0}, BracketOptions{}); ok {
+		t.Fatalf("expected unbalanced bracket to have no match")
+	}
+}
+
+func TestMatchBracket_SkipAndMaxRows(t *testing.T) {
+	b := New(`(")")`, Options{})
+	inString := func(p Pos) bool { return p.Row == 0 && p.GraphemeCol >= 1 && p.GraphemeCol <= 3 }
+
+	if _, match, ok := b.MatchBracket(Pos{0, 0}, BracketOptions{}); !ok || match != (Pos{0, 2}) {
+		t.Fatalf("without skip: got %v ok=%v", match, ok)
+	}
+	if _, match, ok := b.MatchBracket(Pos{0, 0}, BracketOptions{Skip: inString}); !ok || match != (Pos{0, 4}) {
+		t.Fatalf("with skip: got %v ok=%v", match, ok)
+	}
+
+	b = New("(\n\n\n)", Options{})
+	if _, _, ok := b.MatchBracket(Pos{0, 0}, BracketOptions{MaxRows: 2}); ok {
+		t.Fatalf("expected MaxRows to stop the scan")
+	}
+	if _, _, ok := b.MatchBracket(Pos{0, 0}, BracketOptions{MaxRows: 3}); !ok {
+		t.Fatalf("expected match within MaxRows")
+	}
+}
+
+func TestMatchBracket_CustomPairs(t *testing.T) {
+	b := New("<a>(b)", Options{})
+	opt := BracketOptions{Pairs: []BracketPair{{Open: "<", Close: ">"}, {Open: `"`, Close: `"`}}}
+	if _, match, ok := b.MatchBracket(Pos{0, 0}, opt); !ok || match != (Pos{0, 2}) {
+		t.Fatalf("custom pair: got %v ok=%v", match, ok)
+	}
+	if _, _, ok := b.MatchBracket(Pos{0, 4}, opt); ok {
+		t.Fatalf("default pairs should not apply when Pairs is set")
+	}
+}
+
+func TestEnclosingBrackets(t *testing.T) {
+	b := New("a(b[c]d, {e\nf})", Options{})
+
+	open, close, ok := b.EnclosingBrackets(Pos{0, 7}, BracketOptions{})
+	if !ok || open != (Pos{0, 1}) || close != (Pos{1, 2}) {
+		t.Fatalf("outer: got %v %v ok=%v", open, close, ok)
+	}
+	open, close, ok = b.EnclosingBrackets(Pos{0, 5}, BracketOptions{})
+	if !ok || open != (Pos{0, 3}) || close != (Pos{0, 5}) {
+		t.Fatalf("inner: got %v %v ok=%v", open, close, ok)
+	}
+	open, close, ok = b.EnclosingBrackets(Pos{1, 0}, BracketOptions{})
+	if !ok || open != (Pos{0, 9}) || close != (Pos{1, 1}) {
+		t.Fatalf("multi-row: got %v %v ok=%v", open, close, ok)
+	}
+	if _, _, ok := b.EnclosingBrackets(Pos{0, 0}, BracketOptions{}); ok {
+		t.Fatalf("expected no enclosing pair at doc start")
+	}
+}
+
+func TestEnclosingBrackets_SkipsUnclosedOpeners(t *testing.T) {
+	b := New("(a [b {c d)", Options{})
+	open, close, ok := b.EnclosingBrackets(Pos{0, 8}, BracketOptions{})
+	if !ok || open != (Pos{0, 0}) || close != (Pos{0, 10}) {
+		t.Fatalf("got %v %v ok=%v", open, close, ok)
+	}
+
+	// Each bracket is visited at most once per direction.
+	text := strings.Repeat("(", 500) + "x" + strings.Repeat("[", 500)
+	b = New(text, Options{})
+	visits := 0
+	opt := BracketOptions{Skip: func(Pos) bool { visits++; return false }}
+	if _, _, ok := b.EnclosingBrackets(Pos{0, 500}, opt); ok {
+		t.Fatalf("unclosed openers must not enclose")
+	}
+	if visits > 2*len(text) {
+		t.Fatalf("expected a linear scan, got %d visits", visits)
+	}
+}
+
+func TestMove_Bracket(t *testing.T) {
+	b := New("x(a)", Options{})
+	b.SetCursor(Pos{0, 1})
+
+	b.Move(Move{Unit: MoveBracket})
+	if got, want := b.Cursor(), (Pos{0, 3}); got != want {
+		t.Fatalf("jump to closer: got %v, want %v", got, want)
+	}
+	b.Move(Move{Unit: MoveBracket, Count: 2})
+	if got, want := b.Cursor(), (Pos{0, 1}); got != want {
+		t.Fatalf("jump back: got %v, want %v", got, want)
+	}
+
+	b.Move(Move{Unit: MoveBracket, Extend: true})
+	if sel, ok := b.Selection(); !ok || sel != (Range{Start: Pos{0, 1}, End: Pos{0, 3}}) {
+		t.Fatalf("extend: got %v ok=%v", sel, ok)
+	}
+}
@@ -22,6 +22,8 @@ type Buffer struct {
 	version uint64
 	// textVersion advances only when document text changes.
 	textVersion uint64
+	// savedTextVersion is the textVersion recorded by MarkSaved.
+	savedTextVersion uint64
 
 	cursor Pos
 	// preferredCol tracks vertical-move target column across shorter/empty lines.
@@ -36,6 +38,9 @@ type Buffer struct {
 	hist historyState
 
 	offsetIdx lineOffsetIndex
+
+	// lazy backs lines when the buffer was built with NewLazy.
+	lazy *lazyLines
 }
 
 // lineOffsetIndex caches cumulative byte/rune/UTF-16 offsets per line so that
@@ -71,16 +76,16 @@ func New(text string, opt Options) *Buffer {
 }
 
 func (b *Buffer) Text() string {
-	if len(b.lines) == 0 {
+	if b.lineCount() == 0 {
 		return ""
 	}
 
 	var sb strings.Builder
-	for i, line := range b.lines {
+	for i := 0; i < b.lineCount(); i++ {
 		if i > 0 {
 			sb.WriteByte('\n')
 		}
-		sb.WriteString(grapheme.Join(line))
+		sb.WriteString(grapheme.Join(b.line(i)))
 	}
 	return sb.String()
 }
@@ -89,9 +94,38 @@ func (b *Buffer) Text() string {
 // joining grapheme clusters directly without the serialize-then-split
 // round-trip of Text() + strings.Split().
 func (b *Buffer) RawLines() []string {
-	out := make([]string, len(b.lines))
-	for i, line := range b.lines {
-		out[i] = grapheme.Join(line)
+	out := make([]string, b.lineCount())
+	for i := range out {
+		out[i] = grapheme.Join(b.line(i))
+	}
+	return out
+}
+
+// RawLinesInRange returns lines [start, end) clamped to document bounds.
+//
+// Unlike RawLines it touches only the requested rows, which keeps windowed
+// rendering of NewLazy buffers proportional to the window size.
+func (b *Buffer) RawLinesInRange(start, end int) []string {
+	if start < 0 {
+		start = 0
+	}
+	n := 0
+	if b.lazy != nil {
+		// Index only up to end; a full count would scan the whole source.
+		b.lazy.ensureRow(end - 1)
+		n = len(b.lazy.starts)
+	} else {
+		n = len(b.lines)
+	}
+	if end > n {
+		end = n
+	}
+	if end <= start {
+		return nil
+	}
+	out := make([]string, end-start)
+	for i := range out {
+		out[i] = grapheme.Join(b.line(start + i))
 	}
 	return out
 }
@@ -142,7 +176,7 @@ func (b *Buffer) SelectionRaw() (Range, bool) {
 func (b *Buffer) SetSelection(r Range) {
 	change := b.beginChange(ChangeSourceLocal)
 
-	clamped := ClampRange(r, len(b.lines), b.lineLen)
+	clamped := ClampRange(r, b.rowsThrough(max(r.Start.Row, r.End.Row)), b.lineLen)
 	next := selectionState{
 		active: true,
 		anchor: clamped.Start,
@@ -186,15 +220,47 @@ func (b *Buffer) ClearSelection() {
 	b.commitChange(change)
 }
 
+// line returns the grapheme clusters of row. Callers must not modify the result.
+func (b *Buffer) line(row int) []string {
+	if b.lazy != nil {
+		return b.lazy.line(row)
+	}
+	return b.lines[row]
+}
+
+func (b *Buffer) lineCount() int {
+	if b.lazy != nil {
+		return b.lazy.count()
+	}
+	return len(b.lines)
+}
+
+// rowsThrough returns the number of rows known once row is indexed. It
+// exceeds row unless the document is shorter, and reads a lazy source no
+// further than needed to tell.
+func (b *Buffer) rowsThrough(row int) int {
+	if b.lazy != nil {
+		b.lazy.ensureRow(row)
+		return len(b.lazy.starts)
+	}
+	return len(b.lines)
+}
+
+func (b *Buffer) hasRow(row int) bool {
+	return row >= 0 && row < b.rowsThrough(row)
+}
+
+func (b *Buffer) isLastRow(row int) bool { return !b.hasRow(row + 1) }
+
 func (b *Buffer) lineLen(row int) int {
-	if row < 0 || row >= len(b.lines) {
+	if !b.hasRow(row) {
 		return 0
 	}
-	return len(b.lines[row])
+	return len(b.line(row))
 }
 
 func (b *Buffer) clampPos(p Pos) Pos {
-	return ClampPos(p, len(b.lines), b.lineLen)
+	return ClampPos(p, b.rowsThrough(p.Row), b.lineLen)
 }
 
 func (b *Buffer) setCursor(p Pos) {
@@ -225,12 +291,12 @@ func (b *Buffer) TextInRange(r Range) string {
 	if r.IsEmpty() {
 		return ""
 	}
-	if r.Start.Row < 0 || r.Start.Row >= len(b.lines) || r.End.Row < 0 || r.End.Row >= len(b.lines) {
+	if r.Start.Row < 0 || !b.hasRow(r.End.Row) {
 		return ""
 	}
 
 	if r.Start.Row == r.End.Row {
-		line := b.lines[r.Start.Row]
+		line := b.line(r.Start.Row)
 		startCol := r.Start.GraphemeCol
 		endCol := r.End.GraphemeCol
 		if startCol < 0 || endCol < 0 || startCol > len(line) || endCol > len(line) {
@@ -244,7 +310,7 @@ func (b *Buffer) TextInRange(r Range) string {
 		if row > r.Start.Row {
 			sb.WriteByte('\n')
 		}
-		line := b.lines[row]
+		line := b.line(row)
 		startCol := 0
 		endCol := len(line)
 		if row == r.Start.Row {
@@ -262,7 +328,25 @@ func (b *Buffer) TextInRange(r Range) string {
 }
 
 // LineCount returns the number of lines in the buffer.
-func (b *Buffer) LineCount() int { return len(b.lines) }
+//
+// On NewLazy buffers it indexes the whole source; see IndexedLineCount.
+func (b *Buffer) LineCount() int { return b.lineCount() }
+
+// IndexedLineCount returns the number of lines known so far and whether
+// that is the whole document. Only NewLazy buffers, which index line
+// offsets as rows are requested, can be incomplete; IndexLines grows the
+// index.
+func (b *Buffer) IndexedLineCount() (n int, complete bool) {
+	if b.lazy != nil {
+		return len(b.lazy.starts), b.lazy.complete
+	}
+	return len(b.lines), true
+}
+
+// IndexLines extends the line index of a NewLazy buffer through row, or to
+// the end of the source, reading no further. Other buffers are always fully
+// indexed.
+func (b *Buffer) IndexLines(row int) { b.rowsThrough(row) }
 
 func splitLines(text string) [][]string {
 	parts := strings.Split(text, "\n")
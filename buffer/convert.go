@@ -39,7 +39,7 @@ const (
 )
 
 func (b *Buffer) PosFromByteOffset(off int, p ConvertPolicy) (Pos, bool) {
-	off, ok := clampOffset(off, b.docLen(offsetUnitByte), p.ClampMode)
+	off, ok := clampOffset(off, b.docLenPast(off, offsetUnitByte), p.ClampMode)
 	if !ok {
 		return Pos{}, false
 	}
@@ -55,7 +55,7 @@ func (b *Buffer) ByteOffsetFromPos(pos Pos, p ConvertPolicy) (int, bool) {
 }
 
 func (b *Buffer) PosFromRuneOffset(off int, p ConvertPolicy) (Pos, bool) {
-	off, ok := clampOffset(off, b.docLen(offsetUnitRune), p.ClampMode)
+	off, ok := clampOffset(off, b.docLenPast(off, offsetUnitRune), p.ClampMode)
 	if !ok {
 		return Pos{}, false
 	}
@@ -71,7 +71,7 @@ func (b *Buffer) RuneOffsetFromPos(pos Pos, p ConvertPolicy) (int, bool) {
 }
 
 func (b *Buffer) PosFromUTF16Offset(off int, p ConvertPolicy) (Pos, bool) {
-	off, ok := clampOffset(off, b.docLen(offsetUnitUTF16), p.ClampMode)
+	off, ok := clampOffset(off, b.docLenPast(off, offsetUnitUTF16), p.ClampMode)
 	if !ok {
 		return Pos{}, false
 	}
@@ -163,99 +163,118 @@ func unitWidth(cluster string, unit offsetUnit) int {
 	return len(cluster)
 }
 
-func (b *Buffer) ensureByteRuneIndex() {
-	if b.offsetIdx.byteRuneValid && b.offsetIdx.byteRuneVersion == b.textVersion {
-		return
-	}
-	n := len(b.lines)
-	if cap(b.offsetIdx.byteStarts) >= n {
-		b.offsetIdx.byteStarts = b.offsetIdx.byteStarts[:n]
-		b.offsetIdx.runeStarts = b.offsetIdx.runeStarts[:n]
-	} else {
-		b.offsetIdx.byteStarts = make([]int, n)
-		b.offsetIdx.runeStarts = make([]int, n)
-	}
-
-	byteOff, runeOff := 0, 0
-	for i, line := range b.lines {
-		b.offsetIdx.byteStarts[i] = byteOff
-		b.offsetIdx.runeStarts[i] = runeOff
-		for _, cluster := range line {
-			byteOff += len(cluster)
-			runeOff += utf8.RuneCountInString(cluster)
+// ensureByteRuneIndex extends the byte and rune line start indexes until
+// stop reports true for the last indexed start, or every row is indexed.
+// Rows are decoded only as far as the index grows, so lookups near the top
+// of a NewLazy buffer do not read the rest of it.
+func (b *Buffer) ensureByteRuneIndex(stop func(starts []int) bool) {
+	idx := &b.offsetIdx
+	if !idx.byteRuneValid || idx.byteRuneVersion != b.textVersion {
+		idx.byteStarts = idx.byteStarts[:0]
+		idx.runeStarts = idx.runeStarts[:0]
+		idx.byteRuneVersion = b.textVersion
+		idx.byteRuneValid = true
+	}
+
+	for i := len(idx.byteStarts); b.hasRow(i); i++ {
+		if i > 0 && stop(idx.byteStarts) && stop(idx.runeStarts) {
+			return
 		}
-		if i < len(b.lines)-1 {
-			byteOff++
-			runeOff++
+		byteOff, runeOff := 0, 0
+		if i > 0 {
+			byteOff, runeOff = idx.byteStarts[i-1]+1, idx.runeStarts[i-1]+1
+			for _, cluster := range b.line(i - 1) {
+				byteOff += len(cluster)
+				runeOff += utf8.RuneCountInString(cluster)
+			}
 		}
+		idx.byteStarts = append(idx.byteStarts, byteOff)
+		idx.runeStarts = append(idx.runeStarts, runeOff)
 	}
-	b.offsetIdx.byteRuneVersion = b.textVersion
-	b.offsetIdx.byteRuneValid = true
 }
 
-func (b *Buffer) ensureUTF16Index() {
-	if b.offsetIdx.utf16Valid && b.offsetIdx.utf16Version == b.textVersion {
-		return
-	}
-	n := len(b.lines)
-	if cap(b.offsetIdx.utf16Starts) >= n {
-		b.offsetIdx.utf16Starts = b.offsetIdx.utf16Starts[:n]
-	} else {
-		b.offsetIdx.utf16Starts = make([]int, n)
-	}
-
-	utf16Off := 0
-	for i, line := range b.lines {
-		b.offsetIdx.utf16Starts[i] = utf16Off
-		for _, cluster := range line {
-			for _, r := range cluster {
-				n := utf16.RuneLen(r)
-				if n < 0 {
-					n = 1
-				}
-				utf16Off += n
-			}
+// ensureUTF16Index is ensureByteRuneIndex for UTF-16 line starts.
+func (b *Buffer) ensureUTF16Index(stop func(starts []int) bool) {
+	idx := &b.offsetIdx
+	if !idx.utf16Valid || idx.utf16Version != b.textVersion {
+		idx.utf16Starts = idx.utf16Starts[:0]
+		idx.utf16Version = b.textVersion
+		idx.utf16Valid = true
+	}
+
+	for i := len(idx.utf16Starts); b.hasRow(i); i++ {
+		if i > 0 && stop(idx.utf16Starts) {
+			return
 		}
-		if i < len(b.lines)-1 {
-			utf16Off++
+		off := 0
+		if i > 0 {
+			off = idx.utf16Starts[i-1] + 1
+			for _, cluster := range b.line(i - 1) {
+				off += unitWidth(cluster, offsetUnitUTF16)
+			}
 		}
+		idx.utf16Starts = append(idx.utf16Starts, off)
 	}
-	b.offsetIdx.utf16Version = b.textVersion
-	b.offsetIdx.utf16Valid = true
 }
 
-func (b *Buffer) lineStarts(unit offsetUnit) []int {
+// lineStarts returns the line start offsets in unit, indexed at least until
+// stop reports true or through the last row.
+func (b *Buffer) lineStarts(unit offsetUnit, stop func(starts []int) bool) []int {
 	switch unit {
 	case offsetUnitUTF16:
-		b.ensureUTF16Index()
+		b.ensureUTF16Index(stop)
 		return b.offsetIdx.utf16Starts
 	case offsetUnitRune:
-		b.ensureByteRuneIndex()
+		b.ensureByteRuneIndex(stop)
 		return b.offsetIdx.runeStarts
 	default:
-		b.ensureByteRuneIndex()
+		b.ensureByteRuneIndex(stop)
 		return b.offsetIdx.byteStarts
 	}
 }
 
-func (b *Buffer) docLen(unit offsetUnit) int {
-	starts := b.lineStarts(unit)
-	n := len(b.lines)
-	if n == 0 {
+// throughRow stops indexing once row has a start.
+func throughRow(row int) func([]int) bool {
+	return func(starts []int) bool { return len(starts) > row }
+}
+
+// pastOffset stops indexing once a line starts after off, so the row
+// holding off is known.
+func pastOffset(off int) func([]int) bool {
+	return func(starts []int) bool { return starts[len(starts)-1] > off }
+}
+
+// endOf returns the offset just past the last indexed row, given starts
+// from lineStarts.
+func (b *Buffer) endOf(starts []int, unit offsetUnit) int {
+	if len(starts) == 0 {
 		return 0
 	}
-	// Total = start of last line + width of last line content.
-	lastStart := starts[n-1]
+	n := len(starts)
 	w := 0
-	for _, cluster := range b.lines[n-1] {
+	for _, cluster := range b.line(n - 1) {
 		w += unitWidth(cluster, unit)
 	}
-	return lastStart + w
+	return starts[n-1] + w
+}
+
+func (b *Buffer) docLen(unit offsetUnit) int {
+	return b.endOf(b.lineStarts(unit, func([]int) bool { return false }), unit)
+}
+
+// docLenPast returns the document length in unit, or a value above off when
+// the document is longer, indexing rows only until off is passed.
+func (b *Buffer) docLenPast(off int, unit offsetUnit) int {
+	starts := b.lineStarts(unit, pastOffset(off))
+	if b.hasRow(len(starts)) {
+		return starts[len(starts)-1]
+	}
+	return b.endOf(starts, unit)
 }
 
 func (b *Buffer) posFromOffset(off int, unit offsetUnit) (Pos, bool) {
-	starts := b.lineStarts(unit)
-	n := len(b.lines)
+	starts := b.lineStarts(unit, pastOffset(off))
+	n := len(starts)
 	if n == 0 {
 		return Pos{}, false
 	}
@@ -278,7 +297,7 @@ func (b *Buffer) posFromOffset(off int, unit offsetUnit) (Pos, bool) {
 		return Pos{Row: row, GraphemeCol: 0}, true
 	}
 
-	for col, cluster := range b.lines[row] {
+	for col, cluster := range b.line(row) {
 		next := cur + unitWidth(cluster, unit)
 		if off > cur && off < next {
 			return Pos{}, false
@@ -301,11 +320,12 @@ func (b *Buffer) posFromOffset(off int, unit offsetUnit) (Pos, bool) {
 }
 
 func (b *Buffer) offsetFromPos(pos Pos, unit offsetUnit) int {
-	starts := b.lineStarts(unit)
+	starts := b.lineStarts(unit, throughRow(pos.Row))
 	off := starts[pos.Row]
+	line := b.line(pos.Row)
 
 	for col := 0; col < pos.GraphemeCol; col++ {
-		off += unitWidth(b.lines[pos.Row][col], unit)
+		off += unitWidth(line[col], unit)
 	}
 
 	return off
@@ -11,6 +11,9 @@ import (
 // bump version → recordUndo → commitChange.
 // Returns false if nothing changed.
 func (b *Buffer) doLocalEdit(r Range, text string) bool {
+	if b.lazy != nil {
+		return false
+	}
 	prev := b.snapshot()
 	change := b.beginChange(ChangeSourceLocal)
 
@@ -82,7 +85,7 @@ func (b *Buffer) DeleteBackward() {
 	// Join with previous line (delete the newline).
 	prevRow := row - 1
 	b.doLocalEdit(Range{
-		Start: Pos{Row: prevRow, GraphemeCol: len(b.lines[prevRow])},
+		Start: Pos{Row: prevRow, GraphemeCol: len(b.line(prevRow))},
 		End:   Pos{Row: row, GraphemeCol: 0},
 	}, "")
 }
@@ -95,12 +98,11 @@ func (b *Buffer) DeleteForward() {
 	}
 
 	row, col := b.cursor.Row, b.cursor.GraphemeCol
-	lastRow := len(b.lines) - 1
-	if row == lastRow && col == len(b.lines[lastRow]) {
+	if b.isLastRow(row) && col == len(b.line(row)) {
 		return
 	}
 
-	if col < len(b.lines[row]) {
+	if col < len(b.line(row)) {
 		b.doLocalEdit(Range{
 			Start: Pos{Row: row, GraphemeCol: col},
 			End:   Pos{Row: row, GraphemeCol: col + 1},
@@ -123,7 +125,7 @@ func (b *Buffer) DeleteLineRight() {
 	}
 
 	row, col := b.cursor.Row, b.cursor.GraphemeCol
-	lineLen := len(b.lines[row])
+	lineLen := len(b.line(row))
 	if col >= lineLen {
 		return
 	}
@@ -147,7 +149,7 @@ func (b *Buffer) DeleteWordBackward() {
 	if col <= 0 {
 		return
 	}
-	startCol := prevWordBoundary(b.lines[row], col)
+	startCol := prevWordBoundary(b.line(row), col)
 	if startCol >= col {
 		return
 	}
@@ -0,0 +1,119 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// FindOptions configures Find.
+type FindOptions struct {
+	// Backward searches for the last match starting before from.
+	Backward bool
+	// CaseInsensitive compares grapheme clusters with Unicode simple folding.
+	CaseInsensitive bool
+	// Wrap continues the search past the document end (or start) back to from.
+	Wrap bool
+}
+
+// Find returns the range of the next occurrence of query relative to from.
+//
+// Forward searches return the first match starting at or after from; backward
+// searches return the last match starting before from. Matching is literal and
+// grapheme-aligned. Queries containing '\n' never match.
+//
+// Find reads rows one at a time, so it works on NewLazy buffers without
+// materializing the document, and indexes them no further than the match.
+func (b *Buffer) Find(query string, from Pos, opt FindOptions) (Range, bool) {
+	if query == "" || strings.Contains(query, "\n") {
+		return Range{}, false
+	}
+	needle := grapheme.Split(query)
+	from = b.clampPos(from)
+	// n is the row count, needed only once the search wraps; until then
+	// rows are indexed as they are reached.
+	n := -1
+
+	// n+1 iterations revisit from.Row once more after wrapping to cover the
+	// part of the row on the other side of from.
+	for i := 0; n < 0 || i <= n; i++ {
+		row := from.Row + i
+		if opt.Backward {
+			row = from.Row - i
+		}
+		if !b.hasRow(row) {
+			if !opt.Wrap {
+				break
+			}
+			if n < 0 {
+				n = b.lineCount()
+			}
+			row = (row + n) % n
+		}
+
+		minCol, maxCol := 0, maxInt
+		switch {
+		case i == 0 && opt.Backward:
+			maxCol = from.GraphemeCol - 1
+		case i == 0:
+			minCol = from.GraphemeCol
+		case i == n && opt.Backward:
+			minCol = from.GraphemeCol
+		case i == n:
+			maxCol = from.GraphemeCol - 1
+		}
+		line := b.line(row)
+		if col, ok := findInLine(line, needle, minCol, maxCol, opt.Backward, opt.CaseInsensitive); ok {
+			return Range{
+				Start: Pos{Row: row, GraphemeCol: col},
+				End:   Pos{Row: row, GraphemeCol: col + len(needle)},
+			}, true
+		}
+	}
+	return Range{}, false
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// findInLine returns the first (or last, when backward) column in
+// [minCol, maxCol] where needle matches line.
+func findInLine(line, needle []string, minCol, maxCol int, backward, fold bool) (int, bool) {
+	last := len(line) - len(needle)
+	if maxCol < last {
+		last = maxCol
+	}
+	if minCol < 0 {
+		minCol = 0
+	}
+	if last < minCol {
+		return 0, false
+	}
+
+	if backward {
+		for col := last; col >= minCol; col-- {
+			if clustersMatchAt(line, needle, col, fold) {
+				return col, true
+			}
+		}
+		return 0, false
+	}
+	for col := minCol; col <= last; col++ {
+		if clustersMatchAt(line, needle, col, fold) {
+			return col, true
+		}
+	}
+	return 0, false
+}
+
+func clustersMatchAt(line, needle []string, col int, fold bool) bool {
+	for k, want := range needle {
+		got := line[col+k]
+		if got == want {
+			continue
+		}
+		if !fold || !strings.EqualFold(got, want) {
+			return false
+		}
+	}
+	return true
+}
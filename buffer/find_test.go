@@ -0,0 +1,110 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFind_ForwardFromPos(t *testing.T) {
+	b := New("foo bar foo\nfoo", Options{})
+
+	tests := []struct {
+		name string
+		from Pos
+		want Range
+	}{
+		{"at match start", Pos{Row: 0, GraphemeCol: 0}, Range{Start: Pos{0, 0}, End: Pos{0, 3}}},
+		{"after first match", Pos{Row: 0, GraphemeCol: 1}, Range{Start: Pos{0, 8}, End: Pos{0, 11}}},
+		{"next row", Pos{Row: 0, GraphemeCol: 9}, Range{Start: Pos{1, 0}, End: Pos{1, 3}}},
+	}
+	for _, tt := range tests {
+		got, ok := b.Find("foo", tt.from, FindOptions{})
+		if !ok || got != tt.want {
+			t.Fatalf("%s: got %v ok=%v, want %v", tt.name, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := b.Find("foo", Pos{Row: 1, GraphemeCol: 1}, FindOptions{}); ok {
+		t.Fatalf("expected no match past the last occurrence without wrap")
+	}
+}
+
+func TestFind_Backward(t *testing.T) {
+	b := New("foo bar foo\nfoo", Options{})
+
+	got, ok := b.Find("foo", Pos{Row: 1, GraphemeCol: 0}, FindOptions{Backward: true})
+	if want := (Range{Start: Pos{0, 8}, End: Pos{0, 11}}); !ok || got != want {
+		t.Fatalf("backward from row start: got %v ok=%v, want %v", got, ok, want)
+	}
+	got, ok = b.Find("foo", Pos{Row: 0, GraphemeCol: 8}, FindOptions{Backward: true})
+	if want := (Range{Start: Pos{0, 0}, End: Pos{0, 3}}); !ok || got != want {
+		t.Fatalf("backward before match: got %v ok=%v, want %v", got, ok, want)
+	}
+	if _, ok := b.Find("foo", Pos{Row: 0, GraphemeCol: 0}, FindOptions{Backward: true}); ok {
+		t.Fatalf("expected no match before doc start without wrap")
+	}
+}
+
+func TestFind_Wrap(t *testing.T) {
+	b := New("foo x\nbar", Options{})
+
+	got, ok := b.Find("foo", Pos{Row: 1, GraphemeCol: 0}, FindOptions{Wrap: true})
+	if want := (Range{Start: Pos{0, 0}, End: Pos{0, 3}}); !ok || got != want {
+		t.Fatalf("forward wrap: got %v ok=%v, want %v", got, ok, want)
+	}
+	got, ok = b.Find("bar", Pos{Row: 0, GraphemeCol: 0}, FindOptions{Backward: true, Wrap: true})
+	if want := (Range{Start: Pos{1, 0}, End: Pos{1, 3}}); !ok || got != want {
+		t.Fatalf("backward wrap: got %v ok=%v, want %v", got, ok, want)
+	}
+
+	// Wrapping back onto the start row only finds matches before from.
+	got, ok = b.Find("foo", Pos{Row: 0, GraphemeCol: 1}, FindOptions{Wrap: true})
+	if want := (Range{Start: Pos{0, 0}, End: Pos{0, 3}}); !ok || got != want {
+		t.Fatalf("wrap onto start row: got %v ok=%v, want %v", got, ok, want)
+	}
+}
+
+func TestFind_CaseInsensitiveAndGraphemeAligned(t *testing.T) {
+	b := New("Straße é👍🏽 Foo", Options{})
+
+	got, ok := b.Find("foo", Pos{}, FindOptions{CaseInsensitive: true})
+	if want := (Range{Start: Pos{0, 10}, End: Pos{0, 13}}); !ok || got != want {
+		t.Fatalf("case-insensitive: got %v ok=%v, want %v", got, ok, want)
+	}
+	if _, ok := b.Find("foo", Pos{}, FindOptions{}); ok {
+		t.Fatalf("case-sensitive search should not match %q", "Foo")
+	}
+
+	// The skin-tone modifier belongs to the thumbs-up cluster, so a bare
+	// thumbs-up must not match half of it.
+	if _, ok := b.Find("👍", Pos{}, FindOptions{}); ok {
+		t.Fatalf("expected no match inside a grapheme cluster")
+	}
+	got, ok = b.Find("é👍🏽", Pos{}, FindOptions{})
+	if want := (Range{Start: Pos{0, 7}, End: Pos{0, 9}}); !ok || got != want {
+		t.Fatalf("cluster match: got %v ok=%v, want %v", got, ok, want)
+	}
+}
+
+func TestFind_RejectsEmptyAndMultilineQueries(t *testing.T) {
+	b := New("a\nb", Options{})
+	if _, ok := b.Find("", Pos{}, FindOptions{}); ok {
+		t.Fatalf("empty query should not match")
+	}
+	if _, ok := b.Find("a\nb", Pos{}, FindOptions{}); ok {
+		t.Fatalf("multi-line query should not match")
+	}
+}
+
+func TestFind_LazyBuffer(t *testing.T) {
+	text := strings.Repeat("noise\n", 500) + "needle\n" + strings.Repeat("noise\n", 500)
+	b, _ := newLazyFromString(text, LazyOptions{CacheLines: 8, ChunkSize: 128})
+
+	got, ok := b.Find("NEEDLE", Pos{}, FindOptions{CaseInsensitive: true})
+	if want := (Range{Start: Pos{500, 0}, End: Pos{500, 6}}); !ok || got != want {
+		t.Fatalf("lazy find: got %v ok=%v, want %v", got, ok, want)
+	}
+	if got := b.lazy.lru.Len(); got > 8 {
+		t.Fatalf("lazy find exceeded cache bound: %d lines cached", got)
+	}
+}
@@ -15,6 +15,11 @@ type bufferSnapshot struct {
 type historyState struct {
 	undo []bufferSnapshot
 	redo []bufferSnapshot
+
+	// groupDepth counts open BeginUndoGroup calls; grouped is set once the
+	// open group recorded its undo snapshot.
+	groupDepth int
+	grouped    bool
 }
 
 func (b *Buffer) snapshot() bufferSnapshot {
@@ -72,6 +77,13 @@ func (b *Buffer) recordUndo(prev bufferSnapshot) {
 	if limit <= 0 {
 		return
 	}
+	if b.hist.groupDepth > 0 {
+		if b.hist.grouped {
+			b.hist.redo = nil
+			return
+		}
+		b.hist.grouped = true
+	}
 
 	b.hist.undo = append(b.hist.undo, prev)
 	if len(b.hist.undo) > limit {
@@ -80,6 +92,29 @@ func (b *Buffer) recordUndo(prev bufferSnapshot) {
 	b.hist.redo = nil
 }
 
+// BeginUndoGroup starts collapsing local edits into one undo step: edits
+// until the matching EndUndoGroup undo together. Groups nest; only the
+// outermost one takes effect. Undo and Redo inside a group start a new step
+// for the edits that follow.
+func (b *Buffer) BeginUndoGroup() {
+	if b.hist.groupDepth == 0 {
+		b.hist.grouped = false
+	}
+	b.hist.groupDepth++
+}
+
+// EndUndoGroup closes a group opened by BeginUndoGroup. Unbalanced calls are
+// ignored.
+func (b *Buffer) EndUndoGroup() {
+	if b.hist.groupDepth == 0 {
+		return
+	}
+	b.hist.groupDepth--
+	if b.hist.groupDepth == 0 {
+		b.hist.grouped = false
+	}
+}
+
 func (b *Buffer) CanUndo() bool { return len(b.hist.undo) > 0 }
 
 func (b *Buffer) CanRedo() bool { return len(b.hist.redo) > 0 }
@@ -91,6 +126,7 @@ func (b *Buffer) Undo() bool {
 
 	cur := b.snapshot()
 	change := b.beginChange(ChangeSourceLocal)
+	b.hist.grouped = false
 
 	i := len(b.hist.undo) - 1
 	prev := b.hist.undo[i]
@@ -113,6 +149,7 @@ func (b *Buffer) Redo() bool {
 
 	cur := b.snapshot()
 	change := b.beginChange(ChangeSourceLocal)
+	b.hist.grouped = false
 
 	i := len(b.hist.redo) - 1
 	next := b.hist.redo[i]
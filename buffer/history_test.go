@@ -258,3 +258,40 @@ func TestBuffer_UndoRedo_DeleteBackward_JoinsLines_Unicode(t *testing.T) {
 		t.Fatalf("cursor=%v, want %v", got, want)
 	}
 }
+
+func TestHistory_UndoGroupCollapsesEdits(t *testing.T) {
+	b := New("", Options{})
+	b.InsertText("a")
+
+	b.BeginUndoGroup()
+	b.InsertText("b")
+	b.BeginUndoGroup()
+	b.InsertText("c")
+	b.EndUndoGroup()
+	b.Apply(TextEdit{Range: Range{Start: Pos{Row: 0, GraphemeCol: 0}, End: Pos{Row: 0, GraphemeCol: 0}}, Text: "x"})
+	b.EndUndoGroup()
+	b.EndUndoGroup() // unbalanced: ignored
+
+	if got, want := b.Text(), "xabc"; got != want {
+		t.Fatalf("text=%q, want %q", got, want)
+	}
+	if !b.Undo() {
+		t.Fatalf("expected Undo=true")
+	}
+	if got, want := b.Text(), "a"; got != want {
+		t.Fatalf("grouped undo: text=%q, want %q", got, want)
+	}
+	if !b.Redo() {
+		t.Fatalf("expected Redo=true")
+	}
+	if got, want := b.Text(), "xabc"; got != want {
+		t.Fatalf("grouped redo: text=%q, want %q", got, want)
+	}
+
+	// Edits after the group undo separately.
+	b.InsertText("d")
+	_ = b.Undo()
+	if got, want := b.Text(), "xabc"; got != want {
+		t.Fatalf("after group: text=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,233 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// InvalidUTF8Mode controls how ReadFrom handles byte sequences that are not
+// valid UTF-8.
+type InvalidUTF8Mode uint8
+
+const (
+	// InvalidUTF8Replace replaces each invalid byte with U+FFFD and reports it.
+	InvalidUTF8Replace InvalidUTF8Mode = iota
+	// InvalidUTF8Reject stops reading at the first invalid byte and returns
+	// an *InvalidUTF8Error.
+	InvalidUTF8Reject
+)
+
+// ReadOptions configures ReadFrom.
+type ReadOptions struct {
+	// Options is forwarded to the constructed buffer.
+	Options Options
+	// InvalidUTF8 selects invalid-byte handling. Zero value is InvalidUTF8Replace.
+	InvalidUTF8 InvalidUTF8Mode
+	// MaxInvalidReports caps ReadReport.Invalid entries. Values <= 0 default
+	// to 64. InvalidCount is always exact.
+	MaxInvalidReports int
+}
+
+// InvalidUTF8 locates one invalid byte in the source stream.
+type InvalidUTF8 struct {
+	// ByteOffset is the offset of the invalid byte in the source stream.
+	ByteOffset int64
+	// Pos is the document position of the replacement grapheme.
+	Pos Pos
+}
+
+// ReadReport describes the source stream consumed by ReadFrom.
+type ReadReport struct {
+	// Bytes is the number of bytes read from the source.
+	Bytes int64
+	// Lines is the number of logical lines produced.
+	Lines int
+	// BOM reports whether a leading UTF-8 byte order mark was stripped.
+	BOM bool
+	// InvalidCount is the total number of invalid bytes encountered.
+	InvalidCount int
+	// Invalid lists the first MaxInvalidReports invalid bytes in stream order.
+	Invalid []InvalidUTF8
+}
+
+// InvalidUTF8Error is returned by ReadFrom in InvalidUTF8Reject mode.
+type InvalidUTF8Error struct {
+	ByteOffset int64
+	Pos        Pos
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("buffer: invalid UTF-8 at byte offset %d (row %d, col %d)", e.ByteOffset, e.Pos.Row, e.Pos.GraphemeCol)
+}
+
+const defaultMaxInvalidReports = 64
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ReadFrom builds a buffer by streaming r line by line.
+//
+// Line storage is built incrementally, so peak memory is bounded by the
+// document itself plus one line of read-ahead. A leading UTF-8 BOM is stripped.
+// Line breaks are '\n'; other bytes (including '\r') are kept verbatim.
+//
+// On read errors the partially built buffer is discarded and the error is
+// returned together with the report collected so far.
+func ReadFrom(r io.Reader, opt ReadOptions) (*Buffer, ReadReport, error) {
+	maxReports := opt.MaxInvalidReports
+	if maxReports <= 0 {
+		maxReports = defaultMaxInvalidReports
+	}
+
+	br := bufio.NewReader(r)
+	report := ReadReport{}
+	lines := make([][]string, 0, 64)
+
+	if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+		report.Bytes += int64(len(utf8BOM))
+		report.BOM = true
+	}
+
+	var pending []byte
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			pending = append(pending, chunk...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, report, err
+		}
+
+		lineBytes := chunk
+		if len(pending) > 0 {
+			pending = append(pending, chunk...)
+			lineBytes = pending
+		}
+		lineStart := report.Bytes
+		report.Bytes += int64(len(lineBytes))
+
+		hasNewline := len(lineBytes) > 0 && lineBytes[len(lineBytes)-1] == '\n'
+		if hasNewline {
+			lineBytes = lineBytes[:len(lineBytes)-1]
+		}
+
+		line, invalidErr := decodeStreamLine(lineBytes, lineStart, len(lines), opt.InvalidUTF8, maxReports, &report)
+		if invalidErr != nil {
+			return nil, report, invalidErr
+		}
+		lines = append(lines, line)
+		pending = pending[:0]
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, nil)
+	}
+	report.Lines = len(lines)
+
+	b := New("", opt.Options)
+	b.lines = lines
+	return b, report, nil
+}
+
+func decodeStreamLine(
+	raw []byte,
+	lineStart int64,
+	row int,
+	mode InvalidUTF8Mode,
+	maxReports int,
+	report *ReadReport,
+) ([]string, error) {
+	if utf8.Valid(raw) {
+		return grapheme.Split(string(raw)), nil
+	}
+
+	// Split valid runs individually so reported columns line up with the
+	// replacement graphemes in the stored line.
+	var line []string
+	i := 0
+	for i < len(raw) {
+		j := i
+		for j < len(raw) {
+			r, size := utf8.DecodeRune(raw[j:])
+			if r == utf8.RuneError && size == 1 {
+				break
+			}
+			j += size
+		}
+		if j > i {
+			line = append(line, grapheme.Split(string(raw[i:j]))...)
+		}
+		if j >= len(raw) {
+			break
+		}
+
+		pos := Pos{Row: row, GraphemeCol: len(line)}
+		off := lineStart + int64(j)
+		if mode == InvalidUTF8Reject {
+			return nil, &InvalidUTF8Error{ByteOffset: off, Pos: pos}
+		}
+		report.InvalidCount++
+		if len(report.Invalid) < maxReports {
+			report.Invalid = append(report.Invalid, InvalidUTF8{ByteOffset: off, Pos: pos})
+		}
+		line = append(line, string(utf8.RuneError))
+		i = j + 1
+	}
+	return line, nil
+}
+
+// WriteTo streams the document text to w line by line without building the
+// full text in memory. It implements io.WriterTo.
+//
+// WriteTo does not mark the buffer as saved; call MarkSaved after a
+// successful save.
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var n int64
+	for i := 0; i < b.lineCount(); i++ {
+		if i > 0 {
+			if err := bw.WriteByte('\n'); err != nil {
+				return n, err
+			}
+			n++
+		}
+		for _, cluster := range b.line(i) {
+			written, err := bw.WriteString(cluster)
+			n += int64(written)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		// Bytes still held by the bufio.Writer were not delivered.
+		return n - int64(bw.Buffered()), err
+	}
+	return n, nil
+}
+
+// MarkSaved records the current TextVersion as the saved state.
+func (b *Buffer) MarkSaved() {
+	b.savedTextVersion = b.textVersion
+}
+
+// SavedTextVersion returns the TextVersion recorded by the last MarkSaved
+// call (0 for a freshly constructed buffer).
+func (b *Buffer) SavedTextVersion() uint64 { return b.savedTextVersion }
+
+// Dirty reports whether text changed since the last MarkSaved call (or since
+// construction).
+//
+// The marker follows TextVersion, so undoing back to the saved text still
+// reports dirty until MarkSaved is called again.
+func (b *Buffer) Dirty() bool { return b.textVersion != b.savedTextVersion }
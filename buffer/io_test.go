@@ -0,0 +1,187 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFrom_BuildsLinesAndReport(t *testing.T) {
+	b, report, err := ReadFrom(strings.NewReader("ab\néf\n"), ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if got, want := b.Text(), "ab\néf\n"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+	if got, want := b.LineCount(), 3; got != want {
+		t.Fatalf("line count: got %d, want %d", got, want)
+	}
+	if got, want := report.Lines, 3; got != want {
+		t.Fatalf("report lines: got %d, want %d", got, want)
+	}
+	if got, want := report.Bytes, int64(len("ab\néf\n")); got != want {
+		t.Fatalf("report bytes: got %d, want %d", got, want)
+	}
+	if report.InvalidCount != 0 || len(report.Invalid) != 0 {
+		t.Fatalf("unexpected invalid report: %+v", report)
+	}
+	if b.Version() != 0 || b.TextVersion() != 0 || b.Dirty() {
+		t.Fatalf("fresh buffer state: version=%d textVersion=%d dirty=%v", b.Version(), b.TextVersion(), b.Dirty())
+	}
+}
+
+func TestReadFrom_EmptyInput(t *testing.T) {
+	b, report, err := ReadFrom(strings.NewReader(""), ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if b.Text() != "" || b.LineCount() != 1 || report.Lines != 1 {
+		t.Fatalf("empty input: text=%q lines=%d reportLines=%d", b.Text(), b.LineCount(), report.Lines)
+	}
+}
+
+func TestReadFrom_StripsBOM(t *testing.T) {
+	b, report, err := ReadFrom(strings.NewReader("\xef\xbb\xbfhi"), ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if !report.BOM {
+		t.Fatalf("expected BOM to be reported")
+	}
+	if got, want := b.Text(), "hi"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestReadFrom_LongLinesAcrossReadChunks(t *testing.T) {
+	long := strings.Repeat("x", 10000)
+	src := long + "\n" + long
+	b, _, err := ReadFrom(iotest.OneByteReader(strings.NewReader(src)), ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if b.Text() != src {
+		t.Fatalf("long-line round trip mismatch")
+	}
+}
+
+func TestReadFrom_ReplacesAndReportsInvalidUTF8(t *testing.T) {
+	src := "ok\na\xffb\xfe"
+	b, report, err := ReadFrom(strings.NewReader(src), ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if got, want := b.Text(), "ok\na�b�"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+	if got, want := report.InvalidCount, 2; got != want {
+		t.Fatalf("invalid count: got %d, want %d", got, want)
+	}
+	want := []InvalidUTF8{
+		{ByteOffset: 4, Pos: Pos{Row: 1, GraphemeCol: 1}},
+		{ByteOffset: 6, Pos: Pos{Row: 1, GraphemeCol: 3}},
+	}
+	if len(report.Invalid) != len(want) {
+		t.Fatalf("invalid reports: got %+v, want %+v", report.Invalid, want)
+	}
+	for i := range want {
+		if report.Invalid[i] != want[i] {
+			t.Fatalf("invalid[%d]: got %+v, want %+v", i, report.Invalid[i], want[i])
+		}
+	}
+}
+
+func TestReadFrom_CapsInvalidReports(t *testing.T) {
+	_, report, err := ReadFrom(strings.NewReader("\xff\xff\xff"), ReadOptions{MaxInvalidReports: 2})
+	if err != nil {
+		t.Fatalf("ReadFrom error: %v", err)
+	}
+	if report.InvalidCount != 3 || len(report.Invalid) != 2 {
+		t.Fatalf("invalid cap: count=%d reports=%d", report.InvalidCount, len(report.Invalid))
+	}
+}
+
+func TestReadFrom_RejectInvalidUTF8(t *testing.T) {
+	b, _, err := ReadFrom(strings.NewReader("a\nb\xff"), ReadOptions{InvalidUTF8: InvalidUTF8Reject})
+	if b != nil {
+		t.Fatalf("expected nil buffer on reject")
+	}
+	var invalid *InvalidUTF8Error
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidUTF8Error, got %v", err)
+	}
+	if invalid.ByteOffset != 3 || invalid.Pos != (Pos{Row: 1, GraphemeCol: 1}) {
+		t.Fatalf("invalid error location: %+v", invalid)
+	}
+}
+
+func TestReadFrom_PropagatesReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	_, _, err := ReadFrom(iotest.ErrReader(boom), ReadOptions{})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected reader error, got %v", err)
+	}
+}
+
+func TestBuffer_WriteTo_StreamsText(t *testing.T) {
+	b := New("ab\n\nc👍", Options{})
+	var out bytes.Buffer
+	n, err := b.WriteTo(&out)
+	if err != nil {
+		t.Fatalf("WriteTo error: %v", err)
+	}
+	if got, want := out.String(), b.Text(); got != want {
+		t.Fatalf("written text: got %q, want %q", got, want)
+	}
+	if n != int64(out.Len()) {
+		t.Fatalf("written count: got %d, want %d", n, out.Len())
+	}
+
+	var _ io.WriterTo = b
+}
+
+func TestBuffer_WriteTo_ReportsWriterError(t *testing.T) {
+	b := New(strings.Repeat("x", 8192), Options{})
+	boom := errors.New("boom")
+	if _, err := b.WriteTo(failingWriter{err: boom}); !errors.Is(err, boom) {
+		t.Fatalf("expected writer error, got %v", err)
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestBuffer_DirtyTracksTextVersion(t *testing.T) {
+	b := New("ab", Options{})
+	if b.Dirty() {
+		t.Fatalf("fresh buffer should not be dirty")
+	}
+
+	b.SetCursor(Pos{Row: 0, GraphemeCol: 1})
+	if b.Dirty() {
+		t.Fatalf("cursor-only change should not mark dirty")
+	}
+
+	b.InsertText("X")
+	if !b.Dirty() {
+		t.Fatalf("text change should mark dirty")
+	}
+
+	b.MarkSaved()
+	if b.Dirty() {
+		t.Fatalf("MarkSaved should clear dirty")
+	}
+	if got, want := b.SavedTextVersion(), b.TextVersion(); got != want {
+		t.Fatalf("saved text version: got %d, want %d", got, want)
+	}
+
+	_ = b.Undo()
+	if !b.Dirty() {
+		t.Fatalf("undo after save should mark dirty")
+	}
+}
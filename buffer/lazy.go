@@ -0,0 +1,206 @@
+package buffer
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// LazyOptions configures NewLazy.
+type LazyOptions struct {
+	// CacheLines caps decoded lines kept in memory. Values <= 0 default to 4096.
+	CacheLines int
+	// ChunkSize is the read size used while indexing line offsets.
+	// Values <= 0 default to 64 KiB.
+	ChunkSize int
+}
+
+const (
+	defaultLazyCacheLines = 4096
+	defaultLazyChunkSize  = 64 << 10
+)
+
+// lazyLines indexes line start offsets of an io.ReaderAt on demand and keeps
+// a bounded LRU cache of decoded lines.
+type lazyLines struct {
+	src  io.ReaderAt
+	size int64
+
+	// starts holds byte offsets of discovered line starts.
+	starts []int64
+	// scanned is the source offset up to which newlines have been indexed.
+	scanned  int64
+	complete bool
+
+	chunk []byte
+	err   error
+
+	cacheCap int
+	lru      *list.List
+	cache    map[int]*list.Element
+}
+
+type lazyCacheEntry struct {
+	row  int
+	line []string
+}
+
+// NewLazy returns a read-only buffer backed by src.
+//
+// Line offsets are indexed incrementally as rows are requested, and decoded
+// lines are held in a bounded cache, so the whole file is never materialized.
+// Text mutations (Insert*, Delete*, Apply, ApplyRemote) are no-ops; cursor,
+// selection, movement, conversions, TextInRange, WriteTo, and Find work as
+// usual. Text and RawLines materialize the whole document and should be
+// avoided on large sources.
+//
+// Invalid UTF-8 bytes are decoded as U+FFFD. A leading UTF-8 BOM is skipped.
+// Read errors are recorded (see Err) and affected lines read as empty.
+func NewLazy(src io.ReaderAt, size int64, opt LazyOptions) *Buffer {
+	if size < 0 {
+		size = 0
+	}
+	if opt.CacheLines <= 0 {
+		opt.CacheLines = defaultLazyCacheLines
+	}
+	if opt.ChunkSize <= 0 {
+		opt.ChunkSize = defaultLazyChunkSize
+	}
+
+	ll := &lazyLines{
+		src:      src,
+		size:     size,
+		starts:   []int64{0},
+		chunk:    make([]byte, opt.ChunkSize),
+		cacheCap: opt.CacheLines,
+		lru:      list.New(),
+		cache:    make(map[int]*list.Element, opt.CacheLines),
+	}
+	if size >= int64(len(utf8BOM)) {
+		head := make([]byte, len(utf8BOM))
+		if _, err := src.ReadAt(head, 0); err == nil && bytes.Equal(head, utf8BOM) {
+			ll.starts[0] = int64(len(utf8BOM))
+			ll.scanned = int64(len(utf8BOM))
+		}
+	}
+
+	b := New("", Options{HistoryLimit: -1})
+	b.lines = nil
+	b.lazy = ll
+	return b
+}
+
+// IsLazy reports whether the buffer is backed by NewLazy storage.
+func (b *Buffer) IsLazy() bool { return b.lazy != nil }
+
+// Err returns the first read error encountered by lazy storage, if any.
+func (b *Buffer) Err() error {
+	if b.lazy == nil {
+		return nil
+	}
+	return b.lazy.err
+}
+
+// ensureRow indexes line starts until row is known or the source is exhausted.
+func (ll *lazyLines) ensureRow(row int) {
+	for !ll.complete && len(ll.starts) <= row {
+		ll.scanChunk()
+	}
+}
+
+func (ll *lazyLines) scanChunk() {
+	if ll.scanned >= ll.size {
+		ll.complete = true
+		return
+	}
+	n, err := ll.src.ReadAt(ll.chunk, ll.scanned)
+	if n <= 0 {
+		if err != nil && err != io.EOF && ll.err == nil {
+			ll.err = err
+		}
+		ll.complete = true
+		return
+	}
+	buf := ll.chunk[:n]
+	base := ll.scanned
+	for i := 0; i < len(buf); {
+		j := bytes.IndexByte(buf[i:], '\n')
+		if j < 0 {
+			break
+		}
+		ll.starts = append(ll.starts, base+int64(i+j+1))
+		i += j + 1
+	}
+	ll.scanned += int64(n)
+	if ll.scanned >= ll.size {
+		ll.complete = true
+	}
+}
+
+func (ll *lazyLines) count() int {
+	for !ll.complete {
+		ll.scanChunk()
+	}
+	return len(ll.starts)
+}
+
+func (ll *lazyLines) line(row int) []string {
+	if row < 0 {
+		return nil
+	}
+	if el, ok := ll.cache[row]; ok {
+		ll.lru.MoveToFront(el)
+		return el.Value.(*lazyCacheEntry).line
+	}
+
+	ll.ensureRow(row + 1)
+	if row >= len(ll.starts) {
+		return nil
+	}
+	start := ll.starts[row]
+	end := ll.size
+	if row+1 < len(ll.starts) {
+		end = ll.starts[row+1] - 1 // exclude '\n'
+	}
+
+	var line []string
+	if end > start {
+		raw := make([]byte, end-start)
+		n, err := ll.src.ReadAt(raw, start)
+		if err != nil && err != io.EOF && ll.err == nil {
+			ll.err = err
+		}
+		line = grapheme.Split(decodeLazyLine(raw[:n]))
+	}
+
+	ll.cache[row] = ll.lru.PushFront(&lazyCacheEntry{row: row, line: line})
+	for ll.lru.Len() > ll.cacheCap {
+		oldest := ll.lru.Back()
+		ll.lru.Remove(oldest)
+		delete(ll.cache, oldest.Value.(*lazyCacheEntry).row)
+	}
+	return line
+}
+
+func decodeLazyLine(raw []byte) string {
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+	// Replace each invalid byte individually, matching ReadFrom.
+	var sb strings.Builder
+	sb.Grow(len(raw))
+	for len(raw) > 0 {
+		r, size := utf8.DecodeRune(raw)
+		if r == utf8.RuneError && size == 1 {
+			sb.WriteRune(utf8.RuneError)
+		} else {
+			sb.Write(raw[:size])
+		}
+		raw = raw[size:]
+	}
+	return sb.String()
+}
@@ -0,0 +1,201 @@
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// countingReaderAt counts bytes served through ReadAt.
+type countingReaderAt struct {
+	data  []byte
+	bytes int
+}
+
+func (r *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[off:])
+	r.bytes += n
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func newLazyFromString(s string, opt LazyOptions) (*Buffer, *countingReaderAt) {
+	src := &countingReaderAt{data: []byte(s)}
+	return NewLazy(src, int64(len(s)), opt), src
+}
+
+func TestNewLazy_ReadsLinesOnDemand(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		sb.WriteString("line ")
+		sb.WriteString(strings.Repeat("x", i%7))
+		sb.WriteByte('\n')
+	}
+	text := sb.String()
+	b, src := newLazyFromString(text, LazyOptions{ChunkSize: 64})
+
+	if !b.IsLazy() {
+		t.Fatalf("expected lazy buffer")
+	}
+	got := b.RawLinesInRange(0, 3)
+	want := []string{"line ", "line x", "line xx"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("window: got %q, want %q", got, want)
+	}
+	if src.bytes >= len(text)/2 {
+		t.Fatalf("window read touched too much of the source: %d of %d bytes", src.bytes, len(text))
+	}
+
+	if got, want := b.LineCount(), 1001; got != want {
+		t.Fatalf("line count: got %d, want %d", got, want)
+	}
+	if got, want := b.Text(), text; got != want {
+		t.Fatalf("text mismatch")
+	}
+}
+
+func TestNewLazy_CacheIsBounded(t *testing.T) {
+	b, _ := newLazyFromString("a\nb\nc\nd\ne", LazyOptions{CacheLines: 2})
+	for row := 0; row < b.LineCount(); row++ {
+		_ = b.RawLinesInRange(row, row+1)
+	}
+	if got := b.lazy.lru.Len(); got != 2 {
+		t.Fatalf("cache size: got %d, want 2", got)
+	}
+	if got, want := strings.Join(b.RawLinesInRange(0, 5), ","), "a,b,c,d,e"; got != want {
+		t.Fatalf("re-read after eviction: got %q, want %q", got, want)
+	}
+}
+
+func TestNewLazy_SkipsBOMAndReplacesInvalidUTF8(t *testing.T) {
+	b, _ := newLazyFromString("\xef\xbb\xbfa\xffb\nc", LazyOptions{})
+	if got, want := b.Text(), "a�b\nc"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestNewLazy_EmptySource(t *testing.T) {
+	b, _ := newLazyFromString("", LazyOptions{})
+	if b.LineCount() != 1 || b.Text() != "" {
+		t.Fatalf("empty source: lines=%d text=%q", b.LineCount(), b.Text())
+	}
+}
+
+func TestNewLazy_MutationsAreNoOps(t *testing.T) {
+	b, _ := newLazyFromString("hello\nworld", LazyOptions{})
+	b.SetCursor(Pos{Row: 1, GraphemeCol: 2})
+	textVer := b.TextVersion()
+
+	b.InsertText("X")
+	b.InsertNewline()
+	b.DeleteBackward()
+	b.DeleteForward()
+	b.DeleteWordBackward()
+	b.DeleteLineRight()
+	b.Apply(TextEdit{Range: Range{End: Pos{Row: 0, GraphemeCol: 1}}, Text: "y"})
+	if _, ok := b.ApplyRemote([]RemoteEdit{{Text: "z"}}, ApplyRemoteOptions{BaseVersion: b.Version()}); ok {
+		t.Fatalf("ApplyRemote should be rejected on lazy buffers")
+	}
+
+	if b.TextVersion() != textVer || b.Text() != "hello\nworld" || b.CanUndo() {
+		t.Fatalf("lazy buffer mutated: text=%q textVersion=%d", b.Text(), b.TextVersion())
+	}
+}
+
+func TestNewLazy_MovementAndConversions(t *testing.T) {
+	b, _ := newLazyFromString("foo bar\nbaz", LazyOptions{})
+	b.Move(Move{Unit: MoveWord, Dir: DirRight})
+	if got, want := b.Cursor(), (Pos{Row: 0, GraphemeCol: 3}); got != want {
+		t.Fatalf("word move: got %v, want %v", got, want)
+	}
+	b.Move(Move{Unit: MoveDoc, Dir: DirDown})
+	if got, want := b.Cursor(), (Pos{Row: 1, GraphemeCol: 3}); got != want {
+		t.Fatalf("doc end: got %v, want %v", got, want)
+	}
+
+	off, ok := b.ByteOffsetFromPos(Pos{Row: 1, GraphemeCol: 1}, ConvertPolicy{})
+	if !ok || off != 9 {
+		t.Fatalf("byte offset: got %d ok=%v, want 9", off, ok)
+	}
+	if got, want := b.TextInRange(Range{Start: Pos{Row: 0, GraphemeCol: 4}, End: Pos{Row: 1, GraphemeCol: 2}}), "bar\nba"; got != want {
+		t.Fatalf("text in range: got %q, want %q", got, want)
+	}
+}
+
+func TestNewLazy_IndexesOnlyRowsInUse(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	text := sb.String()
+	b, src := newLazyFromString(text, LazyOptions{ChunkSize: 64})
+
+	b.SetCursor(Pos{Row: 5, GraphemeCol: 99})
+	b.Move(Move{Unit: MoveGrapheme, Dir: DirDown})
+	if got, want := b.Cursor(), (Pos{Row: 6, GraphemeCol: 6}); got != want {
+		t.Fatalf("cursor: got %v, want %v", got, want)
+	}
+	if got, ok := b.Find("line 20", Pos{}, FindOptions{}); !ok || got.Start.Row != 20 {
+		t.Fatalf("find: got %v ok=%v", got, ok)
+	}
+	if off, ok := b.ByteOffsetFromPos(Pos{Row: 10}, ConvertPolicy{}); !ok || off != 70 {
+		t.Fatalf("byte offset: got %d ok=%v, want 70", off, ok)
+	}
+	if p, ok := b.PosFromRuneOffset(70, ConvertPolicy{}); !ok || p != (Pos{Row: 10}) {
+		t.Fatalf("pos from offset: got %v ok=%v", p, ok)
+	}
+
+	n, complete := b.IndexedLineCount()
+	if complete || n >= 100 || src.bytes >= len(text)/4 {
+		t.Fatalf("indexed %d rows (complete=%v), read %d of %d bytes", n, complete, src.bytes, len(text))
+	}
+
+	b.IndexLines(500)
+	if n, complete = b.IndexedLineCount(); complete || n <= 500 {
+		t.Fatalf("after IndexLines(500): %d rows, complete=%v", n, complete)
+	}
+	if got, ok := b.PosFromByteOffset(len(text)+5, ConvertPolicy{ClampMode: OffsetClamp}); !ok || got != (Pos{Row: 1000}) {
+		t.Fatalf("clamped offset: got %v ok=%v", got, ok)
+	}
+	if n, complete = b.IndexedLineCount(); !complete || n != 1001 {
+		t.Fatalf("full index: %d rows, complete=%v", n, complete)
+	}
+}
+
+func TestNewLazy_WriteToStreamsDecodedText(t *testing.T) {
+	b, _ := newLazyFromString("one\ntwo", LazyOptions{})
+	var out bytes.Buffer
+	if _, err := b.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo error: %v", err)
+	}
+	if got, want := out.String(), "one\ntwo"; got != want {
+		t.Fatalf("written: got %q, want %q", got, want)
+	}
+}
+
+type failingReaderAt struct{ err error }
+
+func (r failingReaderAt) ReadAt([]byte, int64) (int, error) { return 0, r.err }
+
+func TestNewLazy_RecordsReadError(t *testing.T) {
+	boom := errors.New("boom")
+	b := NewLazy(failingReaderAt{err: boom}, 16, LazyOptions{})
+	if b.LineCount() != 1 {
+		t.Fatalf("line count after read error: got %d, want 1", b.LineCount())
+	}
+	if !errors.Is(b.Err(), boom) {
+		t.Fatalf("Err: got %v, want %v", b.Err(), boom)
+	}
+
+	if New("x", Options{}).Err() != nil {
+		t.Fatalf("regular buffers should not report errors")
+	}
+}
@@ -10,6 +10,9 @@ const (
 	MoveParagraph
 	MoveLine
 	MoveDoc
+	// MoveBracket jumps to the bracket matching the one adjacent to the cursor
+	// (see MatchBracket with default options). Dir and Count are ignored.
+	MoveBracket
 )
 
 type MoveDir int
@@ -88,6 +91,13 @@ func usesPreferredColumn(m Move) bool {
 }
 
 func (b *Buffer) moveCursor(p Pos, m Move, preferredCol int, usePreferred bool) Pos {
+	if m.Unit == MoveBracket {
+		if _, match, ok := b.MatchBracket(p, BracketOptions{}); ok {
+			return match
+		}
+		return p
+	}
+
 	count := m.Count
 	if count <= 0 {
 		count = 1
@@ -119,7 +129,6 @@ func (b *Buffer) moveCursor(p Pos, m Move, preferredCol int, usePreferred bool)
 
 func (b *Buffer) moveGrapheme(p Pos, dir MoveDir, preferredCol int, usePreferred bool) Pos {
 	row, col := p.Row, p.GraphemeCol
-	lastRow := len(b.lines) - 1
 
 	switch dir {
 	case DirLeft:
@@ -130,12 +139,12 @@ func (b *Buffer) moveGrapheme(p Pos, dir MoveDir, preferredCol int, usePreferred
 			return Pos{Row: row, GraphemeCol: col - 1}
 		}
 		prevRow := row - 1
-		return Pos{Row: prevRow, GraphemeCol: len(b.lines[prevRow])}
+		return Pos{Row: prevRow, GraphemeCol: len(b.line(prevRow))}
 	case DirRight:
-		if row == lastRow && col == len(b.lines[lastRow]) {
+		if b.isLastRow(row) && col == len(b.line(row)) {
 			return p
 		}
-		if col < len(b.lines[row]) {
+		if col < len(b.line(row)) {
 			return Pos{Row: row, GraphemeCol: col + 1}
 		}
 		return Pos{Row: row + 1, GraphemeCol: 0}
@@ -148,11 +157,10 @@ func (b *Buffer) moveGrapheme(p Pos, dir MoveDir, preferredCol int, usePreferred
 
 func (b *Buffer) moveWord(p Pos, dir MoveDir) Pos {
 	row, col := p.Row, p.GraphemeCol
-	lastRow := len(b.lines) - 1
 
 	switch dir {
 	case DirLeft:
-		line := b.lines[row]
+		line := b.line(row)
 		nextCol := prevWordBoundary(line, col)
 		if nextCol != col {
 			return Pos{Row: row, GraphemeCol: nextCol}
@@ -161,24 +169,24 @@ func (b *Buffer) moveWord(p Pos, dir MoveDir) Pos {
 			return p
 		}
 		prevRow := row - 1
-		prevLine := b.lines[prevRow]
+		prevLine := b.line(prevRow)
 		return Pos{Row: prevRow, GraphemeCol: prevWordBoundary(prevLine, len(prevLine))}
 	case DirRight:
-		line := b.lines[row]
+		line := b.line(row)
 		nextCol := nextWordBoundary(line, col)
 		if nextCol != col {
 			return Pos{Row: row, GraphemeCol: nextCol}
 		}
-		if row == lastRow {
+		if b.isLastRow(row) {
 			return p
 		}
 		nextRow := row + 1
-		nextLine := b.lines[nextRow]
+		nextLine := b.line(nextRow)
 		return Pos{Row: nextRow, GraphemeCol: nextWordBoundary(nextLine, 0)}
 	case DirHome:
 		return Pos{Row: row, GraphemeCol: 0}
 	case DirEnd:
-		line := b.lines[row]
+		line := b.line(row)
 		return Pos{Row: row, GraphemeCol: len(line)}
 	default:
 		return p
@@ -187,13 +195,12 @@ func (b *Buffer) moveWord(p Pos, dir MoveDir) Pos {
 
 func (b *Buffer) moveParagraph(p Pos, dir MoveDir) Pos {
 	row, col := p.Row, p.GraphemeCol
-	lastRow := len(b.lines) - 1
 
 	targetRow := row
 	switch dir {
 	case DirUp:
 		for nr := row - 1; nr >= 0; nr-- {
-			if len(b.lines[nr]) == 0 {
+			if len(b.line(nr)) == 0 {
 				targetRow = nr
 				break
 			}
@@ -202,31 +209,27 @@ func (b *Buffer) moveParagraph(p Pos, dir MoveDir) Pos {
 			targetRow = 0
 		}
 	case DirDown:
-		for nr := row + 1; nr <= lastRow; nr++ {
-			if len(b.lines[nr]) == 0 {
-				targetRow = nr
+		for nr := row + 1; b.hasRow(nr); nr++ {
+			targetRow = nr
+			if len(b.line(nr)) == 0 {
 				break
 			}
 		}
-		if targetRow == row {
-			targetRow = lastRow
-		}
 	default:
 		return p
 	}
 
-	return Pos{Row: targetRow, GraphemeCol: min(col, len(b.lines[targetRow]))}
+	return Pos{Row: targetRow, GraphemeCol: min(col, len(b.line(targetRow)))}
 }
 
 func (b *Buffer) moveLine(p Pos, dir MoveDir, preferredCol int, usePreferred bool) Pos {
 	row, col := p.Row, p.GraphemeCol
-	lastRow := len(b.lines) - 1
 
 	switch dir {
 	case DirHome:
 		return Pos{Row: row, GraphemeCol: 0}
 	case DirEnd:
-		return Pos{Row: row, GraphemeCol: len(b.lines[row])}
+		return Pos{Row: row, GraphemeCol: len(b.line(row))}
 	case DirUp:
 		if row == 0 {
 			return p
@@ -236,9 +239,9 @@ func (b *Buffer) moveLine(p Pos, dir MoveDir, preferredCol int, usePreferred boo
 		if usePreferred {
 			targetCol = preferredCol
 		}
-		return Pos{Row: nr, GraphemeCol: min(targetCol, len(b.lines[nr]))}
+		return Pos{Row: nr, GraphemeCol: min(targetCol, len(b.line(nr)))}
 	case DirDown:
-		if row == lastRow {
+		if b.isLastRow(row) {
 			return p
 		}
 		nr := row + 1
@@ -246,26 +249,49 @@ func (b *Buffer) moveLine(p Pos, dir MoveDir, preferredCol int, usePreferred boo
 		if usePreferred {
 			targetCol = preferredCol
 		}
-		return Pos{Row: nr, GraphemeCol: min(targetCol, len(b.lines[nr]))}
+		return Pos{Row: nr, GraphemeCol: min(targetCol, len(b.line(nr)))}
 	default:
 		return p
 	}
 }
 
 func (b *Buffer) moveDoc(p Pos, dir MoveDir) Pos {
-	lastRow := len(b.lines) - 1
-	lastCol := len(b.lines[lastRow])
-
 	switch dir {
 	case DirHome, DirUp:
 		return Pos{Row: 0, GraphemeCol: 0}
 	case DirEnd, DirDown:
-		return Pos{Row: lastRow, GraphemeCol: lastCol}
+		lastRow := b.lineCount() - 1
+		return Pos{Row: lastRow, GraphemeCol: len(b.line(lastRow))}
 	default:
 		return p
 	}
 }
 
+// WordRangeAt returns the word at p on its row, using the same word
+// boundaries as MoveWord: a run of non-space graphemes. When p is on
+// whitespace the whitespace run is returned instead. At a line end the run
+// before p is used; an empty line yields an empty range at p.
+func (b *Buffer) WordRangeAt(p Pos) Range {
+	p = b.clampPos(p)
+	line := b.line(p.Row)
+	col := p.GraphemeCol
+	if col == len(line) {
+		if col == 0 {
+			return Range{Start: p, End: p}
+		}
+		col--
+	}
+	space := grapheme.IsSpace(line[col])
+	start, end := col, col+1
+	for start > 0 && grapheme.IsSpace(line[start-1]) == space {
+		start--
+	}
+	for end < len(line) && grapheme.IsSpace(line[end]) == space {
+		end++
+	}
+	return Range{Start: Pos{Row: p.Row, GraphemeCol: start}, End: Pos{Row: p.Row, GraphemeCol: end}}
+}
+
 // Word boundary rules (v0):
 // - skip whitespace, then skip non-whitespace
 func prevWordBoundary(line []string, col int) int {
@@ -360,3 +360,37 @@ func TestBuffer_MoveGrapheme_CombiningAndZWJ(t *testing.T) {
 		t.Fatalf("cursor after ZWJ cluster=%v, want (0,3)", got)
 	}
 }
+
+func TestBuffer_WordRangeAt_MatchesMoveWordBoundaries(t *testing.T) {
+	b := New("foo.bar  baz\n", Options{})
+	r := func(sc, ec int) Range {
+		return Range{Start: Pos{GraphemeCol: sc}, End: Pos{GraphemeCol: ec}}
+	}
+	cases := []struct {
+		col  int
+		want Range
+	}{
+		{0, r(0, 7)},
+		{4, r(0, 7)},
+		{7, r(7, 9)},
+		{8, r(7, 9)},
+		{10, r(9, 12)},
+		{12, r(9, 12)},
+	}
+	for _, tc := range cases {
+		if got := b.WordRangeAt(Pos{GraphemeCol: tc.col}); got != tc.want {
+			t.Errorf("col %d: got %v, want %v", tc.col, got, tc.want)
+		}
+	}
+
+	b.SetCursor(Pos{GraphemeCol: 4})
+	b.Move(Move{Unit: MoveWord, Dir: DirRight})
+	if got := b.Cursor(); got != r(0, 7).End {
+		t.Fatalf("MoveWord stops at %v, want the word end", got)
+	}
+
+	empty := Pos{Row: 1}
+	if got := b.WordRangeAt(empty); got != (Range{Start: empty, End: empty}) {
+		t.Fatalf("empty line: got %v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package clipboard
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// Command names registered by Commands.
+const (
+	CommandCopy  = "clipboard.copy"
+	CommandCut   = "clipboard.cut"
+	CommandPaste = "clipboard.paste"
+)
+
+// Copy stores the selected text in register (and the unnamed register) and
+// returns a tea.SetClipboard command that copies it to the system clipboard.
+// ok is false without a selection.
+func Copy(m editor.Model, regs *Registers, register string) (cmd tea.Cmd, ok bool) {
+	text, ok := selectedText(m)
+	if !ok {
+		return nil, false
+	}
+	regs.Store(register, text)
+	return tea.SetClipboard(text), true
+}
+
+// Cut copies the selection like Copy and deletes it as an editor intent, so
+// intent-mode hosts see an IntentDelete. A read-only editor keeps the text.
+func Cut(m editor.Model, regs *Registers, register string) (editor.Model, tea.Cmd, bool) {
+	cmd, ok := Copy(m, regs, register)
+	if !ok {
+		return m, nil, false
+	}
+	m = m.Dispatch(editor.Intent{
+		Kind:    editor.IntentDelete,
+		Payload: editor.DeleteIntentPayload{Direction: editor.DeleteSelection},
+	})
+	return m, cmd, true
+}
+
+// Paste inserts the text of register through editor.Model.Paste. ok is false
+// when the register is not set.
+func Paste(m editor.Model, regs *Registers, register string) (editor.Model, bool) {
+	text, ok := regs.Get(register)
+	if !ok {
+		return m, false
+	}
+	return m.Paste(text), true
+}
+
+// Commands returns editor commands for the unnamed register, for
+// editor.Config.Commands:
+//
+//   - CommandCopy: Copy
+//   - CommandCut: Cut
+//   - CommandPaste: Paste
+func Commands(regs *Registers) map[string]editor.CommandFunc {
+	return map[string]editor.CommandFunc{
+		CommandCopy: func(m editor.Model) (editor.Model, tea.Cmd) {
+			cmd, _ := Copy(m, regs, "")
+			return m, cmd
+		},
+		CommandCut: func(m editor.Model) (editor.Model, tea.Cmd) {
+			m, cmd, _ := Cut(m, regs, "")
+			return m, cmd
+		},
+		CommandPaste: func(m editor.Model) (editor.Model, tea.Cmd) {
+			m, _ = Paste(m, regs, "")
+			return m, nil
+		},
+	}
+}
+
+// DefaultBindings binds ctrl+c, ctrl+x and ctrl+v to the commands, for
+// editor.Config.Bindings. Copy and cut apply only with a selection, so
+// without one ctrl+c and ctrl+x fall through to other bindings.
+func DefaultBindings() []editor.Binding {
+	return []editor.Binding{
+		{Key: "ctrl+c", Command: CommandCopy, When: "hasSelection"},
+		{Key: "ctrl+x", Command: CommandCut, When: "hasSelection && !readOnly"},
+		{Key: "ctrl+v", Command: CommandPaste, When: "!readOnly"},
+	}
+}
+
+func selectedText(m editor.Model) (string, bool) {
+	b := m.Buffer()
+	if b == nil {
+		return "", false
+	}
+	sel, ok := b.Selection()
+	if !ok {
+		return "", false
+	}
+	return b.TextInRange(sel), true
+}
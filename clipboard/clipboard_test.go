@@ -0,0 +1,104 @@
+package clipboard
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+func ctrl(r rune) tea.KeyPressMsg { return tea.KeyPressMsg{Code: r, Mod: tea.ModCtrl} }
+
+func newEditor(text string, regs *Registers) editor.Model {
+	return editor.New(editor.Config{
+		Text:     text,
+		Commands: Commands(regs),
+		Bindings: DefaultBindings(),
+	})
+}
+
+func selectRange(m editor.Model, r buffer.Range) editor.Model {
+	m.Buffer().SetCursor(r.End)
+	m.Buffer().SetSelection(r)
+	return m
+}
+
+func TestCopyCutPaste_ThroughBindings(t *testing.T) {
+	regs := NewRegisters(0)
+	m := newEditor("hello world", regs)
+
+	m = selectRange(m, buffer.Range{End: buffer.Pos{GraphemeCol: 5}})
+	m, cmd := m.Update(ctrl('c'))
+	if cmd == nil || fmt.Sprint(cmd()) != "hello" {
+		t.Fatalf("copy should return a clipboard command for %q", "hello")
+	}
+	if got, _ := regs.Get(""); got != "hello" {
+		t.Fatalf("unnamed register=%q", got)
+	}
+
+	m = selectRange(m, buffer.Range{Start: buffer.Pos{GraphemeCol: 5}, End: buffer.Pos{GraphemeCol: 11}})
+	m, cmd = m.Update(ctrl('x'))
+	if cmd == nil || m.Buffer().Text() != "hello" {
+		t.Fatalf("cut: text=%q", m.Buffer().Text())
+	}
+
+	m, _ = m.Update(ctrl('v'))
+	m, _ = m.Update(ctrl('v'))
+	if got := m.Buffer().Text(); got != "hello world world" {
+		t.Fatalf("paste: text=%q", got)
+	}
+	m, _ = m.Update(ctrl('z'))
+	if got := m.Buffer().Text(); got != "hello world" {
+		t.Fatalf("each paste should undo as one step, text=%q", got)
+	}
+
+	if got := regs.History(); !reflect.DeepEqual(got, []string{" world", "hello"}) {
+		t.Fatalf("history=%q", got)
+	}
+}
+
+func TestCopy_WithoutSelectionFallsThrough(t *testing.T) {
+	regs := NewRegisters(0)
+	m := newEditor("abc", regs)
+	if _, ok := Copy(m, regs, "a"); ok {
+		t.Fatalf("copy without a selection should not apply")
+	}
+	if _, ok := Paste(m, regs, "a"); ok {
+		t.Fatalf("paste of an unset register should not apply")
+	}
+	if _, cmd := m.Update(ctrl('c')); cmd != nil {
+		t.Fatalf("ctrl+c without a selection should not copy")
+	}
+}
+
+func TestRegisters_NamedAndHistory(t *testing.T) {
+	regs := NewRegisters(2)
+	regs.Store("a", "one")
+	regs.Store("", "two")
+	regs.Store("", "two")
+	regs.Store("b", "three")
+
+	if got, _ := regs.Get("a"); got != "one" {
+		t.Fatalf("a=%q", got)
+	}
+	if got, _ := regs.Get(""); got != "three" {
+		t.Fatalf("unnamed=%q", got)
+	}
+	if got := regs.Names(); !reflect.DeepEqual(got, []string{"", "a", "b"}) {
+		t.Fatalf("names=%q", got)
+	}
+	if got := regs.History(); !reflect.DeepEqual(got, []string{"three", "two"}) {
+		t.Fatalf("history=%q", got)
+	}
+
+	m := editor.New(editor.Config{Text: "x"})
+	m.Buffer().SetCursor(buffer.Pos{GraphemeCol: 1})
+	m, _ = Paste(m, regs, "a")
+	if got := m.Buffer().Text(); got != "xone" {
+		t.Fatalf("text=%q", got)
+	}
+}
@@ -0,0 +1,9 @@
+// Package clipboard adds opt-in copy, cut and paste to editor.Model.
+//
+// Copy and cut store the selection in an in-process register store and
+// return tea.SetClipboard, which copies it to the system clipboard through
+// OSC52. Paste inserts a register through editor.Model.Paste, the same
+// undo-grouped insert hosts use for tea.PasteMsg. Commands plugs the
+// operations into editor.Config.Commands so they can be bound like any
+// editor command.
+package clipboard
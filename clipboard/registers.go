@@ -0,0 +1,64 @@
+package clipboard
+
+import (
+	"maps"
+	"slices"
+)
+
+// DefaultHistoryMax is the default number of entries Registers keeps in its
+// history.
+const DefaultHistoryMax = 30
+
+// Registers is an in-process register store: an unnamed register, named
+// registers, and a history of stored text, most recent first.
+//
+// Registers is not safe for concurrent use; share one store between the
+// models of a program by pointer.
+type Registers struct {
+	named      map[string]string
+	history    []string
+	historyMax int
+}
+
+// NewRegisters returns an empty store keeping up to historyMax history
+// entries. Values <= 0 use DefaultHistoryMax.
+func NewRegisters(historyMax int) *Registers {
+	if historyMax <= 0 {
+		historyMax = DefaultHistoryMax
+	}
+	return &Registers{named: map[string]string{}, historyMax: historyMax}
+}
+
+// Store sets register name and the unnamed register "" to text, and adds
+// text to the history. Storing text equal to the latest history entry does
+// not repeat it.
+func (r *Registers) Store(name, text string) {
+	r.named[""] = text
+	if name != "" {
+		r.named[name] = text
+	}
+	if len(r.history) > 0 && r.history[0] == text {
+		return
+	}
+	r.history = slices.Insert(r.history, 0, text)
+	if len(r.history) > r.historyMax {
+		r.history = r.history[:r.historyMax]
+	}
+}
+
+// Get returns the text of register name; "" is the unnamed register, which
+// holds the latest stored text.
+func (r *Registers) Get(name string) (string, bool) {
+	text, ok := r.named[name]
+	return text, ok
+}
+
+// Names returns the set registers, sorted; the unnamed register is "".
+func (r *Registers) Names() []string {
+	return slices.Sorted(maps.Keys(r.named))
+}
+
+// History returns the stored texts, most recent first.
+func (r *Registers) History() []string {
+	return slices.Clone(r.history)
+}
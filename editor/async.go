@@ -0,0 +1,401 @@
+package editor
+
+import (
+	"slices"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// AsyncProviders selects providers the editor calls asynchronously.
+//
+// An async provider is not called while rendering. Rows rendered without a
+// current result are requested through tea.Cmds returned from Init, Update
+// and AsyncCmd; each command calls the provider off the UI goroutine and
+// returns an AsyncResultMsg. Until it arrives, rows render with the row's
+// previous result or without one. Results requested before the text last
+// changed are discarded and requested again; cursor and selection changes
+// keep them, and providers that depend on the cursor are keyed on it through
+// their context.
+//
+// Async providers are called concurrently with Update and must not touch the
+// Model or its buffer; everything they need is in the context argument.
+type AsyncProviders struct {
+	// Highlighter calls Config.Highlighter.HighlightLine asynchronously.
+	// StatefulHighlighter line states are not used in this mode.
+	Highlighter bool
+	// Links calls Config.LinkProvider asynchronously.
+	Links bool
+	// Ghost calls Config.GhostProvider asynchronously. A ghost is shown (and
+	// accepted) only once its result for the current cursor context arrives.
+	Ghost bool
+	// VirtualText calls Config.VirtualTextProvider asynchronously.
+	VirtualText bool
+}
+
+func (a AsyncProviders) any() bool {
+	return a.Highlighter || a.Links || a.Ghost || a.VirtualText
+}
+
+type asyncKind int
+
+const (
+	asyncHighlight asyncKind = iota
+	asyncLinks
+	asyncGhost
+	asyncVirtualText
+)
+
+// AsyncResultMsg carries provider results requested by an editor with
+// Config.Async. Hosts route it to Model.Update; results requested by other
+// editors are ignored.
+type AsyncResultMsg struct {
+	// DocVersion is the buffer text version (buffer.TextVersion) the results
+	// were requested at.
+	DocVersion uint64
+
+	owner   *asyncState
+	kind    asyncKind
+	results []asyncResult
+}
+
+type asyncResult struct {
+	row int
+	key any
+	val any
+}
+
+// asyncState is shared by copies of a Model so that Init and AsyncCmd can
+// drain requests queued by earlier renders.
+type asyncState struct {
+	highlight asyncRows[highlightKey, []HighlightSpan]
+	links     asyncRows[LinkContext, []LinkSpan]
+	ghost     asyncRows[GhostContext, ghostResult]
+	virtual   asyncRows[VirtualTextContext, VirtualText]
+
+	queue []tea.Cmd
+	// generation counts accepted results, keying render snapshots.
+	generation uint64
+}
+
+// highlightKey is LineContext without the unexported column mapping.
+type highlightKey struct {
+	Text                 string
+	RawText              string
+	CursorGraphemeCol    int
+	RawCursorGraphemeCol int
+	HasCursor            bool
+}
+
+type ghostResult struct {
+	ghost   Ghost
+	present bool
+}
+
+// asyncRows holds per-row async results of one provider.
+type asyncRows[K comparable, V any] struct {
+	rows map[int]*asyncRow[K, V]
+	// want holds requests recorded by lookups since the last render; renders
+	// send those for visible rows.
+	want map[int]asyncJob[K, V]
+}
+
+type asyncRow[K comparable, V any] struct {
+	key K
+	val V
+	has bool
+	// current is cleared by invalidate: val is kept for display but is
+	// requested again.
+	current bool
+
+	pending bool
+	pendKey K
+	pendVer uint64
+}
+
+type asyncJob[K comparable, V any] struct {
+	key K
+	ver uint64
+	run func() V
+}
+
+// asyncValue is the lookup result for one row: the last result and the
+// context it was computed for. fresh reports that it matches the lookup.
+type asyncValue[K comparable, V any] struct {
+	key   K
+	val   V
+	has   bool
+	fresh bool
+}
+
+// lookup returns the last result for row. Unless it or a pending request
+// matches key at ver, run is recorded as wanted.
+func (r *asyncRows[K, V]) lookup(row int, key K, ver uint64, run func() V) asyncValue[K, V] {
+	e := r.rows[row]
+	if e != nil && e.has && e.current && e.key == key {
+		return asyncValue[K, V]{key: e.key, val: e.val, has: true, fresh: true}
+	}
+	if e == nil || !e.pending || e.pendKey != key || e.pendVer != ver {
+		if r.want == nil {
+			r.want = make(map[int]asyncJob[K, V])
+		}
+		r.want[row] = asyncJob[K, V]{key: key, ver: ver, run: run}
+	}
+	if e == nil {
+		return asyncValue[K, V]{}
+	}
+	return asyncValue[K, V]{key: e.key, val: e.val, has: e.has}
+}
+
+// request turns wanted rows within [lo, hi] recorded at ver into one
+// command and drops other wants.
+func (r *asyncRows[K, V]) request(owner *asyncState, kind asyncKind, lo, hi int, ver uint64) tea.Cmd {
+	type rowJob struct {
+		row int
+		job asyncJob[K, V]
+	}
+	var jobs []rowJob
+	for row, job := range r.want {
+		if row < lo || row > hi || job.ver != ver {
+			continue
+		}
+		e := r.entry(row)
+		e.pending, e.pendKey, e.pendVer = true, job.key, ver
+		jobs = append(jobs, rowJob{row: row, job: job})
+	}
+	clear(r.want)
+	if len(jobs) == 0 {
+		return nil
+	}
+	slices.SortFunc(jobs, func(a, b rowJob) int { return a.row - b.row })
+
+	return func() tea.Msg {
+		msg := AsyncResultMsg{DocVersion: ver, owner: owner, kind: kind}
+		msg.results = make([]asyncResult, 0, len(jobs))
+		for _, j := range jobs {
+			msg.results = append(msg.results, asyncResult{row: j.row, key: j.job.key, val: j.job.run()})
+		}
+		return msg
+	}
+}
+
+func (r *asyncRows[K, V]) entry(row int) *asyncRow[K, V] {
+	if r.rows == nil {
+		r.rows = make(map[int]*asyncRow[K, V])
+	}
+	e := r.rows[row]
+	if e == nil {
+		e = &asyncRow[K, V]{}
+		r.rows[row] = e
+	}
+	return e
+}
+
+// accept stores results requested at the current text version.
+func (r *asyncRows[K, V]) accept(results []asyncResult, ver uint64) {
+	for _, res := range results {
+		key := res.key.(K)
+		e := r.entry(res.row)
+		if e.pending && e.pendKey == key && e.pendVer == ver {
+			e.pending = false
+		}
+		e.key, e.val, e.has, e.current = key, res.val.(V), true, true
+	}
+}
+
+// invalidate marks every result for re-request, keeping values for display.
+func (r *asyncRows[K, V]) invalidate() {
+	for _, e := range r.rows {
+		e.current = false
+		e.pending = false
+	}
+}
+
+// applyEdits moves results with their rows for sequentially applied edits.
+// Results for rows merged into an edit's first row are dropped.
+func (r *asyncRows[K, V]) applyEdits(edits []buffer.AppliedEdit) {
+	for _, e := range edits {
+		start, oldEnd := e.RangeBefore.Start.Row, e.RangeBefore.End.Row
+		delta := e.RangeAfter.End.Row - oldEnd
+		if start == oldEnd && delta == 0 {
+			continue
+		}
+		next := make(map[int]*asyncRow[K, V], len(r.rows))
+		for row, ent := range r.rows {
+			switch {
+			case row <= start:
+				next[row] = ent
+			case row > oldEnd:
+				next[row+delta] = ent
+			}
+		}
+		r.rows = next
+	}
+}
+
+// asyncEnabled reports whether kind runs asynchronously.
+func (m *Model) asyncEnabled(kind asyncKind) bool {
+	if m.async == nil {
+		return false
+	}
+	switch kind {
+	case asyncHighlight:
+		return m.cfg.Async.Highlighter
+	case asyncLinks:
+		return m.cfg.Async.Links
+	case asyncGhost:
+		return m.cfg.Async.Ghost
+	case asyncVirtualText:
+		return m.cfg.Async.VirtualText
+	}
+	return false
+}
+
+// requestAsyncRows queues requests wanted by the last render for logical
+// rows [lo, hi].
+func (m *Model) requestAsyncRows(lo, hi int) {
+	if m.async == nil || m.buf == nil {
+		return
+	}
+	a := m.async
+	ver := m.buf.TextVersion()
+	for _, cmd := range []tea.Cmd{
+		a.highlight.request(a, asyncHighlight, lo, hi, ver),
+		a.links.request(a, asyncLinks, lo, hi, ver),
+		a.ghost.request(a, asyncGhost, lo, hi, ver),
+		a.virtual.request(a, asyncVirtualText, lo, hi, ver),
+	} {
+		if cmd != nil {
+			a.queue = append(a.queue, cmd)
+		}
+	}
+}
+
+// syncAsyncRows moves async results with their rows after a text change.
+func (m *Model) syncAsyncRows(prevVersion uint64) {
+	if m.async == nil {
+		return
+	}
+	ch, ok := m.buf.LastChange()
+	if !ok || ch.VersionBefore != prevVersion {
+		return
+	}
+	a := m.async
+	a.highlight.applyEdits(ch.AppliedEdits)
+	a.links.applyEdits(ch.AppliedEdits)
+	a.ghost.applyEdits(ch.AppliedEdits)
+	a.virtual.applyEdits(ch.AppliedEdits)
+}
+
+// applyAsyncResult stores msg when it was requested at the current text
+// version and rerenders. Stale results are dropped; the rerender requests
+// their rows again.
+func (m *Model) applyAsyncResult(msg AsyncResultMsg) {
+	if m.buf == nil {
+		return
+	}
+	if ver := m.buf.TextVersion(); msg.DocVersion == ver {
+		a := m.async
+		switch msg.kind {
+		case asyncHighlight:
+			a.highlight.accept(msg.results, ver)
+		case asyncLinks:
+			a.links.accept(msg.results, ver)
+		case asyncGhost:
+			a.ghost.accept(msg.results, ver)
+		case asyncVirtualText:
+			a.virtual.accept(msg.results, ver)
+		}
+		a.generation++
+	}
+	m.rebuildContent()
+}
+
+// AsyncCmd returns a command for provider requests queued by renders outside
+// Update, e.g. after SetSize, Focus or an Invalidate call. Init and Update
+// return queued requests themselves. It returns nil when nothing is queued
+// or Config.Async selects no provider.
+func (m Model) AsyncCmd() tea.Cmd {
+	if m.async == nil || len(m.async.queue) == 0 {
+		return nil
+	}
+	cmds := m.async.queue
+	m.async.queue = nil
+	return tea.Batch(cmds...)
+}
+
+// rendersVisibleRowsOnly reports whether row content depends on the
+// viewport, so scrolling must rerender: highlights are computed only for
+// visible rows, and async results are requested only for them.
+func (m *Model) rendersVisibleRowsOnly() bool {
+	return m.cfg.Highlighter != nil || m.async != nil
+}
+
+func (m *Model) asyncHighlightSpans(ctx LineContext) []HighlightSpan {
+	h := m.cfg.Highlighter
+	key := highlightKey{
+		Text:                 ctx.Text,
+		RawText:              ctx.RawText,
+		CursorGraphemeCol:    ctx.CursorGraphemeCol,
+		RawCursorGraphemeCol: ctx.RawCursorGraphemeCol,
+		HasCursor:            ctx.HasCursor,
+	}
+	v := m.async.highlight.lookup(ctx.Row, key, m.buf.TextVersion(), func() []HighlightSpan {
+		spans, err := h.HighlightLine(ctx)
+		if err != nil {
+			return nil
+		}
+		return spans
+	})
+	// Stale spans are clamped to the line, so they render until replaced.
+	return v.val
+}
+
+func (m *Model) asyncLinkSpans(ctx LinkContext) []LinkSpan {
+	p := m.cfg.LinkProvider
+	key := ctx
+	key.DocVersion = 0
+	v := m.async.links.lookup(ctx.Row, key, m.buf.TextVersion(), func() []LinkSpan {
+		spans, err := p(ctx)
+		if err != nil {
+			return nil
+		}
+		return spans
+	})
+	if !v.has || v.key.RawText != ctx.RawText {
+		return nil
+	}
+	return v.val
+}
+
+func (m *Model) asyncVirtualText(ctx VirtualTextContext) VirtualText {
+	p := m.cfg.VirtualTextProvider
+	key := ctx
+	key.DocVersion = 0
+	v := m.async.virtual.lookup(ctx.Row, key, m.buf.TextVersion(), func() VirtualText {
+		return p(ctx)
+	})
+	if !v.has || v.key.LineText != ctx.LineText {
+		return VirtualText{}
+	}
+	return v.val
+}
+
+func (m *Model) asyncGhost(ctx GhostContext) (Ghost, bool) {
+	p := m.cfg.GhostProvider
+	key := ctx
+	key.DocVersion = 0
+	v := m.async.ghost.lookup(ctx.Row, key, m.buf.TextVersion(), func() ghostResult {
+		ghost, present := p(ctx)
+		ghost.Text = sanitizeGhostText(ghost.Text)
+		if present && ghost.Text == "" && len(ghost.Edits) == 0 {
+			present = false
+		}
+		return ghostResult{ghost: ghost, present: present}
+	})
+	if !v.fresh {
+		return Ghost{}, false
+	}
+	return v.val.ghost, v.val.present
+}
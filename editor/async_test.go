@@ -0,0 +1,242 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// runAsyncCmd runs cmd and any batched commands, returning the async results.
+func runAsyncCmd(cmd tea.Cmd) []AsyncResultMsg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		var out []AsyncResultMsg
+		for _, c := range msg {
+			out = append(out, runAsyncCmd(c)...)
+		}
+		return out
+	case AsyncResultMsg:
+		return []AsyncResultMsg{msg}
+	}
+	return nil
+}
+
+func deliverAsync(m Model, msgs []AsyncResultMsg) (Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, msg := range msgs {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func upperHighlighter(calls *int) *stubHighlighter {
+	upper := lipgloss.NewStyle().Transform(strings.ToUpper)
+	return &stubHighlighter{fn: func(ctx LineContext) ([]HighlightSpan, error) {
+		*calls++
+		return []HighlightSpan{{StartGraphemeCol: 0, EndGraphemeCol: len(ctx.Text), Style: upper}}, nil
+	}}
+}
+
+func TestAsyncHighlighter_RendersPlainUntilResultArrives(t *testing.T) {
+	calls := 0
+	m := New(Config{
+		Text:        "ab\ncd\nef\ngh",
+		Highlighter: upperHighlighter(&calls),
+		Async:       AsyncProviders{Highlighter: true},
+	})
+	m = m.SetSize(10, 2)
+	m = m.Blur()
+
+	if calls != 0 {
+		t.Fatalf("highlighter called during render: %d", calls)
+	}
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "ab") {
+		t.Fatalf("expected plain text before results:\n%s", got)
+	}
+
+	msgs := runAsyncCmd(m.Init())
+	if calls != 2 || len(msgs) != 1 {
+		t.Fatalf("expected one request covering 2 visible rows, got calls=%d msgs=%d", calls, len(msgs))
+	}
+	m, cmd := deliverAsync(m, msgs)
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "AB") || !strings.Contains(got, "CD") {
+		t.Fatalf("expected highlighted rows after results:\n%s", got)
+	}
+	if cmd != nil {
+		t.Fatalf("current results must not trigger new requests")
+	}
+	if m.AsyncCmd() != nil {
+		t.Fatalf("queue should be drained")
+	}
+}
+
+func TestAsyncHighlighter_KeepsResultsAcrossCursorMoves(t *testing.T) {
+	calls := 0
+	m := New(Config{
+		Text:        "ab\ncd",
+		Highlighter: upperHighlighter(&calls),
+		Async:       AsyncProviders{Highlighter: true},
+	})
+	m = m.SetSize(10, 2)
+
+	inFlight := runAsyncCmd(m.AsyncCmd())
+	m, moved := m.Update(testKeyCode(tea.KeyRight))
+	// Only the cursor row, whose highlight context changed, is requested again.
+	msgs := runAsyncCmd(moved)
+	if len(msgs) != 1 || len(msgs[0].results) != 1 || msgs[0].results[0].row != 0 {
+		t.Fatalf("expected a request for the cursor row only, got %+v", msgs)
+	}
+
+	m, retry := deliverAsync(m, inFlight)
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "CD") {
+		t.Fatalf("results requested before a cursor move should be kept:\n%s", got)
+	}
+	if runAsyncCmd(retry) != nil {
+		t.Fatalf("kept results must not be requested again")
+	}
+}
+
+func TestAsyncHighlighter_DiscardsStaleResults(t *testing.T) {
+	calls := 0
+	m := New(Config{
+		Text:        "ab",
+		Highlighter: upperHighlighter(&calls),
+		Async:       AsyncProviders{Highlighter: true},
+	})
+	m = m.SetSize(10, 1)
+
+	stale := runAsyncCmd(m.AsyncCmd())
+	m, typed := m.Update(testKeyText("x"))
+	if len(stale) != 1 || stale[0].DocVersion == m.Buffer().TextVersion() {
+		t.Fatalf("expected a result for the previous version")
+	}
+
+	m, retry := deliverAsync(m, stale)
+	if got := stripANSI(m.View().Content); strings.Contains(got, "AB") {
+		t.Fatalf("stale result must be discarded:\n%s", got)
+	}
+
+	msgs := runAsyncCmd(tea.Batch(typed, retry))
+	if len(msgs) == 0 {
+		t.Fatalf("expected the row to be requested again")
+	}
+	m, _ = deliverAsync(m, msgs)
+	m = m.Blur()
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "XAB") {
+		t.Fatalf("expected current result to render:\n%s", got)
+	}
+}
+
+func TestAsyncHighlighter_KeepsStaleSpansWhileRequesting(t *testing.T) {
+	calls := 0
+	m := New(Config{
+		Text:        "ab",
+		Highlighter: upperHighlighter(&calls),
+		Async:       AsyncProviders{Highlighter: true},
+	})
+	m = m.SetSize(10, 1)
+	m, _ = deliverAsync(m, runAsyncCmd(m.AsyncCmd()))
+
+	m, cmd := m.Update(testKeyText("x"))
+	if cmd == nil {
+		t.Fatalf("edit should request the changed row")
+	}
+	// The previous spans cover "ab"; the last grapheme is not yet highlighted.
+	m = m.Blur()
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "XAb") {
+		t.Fatalf("expected stale spans while the request is pending:\n%s", got)
+	}
+}
+
+func TestAsyncResultMsg_IgnoredByOtherEditors(t *testing.T) {
+	calls := 0
+	cfg := Config{
+		Text:        "ab",
+		Highlighter: upperHighlighter(&calls),
+		Async:       AsyncProviders{Highlighter: true},
+	}
+	a := New(cfg).SetSize(10, 1)
+	b := New(cfg).SetSize(10, 1)
+
+	b, _ = deliverAsync(b, runAsyncCmd(a.AsyncCmd()))
+	if got := stripANSI(b.View().Content); strings.Contains(got, "AB") {
+		t.Fatalf("editor accepted another editor's results:\n%s", got)
+	}
+}
+
+func TestAsyncVirtualText_RequestsVisibleRowsOnly(t *testing.T) {
+	var rows []int
+	m := New(Config{
+		Text: "ab\ncd\nef\ngh",
+		VirtualTextProvider: func(ctx VirtualTextContext) VirtualText {
+			rows = append(rows, ctx.Row)
+			return VirtualText{Deletions: []VirtualDeletion{{StartGraphemeCol: 0, EndGraphemeCol: 1}}}
+		},
+		Async: AsyncProviders{VirtualText: true},
+	})
+	m = m.SetSize(10, 2)
+	m = m.Blur()
+
+	m, _ = deliverAsync(m, runAsyncCmd(m.AsyncCmd()))
+	if len(rows) != 2 || rows[0] != 0 || rows[1] != 1 {
+		t.Fatalf("requested rows: %v", rows)
+	}
+	got := stripANSI(m.View().Content)
+	if !strings.HasPrefix(got, "b") || !strings.Contains(got, "\nd") {
+		t.Fatalf("expected deletions on visible rows:\n%q", got)
+	}
+
+	m = m.Focus()
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	m, cmd := m.Update(testKeyCode(tea.KeyDown))
+	rows = nil
+	m, _ = deliverAsync(m, runAsyncCmd(cmd))
+	if len(rows) == 0 || rows[len(rows)-1] != 2 {
+		t.Fatalf("scrolling should request the newly visible row, got %v", rows)
+	}
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "f") || strings.Contains(got, "e") {
+		t.Fatalf("expected deletion on the scrolled-in row:\n%q", got)
+	}
+}
+
+func TestAsyncGhost_AcceptedOnlyWhenCurrent(t *testing.T) {
+	m := New(Config{
+		Text: "fo",
+		GhostProvider: func(ctx GhostContext) (Ghost, bool) {
+			if !ctx.IsEndOfLine {
+				return Ghost{}, false
+			}
+			at := buffer.Pos{Row: ctx.Row, GraphemeCol: ctx.GraphemeCol}
+			return Ghost{Text: "o", Edits: []buffer.TextEdit{{Range: buffer.Range{Start: at, End: at}, Text: "o"}}}, true
+		},
+		Async: AsyncProviders{Ghost: true},
+	})
+	m = m.SetSize(10, 1)
+	m, cmd := m.Update(testKeyCode(tea.KeyEnd))
+
+	if _, ok := m.ghostForCursor(); ok {
+		t.Fatalf("ghost must not be available before its result arrives")
+	}
+	if got := stripANSI(m.View().Content); strings.Contains(got, "fo o") {
+		t.Fatalf("ghost rendered before its result arrived:\n%s", got)
+	}
+
+	m, _ = deliverAsync(m, runAsyncCmd(cmd))
+	// The EOL cursor cell precedes the ghost text.
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "fo o") {
+		t.Fatalf("expected ghost after result:\n%s", got)
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if got := m.Buffer().Text(); got != "foo" {
+		t.Fatalf("ghost accept: %q", got)
+	}
+}
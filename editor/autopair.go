@@ -0,0 +1,250 @@
+package editor
+
+import (
+	"github.com/iw2rmb/flourish/buffer"
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// AutoPair is an opener/closer pair inserted together while typing.
+//
+// Open and Close must each be a single grapheme cluster. They may be equal for
+// symmetric pairs such as quotes.
+type AutoPair struct {
+	Open  string
+	Close string
+}
+
+// DefaultAutoPairs returns bracket and quote pairs suitable for most code.
+func DefaultAutoPairs() []AutoPair {
+	return []AutoPair{
+		{Open: "(", Close: ")"},
+		{Open: "[", Close: "]"},
+		{Open: "{", Close: "}"},
+		{Open: `"`, Close: `"`},
+		{Open: "'", Close: "'"},
+		{Open: "`", Close: "`"},
+	}
+}
+
+func normalizeAutoPairs(pairs []AutoPair) []AutoPair {
+	if len(pairs) == 0 {
+		return nil
+	}
+	out := make([]AutoPair, 0, len(pairs))
+	for _, p := range pairs {
+		if graphemeutil.Count(p.Open) != 1 || graphemeutil.Count(p.Close) != 1 {
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// autoClosedCloser records a closer inserted by auto-pairing. It is located by
+// distance from the line end so typing inside the pair keeps it addressable.
+type autoClosedCloser struct {
+	row     int
+	fromEnd int
+	close   string
+}
+
+// autoPairAction is a pair-aware replacement for a plain typing or backspace
+// intent.
+type autoPairAction struct {
+	kind    IntentKind
+	payload any
+	op      localMutationOp
+}
+
+// autoPairForText resolves pair behavior for typed text: skipping over an
+// auto-inserted closer, wrapping the selection, or inserting a closed pair.
+func (m *Model) autoPairForText(text string) (autoPairAction, bool) {
+	if len(m.cfg.AutoPairs) == 0 || m.buf == nil || graphemeutil.Count(text) != 1 {
+		return autoPairAction{}, false
+	}
+
+	if sel, ok := m.buf.Selection(); ok {
+		pair, ok := m.autoPairForOpen(text)
+		if !ok {
+			return autoPairAction{}, false
+		}
+		return surroundSelectionAction(sel, pair), true
+	}
+
+	cur := m.buf.Cursor()
+	rawLine, ok := m.docLine(cur.Row)
+	if !ok {
+		return autoPairAction{}, false
+	}
+	line := graphemeutil.Split(rawLine)
+	col := clampInt(cur.GraphemeCol, 0, len(line))
+
+	if keep, ok := m.autoClosedAt(cur.Row, line, col, text); ok {
+		move := buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirRight}
+		return autoPairAction{
+			kind:    IntentMove,
+			payload: MoveIntentPayload{Move: move},
+			op: func(mm *Model) {
+				mm.buf.Move(move)
+				mm.autoClosed = keep
+			},
+		}, true
+	}
+
+	pair, ok := m.autoPairForOpen(text)
+	if !ok || !m.autoCloseAllowed(line, col, pair) {
+		return autoPairAction{}, false
+	}
+
+	at := buffer.Pos{Row: cur.Row, GraphemeCol: col}
+	// Apply leaves the cursor after the last edit, i.e. between the pair.
+	edits := []buffer.TextEdit{
+		{Range: buffer.Range{Start: at, End: at}, Text: pair.Close},
+		{Range: buffer.Range{Start: at, End: at}, Text: pair.Open},
+	}
+	tracked := append(m.autoClosedOnRow(cur.Row), autoClosedCloser{
+		row:     cur.Row,
+		fromEnd: len(line) - col + 1,
+		close:   pair.Close,
+	})
+	return autoPairAction{
+		kind:    IntentInsert,
+		payload: InsertIntentPayload{Text: text, Edits: cloneTextEdits(edits)},
+		op: func(mm *Model) {
+			mm.buf.Apply(edits...)
+			mm.autoClosed = tracked
+		},
+	}, true
+}
+
+// autoPairForBackspace deletes both halves when the cursor sits between an
+// empty pair whose closer was auto-inserted.
+func (m *Model) autoPairForBackspace() (autoPairAction, bool) {
+	if len(m.cfg.AutoPairs) == 0 || m.buf == nil {
+		return autoPairAction{}, false
+	}
+	if _, ok := m.buf.Selection(); ok {
+		return autoPairAction{}, false
+	}
+
+	cur := m.buf.Cursor()
+	rawLine, ok := m.docLine(cur.Row)
+	if !ok {
+		return autoPairAction{}, false
+	}
+	line := graphemeutil.Split(rawLine)
+	col := cur.GraphemeCol
+	if col <= 0 || col >= len(line) {
+		return autoPairAction{}, false
+	}
+	keep, ok := m.autoClosedAt(cur.Row, line, col, line[col])
+	if !ok {
+		return autoPairAction{}, false
+	}
+	for _, p := range m.cfg.AutoPairs {
+		if line[col-1] != p.Open || line[col] != p.Close {
+			continue
+		}
+		edits := []buffer.TextEdit{{
+			Range: buffer.Range{
+				Start: buffer.Pos{Row: cur.Row, GraphemeCol: col - 1},
+				End:   buffer.Pos{Row: cur.Row, GraphemeCol: col + 1},
+			},
+		}}
+		return autoPairAction{
+			kind:    IntentDelete,
+			payload: DeleteIntentPayload{Direction: DeleteBackward, Edits: cloneTextEdits(edits)},
+			op: func(mm *Model) {
+				mm.buf.Apply(edits...)
+				mm.autoClosed = keep
+			},
+		}, true
+	}
+	return autoPairAction{}, false
+}
+
+func surroundSelectionAction(sel buffer.Range, pair AutoPair) autoPairAction {
+	edits := []buffer.TextEdit{
+		{Range: buffer.Range{Start: sel.End, End: sel.End}, Text: pair.Close},
+		{Range: buffer.Range{Start: sel.Start, End: sel.Start}, Text: pair.Open},
+	}
+	inner := buffer.Range{
+		Start: buffer.Pos{Row: sel.Start.Row, GraphemeCol: sel.Start.GraphemeCol + 1},
+		End:   sel.End,
+	}
+	if sel.End.Row == sel.Start.Row {
+		inner.End.GraphemeCol++
+	}
+	return autoPairAction{
+		kind:    IntentInsert,
+		payload: InsertIntentPayload{Text: pair.Open, Edits: cloneTextEdits(edits)},
+		op: func(mm *Model) {
+			mm.buf.Apply(edits...)
+			mm.buf.SetCursor(inner.End)
+			mm.buf.SetSelection(inner)
+		},
+	}
+}
+
+func (m *Model) autoPairForOpen(text string) (AutoPair, bool) {
+	for _, p := range m.cfg.AutoPairs {
+		if p.Open == text {
+			return p, true
+		}
+	}
+	return AutoPair{}, false
+}
+
+// autoCloseAllowed keeps auto-closing out of the way of existing text: the
+// next grapheme must be end of line, whitespace, or a closer, and symmetric
+// pairs do not close directly after a word grapheme (e.g. apostrophes).
+func (m *Model) autoCloseAllowed(line []string, col int, pair AutoPair) bool {
+	if col < len(line) && !graphemeutil.IsSpace(line[col]) && !m.isAutoPairCloser(line[col]) {
+		return false
+	}
+	if pair.Open == pair.Close && col > 0 {
+		prev := line[col-1]
+		if prev == pair.Open || (!graphemeutil.IsSpace(prev) && !graphemeutil.IsPunct(prev)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Model) isAutoPairCloser(g string) bool {
+	for _, p := range m.cfg.AutoPairs {
+		if p.Close == g {
+			return true
+		}
+	}
+	return false
+}
+
+// autoClosedAt reports whether the closer text at col was auto-inserted and
+// is still tracked. keep is the tracked list without that closer and any
+// nested inside it, for the local mutation that consumes it to store.
+func (m *Model) autoClosedAt(row int, line []string, col int, text string) (keep []autoClosedCloser, ok bool) {
+	if col >= len(line) || line[col] != text {
+		return nil, false
+	}
+	tracked := m.autoClosedOnRow(row)
+	for i := len(tracked) - 1; i >= 0; i-- {
+		c := tracked[i]
+		if c.close == text && len(line)-c.fromEnd == col {
+			return tracked[:i:i], true
+		}
+	}
+	return nil, false
+}
+
+// autoClosedOnRow returns tracked closers still on row. Moving to another row
+// forgets them.
+func (m *Model) autoClosedOnRow(row int) []autoClosedCloser {
+	if len(m.autoClosed) == 0 || m.autoClosed[0].row != row {
+		return nil
+	}
+	return m.autoClosed[:len(m.autoClosed):len(m.autoClosed)]
+}
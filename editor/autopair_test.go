@@ -0,0 +1,237 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func typeText(m Model, text string) Model {
+	for _, r := range text {
+		m, _ = m.Update(testKeyText(string(r)))
+	}
+	return m
+}
+
+func TestAutoPair_DisabledByDefault(t *testing.T) {
+	m := New(Config{})
+	m = typeText(m, "(")
+	if got, want := m.buf.Text(), "("; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_OpenerInsertsCloserAndCloserSkips(t *testing.T) {
+	m := New(Config{AutoPairs: DefaultAutoPairs()})
+
+	m = typeText(m, "f(")
+	if got, want := m.buf.Text(), "f()"; got != want {
+		t.Fatalf("text after opener: got %q, want %q", got, want)
+	}
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 0, GraphemeCol: 2}); got != want {
+		t.Fatalf("cursor after opener: got %v, want %v", got, want)
+	}
+
+	m = typeText(m, "[x])")
+	if got, want := m.buf.Text(), "f([x])"; got != want {
+		t.Fatalf("text after closers: got %q, want %q", got, want)
+	}
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 0, GraphemeCol: 6}); got != want {
+		t.Fatalf("cursor after closers: got %v, want %v", got, want)
+	}
+
+	// A closer typed without a tracked auto-inserted closer is inserted.
+	m = typeText(m, ")")
+	if got, want := m.buf.Text(), "f([x]))"; got != want {
+		t.Fatalf("text after extra closer: got %q, want %q", got, want)
+	}
+
+	// The whole pair is one undo step.
+	m = New(Config{AutoPairs: DefaultAutoPairs()})
+	m = typeText(m, "(")
+	m, _ = m.Update(testKeyCode('z', tea.ModCtrl))
+	if got := m.buf.Text(); got != "" {
+		t.Fatalf("text after undo: got %q, want empty", got)
+	}
+}
+
+func TestAutoPair_SkipsBeforeWordAndAfterWordForQuotes(t *testing.T) {
+	m := New(Config{Text: "x", AutoPairs: DefaultAutoPairs()})
+	m = typeText(m, "(")
+	if got, want := m.buf.Text(), "(x"; got != want {
+		t.Fatalf("opener before word: got %q, want %q", got, want)
+	}
+
+	m = New(Config{Text: "don", AutoPairs: DefaultAutoPairs()})
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 3})
+	m = typeText(m, "'")
+	if got, want := m.buf.Text(), "don'"; got != want {
+		t.Fatalf("quote after word: got %q, want %q", got, want)
+	}
+
+	m = New(Config{AutoPairs: DefaultAutoPairs()})
+	m = typeText(m, `"a"`)
+	if got, want := m.buf.Text(), `"a"`; got != want {
+		t.Fatalf("typed quoted string: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_BackspaceDeletesEmptyPair(t *testing.T) {
+	m := New(Config{AutoPairs: DefaultAutoPairs()})
+	m = typeText(m, "a{")
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	if got, want := m.buf.Text(), "a"; got != want {
+		t.Fatalf("text after backspace: got %q, want %q", got, want)
+	}
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 0, GraphemeCol: 1}); got != want {
+		t.Fatalf("cursor after backspace: got %v, want %v", got, want)
+	}
+
+	m = New(Config{Text: "(x)", AutoPairs: DefaultAutoPairs()})
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 2})
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	if got, want := m.buf.Text(), "()"; got != want {
+		t.Fatalf("non-empty pair backspace: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_SurroundsSelection(t *testing.T) {
+	m := New(Config{Text: "say hi", AutoPairs: DefaultAutoPairs()})
+	m.buf.SetSelection(buffer.Range{
+		Start: buffer.Pos{Row: 0, GraphemeCol: 4},
+		End:   buffer.Pos{Row: 0, GraphemeCol: 6},
+	})
+
+	m = typeText(m, `"`)
+	if got, want := m.buf.Text(), `say "hi"`; got != want {
+		t.Fatalf("text after surround: got %q, want %q", got, want)
+	}
+	sel, ok := m.buf.Selection()
+	want := buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 5}, End: buffer.Pos{Row: 0, GraphemeCol: 7}}
+	if !ok || sel != want {
+		t.Fatalf("selection after surround: got %v ok=%v, want %v", sel, ok, want)
+	}
+
+	// Non-openers still replace the selection.
+	m = typeText(m, "x")
+	if got, want := m.buf.Text(), `say "x"`; got != want {
+		t.Fatalf("text after replacing selection: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_CustomPairs(t *testing.T) {
+	m := New(Config{AutoPairs: []AutoPair{{Open: "*", Close: "*"}, {Open: "<<", Close: ">>"}}})
+	if got, want := len(m.cfg.AutoPairs), 1; got != want {
+		t.Fatalf("normalized pairs: got %d, want %d", got, want)
+	}
+	m = typeText(m, "*b*(")
+	if got, want := m.buf.Text(), "*b*("; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_BackspaceKeepsTypedPairs(t *testing.T) {
+	m := New(Config{Text: "()", AutoPairs: DefaultAutoPairs()})
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 1})
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	if got, want := m.buf.Text(), ")"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_ReadOnlyIgnoresPairs(t *testing.T) {
+	m := New(Config{Text: "()", ReadOnly: true, AutoPairs: DefaultAutoPairs()})
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 1})
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	m = typeText(m, "(")
+	if got, want := m.buf.Text(), "()"; got != want {
+		t.Fatalf("text: got %q, want %q", got, want)
+	}
+}
+
+func TestAutoPair_IntentModesEmitEdits(t *testing.T) {
+	var intents []Intent
+	m := New(Config{
+		Text:         "a",
+		AutoPairs:    DefaultAutoPairs(),
+		MutationMode: EmitIntentsOnly,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			intents = append(intents, batch.Intents...)
+			return IntentDecision{}
+		},
+	})
+
+	m = typeText(m, "(")
+	if got, want := m.buf.Text(), "a"; got != want {
+		t.Fatalf("text in intents-only mode: got %q, want %q", got, want)
+	}
+	if len(intents) != 1 || intents[0].Kind != IntentInsert {
+		t.Fatalf("intents: got %+v", intents)
+	}
+	// Text "a" follows the cursor, so the opener is a plain insert.
+	if got, want := intents[0].Payload, (InsertIntentPayload{Text: "("}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("payload before word: got %+v, want %+v", got, want)
+	}
+
+	m = New(Config{
+		AutoPairs:    DefaultAutoPairs(),
+		MutationMode: EmitIntentsOnly,
+		OnIntent:     func(IntentBatch) IntentDecision { return IntentDecision{} },
+	})
+	m = typeText(m, "(")
+	if len(m.autoClosed) != 0 {
+		t.Fatalf("a refused insert should not track its closer: %+v", m.autoClosed)
+	}
+
+	m = New(Config{
+		AutoPairs:    DefaultAutoPairs(),
+		MutationMode: EmitIntentsAndMutate,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			intents = append(intents, batch.Intents...)
+			return IntentDecision{ApplyLocally: true}
+		},
+	})
+	m = typeText(m, "(")
+	intents = nil
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	if got := m.buf.Text(); got != "" {
+		t.Fatalf("text after pair delete: got %q, want empty", got)
+	}
+	if len(intents) != 1 || intents[0].Kind != IntentDelete {
+		t.Fatalf("delete intents: got %+v", intents)
+	}
+	wantDelete := DeleteIntentPayload{Direction: DeleteBackward, Edits: []buffer.TextEdit{{
+		Range: buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 0}, End: buffer.Pos{Row: 0, GraphemeCol: 2}},
+	}}}
+	if !reflect.DeepEqual(intents[0].Payload, wantDelete) {
+		t.Fatalf("delete payload: got %+v, want %+v", intents[0].Payload, wantDelete)
+	}
+
+	intents = nil
+	m = New(Config{
+		AutoPairs:    DefaultAutoPairs(),
+		MutationMode: EmitIntentsAndMutate,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			intents = append(intents, batch.Intents...)
+			return IntentDecision{ApplyLocally: true}
+		},
+	})
+	m = typeText(m, "[]")
+	if got, want := m.buf.Text(), "[]"; got != want {
+		t.Fatalf("text after pair and skip: got %q, want %q", got, want)
+	}
+	if len(intents) != 2 || intents[0].Kind != IntentInsert || intents[1].Kind != IntentMove {
+		t.Fatalf("pair/skip intents: got %+v", intents)
+	}
+	at := buffer.Pos{Row: 0, GraphemeCol: 0}
+	wantInsert := InsertIntentPayload{Text: "[", Edits: []buffer.TextEdit{
+		{Range: buffer.Range{Start: at, End: at}, Text: "]"},
+		{Range: buffer.Range{Start: at, End: at}, Text: "["},
+	}}
+	if !reflect.DeepEqual(intents[0].Payload, wantInsert) {
+		t.Fatalf("insert payload: got %+v, want %+v", intents[0].Payload, wantInsert)
+	}
+}
@@ -0,0 +1,128 @@
+package editor
+
+import "github.com/iw2rmb/flourish/buffer"
+
+// BracketSkipper is an optional Highlighter extension. Brackets for which
+// SkipBracket returns true (for example inside strings or comments) are
+// ignored by bracket matching.
+type BracketSkipper interface {
+	SkipBracket(row int, rawLine string, graphemeCol int) bool
+}
+
+// bracketMatchMaxRows bounds the scan for the cursor bracket match highlight.
+const bracketMatchMaxRows = 2000
+
+type bracketMatch struct {
+	at    buffer.Pos
+	match buffer.Pos
+	ok    bool
+}
+
+func (m *Model) bracketOptions() buffer.BracketOptions {
+	opt := buffer.BracketOptions{Pairs: m.cfg.BracketPairs}
+	if s, ok := m.cfg.Highlighter.(BracketSkipper); ok {
+		opt.Skip = func(p buffer.Pos) bool {
+			line, ok := m.docLine(p.Row)
+			return ok && s.SkipBracket(p.Row, line, p.GraphemeCol)
+		}
+	}
+	return opt
+}
+
+// computeBracketMatch resolves the highlighted bracket pair for the cursor.
+// It is skipped when Style.MatchingBracket is unset.
+func (m *Model) computeBracketMatch() bracketMatch {
+	if m.buf == nil || isLipglossZero(m.cfg.Style.MatchingBracket) {
+		return bracketMatch{}
+	}
+	opt := m.bracketOptions()
+	opt.MaxRows = bracketMatchMaxRows
+	at, match, ok := m.buf.MatchBracket(m.buf.Cursor(), opt)
+	return bracketMatch{at: at, match: match, ok: ok}
+}
+
+// addBracketMatchDirtyRows marks rows of the previous and current bracket
+// match for re-rendering.
+func (m *Model) addBracketMatchDirtyRows(dirty map[int]struct{}, lineCount int) {
+	for _, bm := range [2]bracketMatch{m.lastBracketMatch, m.bracketMatch} {
+		if !bm.ok {
+			continue
+		}
+		addDirtyRow(dirty, lineCount, bm.at.Row)
+		addDirtyRow(dirty, lineCount, bm.match.Row)
+	}
+}
+
+// withBracketMatchSpans appends Style.MatchingBracket spans for the matched
+// brackets on row. highlights is not modified.
+func (m *Model) withBracketMatchSpans(row int, line *wrapLayoutLine, highlights []HighlightSpan) []HighlightSpan {
+	bm := m.bracketMatch
+	if !m.focused || !bm.ok || (row != bm.at.Row && row != bm.match.Row) {
+		return highlights
+	}
+	if !line.visibleInfoComputed {
+		line.visibleInfo = computeVisibleLineInfo(line.rawLine, line.vt)
+		line.visibleInfoComputed = true
+	}
+	vi := line.visibleInfo
+	out := highlights[:len(highlights):len(highlights)]
+	for _, p := range [2]buffer.Pos{bm.at, bm.match} {
+		col := p.GraphemeCol
+		if p.Row != row || col < 0 || col+1 >= len(vi.rawToVisible) {
+			continue
+		}
+		start, end := vi.rawToVisible[col], vi.rawToVisible[col+1]
+		if start >= end {
+			// Hidden by a virtual deletion.
+			continue
+		}
+		out = append(out, HighlightSpan{
+			StartGraphemeCol: start,
+			EndGraphemeCol:   end,
+			Style:            m.cfg.Style.MatchingBracket,
+		})
+	}
+	return out
+}
+
+// JumpToMatchingBracket moves the cursor onto the bracket matching the one
+// adjacent to the cursor (the grapheme at the cursor first, then the one
+// before it) and scrolls it into view. ok is false when there is no match.
+//
+// Matching uses Config.BracketPairs and honors a BracketSkipper highlighter.
+func (m Model) JumpToMatchingBracket() (Model, bool) {
+	if m.buf == nil {
+		return m, false
+	}
+	_, match, ok := m.buf.MatchBracket(m.buf.Cursor(), m.bracketOptions())
+	if !ok {
+		return m, false
+	}
+	m.buf.ClearSelection()
+	m.buf.SetCursor(match)
+	m.syncFromBuffer()
+	m.followCursorWithForce(true)
+	return m, true
+}
+
+// SelectBracketContents selects the text between the innermost bracket pair
+// enclosing the cursor, excluding the brackets. The cursor is placed at the
+// end of the contents. ok is false when no pair encloses the cursor.
+func (m Model) SelectBracketContents() (Model, bool) {
+	if m.buf == nil {
+		return m, false
+	}
+	open, close, ok := m.buf.EnclosingBrackets(m.buf.Cursor(), m.bracketOptions())
+	if !ok {
+		return m, false
+	}
+	inner := buffer.Range{
+		Start: buffer.Pos{Row: open.Row, GraphemeCol: open.GraphemeCol + 1},
+		End:   close,
+	}
+	m.buf.SetCursor(inner.End)
+	m.buf.SetSelection(inner)
+	m.syncFromBuffer()
+	m.followCursorWithForce(true)
+	return m, true
+}
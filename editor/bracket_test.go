@@ -0,0 +1,121 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+type skipQuotedHighlighter struct{}
+
+func (skipQuotedHighlighter) HighlightLine(LineContext) ([]HighlightSpan, error) { return nil, nil }
+
+// SkipBracket ignores graphemes between double quotes on the line.
+func (skipQuotedHighlighter) SkipBracket(_ int, rawLine string, col int) bool {
+	inString := false
+	for i, r := range []rune(rawLine) {
+		if i == col {
+			return inString
+		}
+		if r == '"' {
+			inString = !inString
+		}
+	}
+	return false
+}
+
+func newBracketTestModel(text string, cfg Config) Model {
+	cfg.Text = text
+	cfg.Style = DefaultStyle()
+	cfg.Style.MatchingBracket = lipgloss.NewStyle().Transform(func(s string) string { return "<" + s + ">" })
+	return New(cfg).SetSize(30, 4)
+}
+
+func TestBracketMatch_HighlightsAdjacentPair(t *testing.T) {
+	m := newBracketTestModel("f(x)\n{\n}", Config{})
+
+	if view := stripANSI(m.View().Content); strings.Contains(view, "<") {
+		t.Fatalf("unexpected highlight without adjacent bracket:\n%s", view)
+	}
+
+	// The bracket under the cursor keeps the cursor style.
+	m, _ = m.Update(testKeyCode(tea.KeyRight))
+	view := stripANSI(m.View().Content)
+	if !strings.Contains(view, "f(x<)>") {
+		t.Fatalf("expected highlighted pair:\n%s", view)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	view = stripANSI(m.View().Content)
+	if strings.Contains(view, "<)>") || !strings.Contains(view, "<{>") || !strings.Contains(view, "<}>") {
+		t.Fatalf("expected highlight to move to the multi-row pair:\n%s", view)
+	}
+
+	m = m.Blur()
+	if view := stripANSI(m.View().Content); strings.Contains(view, "<{>") {
+		t.Fatalf("unexpected highlight while blurred:\n%s", view)
+	}
+}
+
+func TestBracketMatch_DisabledWithoutStyle(t *testing.T) {
+	m := New(Config{Text: "()"})
+	m.cfg.Style.MatchingBracket = lipgloss.Style{}
+	m.syncFromBuffer()
+	if bm := m.computeBracketMatch(); bm.ok {
+		t.Fatalf("expected no match computation without MatchingBracket style")
+	}
+}
+
+func TestBracketMatch_HighlighterSkipsStrings(t *testing.T) {
+	m := newBracketTestModel(`(")")`, Config{Highlighter: skipQuotedHighlighter{}})
+
+	view := stripANSI(m.View().Content)
+	if !strings.Contains(view, `(")"<)>`) {
+		t.Fatalf("expected quoted closer to be skipped:\n%s", view)
+	}
+}
+
+func TestJumpToMatchingBracket(t *testing.T) {
+	m := New(Config{Text: "a(b\nc)d", BracketPairs: []buffer.BracketPair{{Open: "(", Close: ")"}}})
+	m = m.SetSize(20, 3)
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 1})
+
+	m, ok := m.JumpToMatchingBracket()
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 1, GraphemeCol: 1}); !ok || got != want {
+		t.Fatalf("jump: got %v ok=%v, want %v", got, ok, want)
+	}
+	m, ok = m.JumpToMatchingBracket()
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 0, GraphemeCol: 1}); !ok || got != want {
+		t.Fatalf("jump back: got %v ok=%v, want %v", got, ok, want)
+	}
+
+	m.buf.SetCursor(buffer.Pos{Row: 1, GraphemeCol: 3})
+	if _, ok := m.JumpToMatchingBracket(); ok {
+		t.Fatalf("expected no match away from brackets")
+	}
+}
+
+func TestSelectBracketContents(t *testing.T) {
+	m := New(Config{Text: "call(a, [b], c)"})
+	m = m.SetSize(30, 3)
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 6})
+
+	m, ok := m.SelectBracketContents()
+	want := buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 5}, End: buffer.Pos{Row: 0, GraphemeCol: 14}}
+	if sel, selOK := m.buf.Selection(); !ok || !selOK || sel != want {
+		t.Fatalf("selection: got %v ok=%v, want %v", sel, ok, want)
+	}
+	if got := m.buf.Cursor(); got != want.End {
+		t.Fatalf("cursor: got %v, want %v", got, want.End)
+	}
+
+	m.buf.ClearSelection()
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 2})
+	if _, ok := m.SelectBracketContents(); ok {
+		t.Fatalf("expected no enclosing pair outside brackets")
+	}
+}
@@ -0,0 +1,273 @@
+package editor
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+// ChordTracker matches key presses against bindings whose keys may be
+// chords: space-separated key sequences such as "ctrl+k ctrl+c". The
+// editor tracks its own key maps and Bindings with one; wrappers such as
+// the emacs package track theirs with another, so prefixes, timeouts and
+// PendingChord behave the same in both.
+//
+// The zero value is ready to use.
+type ChordTracker struct {
+	// keys are the leading keys typed so far.
+	keys []string
+	// id identifies the pending prefix for its timeout tick.
+	id uint64
+}
+
+// chordTimeoutMsg cancels the pending chord it was scheduled for.
+type chordTimeoutMsg struct {
+	id uint64
+}
+
+// chordIDs issues chord ids unique across trackers, so a tick only cancels
+// the prefix it was scheduled for.
+var chordIDs atomic.Uint64
+
+// PendingChord returns the leading keys of a partially typed chord (for
+// example "ctrl+k"), or "" when none is pending, for display in a status
+// line.
+func (m Model) PendingChord() string { return m.chord.Pending() }
+
+// Pending returns the leading keys of a partially typed chord, or "" when
+// none is pending.
+func (c ChordTracker) Pending() string { return strings.Join(c.keys, " ") }
+
+// Reset drops a pending chord.
+func (c *ChordTracker) Reset() { *c = ChordTracker{} }
+
+// Expire reports whether msg is the timeout tick of the pending chord, and
+// drops the chord if so. Ticks of other trackers, and stale ones, report
+// false.
+func (c *ChordTracker) Expire(msg tea.Msg) bool {
+	t, ok := msg.(chordTimeoutMsg)
+	if !ok || len(c.keys) == 0 || t.id != c.id {
+		return false
+	}
+	c.keys = nil
+	return true
+}
+
+// Resolve tracks chords across key presses. ok reports that msg should be
+// handled as a key press, with typed set to the key or the chord it
+// completes (see ChordMatches). Otherwise msg was consumed: it extended the
+// pending prefix (cmd then schedules the timeout when timeout is positive),
+// or it broke a pending prefix, which drops the whole sequence.
+//
+// A key bound on its own completes at once, even if it also starts longer
+// chords; see KeyMap.Conflicts.
+func (c *ChordTracker) Resolve(msg tea.KeyPressMsg, timeout time.Duration, bindings []key.Binding) (typed string, cmd tea.Cmd, ok bool) {
+	seq := append(slices.Clone(c.keys), msg.String())
+	pending := len(c.keys) > 0
+	c.keys = nil
+
+	exact, prefix := false, false
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		for _, k := range b.Keys() {
+			keys := strings.Fields(k)
+			switch {
+			case slices.Equal(keys, seq):
+				exact = true
+			case len(keys) > len(seq) && slices.Equal(keys[:len(seq)], seq):
+				prefix = true
+			}
+		}
+	}
+
+	switch {
+	case exact:
+		// A complete binding wins over longer chords it starts.
+	case prefix:
+		c.keys = seq
+		c.id = chordIDs.Add(1)
+		if timeout <= 0 {
+			return "", nil, false
+		}
+		id := c.id
+		return "", tea.Tick(timeout, func(time.Time) tea.Msg {
+			return chordTimeoutMsg{id: id}
+		}), false
+	case pending:
+		return "", nil, false
+	}
+	return strings.Join(seq, " "), nil, true
+}
+
+// ChordMatches reports whether typed, as returned by ChordTracker.Resolve,
+// triggers b.
+func ChordMatches(b key.Binding, typed string) bool {
+	if !b.Enabled() {
+		return false
+	}
+	for _, k := range b.Keys() {
+		if strings.Join(strings.Fields(k), " ") == typed {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveChord resolves msg against the editor's bindings, setting
+// m.chordTyped when it is to be handled as a key press.
+func (m *Model) resolveChord(msg tea.KeyPressMsg) (tea.Cmd, bool) {
+	typed, cmd, ok := m.chord.Resolve(msg, m.cfg.ChordTimeout, m.chordBindings())
+	m.chordTyped = typed
+	return cmd, ok
+}
+
+func (m *Model) chordBindings() []key.Binding {
+	out := append(m.cfg.KeyMap.bindings(), m.cfg.CompletionKeyMap.bindings()...)
+	for _, b := range m.bindings {
+		out = append(out, b.binding)
+	}
+	return out
+}
+
+// keyMatches reports whether the key press being handled triggers b. It
+// replaces key.Matches so that chord bindings match once their last key is
+// typed.
+func (m *Model) keyMatches(msg tea.KeyPressMsg, b key.Binding) bool {
+	typed := m.chordTyped
+	if typed == "" {
+		typed = msg.String()
+	}
+	return ChordMatches(b, typed)
+}
+
+// typedChord reports whether the key press being handled completed a
+// chord. Chords never insert their last key as text.
+func (m *Model) typedChord() bool {
+	return strings.Contains(m.chordTyped, " ")
+}
+
+// KeyConflict reports bindings of one key map that cannot all fire.
+type KeyConflict struct {
+	// Keys is the key or chord in conflict, for example "ctrl+k".
+	Keys string
+	// Bindings names the conflicting key map fields, for example
+	// "KillLineRight".
+	Bindings []string
+	// Prefix reports that Keys is bound and also starts longer chords of the
+	// other bindings, which can then never complete. Otherwise Keys is bound
+	// more than once in the same context.
+	Prefix bool
+}
+
+type namedBinding struct {
+	name string
+	// context groups bindings that are active at the same time; the same
+	// key in different contexts is not a conflict.
+	context string
+	binding key.Binding
+}
+
+// Conflicts reports duplicate keys among bindings active in the same
+// context, and keys that shadow longer chords. Bindings used only while a
+// ghost suggestion, snippet session or popup is shown form their own
+// contexts, so sharing keys with document bindings is not a conflict.
+func (km KeyMap) Conflicts() []KeyConflict {
+	return keyConflicts([]namedBinding{
+		{"Left", "", km.Left}, {"Right", "", km.Right}, {"Up", "", km.Up}, {"Down", "", km.Down},
+		{"ParagraphUp", "", km.ParagraphUp}, {"ParagraphDown", "", km.ParagraphDown},
+		{"PageUp", "", km.PageUp}, {"PageDown", "", km.PageDown},
+		{"ShiftLeft", "", km.ShiftLeft}, {"ShiftRight", "", km.ShiftRight},
+		{"ShiftUp", "", km.ShiftUp}, {"ShiftDown", "", km.ShiftDown},
+		{"ParagraphShiftUp", "", km.ParagraphShiftUp}, {"ParagraphShiftDown", "", km.ParagraphShiftDown},
+		{"WordLeft", "", km.WordLeft}, {"WordRight", "", km.WordRight},
+		{"WordShiftLeft", "", km.WordShiftLeft}, {"WordShiftRight", "", km.WordShiftRight},
+		{"Home", "", km.Home}, {"End", "", km.End},
+		{"Backspace", "", km.Backspace}, {"Delete", "", km.Delete},
+		{"DeleteWordBackward", "", km.DeleteWordBackward}, {"KillLineRight", "", km.KillLineRight},
+		{"Enter", "", km.Enter}, {"ToggleComment", "", km.ToggleComment},
+		{"Undo", "", km.Undo}, {"Redo", "", km.Redo},
+		{"GhostAcceptWord", "ghost", km.GhostAcceptWord}, {"GhostAcceptLine", "ghost", km.GhostAcceptLine},
+		{"SnippetNext", "snippet", km.SnippetNext}, {"SnippetPrev", "snippet", km.SnippetPrev},
+		{"SnippetExit", "snippet", km.SnippetExit},
+		{"DismissPopup", "popup", km.DismissPopup},
+	})
+}
+
+// Conflicts reports duplicate keys among the popup bindings, and keys that
+// shadow longer chords. Trigger applies only while the popup is hidden, so
+// it may share keys with the others.
+func (km CompletionKeyMap) Conflicts() []KeyConflict {
+	return keyConflicts([]namedBinding{
+		{"Trigger", "hidden", km.Trigger},
+		{"Accept", "", km.Accept}, {"Dismiss", "", km.Dismiss},
+		{"Next", "", km.Next}, {"Prev", "", km.Prev},
+		{"PageNext", "", km.PageNext}, {"PagePrev", "", km.PagePrev},
+		{"DocScrollDown", "", km.DocScrollDown}, {"DocScrollUp", "", km.DocScrollUp},
+	})
+}
+
+func keyConflicts(named []namedBinding) []KeyConflict {
+	type user struct{ name, context string }
+	byKeys := map[string][]user{}
+	for _, nb := range named {
+		if !nb.binding.Enabled() {
+			continue
+		}
+		for _, k := range nb.binding.Keys() {
+			k = strings.Join(strings.Fields(k), " ")
+			if !slices.Contains(byKeys[k], user{nb.name, nb.context}) {
+				byKeys[k] = append(byKeys[k], user{nb.name, nb.context})
+			}
+		}
+	}
+
+	var out []KeyConflict
+	for k, users := range byKeys {
+		contexts := map[string][]string{}
+		for _, u := range users {
+			contexts[u.context] = append(contexts[u.context], u.name)
+		}
+		for _, names := range contexts {
+			if len(names) > 1 {
+				out = append(out, KeyConflict{Keys: k, Bindings: names})
+			}
+		}
+
+		var shadowed []string
+		for longer, longerUsers := range byKeys {
+			if strings.HasPrefix(longer, k+" ") {
+				for _, u := range longerUsers {
+					shadowed = append(shadowed, u.name)
+				}
+			}
+		}
+		if len(shadowed) > 0 {
+			names := make([]string, 0, len(users)+len(shadowed))
+			for _, u := range users {
+				names = append(names, u.name)
+			}
+			out = append(out, KeyConflict{Keys: k, Bindings: append(names, shadowed...), Prefix: true})
+		}
+	}
+	for i := range out {
+		sort.Strings(out[i].Bindings)
+		out[i].Bindings = slices.Compact(out[i].Bindings)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Keys != out[j].Keys {
+			return out[i].Keys < out[j].Keys
+		}
+		if out[i].Prefix != out[j].Prefix {
+			return !out[i].Prefix
+		}
+		return out[i].Bindings[0] < out[j].Bindings[0]
+	})
+	return out
+}
@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+)
+
+func chordKeyMap() KeyMap {
+	km := DefaultKeyMap()
+	km.Undo = key.NewBinding(key.WithKeys("ctrl+z", "g u"))
+	km.SnippetExit = key.NewBinding(key.WithKeys("g s"))
+	return km
+}
+
+func TestChord_CompletesPrefixedBinding(t *testing.T) {
+	m := New(Config{Text: "", KeyMap: chordKeyMap()})
+
+	m, _ = m.Update(testKeyText("a"))
+	m, _ = m.Update(testKeyText("g"))
+	if got := m.PendingChord(); got != "g" {
+		t.Fatalf("pending chord=%q, want %q", got, "g")
+	}
+	if got := m.Buffer().Text(); got != "a" {
+		t.Fatalf("prefix key should not be typed, text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyText("u"))
+	if got := m.PendingChord(); got != "" {
+		t.Fatalf("pending chord=%q after completion", got)
+	}
+	if got := m.Buffer().Text(); got != "" {
+		t.Fatalf("chord should undo, text=%q", got)
+	}
+}
+
+func TestChord_UnboundContinuationDropsSequence(t *testing.T) {
+	m := New(Config{Text: "", KeyMap: chordKeyMap()})
+
+	for _, k := range []string{"g", "x", "g", "s", "y"} {
+		m, _ = m.Update(testKeyText(k))
+	}
+	// "g x" is unbound, and "g s" ends no snippet outside a session; neither
+	// types its keys.
+	if got := m.Buffer().Text(); got != "y" {
+		t.Fatalf("text=%q, want %q", got, "y")
+	}
+}
+
+func TestChord_TimeoutCancelsPendingPrefix(t *testing.T) {
+	m := New(Config{Text: "", KeyMap: chordKeyMap(), ChordTimeout: time.Millisecond})
+
+	m, cmd := m.Update(testKeyText("g"))
+	if cmd == nil {
+		t.Fatalf("expected a timeout command for the pending chord")
+	}
+	stale := cmd()
+
+	m, _ = m.Update(testKeyCode(tea.KeyEscape))
+	m, cmd = m.Update(testKeyText("g"))
+	m, _ = m.Update(stale)
+	if got := m.PendingChord(); got != "g" {
+		t.Fatalf("a stale timeout should not cancel a newer prefix, pending=%q", got)
+	}
+
+	m, _ = m.Update(cmd())
+	if got := m.PendingChord(); got != "" {
+		t.Fatalf("timeout should cancel the prefix, pending=%q", got)
+	}
+	m, _ = m.Update(testKeyText("u"))
+	if got := m.Buffer().Text(); got != "u" {
+		t.Fatalf("key after timeout should be typed, text=%q", got)
+	}
+}
+
+func TestChord_CompletionKeyMap(t *testing.T) {
+	ckm := DefaultCompletionKeyMap()
+	ckm.Trigger = key.NewBinding(key.WithKeys("ctrl+o space"))
+	m := New(Config{Text: "", CompletionKeyMap: ckm})
+
+	m, _ = m.Update(testKeyCode('o', tea.ModCtrl))
+	if got := m.PendingChord(); got != "ctrl+o" {
+		t.Fatalf("pending chord=%q", got)
+	}
+	m, _ = m.Update(testKeyCode(tea.KeySpace))
+	if !m.CompletionState().Visible {
+		t.Fatalf("chord should trigger completion")
+	}
+	if got := m.Buffer().Text(); got != "" {
+		t.Fatalf("text=%q", got)
+	}
+}
+
+func TestKeyMapConflicts(t *testing.T) {
+	if got := DefaultKeyMap().Conflicts(); len(got) != 0 {
+		t.Fatalf("default key map conflicts: %+v", got)
+	}
+	if got := DefaultCompletionKeyMap().Conflicts(); len(got) != 0 {
+		t.Fatalf("default completion key map conflicts: %+v", got)
+	}
+
+	km := DefaultKeyMap()
+	km.ToggleComment = key.NewBinding(key.WithKeys("ctrl+k  ctrl+c"))
+	km.Undo = key.NewBinding(key.WithKeys("left"))
+	want := []KeyConflict{
+		{Keys: "ctrl+k", Bindings: []string{"KillLineRight", "ToggleComment"}, Prefix: true},
+		{Keys: "left", Bindings: []string{"Left", "Undo"}},
+	}
+	if got := km.Conflicts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("conflicts=%+v, want %+v", got, want)
+	}
+}
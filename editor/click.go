@@ -0,0 +1,112 @@
+package editor
+
+import (
+	"time"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// DefaultMultiClickInterval is the default longest delay between clicks of a
+// double or triple click.
+const DefaultMultiClickInterval = 500 * time.Millisecond
+
+// Clock supplies the time used for double- and triple-click detection.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock, for example to feed host-supplied
+// event timestamps.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+// selectionUnit is the unit a mouse drag extends the selection by.
+type selectionUnit uint8
+
+const (
+	unitGrapheme selectionUnit = iota
+	unitWord
+	unitLine
+)
+
+// clickState tracks recent clicks for multi-click detection, and the unit
+// the current drag selects by.
+type clickState struct {
+	count int
+	at    time.Time
+	pos   buffer.Pos
+
+	unit selectionUnit
+	// origin is the word or line the multi-click selected; a drag keeps it
+	// selected.
+	origin buffer.Range
+}
+
+// registerClick counts a plain left click at p: clicks at the same position
+// within Config.MultiClickInterval count up to a triple click, after which
+// the count starts over.
+func (m *Model) registerClick(p buffer.Pos) int {
+	interval := m.cfg.MultiClickInterval
+	if interval == 0 {
+		interval = DefaultMultiClickInterval
+	}
+	var now time.Time
+	if m.cfg.Clock != nil {
+		now = m.cfg.Clock.Now()
+	} else {
+		now = time.Now()
+	}
+
+	count := 1
+	if interval > 0 && m.click.count > 0 && p == m.click.pos {
+		if d := now.Sub(m.click.at); d >= 0 && d <= interval {
+			count = m.click.count%3 + 1
+		}
+	}
+	m.click.count, m.click.at, m.click.pos = count, now, p
+	return count
+}
+
+// selectUnitAt selects the word (unitWord) or line (unitLine) at p and
+// remembers it as the drag origin.
+func (m *Model) selectUnitAt(p buffer.Pos, unit selectionUnit) {
+	r := m.unitRangeAt(p, unit)
+	m.click.unit, m.click.origin = unit, r
+	m.buf.SetCursor(r.End)
+	m.buf.SetSelection(r)
+}
+
+// extendUnitSelection extends a word or line drag to p: the selection covers
+// the origin and the unit at p, anchored at the origin's far side.
+func (m *Model) extendUnitSelection(p buffer.Pos) {
+	r := m.unitRangeAt(p, m.click.unit)
+	origin := m.click.origin
+	sel := buffer.Range{Start: origin.Start, End: origin.End}
+	switch {
+	case buffer.ComparePos(r.Start, origin.Start) < 0:
+		sel = buffer.Range{Start: origin.End, End: r.Start}
+	case buffer.ComparePos(r.End, origin.End) > 0:
+		sel.End = r.End
+	}
+	m.buf.SetCursor(sel.End)
+	m.buf.SetSelection(sel)
+}
+
+// unitRangeAt returns the word or line at p. A line includes its line break,
+// except on the last row.
+func (m *Model) unitRangeAt(p buffer.Pos, unit selectionUnit) buffer.Range {
+	switch unit {
+	case unitWord:
+		return m.buf.WordRangeAt(p)
+	case unitLine:
+		start := buffer.Pos{Row: p.Row}
+		m.buf.IndexLines(p.Row + 1)
+		if n, _ := m.buf.IndexedLineCount(); p.Row+1 < n {
+			return buffer.Range{Start: start, End: buffer.Pos{Row: p.Row + 1}}
+		}
+		return buffer.Range{Start: start, End: m.documentEnd()}
+	default:
+		return buffer.Range{Start: p, End: p}
+	}
+}
@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func multiClickModel(text string, clock *fakeClock) Model {
+	m := New(Config{Text: text, Clock: clock})
+	return m.SetSize(30, 4)
+}
+
+func clickAt(m Model, x, y int) Model {
+	m, _ = m.Update(testMouseClick(x, y, tea.MouseLeft))
+	m, _ = m.Update(tea.MouseReleaseMsg{X: x, Y: y, Button: tea.MouseLeft})
+	return m
+}
+
+func assertSelection(t *testing.T, m Model, want buffer.Range, cursor buffer.Pos) {
+	t.Helper()
+	got, ok := m.Buffer().SelectionRaw()
+	if !ok || got != want {
+		t.Fatalf("selection=%v ok=%v, want %v", got, ok, want)
+	}
+	if c := m.Buffer().Cursor(); c != cursor {
+		t.Fatalf("cursor=%v, want %v", c, cursor)
+	}
+}
+
+func pos(row, col int) buffer.Pos { return buffer.Pos{Row: row, GraphemeCol: col} }
+
+func TestMultiClick_SelectsWordThenLine(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m := multiClickModel("foo bar.baz qux\nnext", clock)
+
+	m = clickAt(m, 5, 0)
+	clock.advance(100 * time.Millisecond)
+	m = clickAt(m, 5, 0)
+	assertSelection(t, m, buffer.Range{Start: pos(0, 4), End: pos(0, 11)}, pos(0, 11))
+
+	clock.advance(100 * time.Millisecond)
+	m = clickAt(m, 5, 0)
+	assertSelection(t, m, buffer.Range{Start: pos(0, 0), End: pos(1, 0)}, pos(1, 0))
+
+	clock.advance(100 * time.Millisecond)
+	m = clickAt(m, 5, 0)
+	if _, ok := m.Buffer().Selection(); ok {
+		t.Fatalf("a fourth click should start over with a plain click")
+	}
+
+	clock.advance(time.Second)
+	m = clickAt(m, 5, 0)
+	if _, ok := m.Buffer().Selection(); ok {
+		t.Fatalf("a slow second click should not select a word")
+	}
+	clock.advance(100 * time.Millisecond)
+	m = clickAt(m, 6, 0)
+	if _, ok := m.Buffer().Selection(); ok {
+		t.Fatalf("a click elsewhere should not select a word")
+	}
+}
+
+func TestMultiClick_DragExtendsByUnit(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m := multiClickModel("one two three\nfour five\nsix", clock)
+
+	m = clickAt(m, 5, 0)
+	m, _ = m.Update(testMouseClick(5, 0, tea.MouseLeft))
+	m, _ = m.Update(testMouseMotion(10, 0, tea.MouseLeft))
+	assertSelection(t, m, buffer.Range{Start: pos(0, 4), End: pos(0, 13)}, pos(0, 13))
+
+	m, _ = m.Update(testMouseMotion(1, 0, tea.MouseLeft))
+	assertSelection(t, m, buffer.Range{Start: pos(0, 7), End: pos(0, 0)}, pos(0, 0))
+	m, _ = m.Update(tea.MouseReleaseMsg{X: 1, Y: 0, Button: tea.MouseLeft})
+
+	clock.advance(time.Second)
+	m = clickAt(m, 1, 1)
+	m = clickAt(m, 1, 1)
+	m, _ = m.Update(testMouseClick(1, 1, tea.MouseLeft))
+	m, _ = m.Update(testMouseMotion(0, 2, tea.MouseLeft))
+	assertSelection(t, m, buffer.Range{Start: pos(1, 0), End: pos(2, 3)}, pos(2, 3))
+	m, _ = m.Update(testMouseMotion(0, 0, tea.MouseLeft))
+	assertSelection(t, m, buffer.Range{Start: pos(2, 0), End: pos(0, 0)}, pos(0, 0))
+}
+
+func TestMultiClick_NegativeIntervalDisables(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m := New(Config{Text: "foo bar", Clock: clock, MultiClickInterval: -1}).SetSize(20, 2)
+	m = clickAt(m, 1, 0)
+	m = clickAt(m, 1, 0)
+	if _, ok := m.Buffer().Selection(); ok {
+		t.Fatalf("multi-click selection should be disabled")
+	}
+}
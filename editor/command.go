@@ -0,0 +1,457 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"charm.land/bubbles/v2/key"
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// CommandFunc is a host command registered in Config.Commands.
+type CommandFunc func(m Model) (Model, tea.Cmd)
+
+// Binding binds a key, or a chord such as "ctrl+k ctrl+c", to a command by
+// name. Bindings are usually loaded from a keymap file; see ParseKeymap.
+type Binding struct {
+	Key     string
+	Command string
+	// When limits the binding to a context: condition names combined with
+	// "&&", each optionally negated with "!", for example
+	// "completionVisible && !readOnly". Empty means always.
+	//
+	// Conditions are completionVisible, snippetActive, ghostVisible,
+	// popupVisible (hover or signature help), readOnly and hasSelection.
+	When string
+}
+
+// conditions evaluates Binding.When condition names.
+var conditions = map[string]func(m *Model) bool{
+	"completionVisible": func(m *Model) bool { return m.completionState.Visible },
+	"snippetActive":     func(m *Model) bool { return m.snippet.stops != nil },
+	"ghostVisible": func(m *Model) bool {
+		ghost, ok := m.ghostForCursor()
+		return ok && len(ghost.Edits) > 0
+	},
+	"popupVisible": func(m *Model) bool {
+		return m.hasFloatingWindow(HoverWindowID) || m.hasFloatingWindow(SignatureHelpWindowID)
+	},
+	"readOnly": func(m *Model) bool { return m.cfg.ReadOnly },
+	"hasSelection": func(m *Model) bool {
+		_, ok := m.buf.Selection()
+		return ok
+	},
+}
+
+type condition struct {
+	name   string
+	negate bool
+}
+
+// parseWhen parses a Binding.When expression.
+func parseWhen(when string) ([]condition, error) {
+	if strings.TrimSpace(when) == "" {
+		return nil, nil
+	}
+	var out []condition
+	for _, term := range strings.Split(when, "&&") {
+		term = strings.TrimSpace(term)
+		c := condition{}
+		if rest, ok := strings.CutPrefix(term, "!"); ok {
+			c.negate = true
+			term = strings.TrimSpace(rest)
+		}
+		if _, ok := conditions[term]; !ok {
+			return nil, fmt.Errorf("unknown condition %q in %q", term, when)
+		}
+		c.name = term
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (m *Model) conditionsHold(when []condition) bool {
+	for _, c := range when {
+		if conditions[c.name](m) == c.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// boundCommand is a Config.Bindings entry ready for key matching.
+type boundCommand struct {
+	binding key.Binding
+	command string
+	when    []condition
+}
+
+// compileBindings drops bindings without keys or with an invalid When, which
+// could never fire as intended.
+func compileBindings(bindings []Binding) []boundCommand {
+	out := make([]boundCommand, 0, len(bindings))
+	for _, b := range bindings {
+		when, err := parseWhen(b.When)
+		if err != nil || strings.TrimSpace(b.Key) == "" {
+			continue
+		}
+		out = append(out, boundCommand{
+			binding: key.NewBinding(key.WithKeys(b.Key)),
+			command: b.Command,
+			when:    when,
+		})
+	}
+	return out
+}
+
+// moveCommands maps movement command names to buffer moves. Page moves take
+// their count from the viewport height.
+var moveCommands = map[string]buffer.Move{
+	"cursor.left":          {Unit: buffer.MoveGrapheme, Dir: buffer.DirLeft},
+	"cursor.right":         {Unit: buffer.MoveGrapheme, Dir: buffer.DirRight},
+	"cursor.up":            {Unit: buffer.MoveGrapheme, Dir: buffer.DirUp},
+	"cursor.down":          {Unit: buffer.MoveGrapheme, Dir: buffer.DirDown},
+	"cursor.wordLeft":      {Unit: buffer.MoveWord, Dir: buffer.DirLeft},
+	"cursor.wordRight":     {Unit: buffer.MoveWord, Dir: buffer.DirRight},
+	"cursor.paragraphUp":   {Unit: buffer.MoveParagraph, Dir: buffer.DirUp},
+	"cursor.paragraphDown": {Unit: buffer.MoveParagraph, Dir: buffer.DirDown},
+	"cursor.pageUp":        {Unit: buffer.MoveLine, Dir: buffer.DirUp},
+	"cursor.pageDown":      {Unit: buffer.MoveLine, Dir: buffer.DirDown},
+	"cursor.lineStart":     {Unit: buffer.MoveLine, Dir: buffer.DirHome},
+	"cursor.lineEnd":       {Unit: buffer.MoveLine, Dir: buffer.DirEnd},
+	"cursor.documentStart": {Unit: buffer.MoveDoc, Dir: buffer.DirHome},
+	"cursor.documentEnd":   {Unit: buffer.MoveDoc, Dir: buffer.DirEnd},
+
+	"select.left":          {Unit: buffer.MoveGrapheme, Dir: buffer.DirLeft, Extend: true},
+	"select.right":         {Unit: buffer.MoveGrapheme, Dir: buffer.DirRight, Extend: true},
+	"select.up":            {Unit: buffer.MoveGrapheme, Dir: buffer.DirUp, Extend: true},
+	"select.down":          {Unit: buffer.MoveGrapheme, Dir: buffer.DirDown, Extend: true},
+	"select.wordLeft":      {Unit: buffer.MoveWord, Dir: buffer.DirLeft, Extend: true},
+	"select.wordRight":     {Unit: buffer.MoveWord, Dir: buffer.DirRight, Extend: true},
+	"select.paragraphUp":   {Unit: buffer.MoveParagraph, Dir: buffer.DirUp, Extend: true},
+	"select.paragraphDown": {Unit: buffer.MoveParagraph, Dir: buffer.DirDown, Extend: true},
+	"select.pageUp":        {Unit: buffer.MoveLine, Dir: buffer.DirUp, Extend: true},
+	"select.pageDown":      {Unit: buffer.MoveLine, Dir: buffer.DirDown, Extend: true},
+	"select.lineStart":     {Unit: buffer.MoveLine, Dir: buffer.DirHome, Extend: true},
+	"select.lineEnd":       {Unit: buffer.MoveLine, Dir: buffer.DirEnd, Extend: true},
+	"select.documentStart": {Unit: buffer.MoveDoc, Dir: buffer.DirHome, Extend: true},
+	"select.documentEnd":   {Unit: buffer.MoveDoc, Dir: buffer.DirEnd, Extend: true},
+}
+
+// otherCommands lists the built-in commands that are not plain moves.
+var otherCommands = []string{
+	"cursor.matchingBracket",
+	"select.all", "select.bracketContents", "select.clear",
+	"edit.deleteBackward", "edit.deleteForward", "edit.deleteWordBackward", "edit.killLineRight",
+	"edit.newline", "edit.tab", "edit.toggleComment", "edit.undo", "edit.redo",
+	"ghost.accept", "ghost.acceptWord", "ghost.acceptLine",
+	"snippet.next", "snippet.prev", "snippet.exit",
+	"completion.trigger", "completion.accept", "completion.dismiss",
+	"completion.next", "completion.prev", "completion.pageNext", "completion.pagePrev",
+	"completion.docScrollDown", "completion.docScrollUp",
+	"popup.dismiss", "popup.hover", "popup.signatureHelp",
+	"macro.record", "macro.play",
+	"noop",
+}
+
+// BuiltinCommands returns the names of the built-in commands, sorted.
+func BuiltinCommands() []string {
+	out := make([]string, 0, len(moveCommands)+len(otherCommands))
+	for name := range moveCommands {
+		out = append(out, name)
+	}
+	out = append(out, otherCommands...)
+	sort.Strings(out)
+	return out
+}
+
+// RunCommand runs a command by name: a Config.Commands entry, which may
+// override a built-in, or one of BuiltinCommands. Built-in commands behave
+// as their keys do, including intent reporting per Config.MutationMode.
+//
+// ok is false when the name is unknown or the command does not apply, for
+// example ghost.accept without a ghost suggestion.
+func (m Model) RunCommand(name string) (Model, tea.Cmd, bool) {
+	if m.buf == nil {
+		return m, nil, false
+	}
+	beforeVer, beforeText := m.buf.Version(), m.buf.TextVersion()
+	cmd, ok := (&m).runCommand(name, editorStateFromBuffer(m.buf))
+	if !ok {
+		return m, cmd, false
+	}
+	(&m).syncAfterChange(beforeVer, beforeText)
+	return m, cmd, true
+}
+
+// runCommand runs a named command against m. ok is false when the name is
+// unknown or the command does not apply.
+func (m *Model) runCommand(name string, before EditorState) (tea.Cmd, bool) {
+	if fn, ok := m.cfg.Commands[name]; ok && fn != nil {
+		next, cmd := fn(*m)
+		*m = next
+		return cmd, true
+	}
+
+	switch name {
+	case "noop":
+		return nil, true
+	case "popup.dismiss":
+		return nil, m.dismissFloats()
+	case "popup.hover":
+		if m.cfg.HoverProvider == nil {
+			return nil, false
+		}
+		*m = m.ShowHover(m.buf.Cursor())
+		return nil, true
+	case "popup.signatureHelp":
+		if m.cfg.SignatureHelpProvider == nil {
+			return nil, false
+		}
+		*m = m.ShowSignatureHelp()
+		return nil, true
+	case "macro.record":
+		if m.recording != nil {
+			*m = m.StopMacro()
+		} else {
+			*m = m.StartMacro("")
+		}
+		return nil, true
+	case "macro.play":
+		next, ok := m.PlayMacro("", 1)
+		*m = next
+		return nil, ok
+	}
+
+	if strings.HasPrefix(name, "completion.") {
+		result, ok := m.buildCompletionCommand(name, before)
+		if ok {
+			m.applyCompletionKeyResult(result)
+		}
+		return nil, ok
+	}
+
+	batch, mutations, ok := m.buildCommand(name, before)
+	if !ok {
+		return nil, false
+	}
+	if m.emitDocumentIntentsAndResolveApply(batch) {
+		for _, op := range mutations {
+			op(m)
+		}
+	}
+	return nil, true
+}
+
+// runBoundCommand runs the first Config.Bindings entry that matches the key
+// press, holds in the current context and applies.
+func (m *Model) runBoundCommand(msg tea.KeyPressMsg, before EditorState) (tea.Cmd, bool) {
+	for _, b := range m.bindings {
+		if !m.keyMatches(msg, b.binding) || !m.conditionsHold(b.when) {
+			continue
+		}
+		if cmd, ok := m.runCommand(b.command, before); ok {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+// buildCommand builds the intents and local mutations of a built-in
+// document command. ok is false when name is not such a command, or when it
+// does not apply here and the key should fall through to other bindings
+// (ghost and snippet commands outside their context). Editing commands on a
+// read-only editor apply but do nothing.
+func (m *Model) buildCommand(name string, before EditorState) (IntentBatch, []localMutationOp, bool) {
+	batch := IntentBatch{}
+	mutations := make([]localMutationOp, 0, 1)
+	appendIntent := func(kind IntentKind, payload any) {
+		batch.Intents = append(batch.Intents, Intent{Kind: kind, Before: before, Payload: payload})
+	}
+	// appendApplied appends an intent whose local mutation is applyIntent.
+	appendApplied := func(kind IntentKind, payload any) {
+		in := Intent{Kind: kind, Before: before, Payload: payload}
+		batch.Intents = append(batch.Intents, in)
+		mutations = append(mutations, func(mm *Model) { mm.applyIntent(in) })
+	}
+	appendAction := func(action autoPairAction) {
+		appendIntent(action.kind, action.payload)
+		mutations = append(mutations, action.op)
+	}
+	appendDelete := func(dir DeleteDirection, op localMutationOp) {
+		if _, ok := m.buf.Selection(); ok {
+			dir = DeleteSelection
+		}
+		appendIntent(IntentDelete, DeleteIntentPayload{Direction: dir})
+		mutations = append(mutations, op)
+	}
+
+	if move, ok := moveCommands[name]; ok {
+		if move.Unit == buffer.MoveLine && (move.Dir == buffer.DirUp || move.Dir == buffer.DirDown) {
+			move.Count = m.pageMoveCount()
+		}
+		if move.Extend {
+			appendApplied(IntentSelect, SelectIntentPayload{Move: move})
+		} else {
+			appendApplied(IntentMove, MoveIntentPayload{Move: move})
+		}
+		return batch, mutations, true
+	}
+
+	switch name {
+	case "cursor.matchingBracket":
+		if _, match, ok := m.buf.MatchBracket(m.buf.Cursor(), m.bracketOptions()); ok {
+			appendApplied(IntentSetCursor, SetCursorIntentPayload{Cursor: match})
+		}
+	case "select.bracketContents":
+		if open, close, ok := m.buf.EnclosingBrackets(m.buf.Cursor(), m.bracketOptions()); ok {
+			inner := buffer.Range{
+				Start: buffer.Pos{Row: open.Row, GraphemeCol: open.GraphemeCol + 1},
+				End:   close,
+			}
+			appendApplied(IntentSetCursor, SetCursorIntentPayload{Cursor: inner.End, Selection: inner, Select: true})
+		}
+	case "select.all":
+		end := m.documentEnd()
+		appendApplied(IntentSetCursor, SetCursorIntentPayload{
+			Cursor:    end,
+			Selection: buffer.Range{End: end},
+			Select:    true,
+		})
+	case "select.clear":
+		if _, ok := m.buf.Selection(); ok {
+			appendApplied(IntentSetCursor, SetCursorIntentPayload{Cursor: m.buf.Cursor()})
+		}
+
+	case "edit.deleteBackward":
+		if m.cfg.ReadOnly {
+			break
+		}
+		if action, ok := m.autoPairForBackspace(); ok {
+			appendAction(action)
+			break
+		}
+		appendDelete(DeleteBackward, func(mm *Model) { mm.buf.DeleteBackward() })
+	case "edit.deleteForward":
+		if !m.cfg.ReadOnly {
+			appendDelete(DeleteForward, func(mm *Model) { mm.buf.DeleteForward() })
+		}
+	case "edit.deleteWordBackward":
+		if !m.cfg.ReadOnly {
+			appendDelete(DeleteWordBackward, func(mm *Model) { mm.buf.DeleteWordBackward() })
+		}
+	case "edit.killLineRight":
+		if !m.cfg.ReadOnly {
+			appendDelete(DeleteLineRight, func(mm *Model) { mm.buf.DeleteLineRight() })
+		}
+	case "edit.newline":
+		if !m.cfg.ReadOnly {
+			appendIntent(IntentInsert, InsertIntentPayload{Text: "\n"})
+			mutations = append(mutations, func(mm *Model) { mm.buf.InsertNewline() })
+		}
+	case "edit.tab":
+		if !m.cfg.ReadOnly {
+			appendIntent(IntentInsert, InsertIntentPayload{Text: "\t"})
+			mutations = append(mutations, func(mm *Model) { mm.buf.InsertGrapheme("\t") })
+		}
+	case "edit.toggleComment":
+		if m.cfg.ReadOnly {
+			break
+		}
+		if payload, op, ok := m.commentToggleAction(); ok {
+			payload.Command = name
+			appendIntent(IntentEdit, payload)
+			mutations = append(mutations, op)
+		}
+	case "edit.undo":
+		if !m.cfg.ReadOnly && m.buf.CanUndo() {
+			appendApplied(IntentUndo, UndoIntentPayload{})
+		}
+	case "edit.redo":
+		if !m.cfg.ReadOnly && m.buf.CanRedo() {
+			appendApplied(IntentRedo, RedoIntentPayload{})
+		}
+
+	case "ghost.accept":
+		return m.buildGhostAccept(before, false)
+	case "ghost.acceptWord", "ghost.acceptLine":
+		if m.cfg.GhostProvider == nil || m.cfg.ReadOnly {
+			return batch, nil, false
+		}
+		ghost, ok := m.ghostForCursor()
+		if !ok {
+			return batch, nil, false
+		}
+		edit, rest, ok := ghostPartialAccept(ghost, m.buf.Cursor(), name == "ghost.acceptWord")
+		if !ok {
+			return batch, nil, false
+		}
+		styleKey := ghost.StyleKey
+		appendIntent(IntentInsert, InsertIntentPayload{Text: edit.Text, Edits: []buffer.TextEdit{edit}})
+		mutations = append(mutations, func(mm *Model) {
+			mm.applyInsertEdits([]buffer.TextEdit{edit}, false)
+			mm.keepGhostRest(rest, styleKey)
+		})
+
+	case "snippet.next", "snippet.prev":
+		if m.snippet.stops == nil {
+			return batch, nil, false
+		}
+		delta := 1
+		if name == "snippet.prev" {
+			delta = -1
+		}
+		if target, ok := m.snippetJumpTarget(delta); ok {
+			stop := m.snippet.stops[target]
+			appendIntent(IntentSnippetJump, SnippetJumpIntentPayload{Tabstop: stop.index, Range: stop.ranges[0]})
+			mutations = append(mutations, func(mm *Model) { mm.jumpSnippet(target) })
+		}
+	case "snippet.exit":
+		if m.snippet.stops == nil {
+			return batch, nil, false
+		}
+		mutations = append(mutations, func(mm *Model) { mm.endSnippetSession() })
+
+	default:
+		return batch, nil, false
+	}
+	return batch, mutations, true
+}
+
+// buildGhostAccept accepts the whole ghost suggestion. With inlineOnly it
+// applies only to pure insertions at the cursor, so a right arrow still
+// moves past other suggestions.
+func (m *Model) buildGhostAccept(before EditorState, inlineOnly bool) (IntentBatch, []localMutationOp, bool) {
+	if m.cfg.GhostProvider == nil || m.cfg.ReadOnly {
+		return IntentBatch{}, nil, false
+	}
+	ghost, ok := m.ghostForCursor()
+	if !ok || len(ghost.Edits) == 0 {
+		return IntentBatch{}, nil, false
+	}
+	if inlineOnly && !ghostRightAcceptEligible(ghost.Edits, m.buf.Cursor()) {
+		return IntentBatch{}, nil, false
+	}
+	edits := cloneTextEdits(ghost.Edits)
+	snippet := ghost.Snippet
+	batch := IntentBatch{Intents: []Intent{{
+		Kind:    IntentInsert,
+		Before:  before,
+		Payload: InsertIntentPayload{Text: ghost.Text, Edits: edits, Snippet: snippet},
+	}}}
+	return batch, []localMutationOp{func(mm *Model) { mm.applyInsertEdits(edits, snippet) }}, true
+}
+
+// documentEnd returns the position after the last grapheme.
+func (m *Model) documentEnd() buffer.Pos {
+	row := m.buf.LineCount() - 1
+	if row < 0 {
+		return buffer.Pos{}
+	}
+	line, _ := m.docLine(row)
+	return buffer.Pos{Row: row, GraphemeCol: grapheme.Count(line)}
+}
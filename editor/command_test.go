@@ -0,0 +1,123 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func TestKeyMapCommandsAreBuiltin(t *testing.T) {
+	builtin := BuiltinCommands()
+	km := DefaultKeyMap()
+	all := slices.Concat(km.documentCommands(), km.snippetCommands(), km.ghostCommands(), DefaultCompletionKeyMap().commands())
+	for _, kc := range all {
+		if !slices.Contains(builtin, kc.command) {
+			t.Errorf("key map command %q is not built in", kc.command)
+		}
+	}
+	if !slices.IsSorted(builtin) || len(slices.Compact(slices.Clone(builtin))) != len(builtin) {
+		t.Fatalf("BuiltinCommands should be sorted and unique: %v", builtin)
+	}
+}
+
+func TestRunCommand_Builtins(t *testing.T) {
+	m := New(Config{Text: "ab\ncd"})
+
+	m, _, ok := m.RunCommand("select.all")
+	if !ok {
+		t.Fatalf("select.all should apply")
+	}
+	if sel, ok := m.Buffer().Selection(); !ok || sel != (buffer.Range{End: buffer.Pos{Row: 1, GraphemeCol: 2}}) {
+		t.Fatalf("selection=%v ok=%v", sel, ok)
+	}
+
+	m, _, _ = m.RunCommand("edit.deleteBackward")
+	if got := m.Buffer().Text(); got != "" {
+		t.Fatalf("text=%q", got)
+	}
+	m, _, _ = m.RunCommand("edit.undo")
+	m, _, _ = m.RunCommand("cursor.documentStart")
+	if got := m.Buffer().Text(); got != "ab\ncd" || m.Buffer().Cursor() != (buffer.Pos{}) {
+		t.Fatalf("text=%q cursor=%v", got, m.Buffer().Cursor())
+	}
+
+	for _, name := range []string{"no.such.command", "snippet.next", "ghost.accept", "completion.next"} {
+		if _, _, ok := m.RunCommand(name); ok {
+			t.Errorf("%s should not apply", name)
+		}
+	}
+}
+
+func TestRunCommand_EmitsIntents(t *testing.T) {
+	var got []IntentKind
+	m := New(Config{
+		Text:         "ab",
+		MutationMode: EmitIntentsOnly,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			for _, in := range batch.Intents {
+				got = append(got, in.Kind)
+			}
+			return IntentDecision{}
+		},
+	})
+
+	m, _, _ = m.RunCommand("cursor.lineEnd")
+	m, _, _ = m.RunCommand("edit.newline")
+	if !slices.Equal(got, []IntentKind{IntentMove, IntentInsert}) {
+		t.Fatalf("intents=%v", got)
+	}
+	if m.Buffer().Text() != "ab" || m.Buffer().Cursor() != (buffer.Pos{}) {
+		t.Fatalf("intent-only mode should not mutate")
+	}
+}
+
+func TestBindings_ConditionsAndHostCommands(t *testing.T) {
+	upper := func(m Model) (Model, tea.Cmd) {
+		sel, _ := m.Buffer().Selection()
+		text := strings.ToUpper(m.Buffer().TextInRange(sel))
+		m.Buffer().Apply(buffer.TextEdit{Range: sel, Text: text})
+		return m, nil
+	}
+	m := New(Config{
+		Text: "abc",
+		Bindings: []Binding{
+			{Key: "u", Command: "upper", When: "hasSelection && !readOnly"},
+			{Key: "ctrl+b", Command: "cursor.left"},
+			{Key: "ctrl+x l", Command: "cursor.lineEnd"},
+			{Key: "left", Command: "ghost.accept"},
+			{Key: "z", Command: "cursor.left", When: "noSuchCondition"},
+		},
+		Commands: map[string]CommandFunc{"upper": upper},
+	})
+
+	m, _ = m.Update(testKeyText("u"))
+	if got := m.Buffer().Text(); got != "uabc" {
+		t.Fatalf("without a selection the key should be typed, text=%q", got)
+	}
+	m, _ = m.Update(testKeyCode('b', tea.ModCtrl))
+	m, _ = m.Update(testKeyCode(tea.KeyLeft))
+	if got := m.Buffer().Cursor(); got != (buffer.Pos{}) {
+		t.Fatalf("ctrl+b and the fallen-through left should move left twice, cursor=%v", got)
+	}
+
+	m, _ = m.Update(testKeyCode('x', tea.ModCtrl))
+	m, _ = m.Update(testKeyText("l"))
+	if got := m.Buffer().Cursor(); got != (buffer.Pos{GraphemeCol: 4}) {
+		t.Fatalf("chord binding should move to line end, cursor=%v", got)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyLeft, tea.ModShift))
+	m, _ = m.Update(testKeyText("u"))
+	if got := m.Buffer().Text(); got != "uabC" {
+		t.Fatalf("host command should run with a selection, text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyText("z"))
+	if got := m.Buffer().Text(); !strings.Contains(got, "z") {
+		t.Fatalf("binding with an invalid condition should be ignored, text=%q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package editor
+
+import (
+	"slices"
+
+	"github.com/iw2rmb/flourish/buffer"
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// CommentTokens configures comment toggling for a document language.
+type CommentTokens struct {
+	// Line is the line comment prefix, e.g. "//" or "#".
+	Line string
+	// BlockStart and BlockEnd delimit block comments, e.g. "/*" and "*/".
+	// With Line set, they are used only for a selection inside one row's
+	// text.
+	BlockStart string
+	BlockEnd   string
+}
+
+func (t CommentTokens) enabled() bool {
+	return t.Line != "" || (t.BlockStart != "" && t.BlockEnd != "")
+}
+
+// commentShift records a column change on one row made by a comment toggle:
+// n graphemes inserted at col (n > 0) or removed starting at col (n < 0).
+type commentShift struct {
+	row, col, n int
+}
+
+// commentToggle is a planned comment toggle. Edits are ordered bottom-up and
+// right-to-left so they apply sequentially against original coordinates.
+type commentToggle struct {
+	edits  []buffer.TextEdit
+	shifts []commentShift
+}
+
+func (c *commentToggle) insert(row, col int, text string) {
+	p := buffer.Pos{Row: row, GraphemeCol: col}
+	c.edits = append(c.edits, buffer.TextEdit{Range: buffer.Range{Start: p, End: p}, Text: text})
+	c.shifts = append(c.shifts, commentShift{row: row, col: col, n: graphemeutil.Count(text)})
+}
+
+func (c *commentToggle) remove(row, col, n int) {
+	c.edits = append(c.edits, buffer.TextEdit{Range: buffer.Range{
+		Start: buffer.Pos{Row: row, GraphemeCol: col},
+		End:   buffer.Pos{Row: row, GraphemeCol: col + n},
+	}})
+	c.shifts = append(c.shifts, commentShift{row: row, col: col, n: -n})
+}
+
+// finish orders edits and shifts bottom-up, right-to-left.
+func (c *commentToggle) finish() {
+	slices.SortStableFunc(c.edits, func(a, b buffer.TextEdit) int {
+		return buffer.ComparePos(b.Range.Start, a.Range.Start)
+	})
+	slices.SortStableFunc(c.shifts, func(a, b commentShift) int {
+		if a.row != b.row {
+			return b.row - a.row
+		}
+		return b.col - a.col
+	})
+}
+
+// mapPos maps a pre-toggle position to the same text after the toggle.
+// Positions inside removed tokens collapse to the removal start; text
+// inserted at a position lands after it.
+func (c commentToggle) mapPos(p buffer.Pos) buffer.Pos {
+	return c.mapPosAt(p, false)
+}
+
+// mapPosAfter is mapPos, except that text inserted at p lands before it.
+func (c commentToggle) mapPosAfter(p buffer.Pos) buffer.Pos {
+	return c.mapPosAt(p, true)
+}
+
+func (c commentToggle) mapPosAt(p buffer.Pos, after bool) buffer.Pos {
+	for _, s := range c.shifts {
+		if s.row != p.Row {
+			continue
+		}
+		switch {
+		case s.n > 0 && (p.GraphemeCol > s.col || after && p.GraphemeCol == s.col):
+			p.GraphemeCol += s.n
+		case s.n < 0 && p.GraphemeCol >= s.col-s.n:
+			p.GraphemeCol += s.n
+		case s.n < 0 && p.GraphemeCol > s.col:
+			p.GraphemeCol = s.col
+		}
+	}
+	return p
+}
+
+// commentToggleAction plans a comment toggle for the cursor row or the rows
+// covered by the selection. A selection ending at column 0 excludes that row.
+// With both line and block tokens, a selection starting or ending inside a
+// line's text toggles a block comment around exactly the selected text, and
+// the selection then covers the delimiters.
+func (m *Model) commentToggleAction() (EditIntentPayload, localMutationOp, bool) {
+	tokens := m.cfg.Comment
+	if m.buf == nil || !tokens.enabled() {
+		return EditIntentPayload{}, nil, false
+	}
+
+	cur := m.buf.Cursor()
+	first, last := cur.Row, cur.Row
+	raw, hasSel := m.buf.SelectionRaw()
+	sel := buffer.NormalizeRange(raw)
+	if hasSel {
+		first, last = sel.Start.Row, sel.End.Row
+		if last > first && sel.End.GraphemeCol == 0 {
+			last--
+		}
+	}
+
+	lines := make([][]string, 0, last-first+1)
+	for row := first; row <= last; row++ {
+		text, ok := m.docLine(row)
+		if !ok {
+			return EditIntentPayload{}, nil, false
+		}
+		lines = append(lines, graphemeutil.Split(text))
+	}
+
+	var plan commentToggle
+	blockStart, blockEnd := graphemeutil.Split(tokens.BlockStart), graphemeutil.Split(tokens.BlockEnd)
+	startCol, endCol, partial := partialSelectionSpan(lines[0], sel)
+	partial = partial && hasSel && tokens.Line != "" && len(blockStart) > 0 && len(blockEnd) > 0
+	switch {
+	case partial:
+		plan = planBlockSpan(lines, first, 0, startCol, 0, endCol, blockStart, blockEnd)
+	case tokens.Line != "":
+		plan = planLineComment(lines, first, graphemeutil.Split(tokens.Line))
+	default:
+		plan = planBlockComment(lines, first, blockStart, blockEnd)
+	}
+	if len(plan.edits) == 0 {
+		return EditIntentPayload{}, nil, false
+	}
+	plan.finish()
+
+	edits := plan.edits
+	mapEnd := plan.mapPos
+	if partial {
+		mapEnd = plan.mapPosAfter
+	}
+	mapSel := func(p buffer.Pos) buffer.Pos {
+		if p == sel.End {
+			return mapEnd(p)
+		}
+		return plan.mapPos(p)
+	}
+	nextCursor := mapSel(cur)
+	nextSel := buffer.Range{Start: mapSel(raw.Start), End: mapSel(raw.End)}
+	op := func(mm *Model) {
+		mm.buf.Apply(edits...)
+		mm.buf.SetCursor(nextCursor)
+		if hasSel {
+			mm.buf.SetSelection(nextSel)
+		}
+	}
+	return EditIntentPayload{Edits: cloneTextEdits(edits)}, op, true
+}
+
+// planLineComment removes the prefix when every non-blank line already has it
+// after its indentation, and otherwise inserts "prefix " at the minimum
+// indentation of the non-blank lines. Blank lines are left untouched unless
+// all lines are blank.
+func planLineComment(lines [][]string, firstRow int, prefix []string) commentToggle {
+	var plan commentToggle
+	indents, rows := commentCandidates(lines)
+	if len(rows) == 0 {
+		return plan
+	}
+
+	uncomment := true
+	minIndent := indents[rows[0]]
+	for _, i := range rows {
+		if !hasClustersAt(lines[i], indents[i], prefix) {
+			uncomment = false
+		}
+		minIndent = min(minIndent, indents[i])
+	}
+
+	for _, i := range rows {
+		if uncomment {
+			n := len(prefix)
+			if at := indents[i] + n; at < len(lines[i]) && lines[i][at] == " " {
+				n++
+			}
+			plan.remove(firstRow+i, indents[i], n)
+			continue
+		}
+		plan.insert(firstRow+i, minIndent, graphemeutil.Join(prefix)+" ")
+	}
+	return plan
+}
+
+// partialSelectionSpan returns the selected text span of a single-row
+// selection that lies inside line's text: it starts at or after the
+// indentation, ends at or before the trailing whitespace, and leaves some
+// of the text out. Other selections comment whole rows.
+func partialSelectionSpan(line []string, sel buffer.Range) (startCol, endCol int, partial bool) {
+	if sel.Start.Row != sel.End.Row {
+		return 0, 0, false
+	}
+	indent, end := len(line)-len(trimLeadingSpace(line)), trimmedLen(line)
+	startCol, endCol = sel.Start.GraphemeCol, sel.End.GraphemeCol
+	if startCol < indent || endCol > end || startCol >= endCol {
+		return 0, 0, false
+	}
+	return startCol, endCol, startCol > indent || endCol < end
+}
+
+// planBlockComment removes start/end delimiters wrapping the first and last
+// non-blank lines, and otherwise wraps those lines as "start ... end".
+func planBlockComment(lines [][]string, firstRow int, start, end []string) commentToggle {
+	indents, rows := commentCandidates(lines)
+	if len(rows) == 0 {
+		return commentToggle{}
+	}
+	top, bottom := rows[0], rows[len(rows)-1]
+	return planBlockSpan(lines, firstRow, top, indents[top], bottom, trimmedLen(lines[bottom]), start, end)
+}
+
+// planBlockSpan removes start/end delimiters at the edges of the span from
+// startCol on line top to endCol on line bottom, and otherwise wraps the
+// span as "start ... end".
+func planBlockSpan(lines [][]string, firstRow, top, startCol, bottom, endCol int, start, end []string) commentToggle {
+	var plan commentToggle
+	wrapped := hasClustersAt(lines[top], startCol, start) &&
+		endCol-len(end) >= 0 && hasClustersAt(lines[bottom], endCol-len(end), end) &&
+		(top != bottom || endCol-len(end) >= startCol+len(start))
+	if !wrapped {
+		plan.insert(firstRow+bottom, endCol, " "+graphemeutil.Join(end))
+		plan.insert(firstRow+top, startCol, graphemeutil.Join(start)+" ")
+		return plan
+	}
+
+	endAt, endN := endCol-len(end), len(end)
+	if endAt > 0 && lines[bottom][endAt-1] == " " && (top != bottom || endAt-1 >= startCol+len(start)) {
+		endAt--
+		endN++
+	}
+	startN := len(start)
+	if at := startCol + startN; at < endAt || top != bottom {
+		if at < len(lines[top]) && lines[top][at] == " " {
+			startN++
+		}
+	}
+	plan.remove(firstRow+bottom, endAt, endN)
+	plan.remove(firstRow+top, startCol, startN)
+	return plan
+}
+
+// commentCandidates returns per-line indentation and the indices of
+// non-blank lines (all lines when every line is blank).
+func commentCandidates(lines [][]string) (indents []int, rows []int) {
+	indents = make([]int, len(lines))
+	for i, line := range lines {
+		n := 0
+		for n < len(line) && graphemeutil.IsSpace(line[n]) {
+			n++
+		}
+		indents[i] = n
+		if n < len(line) {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) == 0 {
+		for i := range lines {
+			rows = append(rows, i)
+		}
+	}
+	return indents, rows
+}
+
+func trimLeadingSpace(line []string) []string {
+	for len(line) > 0 && graphemeutil.IsSpace(line[0]) {
+		line = line[1:]
+	}
+	return line
+}
+
+func trimmedLen(line []string) int {
+	n := len(line)
+	for n > 0 && graphemeutil.IsSpace(line[n-1]) {
+		n--
+	}
+	return n
+}
+
+func hasClustersAt(line []string, col int, want []string) bool {
+	if col < 0 || col+len(want) > len(line) {
+		return false
+	}
+	return slices.Equal(line[col:col+len(want)], want)
+}
@@ -0,0 +1,193 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func toggleCommentKey() tea.KeyPressMsg {
+	return testKeyCode('/', tea.ModCtrl)
+}
+
+func TestToggleComment_CursorLine(t *testing.T) {
+	m := New(Config{Text: "a\n  b := 1\nc", Comment: CommentTokens{Line: "//"}})
+	m.buf.SetCursor(buffer.Pos{Row: 1, GraphemeCol: 4})
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "a\n  // b := 1\nc"; got != want {
+		t.Fatalf("comment: got %q, want %q", got, want)
+	}
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 1, GraphemeCol: 7}); got != want {
+		t.Fatalf("cursor after comment: got %v, want %v", got, want)
+	}
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "a\n  b := 1\nc"; got != want {
+		t.Fatalf("uncomment: got %q, want %q", got, want)
+	}
+	if got, want := m.buf.Cursor(), (buffer.Pos{Row: 1, GraphemeCol: 4}); got != want {
+		t.Fatalf("cursor after uncomment: got %v, want %v", got, want)
+	}
+}
+
+func TestToggleComment_SelectionAlignsAtMinIndent(t *testing.T) {
+	text := "func f() {\n    x()\n\n  y()\n}"
+	m := New(Config{Text: text, Comment: CommentTokens{Line: "#"}})
+	m.buf.SetSelection(buffer.Range{
+		Start: buffer.Pos{Row: 1, GraphemeCol: 0},
+		End:   buffer.Pos{Row: 4, GraphemeCol: 0},
+	})
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "func f() {\n  #   x()\n\n  # y()\n}"; got != want {
+		t.Fatalf("comment: got %q, want %q", got, want)
+	}
+	sel, ok := m.buf.Selection()
+	wantSel := buffer.Range{Start: buffer.Pos{Row: 1, GraphemeCol: 0}, End: buffer.Pos{Row: 4, GraphemeCol: 0}}
+	if !ok || sel != wantSel {
+		t.Fatalf("selection after comment: got %v ok=%v, want %v", sel, ok, wantSel)
+	}
+
+	// Mixed commented/uncommented lines are commented again.
+	m.buf.SetSelection(buffer.Range{
+		Start: buffer.Pos{Row: 0, GraphemeCol: 2},
+		End:   buffer.Pos{Row: 1, GraphemeCol: 5},
+	})
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "# func f() {\n#   #   x()\n\n  # y()\n}"; got != want {
+		t.Fatalf("mixed comment: got %q, want %q", got, want)
+	}
+	sel, ok = m.buf.Selection()
+	wantSel = buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 4}, End: buffer.Pos{Row: 1, GraphemeCol: 7}}
+	if !ok || sel != wantSel {
+		t.Fatalf("selection after mixed comment: got %v ok=%v, want %v", sel, ok, wantSel)
+	}
+
+	// The whole toggle is one undo step.
+	m, _ = m.Update(testKeyCode('z', tea.ModCtrl))
+	if got, want := m.buf.Text(), "func f() {\n  #   x()\n\n  # y()\n}"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
+
+func TestToggleComment_Block(t *testing.T) {
+	m := New(Config{Text: "a {\n  color: red;\n}", Comment: CommentTokens{BlockStart: "/*", BlockEnd: "*/"}})
+	m.buf.SetSelection(buffer.Range{
+		Start: buffer.Pos{Row: 0, GraphemeCol: 0},
+		End:   buffer.Pos{Row: 2, GraphemeCol: 1},
+	})
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "/* a {\n  color: red;\n} */"; got != want {
+		t.Fatalf("block comment: got %q, want %q", got, want)
+	}
+	sel, _ := m.buf.Selection()
+	if want := (buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 0}, End: buffer.Pos{Row: 2, GraphemeCol: 1}}); sel != want {
+		t.Fatalf("selection after block comment: got %v, want %v", sel, want)
+	}
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "a {\n  color: red;\n}"; got != want {
+		t.Fatalf("block uncomment: got %q, want %q", got, want)
+	}
+
+	m = New(Config{Text: "  /*x*/", Comment: CommentTokens{BlockStart: "/*", BlockEnd: "*/"}})
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "  x"; got != want {
+		t.Fatalf("tight block uncomment: got %q, want %q", got, want)
+	}
+}
+
+func TestToggleComment_PartialSelectionUsesBlock(t *testing.T) {
+	tokens := CommentTokens{Line: "//", BlockStart: "/*", BlockEnd: "*/"}
+	m := New(Config{Text: "x := a + b\ny", Comment: tokens})
+	m.buf.SetCursor(buffer.Pos{GraphemeCol: 10})
+	m.buf.SetSelection(buffer.Range{Start: buffer.Pos{GraphemeCol: 5}, End: buffer.Pos{GraphemeCol: 10}})
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "x := /* a + b */\ny"; got != want {
+		t.Fatalf("partial comment: got %q, want %q", got, want)
+	}
+	sel, _ := m.buf.Selection()
+	if want := (buffer.Range{Start: buffer.Pos{GraphemeCol: 5}, End: buffer.Pos{GraphemeCol: 16}}); sel != want {
+		t.Fatalf("selection should cover the delimiters: got %v, want %v", sel, want)
+	}
+
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "x := a + b\ny"; got != want {
+		t.Fatalf("partial uncomment: got %q, want %q", got, want)
+	}
+
+	// Whole rows still toggle line comments.
+	m.buf.SetSelection(buffer.Range{End: buffer.Pos{Row: 1, GraphemeCol: 1}})
+	m, _ = m.Update(toggleCommentKey())
+	if got, want := m.buf.Text(), "// x := a + b\n// y"; got != want {
+		t.Fatalf("line comment: got %q, want %q", got, want)
+	}
+}
+
+func TestToggleComment_MultiRowSelectionUsesLineComments(t *testing.T) {
+	tokens := CommentTokens{Line: "//", BlockStart: "/*", BlockEnd: "*/"}
+	for _, sel := range []buffer.Range{
+		// Starting at the end of a line.
+		{Start: buffer.Pos{GraphemeCol: 6}, End: buffer.Pos{Row: 1, GraphemeCol: 6}},
+		// Starting and ending inside the text.
+		{Start: buffer.Pos{GraphemeCol: 2}, End: buffer.Pos{Row: 1, GraphemeCol: 3}},
+	} {
+		m := New(Config{Text: "a := 1\nb := 2", Comment: tokens})
+		m.buf.SetCursor(sel.End)
+		m.buf.SetSelection(sel)
+
+		m, _ = m.Update(toggleCommentKey())
+		if got, want := m.buf.Text(), "// a := 1\n// b := 2"; got != want {
+			t.Fatalf("selection %v: got %q, want %q", sel, got, want)
+		}
+	}
+}
+
+func TestToggleComment_DisabledAndReadOnly(t *testing.T) {
+	m := New(Config{Text: "x"})
+	m, _ = m.Update(toggleCommentKey())
+	if got := m.buf.Text(); got != "x" {
+		t.Fatalf("without tokens: got %q", got)
+	}
+
+	m = New(Config{Text: "x", ReadOnly: true, Comment: CommentTokens{Line: "//"}})
+	m, _ = m.Update(toggleCommentKey())
+	if got := m.buf.Text(); got != "x" {
+		t.Fatalf("read-only: got %q", got)
+	}
+}
+
+func TestToggleComment_EmitsEditIntent(t *testing.T) {
+	var intents []Intent
+	m := New(Config{
+		Text:         "a\nb",
+		Comment:      CommentTokens{Line: "//"},
+		MutationMode: EmitIntentsOnly,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			intents = append(intents, batch.Intents...)
+			return IntentDecision{}
+		},
+	})
+	m.buf.SetSelection(buffer.Range{End: buffer.Pos{Row: 1, GraphemeCol: 1}})
+
+	m, _ = m.Update(toggleCommentKey())
+	if got := m.buf.Text(); got != "a\nb" {
+		t.Fatalf("text in intents-only mode: got %q", got)
+	}
+	if len(intents) != 1 || intents[0].Kind != IntentEdit {
+		t.Fatalf("intents: got %+v", intents)
+	}
+	want := EditIntentPayload{Edits: []buffer.TextEdit{
+		{Range: buffer.Range{Start: buffer.Pos{Row: 1}, End: buffer.Pos{Row: 1}}, Text: "// "},
+		{Range: buffer.Range{Start: buffer.Pos{Row: 0}, End: buffer.Pos{Row: 0}}, Text: "// "},
+	}, Command: "edit.toggleComment"}
+	if !reflect.DeepEqual(intents[0].Payload, want) {
+		t.Fatalf("payload: got %+v, want %+v", intents[0].Payload, want)
+	}
+}
@@ -29,6 +29,15 @@ type CompletionItem struct {
 	Detail []CompletionSegment
 
 	StyleKey string
+
+	// Documentation is shown in a panel beside the popup while the item is
+	// selected. DocumentationFormat selects plain or markdown-lite rendering.
+	Documentation       string
+	DocumentationFormat CompletionDocFormat
+
+	// IsSnippet reports that the inserted text (the first edit's text, or
+	// InsertText without Edits) is snippet syntax (see ParseSnippet).
+	IsSnippet bool
 }
 
 type CompletionState struct {
@@ -39,6 +48,17 @@ type CompletionState struct {
 	Selected int
 
 	VisibleIndices []int
+	// MatchedPositions maps item indices to grapheme positions in the item's
+	// RowText that matched Query. The popup styles them with
+	// CompletionMatchStyleKey.
+	MatchedPositions map[int][]int
+
+	// Loading shows a loading indicator row below the items. The editor sets
+	// it while a CompletionProvider request is pending.
+	Loading bool
+	// Incomplete marks Items as partial: with a CompletionProvider, the
+	// editor requests items again when the query changes.
+	Incomplete bool
 }
 
 type CompletionFilterContext struct {
@@ -48,14 +68,17 @@ type CompletionFilterContext struct {
 	DocID      string
 	DocVersion uint64
 
-	// lowerCache holds pre-computed lowercased flattened text per item,
-	// used by defaultCompletionFilter to avoid re-lowercasing on every keystroke.
-	lowerCache []string
+	// textCache holds pre-split item row text per item, used by
+	// defaultCompletionFilter to avoid re-splitting on every keystroke.
+	textCache []fuzzyText
 }
 
 type CompletionFilterResult struct {
 	VisibleIndices []int
 	SelectedIndex  int
+	// MatchedPositions optionally maps item indices to matched grapheme
+	// positions in CompletionItem.RowText, e.g. from FuzzyMatch.
+	MatchedPositions map[int][]int
 }
 
 type CompletionFilter func(CompletionFilterContext) CompletionFilterResult
@@ -78,13 +101,40 @@ type CompletionKeyMap struct {
 	Prev     key.Binding
 	PageNext key.Binding
 	PagePrev key.Binding
+
+	// DocScrollDown and DocScrollUp scroll the selected item's
+	// documentation panel by one row.
+	DocScrollDown key.Binding
+	DocScrollUp   key.Binding
 }
 
-func (km CompletionKeyMap) isZero() bool {
-	return !km.AcceptTab && allBindingsZero([]key.Binding{
+// bindings returns all key bindings as a slice.
+func (km CompletionKeyMap) bindings() []key.Binding {
+	return []key.Binding{
 		km.Trigger, km.Accept, km.Dismiss,
 		km.Next, km.Prev, km.PageNext, km.PagePrev,
-	})
+		km.DocScrollDown, km.DocScrollUp,
+	}
+}
+
+// commands pairs the popup bindings with their commands, in match order.
+// Trigger is matched on its own, as it also applies while the popup is
+// hidden.
+func (km CompletionKeyMap) commands() []keyCommand {
+	return []keyCommand{
+		{km.Dismiss, "completion.dismiss"},
+		{km.Next, "completion.next"},
+		{km.Prev, "completion.prev"},
+		{km.PageNext, "completion.pageNext"},
+		{km.DocScrollDown, "completion.docScrollDown"},
+		{km.DocScrollUp, "completion.docScrollUp"},
+		{km.PagePrev, "completion.pagePrev"},
+		{km.Accept, "completion.accept"},
+	}
+}
+
+func (km CompletionKeyMap) isZero() bool {
+	return !km.AcceptTab && allBindingsZero(km.bindings())
 }
 
 func DefaultCompletionKeyMap() CompletionKeyMap {
@@ -100,6 +150,9 @@ func DefaultCompletionKeyMap() CompletionKeyMap {
 		Prev:      key.NewBinding(key.WithKeys("up"), key.WithHelp("up", "prev completion")),
 		PageNext:  key.NewBinding(key.WithKeys("pgdown"), key.WithHelp("pgdown", "next completion page")),
 		PagePrev:  key.NewBinding(key.WithKeys("pgup"), key.WithHelp("pgup", "prev completion page")),
+
+		DocScrollDown: key.NewBinding(key.WithKeys("alt+down"), key.WithHelp("alt+down", "scroll completion docs down")),
+		DocScrollUp:   key.NewBinding(key.WithKeys("alt+up"), key.WithHelp("alt+up", "scroll completion docs up")),
 	}
 }
 
@@ -111,6 +164,7 @@ const (
 	IntentCompletionAccept
 	IntentCompletionDismiss
 	IntentCompletionQuery
+	IntentCompletionDocScroll
 )
 
 type CompletionIntent struct {
@@ -139,6 +193,8 @@ type CompletionAcceptIntentPayload struct {
 	VisibleIndex int
 	InsertText   string
 	Edits        []buffer.TextEdit
+	// Snippet reports that Edits[0].Text is snippet syntax.
+	Snippet bool
 }
 
 type CompletionDismissIntentPayload struct{}
@@ -147,6 +203,11 @@ type CompletionQueryIntentPayload struct {
 	Query string
 }
 
+type CompletionDocScrollIntentPayload struct {
+	Delta  int
+	Offset int
+}
+
 func normalizeCompletionInputMode(mode CompletionInputMode) CompletionInputMode {
 	switch mode {
 	case CompletionInputQueryOnly, CompletionInputMutateDocument:
@@ -193,9 +254,21 @@ func normalizeCompletionState(state CompletionState) CompletionState {
 func cloneCompletionState(state CompletionState) CompletionState {
 	state.Items = cloneCompletionItems(state.Items)
 	state.VisibleIndices = slices.Clone(state.VisibleIndices)
+	state.MatchedPositions = cloneMatchedPositions(state.MatchedPositions)
 	return state
 }
 
+func cloneMatchedPositions(in map[int][]int) map[int][]int {
+	if in == nil {
+		return nil
+	}
+	out := make(map[int][]int, len(in))
+	for idx, pos := range in {
+		out[idx] = slices.Clone(pos)
+	}
+	return out
+}
+
 func cloneCompletionItems(items []CompletionItem) []CompletionItem {
 	out := slices.Clone(items)
 	for i := range out {
@@ -241,13 +314,15 @@ func (m Model) CompletionState() CompletionState {
 }
 
 func (m Model) SetCompletionState(state CompletionState) Model {
+	m.completionReq.supersede()
 	m.completionState = cloneCompletionState(state)
-	m.completionLowerCache = nil // invalidate; recomputeCompletionFilter rebuilds
+	m.completionTextCache = nil // invalidate; recomputeCompletionFilter rebuilds
 	m.recomputeCompletionFilter(&m.completionState)
 	return m
 }
 
 func (m Model) ClearCompletion() Model {
+	m.completionReq.supersede()
 	m.completionState = CompletionState{}
 	return m
 }
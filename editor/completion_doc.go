@@ -0,0 +1,344 @@
+package editor
+
+import (
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+const (
+	defaultCompletionDocMaxWidth  = 50
+	defaultCompletionDocMaxHeight = 12
+	// completionDocMinWidth is the narrowest panel placed beside the popup;
+	// narrower space puts the panel below (or above) it instead.
+	completionDocMinWidth = 20
+)
+
+// CompletionDocStyleKey is passed to Config.CompletionStyleForKey to style the
+// documentation panel. When unresolved, Style.CompletionItem is used.
+const CompletionDocStyleKey = "completion.doc"
+
+// CompletionDocCodeStyleKey styles code spans and blocks in markdown
+// documentation over the panel style. When unresolved, code renders faint.
+const CompletionDocCodeStyleKey = "completion.doc.code"
+
+// CompletionDocFormat selects how CompletionItem.Documentation is rendered.
+type CompletionDocFormat uint8
+
+const (
+	// CompletionDocPlain renders documentation as wrapped plain text.
+	CompletionDocPlain CompletionDocFormat = iota
+	// CompletionDocMarkdown renders a markdown subset: "#" headings, "-"/"*"
+	// bullets, ``` fenced code blocks, and inline `code`, **bold** and
+	// *italic*.
+	CompletionDocMarkdown
+)
+
+type completionDocAttr uint8
+
+const (
+	completionDocBold completionDocAttr = 1 << iota
+	completionDocItalic
+	completionDocCode
+)
+
+// completionDocCell is one grapheme of parsed documentation.
+type completionDocCell struct {
+	text  string
+	width int
+	attr  completionDocAttr
+}
+
+// completionDocLayoutResult is the placed documentation panel in
+// content-area coordinates.
+type completionDocLayoutResult struct {
+	rows      []string
+	x, y      int
+	maxOffset int
+}
+
+// parseCompletionDoc splits documentation into unwrapped lines of cells.
+func parseCompletionDoc(doc string, format CompletionDocFormat) [][]completionDocCell {
+	doc = strings.TrimRight(strings.ReplaceAll(doc, "\r", ""), "\n")
+	if doc == "" {
+		return nil
+	}
+	var out [][]completionDocCell
+	inFence := false
+	for _, line := range strings.Split(doc, "\n") {
+		line = sanitizeSegmentText(strings.ReplaceAll(line, "\t", "    "))
+		if format != CompletionDocMarkdown {
+			out = append(out, completionDocCells(line, 0))
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, completionDocCells(line, completionDocCode))
+			continue
+		}
+		out = append(out, parseCompletionDocMarkdownLine(line))
+	}
+	return out
+}
+
+func parseCompletionDocMarkdownLine(line string) []completionDocCell {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(trimmed)]
+	if level := len(trimmed) - len(strings.TrimLeft(trimmed, "#")); level > 0 && level <= 6 && strings.HasPrefix(trimmed[level:], " ") {
+		return parseCompletionDocInline(strings.TrimSpace(trimmed[level:]), completionDocBold)
+	}
+	if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
+		return append(completionDocCells(indent+"• ", 0), parseCompletionDocInline(trimmed[2:], 0)...)
+	}
+	return parseCompletionDocInline(line, 0)
+}
+
+// parseCompletionDocInline resolves `code`, **bold** and *italic* markers. Markers without a closing counterpart render literally.
+func parseCompletionDocInline(line string, base completionDocAttr) []completionDocCell {
+	var out []completionDocCell
+	attr := base
+	for i := 0; i < len(line); {
+		marker, flag := "", completionDocAttr(0)
+		switch {
+		case line[i] == '`':
+			marker, flag = "`", completionDocCode
+		case attr&completionDocCode != 0:
+		case strings.HasPrefix(line[i:], "**"):
+			marker, flag = "**", completionDocBold
+		case line[i] == '*':
+			marker, flag = "*", completionDocItalic
+		}
+		if marker != "" && (attr&flag != 0 || strings.Contains(line[i+len(marker):], marker)) {
+			attr ^= flag
+			i += len(marker)
+			continue
+		}
+		next := i + 1
+		for next < len(line) && line[next] != '`' && line[next] != '*' {
+			next++
+		}
+		out = append(out, completionDocCells(line[i:next], attr)...)
+		i = next
+	}
+	return out
+}
+
+func completionDocCells(text string, attr completionDocAttr) []completionDocCell {
+	parts := graphemeutil.Split(text)
+	out := make([]completionDocCell, 0, len(parts))
+	for _, g := range parts {
+		out = append(out, completionDocCell{text: g, width: max(graphemeCellWidth(g, 0, 4), 1), attr: attr})
+	}
+	return out
+}
+
+// wrapCompletionDoc wraps lines to width cells, breaking after spaces where
+// possible.
+func wrapCompletionDoc(lines [][]completionDocCell, width int) [][]completionDocCell {
+	var out [][]completionDocCell
+	for _, line := range lines {
+		for {
+			used, cut, lastSpace := 0, len(line), -1
+			for i, c := range line {
+				if used+c.width > width {
+					cut = i
+					break
+				}
+				used += c.width
+				if c.text == " " {
+					lastSpace = i
+				}
+			}
+			if cut == len(line) {
+				out = append(out, line)
+				break
+			}
+			if line[cut].text == " " {
+				out = append(out, line[:cut])
+				line = line[cut+1:]
+			} else if lastSpace > 0 {
+				out = append(out, line[:lastSpace])
+				line = line[lastSpace+1:]
+			} else {
+				out = append(out, line[:max(cut, 1)])
+				line = line[max(cut, 1):]
+			}
+		}
+	}
+	return out
+}
+
+func completionDocNaturalWidth(lines [][]completionDocCell) int {
+	width := 0
+	for _, line := range lines {
+		w := 0
+		for _, c := range line {
+			w += c.width
+		}
+		width = max(width, w)
+	}
+	return width
+}
+
+// completionDocLines returns the parsed documentation of item itemIdx.
+func (m Model) completionDocLines(itemIdx int) [][]completionDocCell {
+	if itemIdx < 0 || itemIdx >= len(m.completionState.Items) {
+		return nil
+	}
+	item := m.completionState.Items[itemIdx]
+	return parseCompletionDoc(item.Documentation, item.DocumentationFormat)
+}
+
+// completionDocLayout places the selected item's documentation beside the
+// popup (right, then left), or below/above it when neither side fits
+// completionDocMinWidth. The panel has one cell of horizontal padding.
+func (m Model) completionDocLayout(popup completionPopupLayout) (completionDocLayoutResult, bool) {
+	lines := m.completionDocLines(popup.selectedItem)
+	if len(lines) == 0 {
+		return completionDocLayoutResult{}, false
+	}
+	maxWidth := normalizeCompletionDocMaxWidth(m.cfg.CompletionDocMaxWidth)
+	maxHeight := normalizeCompletionDocMaxHeight(m.cfg.CompletionDocMaxHeight)
+	want := min(completionDocNaturalWidth(lines)+2, maxWidth)
+
+	popupBottom := popup.y + len(popup.rows)
+	rightAvail := popup.contentRight - (popup.x + popup.width)
+	leftAvail := popup.x - popup.contentLeft
+	sideMin := min(want, completionDocMinWidth)
+
+	var x, y, width, height int
+	above := false
+	switch {
+	case rightAvail >= sideMin || leftAvail >= sideMin:
+		if rightAvail >= sideMin && (rightAvail >= want || rightAvail >= leftAvail) {
+			width = min(want, rightAvail)
+			x = popup.x + popup.width
+		} else {
+			width = min(want, leftAvail)
+			x = popup.x - width
+		}
+		height = min(maxHeight, popup.contentHeight)
+		y = popup.y
+	default:
+		width = min(want, popup.contentRight-popup.contentLeft)
+		x = clampInt(popup.x, popup.contentLeft, popup.contentRight-width)
+		if popup.showBelow {
+			y = popupBottom
+			height = min(maxHeight, popup.contentHeight-popupBottom)
+		} else {
+			height = min(maxHeight, popup.y)
+			above = true
+		}
+	}
+	if width <= 2 || height <= 0 {
+		return completionDocLayoutResult{}, false
+	}
+
+	wrapped := wrapCompletionDoc(lines, width-2)
+	height = min(height, len(wrapped))
+	if above {
+		y = popup.y - height
+	}
+	y = clampInt(y, 0, popup.contentHeight-height)
+	maxOffset := len(wrapped) - height
+	offset := clampInt(m.completionDocScroll, 0, maxOffset)
+
+	base := m.completionDocStyle()
+	code := m.completionDocCodeStyle(base)
+	rows := make([]string, 0, height)
+	for _, line := range wrapped[offset : offset+height] {
+		rows = append(rows, renderDocRow(line, base, code, width))
+	}
+	return completionDocLayoutResult{rows: rows, x: x, y: y, maxOffset: maxOffset}, true
+}
+
+func (m Model) completionDocStyle() lipgloss.Style {
+	base := m.cfg.Style.CompletionItem
+	if m.cfg.CompletionStyleForKey != nil {
+		if keyed, ok := m.cfg.CompletionStyleForKey(CompletionDocStyleKey); ok {
+			return keyed.Inherit(base)
+		}
+	}
+	return base
+}
+
+// completionDocCodeStyle resolves the style of code in documentation over
+// base: CompletionDocCodeStyleKey when resolved, faint otherwise.
+func (m Model) completionDocCodeStyle(base lipgloss.Style) lipgloss.Style {
+	if m.cfg.CompletionStyleForKey != nil {
+		if keyed, ok := m.cfg.CompletionStyleForKey(CompletionDocCodeStyleKey); ok {
+			return keyed.Inherit(base)
+		}
+	}
+	return base.Faint(true)
+}
+
+func docAttrStyle(base, code lipgloss.Style, attr completionDocAttr) lipgloss.Style {
+	st := base
+	if attr&completionDocCode != 0 {
+		st = code
+	}
+	if attr&completionDocBold != 0 {
+		st = st.Bold(true)
+	}
+	if attr&completionDocItalic != 0 {
+		st = st.Italic(true)
+	}
+	return st
+}
+
+// renderDocRow renders one wrapped documentation line padded to width, with
+// one cell of padding on the left.
+func renderDocRow(line []completionDocCell, base, code lipgloss.Style, width int) string {
+	var sb strings.Builder
+	sb.WriteString(base.Render(" "))
+	used := 1
+	for i := 0; i < len(line); {
+		j := i
+		var run strings.Builder
+		for j < len(line) && line[j].attr == line[i].attr {
+			run.WriteString(line[j].text)
+			used += line[j].width
+			j++
+		}
+		sb.WriteString(docAttrStyle(base, code, line[i].attr).Render(run.String()))
+		i = j
+	}
+	if used < width {
+		sb.WriteString(base.Render(spaceString(width - used)))
+	}
+	return sb.String()
+}
+
+// completionDocMaxOffset reports how far the selected item's documentation
+// can scroll, or false when no panel is shown.
+func (m Model) completionDocMaxOffset() (int, bool) {
+	popup, ok := m.completionPopupLayout()
+	if !ok {
+		return 0, false
+	}
+	doc, ok := m.completionDocLayout(popup)
+	if !ok {
+		return 0, false
+	}
+	return doc.maxOffset, true
+}
+
+func normalizeCompletionDocMaxWidth(width int) int {
+	if width <= 0 {
+		return defaultCompletionDocMaxWidth
+	}
+	return width
+}
+
+func normalizeCompletionDocMaxHeight(height int) int {
+	if height <= 0 {
+		return defaultCompletionDocMaxHeight
+	}
+	return height
+}
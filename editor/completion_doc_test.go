@@ -0,0 +1,151 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func docCellsText(line []completionDocCell) (string, []completionDocAttr) {
+	var sb strings.Builder
+	var attrs []completionDocAttr
+	for _, c := range line {
+		sb.WriteString(c.text)
+		attrs = append(attrs, c.attr)
+	}
+	return sb.String(), attrs
+}
+
+func TestParseCompletionDoc_MarkdownLite(t *testing.T) {
+	lines := parseCompletionDoc("# Title\n- use `x` **now**\n```\na*b\n```\n2 * 3", CompletionDocMarkdown)
+	var texts []string
+	for _, l := range lines {
+		text, _ := docCellsText(l)
+		texts = append(texts, text)
+	}
+	if want := []string{"Title", "• use x now", "a*b", "2 * 3"}; !reflect.DeepEqual(texts, want) {
+		t.Fatalf("texts: got %q, want %q", texts, want)
+	}
+
+	_, attrs := docCellsText(lines[0])
+	if attrs[0] != completionDocBold {
+		t.Fatalf("heading should be bold: %v", attrs)
+	}
+	_, attrs = docCellsText(lines[1])
+	if attrs[6] != completionDocCode || attrs[8] != completionDocBold || attrs[2] != 0 {
+		t.Fatalf("inline attrs: %v", attrs)
+	}
+	if _, attrs = docCellsText(lines[2]); attrs[1] != completionDocCode {
+		t.Fatalf("fenced code: %v", attrs)
+	}
+
+	plain := parseCompletionDoc("**x**", CompletionDocPlain)
+	if text, _ := docCellsText(plain[0]); text != "**x**" {
+		t.Fatalf("plain text must not parse markers: %q", text)
+	}
+}
+
+func TestWrapCompletionDoc_BreaksAtSpaces(t *testing.T) {
+	wrapped := wrapCompletionDoc(parseCompletionDoc("aa bb cc\nabcdefg", CompletionDocPlain), 5)
+	var got []string
+	for _, l := range wrapped {
+		text, _ := docCellsText(l)
+		got = append(got, text)
+	}
+	if want := []string{"aa bb", "cc", "abcde", "fg"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrapped: got %q, want %q", got, want)
+	}
+}
+
+func docPanelModel(width, height int, docs ...string) Model {
+	items := make([]CompletionItem, len(docs))
+	for i, doc := range docs {
+		items[i] = CompletionItem{ID: doc, Label: []CompletionSegment{{Text: "it"}}, Documentation: doc}
+	}
+	m := New(Config{Text: strings.Repeat(strings.Repeat("0", width)+"\n", height-1) + strings.Repeat("0", width)})
+	m = m.Blur()
+	m = m.SetSize(width, height)
+	return m.SetCompletionState(CompletionState{Visible: true, Anchor: bufferPos(0, 0), Items: items})
+}
+
+func TestCompletionDocPanel_RendersBesidePopup(t *testing.T) {
+	m := docPanelModel(30, 3, "first doc", "second").Focus()
+
+	got := strings.Split(stripANSI(m.View().Content), "\n")
+	assertLines(t, got, []string{
+		strings.Repeat("0", 30),
+		"it first doc " + strings.Repeat("0", 17),
+		"it" + strings.Repeat("0", 28),
+	})
+
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	got = strings.Split(stripANSI(m.View().Content), "\n")
+	if want := "it second 0"; !strings.HasPrefix(got[1], want) {
+		t.Fatalf("expected the selected item's doc: %q", got[1])
+	}
+}
+
+func TestCompletionDocPanel_FallsBelowWhenNoSideSpace(t *testing.T) {
+	m := docPanelModel(12, 5, "some longer doc")
+
+	got := strings.Split(stripANSI(m.View().Content), "\n")
+	assertLines(t, got, []string{
+		strings.Repeat("0", 12),
+		"it" + strings.Repeat("0", 10),
+		// Trailing unstyled padding is trimmed by the compositor.
+		" some",
+		" longer doc",
+		strings.Repeat("0", 12),
+	})
+}
+
+func TestCompletionDocPanel_ScrollKeys(t *testing.T) {
+	var intents []CompletionIntent
+	m := New(Config{
+		Text:                   "000000000000000000000000000000\n000000000000000000000000000000\n000000000000000000000000000000",
+		CompletionDocMaxWidth:  8,
+		CompletionDocMaxHeight: 2,
+		OnCompletionIntent: func(b CompletionIntentBatch) {
+			intents = append(intents, b.Intents...)
+		},
+	}).SetSize(30, 3)
+	m = m.SetCompletionState(CompletionState{Visible: true, Anchor: bufferPos(0, 0), Items: []CompletionItem{
+		{ID: "a", Label: []CompletionSegment{{Text: "it"}}, Documentation: "one two three four"},
+		{ID: "b", Label: []CompletionSegment{{Text: "it"}}},
+	}})
+	m = m.Blur().Focus()
+
+	view := func() []string { return strings.Split(stripANSI(m.View().Content), "\n") }
+	if got := view(); !strings.HasPrefix(got[1], "it one   ") || !strings.HasPrefix(got[2], "it two   ") {
+		t.Fatalf("initial doc rows: %q", got)
+	}
+
+	for range 3 {
+		m, _ = m.Update(testKeyCode(tea.KeyDown, tea.ModAlt))
+	}
+	if got := view(); !strings.HasPrefix(got[1], "it three ") || !strings.HasPrefix(got[2], "it four  ") {
+		t.Fatalf("scrolled doc rows: %q", got)
+	}
+	last := intents[len(intents)-1]
+	if last.Kind != IntentCompletionDocScroll || last.Payload != (CompletionDocScrollIntentPayload{Delta: 1, Offset: 2}) {
+		t.Fatalf("scroll intent: %+v", last)
+	}
+	if got := m.CompletionState().Selected; got != 0 {
+		t.Fatalf("doc scroll must not move selection: %d", got)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyUp, tea.ModAlt))
+	if got := view(); !strings.HasPrefix(got[1], "it two   ") {
+		t.Fatalf("scroll up: %q", got)
+	}
+
+	// Items without docs leave the keys to the editor.
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	before := len(intents)
+	m, _ = m.Update(testKeyCode(tea.KeyDown, tea.ModAlt))
+	if len(intents) != before {
+		t.Fatalf("doc scroll without a panel must not emit intents")
+	}
+}
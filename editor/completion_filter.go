@@ -1,9 +1,11 @@
 package editor
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/internal/grapheme"
 )
 
 func (m *Model) recomputeCompletionFilter(state *CompletionState) {
@@ -11,11 +13,11 @@ func (m *Model) recomputeCompletionFilter(state *CompletionState) {
 		return
 	}
 
-	// Rebuild lowercased text cache when item list changes.
-	if len(m.completionLowerCache) != len(state.Items) {
-		m.completionLowerCache = make([]string, len(state.Items))
+	// Rebuild the split text cache when item list changes.
+	if len(m.completionTextCache) != len(state.Items) {
+		m.completionTextCache = make([]fuzzyText, len(state.Items))
 		for i := range state.Items {
-			m.completionLowerCache[i] = strings.ToLower(flattenCompletionItemText(state.Items[i]))
+			m.completionTextCache[i] = newCompletionFuzzyText(state.Items[i])
 		}
 	}
 
@@ -25,7 +27,7 @@ func (m *Model) recomputeCompletionFilter(state *CompletionState) {
 		Cursor:     m.completionFilterCursor(),
 		DocID:      m.cfg.DocID,
 		DocVersion: m.docVersion(),
-		lowerCache: m.completionLowerCache,
+		textCache:  m.completionTextCache,
 	}
 
 	result := CompletionFilterResult{}
@@ -37,6 +39,8 @@ func (m *Model) recomputeCompletionFilter(state *CompletionState) {
 
 	state.VisibleIndices = sanitizeCompletionVisibleIndices(result.VisibleIndices, len(state.Items))
 	state.Selected = clampCompletionSelected(result.SelectedIndex, len(state.VisibleIndices))
+	state.MatchedPositions = result.MatchedPositions
+	m.completionDocScroll = 0
 }
 
 func (m *Model) completionFilterCursor() buffer.Pos {
@@ -46,36 +50,69 @@ func (m *Model) completionFilterCursor() buffer.Pos {
 	return m.buf.Cursor()
 }
 
+// defaultCompletionFilter keeps items whose label fuzzy-matches the query,
+// best score first; equal scores keep source order. Matched positions index
+// the row text.
 func defaultCompletionFilter(ctx CompletionFilterContext) CompletionFilterResult {
-	query := strings.ToLower(ctx.Query)
-	visible := make([]int, 0, len(ctx.Items))
-	lowerCache := ctx.lowerCache
+	query := lowerGraphemes(ctx.Query)
+	type scoredItem struct {
+		index int
+		score int
+	}
+	scored := make([]scoredItem, 0, len(ctx.Items))
+	var matched map[int][]int
 	for i := range ctx.Items {
-		var itemText string
-		if i < len(lowerCache) {
-			itemText = lowerCache[i]
+		var text fuzzyText
+		if i < len(ctx.textCache) {
+			text = ctx.textCache[i]
 		} else {
-			itemText = strings.ToLower(flattenCompletionItemText(ctx.Items[i]))
+			text = newCompletionFuzzyText(ctx.Items[i])
+		}
+		score, positions, ok := fuzzyMatchText(query, text)
+		if !ok {
+			continue
 		}
-		if strings.Contains(itemText, query) {
-			visible = append(visible, i)
+		scored = append(scored, scoredItem{index: i, score: score})
+		if len(positions) > 0 {
+			if matched == nil {
+				matched = make(map[int][]int)
+			}
+			matched[i] = positions
 		}
 	}
+	slices.SortStableFunc(scored, func(a, b scoredItem) int { return b.score - a.score })
+
+	visible := make([]int, len(scored))
+	for i, s := range scored {
+		visible[i] = s.index
+	}
 	return CompletionFilterResult{
-		VisibleIndices: visible,
-		SelectedIndex:  0,
+		VisibleIndices:   visible,
+		SelectedIndex:    0,
+		MatchedPositions: matched,
 	}
 }
 
-func flattenCompletionItemText(item CompletionItem) string {
+// newCompletionFuzzyText returns the match candidate of item: its label, with
+// positions offset to where the label starts in RowText.
+func newCompletionFuzzyText(item CompletionItem) fuzzyText {
+	var label strings.Builder
+	for _, seg := range completionItemTextSegments(CompletionItem{Label: item.Label}) {
+		label.WriteString(seg.Text)
+	}
+	head := CompletionItem{Prefix: item.Prefix, Label: item.Label}.RowText()
+	t := newFuzzyText(label.String())
+	t.offset = grapheme.Count(head) - len(t.lower)
+	return t
+}
+
+// RowText returns the item text as the popup renders it: Prefix, Label and
+// Detail joined by single spaces, with control characters removed.
+// CompletionState.MatchedPositions index its graphemes.
+func (item CompletionItem) RowText() string {
 	var sb strings.Builder
-	appendSegments := func(segments []CompletionSegment) {
-		for _, seg := range segments {
-			sb.WriteString(seg.Text)
-		}
+	for _, seg := range completionItemTextSegments(item) {
+		sb.WriteString(seg.Text)
 	}
-	appendSegments(item.Prefix)
-	appendSegments(item.Label)
-	appendSegments(item.Detail)
 	return sb.String()
 }
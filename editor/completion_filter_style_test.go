@@ -20,8 +20,9 @@ func TestCompletionFilter_DefaultFilter_MixedCaseStableOrder(t *testing.T) {
 		},
 	})
 
+	// Only labels are matched: Prefix and Detail text never is.
 	state := m.CompletionState()
-	if got, want := state.VisibleIndices, []int{0, 2}; !reflect.DeepEqual(got, want) {
+	if got, want := state.VisibleIndices, []int{0}; !reflect.DeepEqual(got, want) {
 		t.Fatalf("default filter visible indices: got %v, want %v", got, want)
 	}
 	if got, want := state.Selected, 0; got != want {
@@ -29,6 +30,34 @@ func TestCompletionFilter_DefaultFilter_MixedCaseStableOrder(t *testing.T) {
 	}
 }
 
+func TestCompletionFilter_DefaultFilter_FuzzyRanksAndReportsPositions(t *testing.T) {
+	m := New(Config{})
+	m = m.SetCompletionState(CompletionState{
+		Query: "fb",
+		Items: []CompletionItem{
+			{ID: "0", Label: []CompletionSegment{{Text: "afbx"}}},
+			{ID: "1", Label: []CompletionSegment{{Text: "fooBar"}}},
+			{ID: "2", Label: []CompletionSegment{{Text: "xxfxxb"}}},
+			{ID: "3", Label: []CompletionSegment{{Text: "bf"}}},
+			{ID: "4", Prefix: []CompletionSegment{{Text: "fn"}}, Label: []CompletionSegment{{Text: "fb"}}},
+		},
+	})
+
+	state := m.CompletionState()
+	if got, want := state.VisibleIndices, []int{1, 4, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fuzzy ranking: got %v, want %v", got, want)
+	}
+	// Positions index the row text, past the Prefix and its separator.
+	if got, want := state.MatchedPositions, map[int][]int{0: {1, 2}, 1: {0, 3}, 2: {2, 5}, 4: {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("matched positions: got %v, want %v", got, want)
+	}
+
+	m = m.SetCompletionState(CompletionState{Items: state.Items})
+	if got := m.CompletionState().MatchedPositions; got != nil {
+		t.Fatalf("empty query should report no positions, got %v", got)
+	}
+}
+
 func TestCompletionFilter_Callback_SanitizesIndicesAndClampsSelected(t *testing.T) {
 	m := New(Config{
 		CompletionFilter: func(ctx CompletionFilterContext) CompletionFilterResult {
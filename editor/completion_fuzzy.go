@@ -0,0 +1,176 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// Fuzzy match scoring. A match earns fuzzyMatchScore per query grapheme plus
+// position bonuses, and loses fuzzyGapPenalty per text grapheme skipped
+// between two matched graphemes.
+const (
+	fuzzyMatchScore       = 16
+	fuzzyPrefixBonus      = 12
+	fuzzyWordStartBonus   = 10
+	fuzzyCamelBonus       = 8
+	fuzzyConsecutiveBonus = 6
+	fuzzyGapPenalty       = 1
+)
+
+// FuzzyMatch reports whether query is a case-insensitive subsequence of text
+// and scores the best alignment: matches at the text start, at word starts,
+// at camelCase humps and in contiguous runs score higher; gaps between
+// matches score lower. positions are the matched grapheme indices in text.
+// An empty query matches with score 0 and no positions.
+func FuzzyMatch(query, text string) (score int, positions []int, ok bool) {
+	return fuzzyMatchText(lowerGraphemes(query), newFuzzyText(text))
+}
+
+// fuzzyText is a match candidate split into graphemes, cached per completion
+// item so filtering does not re-split and re-lowercase on every keystroke.
+type fuzzyText struct {
+	lower []string
+	bonus []int
+	// offset is added to matched positions, e.g. to index a label within
+	// CompletionItem.RowText.
+	offset int
+}
+
+func newFuzzyText(text string) fuzzyText {
+	parts := grapheme.Split(text)
+	t := fuzzyText{lower: make([]string, len(parts)), bonus: make([]int, len(parts))}
+	prev := fuzzyClassSeparator
+	for i, g := range parts {
+		t.lower[i] = strings.ToLower(g)
+		class := fuzzyClassOf(g)
+		t.bonus[i] = fuzzyPositionBonus(prev, class)
+		if i == 0 {
+			t.bonus[i] += fuzzyPrefixBonus
+		}
+		prev = class
+	}
+	return t
+}
+
+func lowerGraphemes(s string) []string {
+	parts := grapheme.Split(s)
+	for i, g := range parts {
+		parts[i] = strings.ToLower(g)
+	}
+	return parts
+}
+
+type fuzzyClass uint8
+
+const (
+	fuzzyClassSeparator fuzzyClass = iota
+	fuzzyClassLower
+	fuzzyClassUpper
+	fuzzyClassDigit
+)
+
+func fuzzyClassOf(g string) fuzzyClass {
+	r, _ := utf8.DecodeRuneInString(g)
+	switch {
+	case unicode.IsUpper(r):
+		return fuzzyClassUpper
+	case unicode.IsLetter(r):
+		return fuzzyClassLower
+	case unicode.IsDigit(r):
+		return fuzzyClassDigit
+	}
+	return fuzzyClassSeparator
+}
+
+func fuzzyPositionBonus(prev, cur fuzzyClass) int {
+	switch {
+	case cur == fuzzyClassSeparator:
+		return 0
+	case prev == fuzzyClassSeparator:
+		return fuzzyWordStartBonus
+	case prev == fuzzyClassLower && cur == fuzzyClassUpper,
+		prev != fuzzyClassDigit && cur == fuzzyClassDigit:
+		return fuzzyCamelBonus
+	}
+	return 0
+}
+
+// fuzzyMatchText finds the best-scoring alignment of query (lowercased
+// graphemes) in t. score[i][j] is the best score with query[i] matched at
+// t[j]; gaps are scored through a running maximum of score[i-1][k] +
+// fuzzyGapPenalty*k over k < j-1, so the search is O(len(query)*len(t)).
+func fuzzyMatchText(query []string, t fuzzyText) (int, []int, bool) {
+	m, n := len(query), len(t.lower)
+	if m == 0 {
+		return 0, nil, true
+	}
+	if m > n || !fuzzyIsSubsequence(query, t.lower) {
+		return 0, nil, false
+	}
+
+	const none = -1 << 30
+	score := make([]int, m*n)
+	from := make([]int, m*n)
+	for i := range m {
+		best, bestK := none, -1
+		for j := range n {
+			idx := i*n + j
+			score[idx] = none
+			if i > 0 && j >= 2 {
+				if v := score[(i-1)*n+j-2]; v > none && v+fuzzyGapPenalty*(j-2) > best {
+					best, bestK = v+fuzzyGapPenalty*(j-2), j-2
+				}
+			}
+			if t.lower[j] != query[i] {
+				continue
+			}
+			s := fuzzyMatchScore + t.bonus[j]
+			if i == 0 {
+				score[idx], from[idx] = s, -1
+				continue
+			}
+			cand, k := none, -1
+			if bestK >= 0 {
+				cand, k = best-fuzzyGapPenalty*(j-1), bestK
+			}
+			if j >= 1 {
+				if v := score[(i-1)*n+j-1]; v > none && v+fuzzyConsecutiveBonus >= cand {
+					cand, k = v+fuzzyConsecutiveBonus, j-1
+				}
+			}
+			if k < 0 {
+				continue
+			}
+			score[idx], from[idx] = cand+s, k
+		}
+	}
+
+	total, end := none, -1
+	for j := range n {
+		if v := score[(m-1)*n+j]; v > total {
+			total, end = v, j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions := make([]int, m)
+	for i, j := m-1, end; i >= 0; i-- {
+		positions[i] = j + t.offset
+		j = from[i*n+j]
+	}
+	return total, positions, true
+}
+
+func fuzzyIsSubsequence(query, text []string) bool {
+	i := 0
+	for _, g := range text {
+		if i < len(query) && g == query[i] {
+			i++
+		}
+	}
+	return i == len(query)
+}
@@ -0,0 +1,51 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch_PositionsAndBonuses(t *testing.T) {
+	cases := []struct {
+		query, text string
+		want        []int
+	}{
+		{"gp", "getPath", []int{0, 3}},
+		{"abc", "axbxc abc", []int{6, 7, 8}},
+		{"fb", "foo_bar", []int{0, 4}},
+		{"v2", "xVersionV2", []int{8, 9}},
+		{"", "anything", nil},
+	}
+	for _, tc := range cases {
+		_, got, ok := FuzzyMatch(tc.query, tc.text)
+		if !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("FuzzyMatch(%q, %q): got %v ok=%v, want %v", tc.query, tc.text, got, ok, tc.want)
+		}
+	}
+
+	if _, _, ok := FuzzyMatch("ba", "ab"); ok {
+		t.Fatalf("out-of-order query must not match")
+	}
+	if _, pos, ok := FuzzyMatch("é", "café"); !ok || !reflect.DeepEqual(pos, []int{3}) {
+		t.Fatalf("grapheme positions: %v ok=%v", pos, ok)
+	}
+}
+
+func TestFuzzyMatch_ScoreOrdering(t *testing.T) {
+	score := func(query, text string) int {
+		s, _, ok := FuzzyMatch(query, text)
+		if !ok {
+			t.Fatalf("FuzzyMatch(%q, %q) did not match", query, text)
+		}
+		return s
+	}
+	if score("ab", "abx") <= score("ab", "xab") {
+		t.Fatalf("prefix match should outscore a later match")
+	}
+	if score("ab", "xab") <= score("ab", "xaxb") {
+		t.Fatalf("contiguous match should outscore a gapped one")
+	}
+	if score("fb", "fooBar") <= score("fb", "foobar") {
+		t.Fatalf("camelCase hump should outscore a mid-word match")
+	}
+}
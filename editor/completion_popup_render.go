@@ -1,98 +1,105 @@
 package editor
 
 import (
+	"slices"
 	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
 )
 
-type completionPopupRender struct {
-	View string
+// completionPopupLayout is the placed popup in content-area coordinates.
+type completionPopupLayout struct {
+	rows          []string
+	x, y, width   int
+	showBelow     bool
+	contentLeft   int
+	contentRight  int
+	contentHeight int
+	// selectedItem is the selected item index, or -1.
+	selectedItem int
 }
 
-func (m Model) completionPopupRender(base string) (completionPopupRender, bool) {
+func (m Model) completionPopupLayout() (completionPopupLayout, bool) {
 	state := m.completionState // read-only; no clone needed for rendering
 	if !state.Visible || m.buf == nil {
-		return completionPopupRender{}, false
+		return completionPopupLayout{}, false
 	}
 
-	mm := &m
-	lines := mm.ensureLines()
-	layout := mm.ensureLayoutCache(lines)
-	metrics := mm.resolveScrollbarMetrics(lines, layout)
-	viewportHeight := metrics.contentHeight
-	if metrics.innerWidth <= 0 || viewportHeight <= 0 {
-		return completionPopupRender{}, false
-	}
-	gutterWidth := mm.resolvedGutterWidth(len(lines))
-	contentLeft := gutterWidth
-	contentRight := contentLeft + metrics.contentWidth
-	if contentRight <= contentLeft {
-		return completionPopupRender{}, false
+	area, ok := (&m).floatArea()
+	if !ok {
+		return completionPopupLayout{}, false
 	}
+	viewportHeight := area.height
+	contentLeft, contentRight := area.left, area.right
 
 	anchorX, anchorY, ok := m.DocToScreen(state.Anchor)
 	if !ok {
-		return completionPopupRender{}, false
+		return completionPopupLayout{}, false
 	}
 
 	visible := sanitizeCompletionVisibleIndices(state.VisibleIndices, len(state.Items))
-	if len(visible) == 0 {
-		return completionPopupRender{}, false
+	loadingRows := 0
+	if state.Loading {
+		loadingRows = 1
+	}
+	if len(visible)+loadingRows == 0 {
+		return completionPopupLayout{}, false
 	}
 
 	maxRows := normalizeCompletionMaxVisibleRows(m.cfg.CompletionMaxVisibleRows)
 	if maxRows <= 0 {
-		return completionPopupRender{}, false
-	}
-	targetRows := min(maxRows, len(visible))
-
-	belowAvail := max(viewportHeight-(anchorY+1), 0)
-	aboveAvail := max(anchorY, 0)
-	showBelow := true
-	rowCount := targetRows
-	if rowCount > belowAvail {
-		if aboveAvail >= rowCount {
-			showBelow = false
-		} else if aboveAvail > belowAvail {
-			showBelow = false
-			rowCount = aboveAvail
-		} else {
-			rowCount = belowAvail
-		}
+		return completionPopupLayout{}, false
 	}
+	targetRows := min(maxRows, len(visible)+loadingRows)
+
+	showBelow, rowCount := floatVerticalPlacement(anchorY, targetRows, viewportHeight, true)
 	if rowCount <= 0 {
-		return completionPopupRender{}, false
+		return completionPopupLayout{}, false
 	}
 
+	// The loading row takes the last row; items scroll in the rest.
+	itemRows := max(rowCount-loadingRows, 0)
 	itemIndices := visible
 	selected := clampCompletionSelected(state.Selected, len(visible))
 	start := 0
-	if len(itemIndices) > rowCount {
-		start = clampInt(selected-rowCount+1, 0, len(itemIndices)-rowCount)
-		itemIndices = itemIndices[start : start+rowCount]
+	if len(itemIndices) > itemRows {
+		start = clampInt(selected-itemRows+1, 0, len(itemIndices)-itemRows)
+		itemIndices = itemIndices[start : start+itemRows]
 	}
 
-	widthCap := min(normalizeCompletionMaxWidth(m.cfg.CompletionMaxWidth), metrics.contentWidth)
+	widthCap := min(normalizeCompletionMaxWidth(m.cfg.CompletionMaxWidth), contentRight-contentLeft)
 	if widthCap <= 0 {
-		return completionPopupRender{}, false
+		return completionPopupLayout{}, false
 	}
 
 	// Single pass: compute segments for each item, measure width, and store for rendering.
 	type itemEntry struct {
 		item     CompletionItem
 		segments []CompletionSegment
+		matched  []int
 	}
 	entries := make([]itemEntry, len(itemIndices))
 	popupWidth := 0
 	for i, idx := range itemIndices {
 		item := state.Items[idx]
 		segs := completionItemSegments(item)
-		entries[i] = itemEntry{item: item, segments: segs}
+		entries[i] = itemEntry{item: item, segments: segs, matched: state.MatchedPositions[idx]}
 		if w := completionSegmentsCellWidth(segs); w > popupWidth {
 			popupWidth = w
 		}
 	}
+	var loading itemEntry
+	if loadingRows > 0 {
+		loading.item = m.completionLoadingItem()
+		loading.segments = completionItemSegments(loading.item)
+		if w := completionSegmentsCellWidth(loading.segments); w > popupWidth {
+			popupWidth = w
+		}
+	}
 	if popupWidth <= 0 {
-		return completionPopupRender{}, false
+		return completionPopupLayout{}, false
 	}
 	if popupWidth > widthCap {
 		popupWidth = widthCap
@@ -101,7 +108,10 @@ func (m Model) completionPopupRender(base string) (completionPopupRender, bool)
 	rendered := make([]string, 0, len(itemIndices))
 	for row, e := range entries {
 		selectedRow := row == selected-start
-		rendered = append(rendered, m.renderCompletionPopupRowFromSegments(e.item, e.segments, selectedRow, popupWidth))
+		rendered = append(rendered, m.renderCompletionPopupRowFromSegments(e.item, e.segments, e.matched, selectedRow, popupWidth))
+	}
+	if loadingRows > 0 && rowCount > 0 {
+		rendered = append(rendered, m.renderCompletionPopupRowFromSegments(loading.item, loading.segments, nil, false, popupWidth))
 	}
 
 	y := anchorY + 1
@@ -123,32 +133,41 @@ func (m Model) completionPopupRender(base string) (completionPopupRender, bool)
 	maxX := contentRight - popupWidth
 	x = clampInt(x, contentLeft, maxX)
 
-	leftFrame := m.viewport.Style.GetMarginLeft() + m.viewport.Style.GetBorderLeftSize() + m.viewport.Style.GetPaddingLeft()
-	topFrame := m.viewport.Style.GetMarginTop() + m.viewport.Style.GetBorderTopSize() + m.viewport.Style.GetPaddingTop()
-
-	return completionPopupRender{
-		View: compositeTopLeft(
-			strings.Join(rendered, "\n"),
-			base,
-			leftFrame+x,
-			topFrame+y,
-		),
+	selectedItem := -1
+	if len(visible) > 0 {
+		selectedItem = visible[selected]
+	}
+	return completionPopupLayout{
+		rows:          rendered,
+		x:             x,
+		y:             y,
+		width:         popupWidth,
+		showBelow:     showBelow,
+		contentLeft:   contentLeft,
+		contentRight:  contentRight,
+		contentHeight: viewportHeight,
+		selectedItem:  selectedItem,
 	}, true
 }
 
-func (m Model) renderCompletionPopupRowFromSegments(item CompletionItem, precomputed []CompletionSegment, selected bool, width int) string {
+func (m Model) renderCompletionPopupRowFromSegments(item CompletionItem, precomputed []CompletionSegment, matched []int, selected bool, width int) string {
 	segments := truncateCompletionSegments(precomputed, width)
 	base := completionRowBaseStyle(m.cfg.Style, selected)
 
 	var sb strings.Builder
 	used := 0
+	col := 0 // grapheme index in the row text, for matched positions
 	for _, seg := range segments {
 		text := sanitizeSegmentText(seg.Text)
 		if text == "" {
 			continue
 		}
 		segStyle := resolveCompletionSegmentStyle(base, m.cfg.CompletionStyleForKey, item, seg)
-		sb.WriteString(segStyle.Render(text))
+		if len(matched) == 0 {
+			sb.WriteString(segStyle.Render(text))
+		} else {
+			col = renderCompletionMatchedText(&sb, text, col, matched, segStyle, m.completionMatchStyle(segStyle))
+		}
 		if seg.cellWidth >= 0 {
 			used += seg.cellWidth
 		} else {
@@ -163,7 +182,75 @@ func (m Model) renderCompletionPopupRowFromSegments(item CompletionItem, precomp
 	return sb.String()
 }
 
+// renderCompletionMatchedText renders text starting at row grapheme col,
+// styling runs at matched positions with matchStyle. It returns the column
+// after text.
+func renderCompletionMatchedText(sb *strings.Builder, text string, col int, matched []int, style, matchStyle lipgloss.Style) int {
+	var run strings.Builder
+	runMatched := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if runMatched {
+			sb.WriteString(matchStyle.Render(run.String()))
+		} else {
+			sb.WriteString(style.Render(run.String()))
+		}
+		run.Reset()
+	}
+	for _, g := range graphemeutil.Split(text) {
+		isMatched := slices.Contains(matched, col)
+		if isMatched != runMatched {
+			flush()
+			runMatched = isMatched
+		}
+		run.WriteString(g)
+		col++
+	}
+	flush()
+	return col
+}
+
+// completionMatchStyle resolves the style of matched graphemes over style:
+// CompletionMatchStyleKey when resolved, bold otherwise.
+func (m Model) completionMatchStyle(style lipgloss.Style) lipgloss.Style {
+	if m.cfg.CompletionStyleForKey != nil {
+		if keyed, ok := m.cfg.CompletionStyleForKey(CompletionMatchStyleKey); ok {
+			return keyed.Inherit(style)
+		}
+	}
+	return style.Bold(true)
+}
+
+// completionLoadingItem is the pseudo-item rendered as the loading indicator.
+func (m Model) completionLoadingItem() CompletionItem {
+	text := m.cfg.CompletionLoadingText
+	if text == "" {
+		text = defaultCompletionLoadingText
+	}
+	return CompletionItem{
+		Label:    []CompletionSegment{{Text: text}},
+		StyleKey: CompletionLoadingStyleKey,
+	}
+}
+
 func completionItemSegments(item CompletionItem) []CompletionSegment {
+	out := completionItemTextSegments(item)
+
+	// Precompute cell widths so rendering doesn't have to re-split graphemes.
+	pos := 0
+	for i := range out {
+		w := computeSegmentCellWidth(out[i].Text, pos)
+		out[i].cellWidth = w
+		pos += w
+	}
+	return out
+}
+
+// completionItemTextSegments returns the sanitized row segments of item with
+// separators between non-empty groups; cell widths are not computed.
+func completionItemTextSegments(item CompletionItem) []CompletionSegment {
 	prefix := item.Prefix
 	label := item.Label
 	detail := item.Detail
@@ -188,14 +275,6 @@ func completionItemSegments(item CompletionItem) []CompletionSegment {
 		out = append(out, CompletionSegment{Text: " ", cellWidth: -1})
 	}
 	appendGroup(detail)
-
-	// Precompute cell widths so rendering doesn't have to re-split graphemes.
-	pos := 0
-	for i := range out {
-		w := computeSegmentCellWidth(out[i].Text, pos)
-		out[i].cellWidth = w
-		pos += w
-	}
 	return out
 }
 
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"charm.land/lipgloss/v2"
+
 	"github.com/iw2rmb/flourish/buffer"
 )
 
@@ -235,3 +237,29 @@ func assertLines(t *testing.T, got, want []string) {
 func bufferPos(row, col int) buffer.Pos {
 	return buffer.Pos{Row: row, GraphemeCol: col}
 }
+
+func TestCompletionPopupRender_StylesMatchedGraphemes(t *testing.T) {
+	m := New(Config{
+		Text: "000000000\n111111111",
+		CompletionStyleForKey: func(key string) (lipgloss.Style, bool) {
+			if key == CompletionMatchStyleKey {
+				return lipgloss.NewStyle().Transform(strings.ToUpper), true
+			}
+			return lipgloss.Style{}, false
+		},
+	})
+	m = m.Blur()
+	m = m.SetSize(9, 2)
+	m = m.SetCompletionState(CompletionState{
+		Visible: true,
+		Anchor:  bufferPos(0, 0),
+		Query:   "fr",
+		Items: []CompletionItem{
+			{ID: "0", Prefix: []CompletionSegment{{Text: "f"}}, Label: []CompletionSegment{{Text: "fooBar"}}},
+		},
+	})
+
+	got := strings.Split(stripANSI(m.View().Content), "\n")
+	// The prefix is not matched; highlights land in the label.
+	assertLines(t, got, []string{"000000000", "f FooBaR1"})
+}
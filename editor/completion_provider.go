@@ -0,0 +1,286 @@
+package editor
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+const defaultCompletionLoadingText = "Loading…"
+
+// CompletionLoadingStyleKey is passed to Config.CompletionStyleForKey to style
+// the loading indicator row.
+const CompletionLoadingStyleKey = "completion.loading"
+
+// CompletionTriggerKind reports why a CompletionProvider request was made.
+type CompletionTriggerKind uint8
+
+const (
+	// CompletionTriggerInvoked is an explicit CompletionKeyMap.Trigger press.
+	CompletionTriggerInvoked CompletionTriggerKind = iota
+	// CompletionTriggerCharacter is typing one of Config.CompletionTriggerCharacters.
+	CompletionTriggerCharacter
+	// CompletionTriggerIncomplete re-queries a result marked Incomplete after
+	// the query changed.
+	CompletionTriggerIncomplete
+)
+
+// CompletionRequest describes one CompletionProvider call.
+type CompletionRequest struct {
+	Kind CompletionTriggerKind
+	// Character is the trigger sequence for CompletionTriggerCharacter, and
+	// the original one when re-querying a result it produced.
+	Character string
+
+	Anchor   buffer.Pos
+	Query    string
+	Cursor   buffer.Pos
+	LineText string
+
+	DocID string
+	// DocVersion is the buffer text version (buffer.TextVersion) the
+	// request was made at.
+	DocVersion uint64
+}
+
+// CompletionResult is a CompletionProvider response.
+type CompletionResult struct {
+	Items []CompletionItem
+	// Incomplete reports that Items are partial; the editor requests again
+	// when the query changes instead of only filtering Items.
+	Incomplete bool
+}
+
+// CompletionProvider supplies completion items. The editor calls it from a
+// tea.Cmd, off the UI goroutine, so it must not touch the Model or its buffer.
+// ctx is cancelled once the request is superseded or the popup closes.
+type CompletionProvider func(ctx context.Context, req CompletionRequest) (CompletionResult, error)
+
+// CompletionResultMsg carries a CompletionProvider response. Hosts route it to
+// Model.Update. Responses for superseded requests, for other editors, or
+// requested at a buffer version that is no longer current are dropped.
+type CompletionResultMsg struct {
+	Request CompletionRequest
+	Result  CompletionResult
+	Err     error
+
+	owner *completionRequests
+	seq   uint64
+}
+
+type completionDebounceMsg struct {
+	owner *completionRequests
+	seq   uint64
+}
+
+// completionRequests is shared by copies of a Model so that a response can be
+// matched with the latest request and cancelled from any copy.
+type completionRequests struct {
+	// seq increments with every request; only the latest one is current.
+	seq    uint64
+	last   CompletionRequest
+	cancel context.CancelFunc
+	// cmd is the pending command returned from the next Update.
+	cmd tea.Cmd
+}
+
+type completionRequestMark struct {
+	seq     uint64
+	query   string
+	version uint64
+}
+
+func (r *completionRequests) stop() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.cmd = nil
+}
+
+// supersede cancels the pending request and drops its response.
+func (r *completionRequests) supersede() {
+	if r != nil {
+		r.stop()
+		r.seq++
+	}
+}
+
+// startCompletion opens an empty popup at the cursor and requests items.
+func (m *Model) startCompletion(kind CompletionTriggerKind, character string) {
+	m.completionState = CompletionState{Visible: true, Anchor: m.buf.Cursor()}
+	m.completionTextCache = nil
+	m.requestCompletion(kind, character, kind != CompletionTriggerInvoked)
+}
+
+// requestCompletion supersedes any pending request with one for the current
+// popup state. Debounced requests are sent after Config.CompletionDebounce
+// unless superseded first.
+func (m *Model) requestCompletion(kind CompletionTriggerKind, character string, debounce bool) {
+	r := m.completionReq
+	r.stop()
+	r.seq++
+
+	state := m.completionState
+	cursor := m.buf.Cursor()
+	line, _ := m.docLine(cursor.Row)
+	r.last = CompletionRequest{
+		Kind:       kind,
+		Character:  character,
+		Anchor:     state.Anchor,
+		Query:      state.Query,
+		Cursor:     cursor,
+		LineText:   line,
+		DocID:      m.cfg.DocID,
+		DocVersion: m.buf.TextVersion(),
+	}
+	m.completionState.Loading = true
+
+	if debounce && m.cfg.CompletionDebounce > 0 {
+		seq := r.seq
+		r.cmd = tea.Tick(m.cfg.CompletionDebounce, func(time.Time) tea.Msg {
+			return completionDebounceMsg{owner: r, seq: seq}
+		})
+		return
+	}
+	r.cmd = m.completionProviderCmd()
+}
+
+func (m *Model) completionProviderCmd() tea.Cmd {
+	r := m.completionReq
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	provider, req, seq := m.cfg.CompletionProvider, r.last, r.seq
+	return func() tea.Msg {
+		res, err := provider(ctx, req)
+		return CompletionResultMsg{Request: req, Result: res, Err: err, owner: r, seq: seq}
+	}
+}
+
+func (m *Model) markCompletionRequest() completionRequestMark {
+	if m.completionReq == nil || m.buf == nil {
+		return completionRequestMark{}
+	}
+	return completionRequestMark{
+		seq:     m.completionReq.seq,
+		query:   m.completionState.Query,
+		version: m.buf.TextVersion(),
+	}
+}
+
+// syncCompletionRequest runs after each Update. It cancels the pending
+// request once the popup closed, and re-queries when the query or document
+// changed while results are loading or incomplete.
+func (m *Model) syncCompletionRequest(mark completionRequestMark) {
+	r := m.completionReq
+	if r == nil || m.buf == nil {
+		return
+	}
+	state := m.completionState
+	if !state.Visible {
+		r.stop()
+		return
+	}
+	if r.seq != mark.seq {
+		return
+	}
+	if state.Query == mark.query && m.buf.TextVersion() == mark.version {
+		return
+	}
+	switch {
+	case state.Loading:
+		m.requestCompletion(r.last.Kind, r.last.Character, true)
+	case state.Incomplete:
+		m.requestCompletion(CompletionTriggerIncomplete, r.last.Character, true)
+	}
+}
+
+// applyCompletionResult stores a response for the current request.
+func (m *Model) applyCompletionResult(msg CompletionResultMsg) {
+	r := m.completionReq
+	if msg.seq != r.seq || !m.completionState.Visible || m.buf == nil {
+		return
+	}
+	if msg.Request.DocVersion != m.buf.TextVersion() {
+		m.requestCompletion(msg.Request.Kind, msg.Request.Character, false)
+		return
+	}
+	r.stop()
+
+	state := m.completionState
+	state.Loading = false
+	if msg.Err == nil {
+		state.Items = cloneCompletionItems(msg.Result.Items)
+		state.Incomplete = msg.Result.Incomplete
+		m.completionTextCache = nil
+		m.recomputeCompletionFilter(&state)
+	}
+	m.completionState = state
+}
+
+// completionTriggerBeforeCursor returns the configured trigger sequence that
+// ends at the cursor, preferring the longest.
+func (m *Model) completionTriggerBeforeCursor() (string, bool) {
+	return m.triggerBeforeCursor(m.cfg.CompletionTriggerCharacters)
+}
+
+// triggerBeforeCursor returns the longest of triggers that ends at the cursor.
+func (m *Model) triggerBeforeCursor(triggers []string) (string, bool) {
+	cursor := m.buf.Cursor()
+	prefix := m.buf.TextInRange(buffer.Range{Start: buffer.Pos{Row: cursor.Row}, End: cursor})
+	best := ""
+	for _, t := range triggers {
+		if len(t) > len(best) && strings.HasSuffix(prefix, t) {
+			best = t
+		}
+	}
+	return best, best != ""
+}
+
+// maybeTriggerCompletion opens completion when the key just typed text that
+// ends with a trigger sequence.
+func (m *Model) maybeTriggerCompletion(msg tea.KeyPressMsg, before EditorState, beforeText uint64) {
+	if m.completionReq == nil || len(m.cfg.CompletionTriggerCharacters) == 0 {
+		return
+	}
+	if keyText(msg) == "" || hasAltMod(msg) || m.buf.TextVersion() == beforeText {
+		return
+	}
+	character, ok := m.completionTriggerBeforeCursor()
+	if !ok {
+		return
+	}
+	if m.cfg.OnCompletionIntent != nil {
+		m.cfg.OnCompletionIntent(CompletionIntentBatch{Intents: []CompletionIntent{{
+			Kind:    IntentCompletionTrigger,
+			Before:  before,
+			Payload: CompletionTriggerIntentPayload{Anchor: m.buf.Cursor()},
+		}}})
+	}
+	m.startCompletion(CompletionTriggerCharacter, character)
+}
+
+// completionCmd returns the pending provider or debounce command.
+func (m *Model) completionCmd() tea.Cmd {
+	if m.completionReq == nil {
+		return nil
+	}
+	cmd := m.completionReq.cmd
+	m.completionReq.cmd = nil
+	return cmd
+}
+
+func normalizeCompletionTriggerCharacters(triggers []string) []string {
+	out := make([]string, 0, len(triggers))
+	for _, t := range triggers {
+		if t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,272 @@
+package editor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// runCompletionCmd runs cmd and any batched commands, returning completion
+// provider and debounce messages.
+func runCompletionCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		var out []tea.Msg
+		for _, c := range msg {
+			out = append(out, runCompletionCmd(c)...)
+		}
+		return out
+	case CompletionResultMsg, completionDebounceMsg:
+		return []tea.Msg{msg}
+	}
+	return nil
+}
+
+func deliverCompletion(t *testing.T, m Model, msgs []tea.Msg) (Model, tea.Cmd) {
+	t.Helper()
+	var cmds []tea.Cmd
+	for _, msg := range msgs {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+type recordingProvider struct {
+	reqs []CompletionRequest
+	ctxs []context.Context
+	res  func(CompletionRequest) CompletionResult
+}
+
+func (p *recordingProvider) provide(ctx context.Context, req CompletionRequest) (CompletionResult, error) {
+	p.reqs = append(p.reqs, req)
+	p.ctxs = append(p.ctxs, ctx)
+	if p.res == nil {
+		return CompletionResult{Items: []CompletionItem{providerItem("id", "item")}}, nil
+	}
+	return p.res(req), nil
+}
+
+func providerItem(id, label string) CompletionItem {
+	return CompletionItem{ID: id, InsertText: label, Label: []CompletionSegment{{Text: label}}}
+}
+
+func newProviderModel(p *recordingProvider, cfg Config) Model {
+	cfg.CompletionProvider = p.provide
+	m := New(cfg)
+	return m.SetSize(30, 6)
+}
+
+func TestCompletionProvider_TriggerShowsLoadingUntilResult(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{Text: "ab"})
+
+	m, cmd := m.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	state := m.CompletionState()
+	if !state.Visible || !state.Loading {
+		t.Fatalf("expected visible loading popup, got %+v", state)
+	}
+	if got := stripANSI(m.View().Content); !strings.Contains(got, "Loading…") {
+		t.Fatalf("expected loading row:\n%s", got)
+	}
+	if len(p.reqs) != 0 {
+		t.Fatalf("provider must be called from the command")
+	}
+
+	m, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	if len(p.reqs) != 1 || p.reqs[0].Kind != CompletionTriggerInvoked {
+		t.Fatalf("requests: %+v", p.reqs)
+	}
+	state = m.CompletionState()
+	if state.Loading || len(state.Items) != 1 || len(state.VisibleIndices) != 1 {
+		t.Fatalf("expected result items, got %+v", state)
+	}
+	got := stripANSI(m.View().Content)
+	if strings.Contains(got, "Loading") || !strings.Contains(got, "item") {
+		t.Fatalf("expected items without loading row:\n%s", got)
+	}
+}
+
+func TestCompletionProvider_TriggerCharacters(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{CompletionTriggerCharacters: []string{".", "::"}})
+
+	m, cmd := m.Update(testKeyText("a"))
+	if runCompletionCmd(cmd) != nil || m.CompletionState().Visible {
+		t.Fatalf("non-trigger text must not request completion")
+	}
+
+	m, cmd = m.Update(testKeyText("."))
+	m, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	if len(p.reqs) != 1 || p.reqs[0].Kind != CompletionTriggerCharacter || p.reqs[0].Character != "." {
+		t.Fatalf("requests: %+v", p.reqs)
+	}
+	if got := p.reqs[0]; got.Anchor.GraphemeCol != 2 || got.LineText != "a." {
+		t.Fatalf("request context: %+v", got)
+	}
+
+	m = m.ClearCompletion()
+	m, _ = m.Update(testKeyText("b"))
+	m, _ = m.Update(testKeyText(":"))
+	m, cmd = m.Update(testKeyText(":"))
+	_, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	if last := p.reqs[len(p.reqs)-1]; last.Character != "::" {
+		t.Fatalf("expected longest trigger, got %q", last.Character)
+	}
+}
+
+func TestCompletionProvider_DropsSupersededAndStaleResponses(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{Text: "ab", CompletionInputMode: CompletionInputMutateDocument})
+
+	m, first := m.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	stale := runCompletionCmd(first)
+
+	// Typing while loading re-requests for the new query.
+	m, second := m.Update(testKeyText("x"))
+	m, _ = deliverCompletion(t, m, stale)
+	if state := m.CompletionState(); !state.Loading || len(state.Items) != 0 {
+		t.Fatalf("superseded response must be dropped, got %+v", state)
+	}
+
+	msgs := runCompletionCmd(second)
+	if len(msgs) != 1 || p.reqs[1].Query != "x" {
+		t.Fatalf("expected a request for the new query, got %d msgs, reqs %+v", len(msgs), p.reqs)
+	}
+
+	// A response requested at an older version is requested again.
+	m.buf.InsertText("y")
+	m, retry := deliverCompletion(t, m, msgs)
+	if state := m.CompletionState(); !state.Loading || len(state.Items) != 0 {
+		t.Fatalf("stale response must be dropped, got %+v", state)
+	}
+	m, _ = deliverCompletion(t, m, runCompletionCmd(retry))
+	if last := p.reqs[len(p.reqs)-1]; last.DocVersion != m.buf.TextVersion() {
+		t.Fatalf("expected a request at the current version: %+v", last)
+	}
+	if state := m.CompletionState(); state.Loading || len(state.Items) != 1 {
+		t.Fatalf("expected current response to be applied, got %+v", state)
+	}
+}
+
+func TestCompletionProvider_KeepsResponsesAcrossCursorMoves(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{Text: "ab"})
+
+	m, cmd := m.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	msgs := runCompletionCmd(cmd)
+
+	// A cursor-only change does not make the response stale.
+	m.buf.SetCursor(buffer.Pos{GraphemeCol: 1})
+	m, retry := deliverCompletion(t, m, msgs)
+	if len(runCompletionCmd(retry)) != 0 || len(p.reqs) != 1 {
+		t.Fatalf("cursor move must not re-request, reqs %+v", p.reqs)
+	}
+	if state := m.CompletionState(); state.Loading || len(state.Items) != 1 {
+		t.Fatalf("expected response to be applied, got %+v", state)
+	}
+}
+
+func TestCompletionProvider_RequeriesIncompleteResults(t *testing.T) {
+	p := &recordingProvider{res: func(req CompletionRequest) CompletionResult {
+		return CompletionResult{
+			Items:      []CompletionItem{providerItem(req.Query, "q"+req.Query)},
+			Incomplete: req.Query == "",
+		}
+	}}
+	m := newProviderModel(p, Config{CompletionTriggerCharacters: []string{"."}})
+
+	m, cmd := m.Update(testKeyText("."))
+	m, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	if !m.CompletionState().Incomplete {
+		t.Fatalf("expected incomplete result")
+	}
+
+	m, cmd = m.Update(testKeyText("a"))
+	if !m.CompletionState().Loading {
+		t.Fatalf("re-query should show the loading row")
+	}
+	m, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	last := p.reqs[len(p.reqs)-1]
+	if len(p.reqs) != 2 || last.Kind != CompletionTriggerIncomplete || last.Query != "a" || last.Character != "." {
+		t.Fatalf("requests: %+v", p.reqs)
+	}
+	state := m.CompletionState()
+	if state.Incomplete || len(state.Items) != 1 || state.Items[0].ID != "a" {
+		t.Fatalf("expected re-queried items, got %+v", state)
+	}
+
+	// Complete results are filtered locally.
+	m, cmd = m.Update(testKeyText("b"))
+	if runCompletionCmd(cmd) != nil || len(p.reqs) != 2 {
+		t.Fatalf("complete result must not be re-queried")
+	}
+}
+
+func TestCompletionProvider_DebouncesTypedTriggers(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{
+		CompletionTriggerCharacters: []string{"."},
+		CompletionDebounce:          time.Millisecond,
+	})
+
+	m, first := m.Update(testKeyText("."))
+	m, second := m.Update(testKeyText("a"))
+
+	msgs := runCompletionCmd(first)
+	if len(msgs) != 1 {
+		t.Fatalf("expected a debounce message, got %v", msgs)
+	}
+	m, cmd := deliverCompletion(t, m, msgs)
+	if runCompletionCmd(cmd) != nil {
+		t.Fatalf("superseded debounce must not call the provider")
+	}
+
+	m, cmd = deliverCompletion(t, m, runCompletionCmd(second))
+	m, _ = deliverCompletion(t, m, runCompletionCmd(cmd))
+	if len(p.reqs) != 1 || p.reqs[0].Query != "a" {
+		t.Fatalf("expected one request after the debounce, got %+v", p.reqs)
+	}
+	if state := m.CompletionState(); state.Loading || len(state.Items) != 1 {
+		t.Fatalf("expected result after debounce, got %+v", state)
+	}
+}
+
+func TestCompletionProvider_DismissCancelsRequest(t *testing.T) {
+	p := &recordingProvider{}
+	m := newProviderModel(p, Config{Text: "ab"})
+
+	m, cmd := m.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	m, _ = m.Update(testKeyCode(tea.KeyEscape))
+	msgs := runCompletionCmd(cmd)
+	if len(p.ctxs) != 1 || p.ctxs[0].Err() == nil {
+		t.Fatalf("expected cancelled request context")
+	}
+	m, _ = deliverCompletion(t, m, msgs)
+	if m.CompletionState().Visible {
+		t.Fatalf("dismissed popup must stay closed")
+	}
+}
+
+func TestCompletionProvider_IgnoresOtherEditors(t *testing.T) {
+	p := &recordingProvider{}
+	a := newProviderModel(p, Config{Text: "ab"})
+	b := newProviderModel(p, Config{Text: "ab"})
+
+	a, cmd := a.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	b, _ = b.Update(testKeyCode(tea.KeySpace, tea.ModCtrl))
+	b, _ = deliverCompletion(t, b, runCompletionCmd(cmd))
+	if state := b.CompletionState(); !state.Loading || len(state.Items) != 0 {
+		t.Fatalf("editor accepted another editor's response: %+v", state)
+	}
+	_ = a
+}
@@ -7,6 +7,11 @@ import (
 	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
 )
 
+// CompletionMatchStyleKey is passed to Config.CompletionStyleForKey to style
+// item graphemes that matched the query (CompletionState.MatchedPositions).
+// When unresolved, matched graphemes render bold.
+const CompletionMatchStyleKey = "completion.match"
+
 func completionRowBaseStyle(st Style, selected bool) lipgloss.Style {
 	if selected {
 		return st.CompletionSelected
@@ -27,6 +27,36 @@ func compositeTopLeft(fg, bg string, xOff, yOff int) string {
 	x := clampInt(xOff, 0, bgWidth-fgWidth)
 	y := clampInt(yOff, 0, bgHeight-fgHeight)
 	base := lipgloss.NewLayer(bg)
-	overlay := lipgloss.NewLayer(fg).X(x).Y(y).Z(1)
-	return lipgloss.NewCompositor(base, overlay).Render()
+	top := lipgloss.NewLayer(fg).X(x).Y(y).Z(1)
+	return lipgloss.NewCompositor(base, top).Render()
+}
+
+// overlay is rendered content placed at a cell offset over a background.
+type overlay struct {
+	content string
+	x, y    int
+}
+
+// compositeLayers draws overlays onto bg in one compositor pass, later
+// overlays on top. Offsets are clamped so each overlay stays inside bg.
+func compositeLayers(bg string, overlays []overlay) string {
+	if bg == "" || len(overlays) == 0 {
+		return bg
+	}
+	bgWidth, bgHeight := lipgloss.Size(bg)
+	layers := make([]*lipgloss.Layer, 0, len(overlays)+1)
+	layers = append(layers, lipgloss.NewLayer(bg))
+	for _, o := range overlays {
+		if o.content == "" {
+			continue
+		}
+		w, h := lipgloss.Size(o.content)
+		x := clampInt(o.x, 0, max(bgWidth-w, 0))
+		y := clampInt(o.y, 0, max(bgHeight-h, 0))
+		layers = append(layers, lipgloss.NewLayer(o.content).X(x).Y(y).Z(len(layers)))
+	}
+	if len(layers) == 1 {
+		return bg
+	}
+	return lipgloss.NewCompositor(layers...).Render()
 }
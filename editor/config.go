@@ -1,6 +1,8 @@
 package editor
 
 import (
+	"time"
+
 	"charm.land/lipgloss/v2"
 
 	"github.com/iw2rmb/flourish/buffer"
@@ -30,6 +32,12 @@ type ScrollbarConfig struct {
 type Config struct {
 	// Initial text for the internal buffer.
 	Text string
+	// Buffer optionally supplies a prebuilt buffer instead of building one from
+	// Text. When set, Text and HistoryLimit are ignored.
+	//
+	// Lazily backed buffers (buffer.NewLazy) force ReadOnly and WrapNone; the
+	// editor then lays out and renders only the visible row window.
+	Buffer *buffer.Buffer
 	// Optional host metadata used by hooks for caching.
 	DocID string
 
@@ -83,8 +91,50 @@ type Config struct {
 	// If true, movement/selection still work but buffer mutations are ignored.
 	ReadOnly bool
 
+	// Clock timestamps clicks for double-click word selection and triple-click
+	// line selection. Nil uses the system clock.
+	Clock Clock
+	// MultiClickInterval is the longest delay between clicks at the same
+	// position that count as a double or triple click. Zero uses
+	// DefaultMultiClickInterval; negative disables multi-click selection.
+	MultiClickInterval time.Duration
+	// DragScrollInterval is the delay between auto-scroll steps while a drag
+	// selection is past the edge of the content area; each step scrolls by
+	// the pointer's distance past the edge. Zero uses
+	// DefaultDragScrollInterval; negative disables auto-scroll.
+	DragScrollInterval time.Duration
+
+	// AutoPairs enables auto-closing pairs and surround-selection for typed
+	// openers. When nil, auto-pairing is disabled. Use DefaultAutoPairs() for
+	// brackets and quotes, or a per-document set (e.g. markdown "*" and "_").
+	// Entries whose Open or Close is not a single grapheme are ignored.
+	AutoPairs []AutoPair
+
+	// BracketPairs configures bracket matching for the Style.MatchingBracket
+	// highlight, JumpToMatchingBracket, and SelectBracketContents.
+	// When nil, buffer.DefaultBracketPairs() is used.
+	BracketPairs []buffer.BracketPair
+
+	// Comment configures comment tokens for KeyMap.ToggleComment. When no
+	// tokens are set, toggling is disabled.
+	Comment CommentTokens
+
 	// KeyMap controls default keybindings. Zero value uses DefaultKeyMap().
+	// Binding keys may be chords: space-separated key sequences such as
+	// "ctrl+k ctrl+c" (see PendingChord and KeyMap.Conflicts).
 	KeyMap KeyMap
+	// ChordTimeout cancels a partially typed chord when no key follows within
+	// it. Zero waits for the next key.
+	ChordTimeout time.Duration
+	// Bindings bind keys to commands by name (see RunCommand), for example
+	// from a keymap file loaded with LoadKeymapFile. They take precedence
+	// over KeyMap and CompletionKeyMap: the first binding whose key matches,
+	// whose When holds and whose command applies runs; otherwise the key is
+	// handled as usual. Bindings with an invalid When are ignored.
+	Bindings []Binding
+	// Commands registers host commands by name for Bindings and RunCommand.
+	// A name that matches a built-in command overrides it.
+	Commands map[string]CommandFunc
 
 	// Forwarded to buffer.Options.
 	HistoryLimit int
@@ -115,6 +165,11 @@ type Config struct {
 	// LinkAt / LinkAtScreen.
 	LinkProvider LinkProvider
 
+	// Async moves selected providers (Highlighter, LinkProvider, GhostProvider,
+	// VirtualTextProvider) off the render path. Requests are returned as
+	// tea.Cmds from Init, Update and AsyncCmd; see AsyncProviders.
+	Async AsyncProviders
+
 	// OnChange, if set, fires after every effective buffer change triggered via
 	// Update. It is not fired for host-driven buffer changes.
 	OnChange func(buffer.Change)
@@ -132,6 +187,11 @@ type Config struct {
 	// CompletionStyleForKey resolves completion row/segment style overrides by key.
 	// When nil or key is unresolved, completion row defaults are used.
 	CompletionStyleForKey func(key string) (lipgloss.Style, bool)
+
+	// FloatStyleForKey resolves floating window styles by key. Keys include
+	// FloatStyleKey, FloatCodeStyleKey and each window's StyleKey.
+	FloatStyleForKey func(key string) (lipgloss.Style, bool)
+
 	// CompletionKeyMap controls completion-specific key bindings.
 	// Zero value uses DefaultCompletionKeyMap().
 	CompletionKeyMap CompletionKeyMap
@@ -144,6 +204,36 @@ type Config struct {
 	// CompletionMaxWidth caps completion popup width in terminal cells.
 	// Values <= 0 default to 60.
 	CompletionMaxWidth int
+	// CompletionProvider, if set, supplies completion items asynchronously on
+	// CompletionKeyMap.Trigger and after typing a trigger sequence; see
+	// CompletionProvider and CompletionResultMsg.
+	CompletionProvider CompletionProvider
+	// CompletionTriggerCharacters lists sequences (e.g. "." or "::") that open
+	// completion through CompletionProvider when typed.
+	CompletionTriggerCharacters []string
+	// CompletionDebounce delays provider requests made while typing (trigger
+	// sequences and re-queries). Explicit triggers are not delayed.
+	CompletionDebounce time.Duration
+	// CompletionLoadingText is the loading indicator row text.
+	// Empty uses "Loading…".
+	CompletionLoadingText string
+	// CompletionDocMaxWidth caps the documentation panel width in terminal cells.
+	// Values <= 0 default to 50.
+	CompletionDocMaxWidth int
+	// CompletionDocMaxHeight caps the documentation panel height in rows.
+	// Values <= 0 default to 12.
+	CompletionDocMaxHeight int
+	// HoverProvider, if set, supplies hover contents shown above the grapheme
+	// under the mouse pointer (see ShowHover). Motion reports require a
+	// terminal mouse mode with all-motion tracking.
+	HoverProvider HoverProvider
+	// SignatureHelpProvider, if set, supplies signature help shown above the
+	// cursor after typing a trigger sequence and re-queried while shown.
+	SignatureHelpProvider SignatureHelpProvider
+	// SignatureHelpTriggerCharacters lists sequences that open signature help.
+	// Nil defaults to "(" and "," when SignatureHelpProvider is set.
+	SignatureHelpTriggerCharacters []string
+
 	// OnCompletionIntent receives completion semantic intents.
 	// This is separate from document intents emitted by OnIntent.
 	OnCompletionIntent func(CompletionIntentBatch)
@@ -0,0 +1,142 @@
+package editor
+
+// Dispatch runs intents as if key handling had produced them: they are
+// reported to Config.OnIntent per Config.MutationMode and, when applied
+// locally, executed in order. Before is filled from the current buffer state.
+//
+// Dispatch lets input layers built on top of the editor (for example modal
+// keymaps) express their actions semantically, so intent-mode hosts see the
+// same batches they would for built-in keys. Editing intents are ignored
+// when the editor is read-only.
+func (m Model) Dispatch(intents ...Intent) Model {
+	if m.buf == nil || len(intents) == 0 {
+		return m
+	}
+
+	before := editorStateFromBuffer(m.buf)
+	batch := IntentBatch{Intents: make([]Intent, 0, len(intents))}
+	for _, in := range intents {
+		if m.cfg.ReadOnly && intentEditsText(in.Kind) {
+			continue
+		}
+		in.Before = before
+		batch.Intents = append(batch.Intents, in)
+	}
+	if len(batch.Intents) == 0 {
+		return m
+	}
+
+	beforeVer, beforeText := m.buf.Version(), m.buf.TextVersion()
+	if !(&m).emitDocumentIntentsAndResolveApply(batch) {
+		return m
+	}
+	for _, in := range batch.Intents {
+		(&m).applyIntent(in)
+	}
+	(&m).syncAfterChange(beforeVer, beforeText)
+	return m
+}
+
+// syncAfterChange runs the bookkeeping key handling does after changing the
+// buffer outside of Update: change notification, snippet mirrors and
+// following the cursor.
+func (m *Model) syncAfterChange(beforeVer, beforeText uint64) {
+	cursorChanged, versionChanged := m.syncFromBuffer()
+	if m.buf.Version() != beforeVer {
+		m.notifyChange()
+	}
+	if m.buf.TextVersion() != beforeText && m.applySnippetMirrors() {
+		m.syncFromBuffer()
+		cursorChanged, versionChanged = true, true
+	}
+	if cursorChanged || versionChanged {
+		m.followCursorWithForce(false)
+	}
+}
+
+func intentEditsText(kind IntentKind) bool {
+	switch kind {
+	case IntentInsert, IntentDelete, IntentUndo, IntentRedo, IntentEdit:
+		return true
+	default:
+		return false
+	}
+}
+
+// applyIntent executes one intent against the local buffer. Payloads of the
+// wrong type are ignored.
+func (m *Model) applyIntent(in Intent) {
+	switch in.Kind {
+	case IntentInsert:
+		p, ok := in.Payload.(InsertIntentPayload)
+		if !ok {
+			return
+		}
+		if len(p.Edits) > 0 {
+			m.applyInsertEdits(cloneTextEdits(p.Edits), p.Snippet)
+			return
+		}
+		m.buf.InsertText(p.Text)
+	case IntentDelete:
+		p, ok := in.Payload.(DeleteIntentPayload)
+		if !ok {
+			return
+		}
+		if len(p.Edits) > 0 {
+			m.buf.Apply(cloneTextEdits(p.Edits)...)
+			return
+		}
+		switch p.Direction {
+		case DeleteBackward:
+			m.buf.DeleteBackward()
+		case DeleteForward:
+			m.buf.DeleteForward()
+		case DeleteSelection:
+			m.buf.DeleteSelection()
+		case DeleteWordBackward:
+			m.buf.DeleteWordBackward()
+		case DeleteLineRight:
+			m.buf.DeleteLineRight()
+		}
+	case IntentMove:
+		if p, ok := in.Payload.(MoveIntentPayload); ok {
+			m.buf.Move(p.Move)
+		}
+	case IntentSelect:
+		if p, ok := in.Payload.(SelectIntentPayload); ok {
+			m.buf.Move(p.Move)
+		}
+	case IntentUndo:
+		m.endSnippetSession()
+		_ = m.buf.Undo()
+	case IntentRedo:
+		m.endSnippetSession()
+		_ = m.buf.Redo()
+	case IntentEdit:
+		if p, ok := in.Payload.(EditIntentPayload); ok {
+			m.buf.Apply(cloneTextEdits(p.Edits)...)
+		}
+	case IntentSnippetJump:
+		p, ok := in.Payload.(SnippetJumpIntentPayload)
+		if !ok {
+			return
+		}
+		for i, stop := range m.snippet.stops {
+			if stop.index == p.Tabstop {
+				m.jumpSnippet(i)
+				return
+			}
+		}
+	case IntentSetCursor:
+		p, ok := in.Payload.(SetCursorIntentPayload)
+		if !ok {
+			return
+		}
+		m.buf.SetCursor(p.Cursor)
+		if p.Select && p.Selection.Start != p.Selection.End {
+			m.buf.SetSelection(p.Selection)
+		} else {
+			m.buf.ClearSelection()
+		}
+	}
+}
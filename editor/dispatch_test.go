@@ -0,0 +1,81 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func TestDispatch_AppliesIntentsInOrder(t *testing.T) {
+	changes := 0
+	m := New(Config{Text: "hello world", OnChange: func(buffer.Change) { changes++ }})
+
+	m = m.Dispatch(
+		Intent{Kind: IntentEdit, Payload: EditIntentPayload{Edits: []buffer.TextEdit{{
+			Range: buffer.Range{Start: bufferPos(0, 0), End: bufferPos(0, 6)},
+		}}}},
+		Intent{Kind: IntentSetCursor, Payload: SetCursorIntentPayload{
+			Cursor:    bufferPos(0, 4),
+			Selection: buffer.Range{Start: bufferPos(0, 1), End: bufferPos(0, 4)},
+			Select:    true,
+		}},
+	)
+
+	if got := m.buf.Text(); got != "world" {
+		t.Fatalf("text=%q", got)
+	}
+	if got := m.buf.Cursor(); got != bufferPos(0, 4) {
+		t.Fatalf("cursor=%v", got)
+	}
+	if r, ok := m.buf.Selection(); !ok || r != (buffer.Range{Start: bufferPos(0, 1), End: bufferPos(0, 4)}) {
+		t.Fatalf("selection=%v %v", r, ok)
+	}
+	if changes == 0 {
+		t.Fatalf("expected OnChange")
+	}
+}
+
+func TestDispatch_EmitsBatchWithBeforeState(t *testing.T) {
+	var batches []IntentBatch
+	m := New(Config{
+		Text:         "ab",
+		MutationMode: EmitIntentsOnly,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			batches = append(batches, batch)
+			return IntentDecision{ApplyLocally: true}
+		},
+	})
+
+	m = m.Dispatch(
+		Intent{Kind: IntentMove, Payload: MoveIntentPayload{Move: buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirEnd}}},
+		Intent{Kind: IntentInsert, Payload: InsertIntentPayload{Text: "c"}},
+	)
+
+	if got := m.buf.Text(); got != "ab" {
+		t.Fatalf("intents-only dispatch should not mutate, text=%q", got)
+	}
+	if len(batches) != 1 || len(batches[0].Intents) != 2 {
+		t.Fatalf("batches=%+v", batches)
+	}
+	for _, in := range batches[0].Intents {
+		if in.Before.Version != m.buf.Version() || in.Before.Cursor != bufferPos(0, 0) {
+			t.Fatalf("before=%+v", in.Before)
+		}
+	}
+}
+
+func TestDispatch_ReadOnlyDropsEdits(t *testing.T) {
+	m := New(Config{Text: "ab", ReadOnly: true})
+
+	m = m.Dispatch(
+		Intent{Kind: IntentInsert, Payload: InsertIntentPayload{Text: "x"}},
+		Intent{Kind: IntentMove, Payload: MoveIntentPayload{Move: buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirRight}}},
+	)
+
+	if got := m.buf.Text(); got != "ab" {
+		t.Fatalf("text=%q", got)
+	}
+	if got := m.buf.Cursor(); got != bufferPos(0, 1) {
+		t.Fatalf("cursor=%v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package editor
+
+import (
+	"sync/atomic"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// DefaultDragScrollInterval is the default delay between auto-scroll steps
+// while drag-selecting past the edge of the content area.
+const DefaultDragScrollInterval = 50 * time.Millisecond
+
+// dragScrollState tracks edge auto-scroll during a selection drag.
+type dragScrollState struct {
+	// x, y is the latest pointer position, possibly outside the viewport.
+	x, y int
+	// id identifies the pending tick; zero when none is scheduled.
+	id uint64
+}
+
+// dragScrollMsg scrolls one step for the drag it was scheduled for.
+type dragScrollMsg struct {
+	id uint64
+}
+
+// dragScrollIDs issues tick ids unique across editors, so a tick only
+// scrolls the drag it was scheduled for.
+var dragScrollIDs atomic.Uint64
+
+// dragSelectTo extends the drag selection to the pointer at x, y. While the
+// pointer is past an edge the viewport can scroll towards, it schedules the
+// auto-scroll tick unless one is already pending.
+func (m *Model) dragSelectTo(x, y int) tea.Cmd {
+	m.dragScroll.x, m.dragScroll.y = x, y
+	dx, dy := m.extendDragSelection()
+	if dx == 0 && dy == 0 {
+		m.dragScroll.id = 0
+		return nil
+	}
+	if m.dragScroll.id != 0 {
+		return nil
+	}
+	return m.scheduleDragScroll()
+}
+
+// stepDragScroll scrolls by the pointer's distance past each edge, one row
+// or column per cell, and extends the selection into the revealed text.
+// Ticks stop once the pointer is back inside or the document edge is
+// reached.
+func (m *Model) stepDragScroll(msg dragScrollMsg) tea.Cmd {
+	if !m.focused || !m.mouseDragging || m.buf == nil || msg.id != m.dragScroll.id {
+		return nil
+	}
+	m.dragScroll.id = 0
+
+	_, _, dx, dy := m.dragPointer()
+	if dx == 0 && dy == 0 {
+		return nil
+	}
+	lines := m.ensureLines()
+	metrics := m.resolveScrollbarMetrics(lines, m.ensureLayoutCache(lines))
+	if dy != 0 {
+		m.pageVerticalScrollbar(dy, metrics)
+	}
+	if dx != 0 {
+		m.pageHorizontalScrollbar(dx, metrics)
+	}
+	if dx, dy = m.extendDragSelection(); dx == 0 && dy == 0 {
+		return nil
+	}
+	return m.scheduleDragScroll()
+}
+
+func (m *Model) scheduleDragScroll() tea.Cmd {
+	interval := m.cfg.DragScrollInterval
+	if interval == 0 {
+		interval = DefaultDragScrollInterval
+	}
+	if interval < 0 {
+		return nil
+	}
+	m.dragScroll.id = dragScrollIDs.Add(1)
+	id := m.dragScroll.id
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return dragScrollMsg{id: id}
+	})
+}
+
+// extendDragSelection moves the cursor and selection (or the drop caret
+// while dragging the selection) to the document position under the last
+// drag pointer, by the unit of the drag, and returns the pointer's
+// scrollable distance past the edges.
+func (m *Model) extendDragSelection() (dx, dy int) {
+	x, y, dx, dy := m.dragPointer()
+	p := m.screenToDocPos(x, y)
+	if m.drop.active {
+		m.setDropTarget(p)
+		return dx, dy
+	}
+	if m.click.unit != unitGrapheme {
+		m.extendUnitSelection(p)
+		return dx, dy
+	}
+	m.buf.SetCursor(p)
+	m.buf.SetSelection(buffer.Range{Start: m.mouseAnchor, End: p})
+	return dx, dy
+}
+
+// dragPointer returns the last drag pointer clamped for hit-testing, and
+// its distance past the content area edges on the axes the viewport can
+// still scroll: negative above or left of it, positive below or right of
+// it. Horizontal distance applies only to WrapNone.
+func (m *Model) dragPointer() (x, y, dx, dy int) {
+	x, y = m.clampMouseToBounds(m.dragScroll.x, m.dragScroll.y)
+
+	lines := m.ensureLines()
+	metrics := m.resolveScrollbarMetrics(lines, m.ensureLayoutCache(lines))
+	if h := metrics.contentHeight; h > 0 {
+		maxYOffset := max(metrics.totalRows-h, 0)
+		switch py := m.dragScroll.y; {
+		case py < 0 && metrics.yOffset > 0:
+			dy = py
+		case py >= h && metrics.yOffset < maxYOffset:
+			dy = py - h + 1
+		}
+		if dy != 0 {
+			y = clampInt(y, 0, h-1)
+		}
+	}
+	if w := metrics.contentWidth; w > 0 && m.cfg.WrapMode == WrapNone {
+		left := m.resolvedGutterWidth(m.docLineCount(lines))
+		maxXOffset := max(metrics.totalCols-w, 0)
+		switch px := m.dragScroll.x; {
+		case px < left && metrics.xOffset > 0:
+			dx = px - left
+		case px >= left+w && metrics.xOffset < maxXOffset:
+			dx = px - (left + w) + 1
+		}
+		if dx != 0 {
+			x = clampInt(x, left, left+w-1)
+		}
+	}
+	return x, y, dx, dy
+}
@@ -0,0 +1,118 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// dragScrollTick runs cmd and returns the auto-scroll tick it schedules.
+func dragScrollTick(t *testing.T, cmd tea.Cmd) dragScrollMsg {
+	t.Helper()
+	if cmd == nil {
+		t.Fatalf("expected an auto-scroll tick")
+	}
+	switch msg := cmd().(type) {
+	case dragScrollMsg:
+		return msg
+	case tea.BatchMsg:
+		for _, c := range msg {
+			if c == nil {
+				continue
+			}
+			if tick, ok := c().(dragScrollMsg); ok {
+				return tick
+			}
+		}
+	}
+	t.Fatalf("expected an auto-scroll tick")
+	return dragScrollMsg{}
+}
+
+func dragScrollModel(text string, width, height int) Model {
+	m := New(Config{Text: text, DragScrollInterval: time.Millisecond})
+	return m.SetSize(width, height)
+}
+
+func TestDragScroll_VerticalTicksScaleWithDistance(t *testing.T) {
+	rows := make([]string, 20)
+	for i := range rows {
+		rows[i] = fmt.Sprintf("row %02d", i)
+	}
+	m := dragScrollModel(strings.Join(rows, "\n"), 20, 4)
+
+	m, _ = m.Update(testMouseClick(0, 0, tea.MouseLeft))
+	m, cmd := m.Update(testMouseMotion(2, 6, tea.MouseLeft))
+	assertSelection(t, m, buffer.Range{End: pos(3, 2)}, pos(3, 2))
+
+	// Three rows past the bottom edge scroll three rows per tick.
+	m, cmd = m.Update(dragScrollTick(t, cmd))
+	if top := m.ViewportState().TopVisualRow; top != 3 {
+		t.Fatalf("top=%d, want 3", top)
+	}
+	assertSelection(t, m, buffer.Range{End: pos(6, 2)}, pos(6, 2))
+	stale := dragScrollTick(t, cmd)
+	m, cmd = m.Update(stale)
+	if top := m.ViewportState().TopVisualRow; top != 6 {
+		t.Fatalf("top=%d, want 6", top)
+	}
+
+	// A superseded tick does nothing.
+	if m, _ = m.Update(stale); m.ViewportState().TopVisualRow != 6 {
+		t.Fatalf("a stale tick should not scroll")
+	}
+
+	// Back inside, ticks stop; above the top edge, scrolling reverses.
+	m, cmd = m.Update(testMouseMotion(2, 1, tea.MouseLeft))
+	if cmd != nil {
+		t.Fatalf("no tick expected inside the content area")
+	}
+	m, cmd = m.Update(testMouseMotion(2, -1, tea.MouseLeft))
+	m, cmd = m.Update(dragScrollTick(t, cmd))
+	if top := m.ViewportState().TopVisualRow; top != 5 {
+		t.Fatalf("top=%d, want 5", top)
+	}
+	assertSelection(t, m, buffer.Range{End: pos(5, 2)}, pos(5, 2))
+
+	m, _ = m.Update(tea.MouseReleaseMsg{X: 2, Y: -1, Button: tea.MouseLeft})
+	m, _ = m.Update(dragScrollTick(t, cmd))
+	if top := m.ViewportState().TopVisualRow; top != 5 {
+		t.Fatalf("ticks after release should not scroll, top=%d", top)
+	}
+}
+
+func TestDragScroll_StopsAtDocumentEdge(t *testing.T) {
+	m := dragScrollModel("a\nb\nc\nd\ne", 10, 4)
+
+	m, _ = m.Update(testMouseClick(0, 0, tea.MouseLeft))
+	m, cmd := m.Update(testMouseMotion(0, 9, tea.MouseLeft))
+	m, cmd = m.Update(dragScrollTick(t, cmd))
+	if top := m.ViewportState().TopVisualRow; top != 1 {
+		t.Fatalf("top=%d, want 1", top)
+	}
+	if cmd != nil {
+		t.Fatalf("ticks should stop at the end of the document")
+	}
+	assertSelection(t, m, buffer.Range{End: pos(4, 0)}, pos(4, 0))
+
+	if _, cmd = m.Update(testMouseMotion(0, -3, tea.MouseLeft)); cmd == nil {
+		t.Fatalf("expected a tick towards the document start")
+	}
+}
+
+func TestDragScroll_HorizontalInWrapNone(t *testing.T) {
+	m := dragScrollModel(strings.Repeat("x", 40), 10, 3)
+
+	m, _ = m.Update(testMouseClick(0, 0, tea.MouseLeft))
+	m, cmd := m.Update(testMouseMotion(11, 0, tea.MouseLeft))
+	m, _ = m.Update(dragScrollTick(t, cmd))
+	if left := m.ViewportState().LeftCellOffset; left != 2 {
+		t.Fatalf("left=%d, want 2", left)
+	}
+	assertSelection(t, m, buffer.Range{End: pos(0, 11)}, pos(0, 11))
+}
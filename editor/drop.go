@@ -0,0 +1,124 @@
+package editor
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// dropCopyMods are the modifiers that copy dragged text instead of moving
+// it when held on release.
+const dropCopyMods = tea.ModCtrl | tea.ModAlt
+
+// dropState tracks dragging the selection to a new location.
+type dropState struct {
+	active bool
+	// source is the dragged selection.
+	source buffer.Range
+	// press is the position the drag started at.
+	press buffer.Pos
+	// target is where the drop caret renders and the text lands.
+	target buffer.Pos
+	// moved reports that the pointer left press; otherwise the release is a
+	// plain click.
+	moved bool
+}
+
+// startDrop starts dragging the selection when p lies inside it. Read-only
+// editors and empty selections do not drag.
+func (m *Model) startDrop(p buffer.Pos) bool {
+	if m.cfg.ReadOnly {
+		return false
+	}
+	sel, ok := m.buf.Selection()
+	if !ok || buffer.ComparePos(p, sel.Start) < 0 || buffer.ComparePos(p, sel.End) >= 0 {
+		return false
+	}
+	m.drop = dropState{active: true, source: sel, press: p, target: p}
+	return true
+}
+
+// setDropTarget moves the drop caret to p.
+func (m *Model) setDropTarget(p buffer.Pos) {
+	if p == m.drop.target {
+		return
+	}
+	m.drop.target = p
+	m.drop.moved = m.drop.moved || p != m.drop.press
+	m.rebuildContent()
+}
+
+// cancelDrop ends a drag without dropping.
+func (m *Model) cancelDrop() {
+	if !m.drop.active {
+		return
+	}
+	m.drop = dropState{}
+	m.rebuildContent()
+}
+
+// finishDrop drops the dragged text at the drop caret: it moves, or with a
+// dropCopyMods modifier copies, the text as one IntentEdit and selects it
+// at its new location. A release without motion places the cursor like a
+// plain click; moving text into itself keeps the selection.
+func (m Model) finishDrop(mod tea.KeyMod) Model {
+	d := m.drop
+	(&m).cancelDrop()
+	if !d.moved {
+		m.buf.SetCursor(d.press)
+		m.buf.ClearSelection()
+		return m
+	}
+
+	edits, sel, ok := dropEdits(m.buf.TextInRange(d.source), d.source, d.target, mod&dropCopyMods != 0)
+	if !ok {
+		return m
+	}
+	return m.Dispatch(
+		Intent{Kind: IntentEdit, Payload: EditIntentPayload{Edits: edits}},
+		Intent{Kind: IntentSetCursor, Payload: SetCursorIntentPayload{Cursor: sel.End, Selection: sel, Select: true}},
+	)
+}
+
+// caretPos returns where the cursor renders: the drop caret while the
+// selection is dragged, the buffer cursor otherwise.
+func (m *Model) caretPos() buffer.Pos {
+	if m.drop.active && m.drop.moved {
+		return m.drop.target
+	}
+	return m.buf.Cursor()
+}
+
+// dropEdits returns the edits that move (or copy) text at source to target,
+// in buffer.Apply order, and the range the text occupies afterwards. ok is
+// false when moving text into its own range.
+func dropEdits(text string, source buffer.Range, target buffer.Pos, copyText bool) (edits []buffer.TextEdit, moved buffer.Range, ok bool) {
+	insert := buffer.TextEdit{Range: buffer.Range{Start: target, End: target}, Text: text}
+	remove := buffer.TextEdit{Range: source}
+	at := target
+	switch {
+	case copyText:
+		edits = []buffer.TextEdit{insert}
+	case buffer.ComparePos(target, source.Start) < 0:
+		edits = []buffer.TextEdit{remove, insert}
+	case buffer.ComparePos(target, source.End) > 0:
+		// Insert first so source keeps its position, then shift the landing
+		// spot back over the removed text.
+		edits = []buffer.TextEdit{insert, remove}
+		if target.Row == source.End.Row {
+			at = buffer.Pos{Row: source.Start.Row, GraphemeCol: source.Start.GraphemeCol + target.GraphemeCol - source.End.GraphemeCol}
+		} else {
+			at.Row -= source.End.Row - source.Start.Row
+		}
+	default:
+		return nil, buffer.Range{}, false
+	}
+
+	end := snippetPosAt(text, len(text))
+	if end.Row == 0 {
+		end = buffer.Pos{Row: at.Row, GraphemeCol: at.GraphemeCol + end.GraphemeCol}
+	} else {
+		end.Row += at.Row
+	}
+	return edits, buffer.Range{Start: at, End: end}, true
+}
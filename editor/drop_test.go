@@ -0,0 +1,107 @@
+package editor
+
+import (
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func dropModel(cfg Config, sel buffer.Range) Model {
+	m := New(cfg).SetSize(30, 4)
+	m.Buffer().SetCursor(sel.End)
+	m.Buffer().SetSelection(sel)
+	return m
+}
+
+func dragTo(m Model, fromX, fromY, toX, toY int, mods ...tea.KeyMod) Model {
+	m, _ = m.Update(testMouseClick(fromX, fromY, tea.MouseLeft))
+	m, _ = m.Update(testMouseMotion(toX, toY, tea.MouseLeft))
+	m, _ = m.Update(testMouseRelease(toX, toY, tea.MouseLeft, mods...))
+	return m
+}
+
+func TestDrop_MovesSelectionAsOneUndoStep(t *testing.T) {
+	m := dropModel(Config{Text: "one two three"}, buffer.Range{End: pos(0, 3)})
+
+	m, _ = m.Update(testMouseClick(1, 0, tea.MouseLeft))
+	m, _ = m.Update(testMouseMotion(13, 0, tea.MouseLeft))
+	if got := m.caretPos(); got != pos(0, 13) {
+		t.Fatalf("drop caret=%v, want %v", got, pos(0, 13))
+	}
+	m, _ = m.Update(testMouseRelease(13, 0, tea.MouseLeft))
+	if got := m.Buffer().Text(); got != " two threeone" {
+		t.Fatalf("text=%q", got)
+	}
+	assertSelection(t, m, buffer.Range{Start: pos(0, 10), End: pos(0, 13)}, pos(0, 13))
+
+	m, _ = m.Update(testKeyCode('z', tea.ModCtrl))
+	if got := m.Buffer().Text(); got != "one two three" {
+		t.Fatalf("undo should restore the text in one step, text=%q", got)
+	}
+}
+
+func TestDrop_MovesAcrossLinesAndCopiesWithModifier(t *testing.T) {
+	m := dropModel(Config{Text: "abc\ndef"}, buffer.Range{Start: pos(0, 1), End: pos(1, 1)})
+	m = dragTo(m, 2, 0, 3, 1)
+	if got := m.Buffer().Text(); got != "aefbc\nd" {
+		t.Fatalf("text=%q", got)
+	}
+	assertSelection(t, m, buffer.Range{Start: pos(0, 3), End: pos(1, 1)}, pos(1, 1))
+
+	m = dropModel(Config{Text: "abc\ndef"}, buffer.Range{Start: pos(1, 0), End: pos(1, 3)})
+	m = dragTo(m, 1, 1, 1, 0, tea.ModCtrl)
+	if got := m.Buffer().Text(); got != "adefbc\ndef" {
+		t.Fatalf("copy: text=%q", got)
+	}
+	assertSelection(t, m, buffer.Range{Start: pos(0, 1), End: pos(0, 4)}, pos(0, 4))
+}
+
+func TestDrop_ClickAndDropIntoSelection(t *testing.T) {
+	sel := buffer.Range{Start: pos(0, 2), End: pos(0, 6)}
+	m := dropModel(Config{Text: "abcdefgh"}, sel)
+	m = dragTo(m, 3, 0, 5, 0)
+	if got := m.Buffer().Text(); got != "abcdefgh" {
+		t.Fatalf("dropping into the selection should not edit, text=%q", got)
+	}
+	assertSelection(t, m, sel, pos(0, 6))
+
+	m = clickAt(m, 4, 0)
+	if _, ok := m.Buffer().Selection(); ok || m.Buffer().Cursor() != pos(0, 4) {
+		t.Fatalf("a click inside the selection should place the cursor, cursor=%v", m.Buffer().Cursor())
+	}
+
+	m = dropModel(Config{Text: "abcdefgh", ReadOnly: true}, sel)
+	m = dragTo(m, 3, 0, 7, 0)
+	if got := m.Buffer().Text(); got != "abcdefgh" {
+		t.Fatalf("read-only editors should not drop, text=%q", got)
+	}
+}
+
+func TestDrop_EmitsIntentsInIntentMode(t *testing.T) {
+	var batches []IntentBatch
+	m := dropModel(Config{
+		Text:         "ab cd",
+		MutationMode: EmitIntentsOnly,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			batches = append(batches, batch)
+			return IntentDecision{}
+		},
+	}, buffer.Range{End: pos(0, 2)})
+
+	m = dragTo(m, 0, 0, 5, 0)
+	if got := m.Buffer().Text(); got != "ab cd" {
+		t.Fatalf("EmitIntentsOnly should not mutate, text=%q", got)
+	}
+	if len(batches) != 1 || len(batches[0].Intents) != 2 {
+		t.Fatalf("batches=%+v", batches)
+	}
+	edit, ok := batches[0].Intents[0].Payload.(EditIntentPayload)
+	if !ok || len(edit.Edits) != 2 || edit.Edits[0].Text != "ab" || edit.Edits[1].Range != (buffer.Range{End: pos(0, 2)}) {
+		t.Fatalf("edit intent=%+v", batches[0].Intents[0])
+	}
+	if set, ok := batches[0].Intents[1].Payload.(SetCursorIntentPayload); !ok || set.Selection != (buffer.Range{Start: pos(0, 3), End: pos(0, 5)}) {
+		t.Fatalf("cursor intent=%+v", batches[0].Intents[1])
+	}
+}
@@ -0,0 +1,35 @@
+package editor
+
+import "github.com/iw2rmb/flourish/buffer"
+
+// Find selects the next occurrence of query and scrolls it into view.
+//
+// Forward searches start at the end of the active selection (or the cursor),
+// so repeated calls step through matches; backward searches start at the
+// selection start. The cursor is placed at the match end. ok is false when
+// nothing matched, in which case the model is unchanged.
+//
+// Find reads rows on demand and is safe on lazily backed buffers.
+func (m Model) Find(query string, opt buffer.FindOptions) (Model, bool) {
+	if m.buf == nil {
+		return m, false
+	}
+
+	from := m.buf.Cursor()
+	if sel, ok := m.buf.Selection(); ok {
+		from = sel.End
+		if opt.Backward {
+			from = sel.Start
+		}
+	}
+	r, ok := m.buf.Find(query, from, opt)
+	if !ok {
+		return m, false
+	}
+
+	m.buf.SetCursor(r.End)
+	m.buf.SetSelection(r)
+	m.syncFromBuffer()
+	m.followCursorWithForce(true)
+	return m, true
+}
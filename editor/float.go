@@ -0,0 +1,278 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+const (
+	defaultFloatMaxWidth  = 60
+	defaultFloatMaxHeight = 10
+)
+
+// Z orders of editor-owned floating content. Windows with a higher Z draw on
+// top; equal Z keeps insertion order.
+const (
+	SignatureHelpZ = 50
+	CompletionZ    = 100
+	HoverZ         = 150
+	defaultFloatZ  = 200
+)
+
+// FloatStyleKey is passed to Config.FloatStyleForKey for windows without a
+// StyleKey. When unresolved, Style.CompletionItem is used.
+const FloatStyleKey = "float"
+
+// FloatCodeStyleKey styles code spans and blocks in markdown floating window
+// content over the window style. When unresolved, code renders faint.
+const FloatCodeStyleKey = "float.code"
+
+// FloatPlacement selects the preferred side of the anchor row.
+type FloatPlacement uint8
+
+const (
+	// FloatBelow places the window below the anchor row, flipping above when
+	// below has too few rows.
+	FloatBelow FloatPlacement = iota
+	// FloatAbove places the window above the anchor row, flipping below when
+	// above has too few rows.
+	FloatAbove
+)
+
+// FloatingWindow is content drawn over the viewport next to a document
+// position. It is hidden while the anchor is scrolled out of view.
+type FloatingWindow struct {
+	// ID identifies the window for SetFloatingWindow and RemoveFloatingWindow.
+	ID     string
+	Anchor buffer.Pos
+	// Content is rendered like completion documentation: Format selects plain
+	// text or the markdown subset, and text wraps at spaces.
+	Content string
+	Format  CompletionDocFormat
+
+	Placement FloatPlacement
+	// Z orders the window against other windows and the completion popup
+	// (CompletionZ). Zero uses 200, above all editor-owned windows.
+	Z int
+	// MaxWidth and MaxHeight cap the window in cells, including one cell of
+	// horizontal padding per side. Values <= 0 default to 60 and 10.
+	MaxWidth, MaxHeight int
+	// StyleKey is resolved through Config.FloatStyleForKey; empty uses
+	// FloatStyleKey.
+	StyleKey string
+
+	// cells replaces Content with pre-parsed lines (signature help).
+	cells [][]completionDocCell
+}
+
+// floatLayer is a placed overlay in content-area coordinates.
+type floatLayer struct {
+	rows []string
+	x, y int
+	z    int
+}
+
+// floatArea is the viewport region overlays may cover, in content-area
+// coordinates: columns [left, right) after the gutter and rows [0, height)
+// above the horizontal scrollbar.
+type floatArea struct {
+	left, right int
+	height      int
+}
+
+// SetFloatingWindow shows w, replacing any window with the same ID.
+func (m Model) SetFloatingWindow(w FloatingWindow) Model {
+	m.floats = setFloatingWindow(m.floats, w)
+	return m
+}
+
+// RemoveFloatingWindow hides the window with the given ID.
+func (m Model) RemoveFloatingWindow(id string) Model {
+	m.floats = removeFloatingWindow(m.floats, id)
+	return m
+}
+
+// FloatingWindows returns the shown windows, including the editor's hover
+// and signature help windows.
+func (m Model) FloatingWindows() []FloatingWindow {
+	out := slices.Clone(m.floats)
+	for i := range out {
+		out[i].cells = nil
+	}
+	return out
+}
+
+func setFloatingWindow(floats []FloatingWindow, w FloatingWindow) []FloatingWindow {
+	out := slices.Clone(floats)
+	if i := slices.IndexFunc(out, func(f FloatingWindow) bool { return f.ID == w.ID }); i >= 0 {
+		out[i] = w
+		return out
+	}
+	return append(out, w)
+}
+
+func removeFloatingWindow(floats []FloatingWindow, id string) []FloatingWindow {
+	i := slices.IndexFunc(floats, func(f FloatingWindow) bool { return f.ID == id })
+	if i < 0 {
+		return floats
+	}
+	return slices.Delete(slices.Clone(floats), i, i+1)
+}
+
+func (m Model) hasFloatingWindow(id string) bool {
+	return slices.ContainsFunc(m.floats, func(f FloatingWindow) bool { return f.ID == id })
+}
+
+// floatArea returns the region overlays may cover.
+func (m *Model) floatArea() (floatArea, bool) {
+	lines := m.ensureLines()
+	layout := m.ensureLayoutCache(lines)
+	metrics := m.resolveScrollbarMetrics(lines, layout)
+	if metrics.innerWidth <= 0 || metrics.contentHeight <= 0 {
+		return floatArea{}, false
+	}
+	left := m.resolvedGutterWidth(m.docLineCount(lines))
+	right := left + metrics.contentWidth
+	if right <= left {
+		return floatArea{}, false
+	}
+	return floatArea{left: left, right: right, height: metrics.contentHeight}, true
+}
+
+// floatVerticalPlacement picks the side of anchorY for want rows: the
+// preferred side when it fits, else the other side when it fits, else the
+// side with more room, shrunk to fit.
+func floatVerticalPlacement(anchorY, want, height int, preferBelow bool) (below bool, rows int) {
+	belowAvail := max(height-(anchorY+1), 0)
+	aboveAvail := max(anchorY, 0)
+	first, second := belowAvail, aboveAvail
+	if !preferBelow {
+		first, second = aboveAvail, belowAvail
+	}
+	switch {
+	case want <= first:
+		return preferBelow, want
+	case want <= second:
+		return !preferBelow, want
+	case second > first:
+		return !preferBelow, second
+	}
+	return preferBelow, first
+}
+
+// floatingWindowLayer places and renders w.
+func (m Model) floatingWindowLayer(w FloatingWindow, area floatArea) (floatLayer, bool) {
+	lines := w.cells
+	if lines == nil {
+		lines = parseCompletionDoc(w.Content, w.Format)
+	}
+	if len(lines) == 0 {
+		return floatLayer{}, false
+	}
+	anchorX, anchorY, ok := m.DocToScreen(w.Anchor)
+	if !ok {
+		return floatLayer{}, false
+	}
+
+	maxWidth, maxHeight := w.MaxWidth, w.MaxHeight
+	if maxWidth <= 0 {
+		maxWidth = defaultFloatMaxWidth
+	}
+	if maxHeight <= 0 {
+		maxHeight = defaultFloatMaxHeight
+	}
+	width := min(completionDocNaturalWidth(lines)+2, maxWidth, area.right-area.left)
+	if width <= 2 {
+		return floatLayer{}, false
+	}
+	wrapped := wrapCompletionDoc(lines, width-2)
+	below, height := floatVerticalPlacement(anchorY, min(len(wrapped), maxHeight), area.height, w.Placement == FloatBelow)
+	if height <= 0 {
+		return floatLayer{}, false
+	}
+
+	y := anchorY + 1
+	if !below {
+		y = anchorY - height
+	}
+	base := m.floatStyle(w.StyleKey)
+	code := base.Faint(true)
+	if keyed, ok := m.floatKeyedStyle(FloatCodeStyleKey); ok {
+		code = keyed.Inherit(base)
+	}
+	rows := make([]string, 0, height)
+	for _, line := range wrapped[:height] {
+		rows = append(rows, renderDocRow(line, base, code, width))
+	}
+	z := w.Z
+	if z == 0 {
+		z = defaultFloatZ
+	}
+	return floatLayer{
+		rows: rows,
+		x:    clampInt(anchorX, area.left, area.right-width),
+		y:    clampInt(y, 0, area.height-height),
+		z:    z,
+	}, true
+}
+
+func (m Model) floatKeyedStyle(key string) (lipgloss.Style, bool) {
+	if m.cfg.FloatStyleForKey == nil {
+		return lipgloss.Style{}, false
+	}
+	return m.cfg.FloatStyleForKey(key)
+}
+
+// floatStyle resolves the base style of a window with styleKey.
+func (m Model) floatStyle(styleKey string) lipgloss.Style {
+	base := m.cfg.Style.CompletionItem
+	if styleKey == "" {
+		styleKey = FloatStyleKey
+	}
+	if keyed, ok := m.floatKeyedStyle(styleKey); ok {
+		return keyed.Inherit(base)
+	}
+	return base
+}
+
+// overlayLayers returns the completion popup and floating windows to draw
+// over the viewport, ordered bottom to top.
+func (m Model) overlayLayers() []floatLayer {
+	var layers []floatLayer
+	if popup, ok := m.completionPopupLayout(); ok {
+		layers = append(layers, floatLayer{rows: popup.rows, x: popup.x, y: popup.y, z: CompletionZ})
+		if doc, ok := m.completionDocLayout(popup); ok {
+			layers = append(layers, floatLayer{rows: doc.rows, x: doc.x, y: doc.y, z: CompletionZ})
+		}
+	}
+	if len(m.floats) > 0 && m.buf != nil {
+		if area, ok := (&m).floatArea(); ok {
+			for _, w := range m.floats {
+				if layer, ok := m.floatingWindowLayer(w, area); ok {
+					layers = append(layers, layer)
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(layers, func(a, b floatLayer) int { return a.z - b.z })
+	return layers
+}
+
+// renderOverlays composites layers over base, offset by the viewport frame.
+func (m Model) renderOverlays(base string, layers []floatLayer) string {
+	leftFrame := m.viewport.Style.GetMarginLeft() + m.viewport.Style.GetBorderLeftSize() + m.viewport.Style.GetPaddingLeft()
+	topFrame := m.viewport.Style.GetMarginTop() + m.viewport.Style.GetBorderTopSize() + m.viewport.Style.GetPaddingTop()
+	overlays := make([]overlay, 0, len(layers))
+	for _, layer := range layers {
+		overlays = append(overlays, overlay{
+			content: strings.Join(layer.rows, "\n"),
+			x:       leftFrame + layer.x,
+			y:       topFrame + layer.y,
+		})
+	}
+	return compositeLayers(base, overlays)
+}
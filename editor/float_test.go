@@ -0,0 +1,64 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFloatVerticalPlacement(t *testing.T) {
+	cases := []struct {
+		name                   string
+		anchorY, want, height  int
+		preferBelow, wantBelow bool
+		wantRows               int
+	}{
+		{"below fits", 0, 2, 5, true, true, 2},
+		{"above fits", 3, 2, 5, false, false, 2},
+		{"flips above", 4, 2, 5, true, false, 2},
+		{"flips below", 0, 2, 5, false, true, 2},
+		{"shrinks on larger side", 1, 4, 5, false, true, 3},
+	}
+	for _, tc := range cases {
+		below, rows := floatVerticalPlacement(tc.anchorY, tc.want, tc.height, tc.preferBelow)
+		if below != tc.wantBelow || rows != tc.wantRows {
+			t.Fatalf("%s: got below=%v rows=%d, want below=%v rows=%d", tc.name, below, rows, tc.wantBelow, tc.wantRows)
+		}
+	}
+}
+
+func TestFloatingWindow_PlacesAboveAndFlipsAtTop(t *testing.T) {
+	m := New(Config{Text: "00000000\n11111111\n22222222"})
+	m = m.Blur()
+	m = m.SetSize(8, 3)
+	m = m.SetFloatingWindow(FloatingWindow{ID: "w", Anchor: bufferPos(1, 2), Content: "ab", Placement: FloatAbove})
+
+	got := strings.Split(stripANSI(m.View().Content), "\n")
+	assertLines(t, got, []string{"00 ab 00", "11111111", "22222222"})
+
+	m = m.SetFloatingWindow(FloatingWindow{ID: "w", Anchor: bufferPos(0, 2), Content: "ab", Placement: FloatAbove})
+	got = strings.Split(stripANSI(m.View().Content), "\n")
+	assertLines(t, got, []string{"00000000", "11 ab 11", "22222222"})
+
+	m = m.RemoveFloatingWindow("w")
+	if len(m.FloatingWindows()) != 0 {
+		t.Fatalf("expected no windows, got %+v", m.FloatingWindows())
+	}
+}
+
+func TestFloatingWindow_ZOrderAgainstCompletion(t *testing.T) {
+	render := func(z int) []string {
+		m := New(Config{Text: "00000000\n11111111\n22222222"})
+		m = m.Blur()
+		m = m.SetSize(8, 3)
+		m = m.SetCompletionState(CompletionState{
+			Visible: true,
+			Anchor:  bufferPos(0, 1),
+			Items:   []CompletionItem{{ID: "0", Label: []CompletionSegment{{Text: "abc"}}}},
+		})
+		m = m.SetFloatingWindow(FloatingWindow{ID: "w", Anchor: bufferPos(2, 1), Content: "QQ", Placement: FloatAbove, Z: z})
+		return strings.Split(stripANSI(m.View().Content), "\n")
+	}
+
+	assertLines(t, render(HoverZ), []string{"00000000", "1 QQ 111", "22222222"})
+	assertLines(t, render(SignatureHelpZ), []string{"00000000", "1abc 111", "22222222"})
+}
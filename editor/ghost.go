@@ -1,6 +1,10 @@
 package editor
 
 import (
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
 	"github.com/iw2rmb/flourish/buffer"
 	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
 )
@@ -17,9 +21,14 @@ type GhostContext struct {
 }
 
 type Ghost struct {
+	// Text is the displayed suggestion. The first line renders inline at the
+	// cursor; further lines render as virtual rows below the cursor row.
 	Text     string
 	StyleKey string
 	Edits    []buffer.TextEdit // deterministic apply
+	// Snippet reports that Edits[0].Text is snippet syntax (see ParseSnippet);
+	// accepting it starts a snippet session.
+	Snippet bool
 }
 
 type GhostProvider func(ctx GhostContext) (Ghost, bool)
@@ -81,6 +90,23 @@ func (m *Model) ghostFor(row, col int, lineText string, rawLen int) (Ghost, bool
 		row:        row,
 		col:        col,
 	}
+	// The rest of a partially accepted ghost takes precedence at the position
+	// the accept left the cursor.
+	if g, present := m.ghostRest.get(key); present {
+		return g, true
+	}
+
+	if m.asyncEnabled(asyncGhost) {
+		return m.asyncGhost(GhostContext{
+			Row:         row,
+			GraphemeCol: col,
+			LineText:    lineText,
+			IsEndOfLine: col == rawLen,
+			DocID:       m.cfg.DocID,
+			DocVersion:  m.buf.Version(),
+		})
+	}
+
 	if g, present := m.ghostCache.get(key); m.ghostCache.valid && m.ghostCache.key == key {
 		return g, present
 	}
@@ -95,7 +121,7 @@ func (m *Model) ghostFor(row, col int, lineText string, rawLen int) (Ghost, bool
 	}
 
 	ghost, present := m.cfg.GhostProvider(ctx)
-	ghost.Text = sanitizeSingleLine(ghost.Text)
+	ghost.Text = sanitizeGhostText(ghost.Text)
 	if present && ghost.Text == "" && len(ghost.Edits) == 0 {
 		present = false
 	}
@@ -117,15 +143,124 @@ func (m *Model) virtualTextWithGhost(row int, rawLine string, vt VirtualText) Vi
 	col := clampInt(cursor.GraphemeCol, 0, rawLen)
 
 	ghost, ok := m.ghostFor(row, col, rawLine, rawLen)
-	if !ok || ghost.Text == "" {
+	if !ok {
+		return vt
+	}
+	first, _, _ := strings.Cut(ghost.Text, "\n")
+	if first == "" {
 		return vt
 	}
 
 	vt.Insertions = append(vt.Insertions, VirtualInsertion{
 		GraphemeCol: col,
-		Text:        ghost.Text,
+		Text:        first,
 		Role:        VirtualRoleGhost,
 		StyleKey:    ghost.StyleKey,
 	})
 	return normalizeVirtualText(vt, rawLen)
 }
+
+// sanitizeGhostText normalizes line breaks of ghost text to "\n".
+func sanitizeGhostText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "")
+}
+
+// ghostRowsFor returns the ghost lines after the first, rendered as virtual
+// rows below row, and the ghost style key.
+func (m *Model) ghostRowsFor(row int, rawLine string) ([]string, string) {
+	if m.buf == nil || m.cfg.GhostProvider == nil || !m.focused || m.windowed() {
+		return nil, ""
+	}
+	cursor := m.buf.Cursor()
+	if cursor.Row != row {
+		return nil, ""
+	}
+	rawLen := graphemeutil.Count(rawLine)
+	ghost, ok := m.ghostFor(row, clampInt(cursor.GraphemeCol, 0, rawLen), rawLine, rawLen)
+	if !ok {
+		return nil, ""
+	}
+	lines := strings.Split(ghost.Text, "\n")
+	if len(lines) < 2 {
+		return nil, ""
+	}
+	return lines[1:], ghost.StyleKey
+}
+
+// renderGhostRow renders a virtual ghost row clipped to cells [left, right).
+func renderGhostRow(sb *strings.Builder, st Style, ghostStyleForKey func(string) (lipgloss.Style, bool), text, styleKey string, tabWidth, left, right int) {
+	style := st.Ghost.Inherit(st.Text)
+	if ghostStyleForKey != nil && styleKey != "" {
+		if keyed, ok := ghostStyleForKey(styleKey); ok {
+			style = keyed.Inherit(style)
+		}
+	}
+	var visible strings.Builder
+	for _, tok := range BuildVisualLine(text, VirtualText{}, tabWidth).Tokens {
+		if tok.StartCell >= left && tok.StartCell+tok.CellWidth <= right {
+			visible.WriteString(tok.Text)
+		}
+	}
+	if visible.Len() > 0 {
+		sb.WriteString(style.Render(visible.String()))
+	}
+}
+
+// ghostPrefixEnd returns the byte length of the part of text accepted by a
+// partial accept: through the next word (leading whitespace included) when
+// word is true, else through the next line break.
+func ghostPrefixEnd(text string, word bool) int {
+	if !word {
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			return i + 1
+		}
+		return len(text)
+	}
+	end := 0
+	clusters := graphemeutil.Split(text)
+	i := 0
+	for i < len(clusters) && graphemeutil.IsSpace(clusters[i]) {
+		end += len(clusters[i])
+		i++
+	}
+	for i < len(clusters) && !graphemeutil.IsSpace(clusters[i]) {
+		end += len(clusters[i])
+		i++
+	}
+	return end
+}
+
+// ghostPartialAccept splits a single-insertion ghost at the cursor into the
+// accepted prefix edit and the remaining text.
+func ghostPartialAccept(ghost Ghost, cursor buffer.Pos, word bool) (buffer.TextEdit, string, bool) {
+	if ghost.Snippet || len(ghost.Edits) != 1 || !ghostRightAcceptEligible(ghost.Edits, cursor) {
+		return buffer.TextEdit{}, "", false
+	}
+	text := sanitizeGhostText(ghost.Edits[0].Text)
+	end := ghostPrefixEnd(text, word)
+	if end == 0 {
+		return buffer.TextEdit{}, "", false
+	}
+	edit := ghost.Edits[0]
+	edit.Text = text[:end]
+	return edit, text[end:], true
+}
+
+// keepGhostRest shows rest as the ghost at the cursor after a partial accept.
+func (m *Model) keepGhostRest(rest, styleKey string) {
+	if rest == "" {
+		return
+	}
+	cursor := m.buf.Cursor()
+	m.ghostRest.put(ghostCacheKey{
+		docID:      m.cfg.DocID,
+		docVersion: m.buf.Version(),
+		row:        cursor.Row,
+		col:        cursor.GraphemeCol,
+	}, Ghost{
+		Text:     rest,
+		StyleKey: styleKey,
+		Edits:    []buffer.TextEdit{{Range: buffer.Range{Start: cursor, End: cursor}, Text: rest}},
+	}, true)
+}
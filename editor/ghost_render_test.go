@@ -1,6 +1,7 @@
 package editor
 
 import (
+	"strings"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
@@ -314,3 +315,92 @@ func TestUpdate_GhostAccept_RightSuppressedWhenCompletionVisible(t *testing.T) {
 		t.Fatalf("completion popup should remain visible")
 	}
 }
+
+func multiLineGhostProvider(text string) GhostProvider {
+	return func(ctx GhostContext) (Ghost, bool) {
+		if ctx.Row != 0 || !ctx.IsEndOfLine {
+			return Ghost{}, false
+		}
+		at := buffer.Pos{Row: ctx.Row, GraphemeCol: ctx.GraphemeCol}
+		return Ghost{
+			Text:  text,
+			Edits: []buffer.TextEdit{{Range: buffer.Range{Start: at, End: at}, Text: text}},
+		}, true
+	}
+}
+
+func trimmedViewLines(m Model) []string {
+	lines := strings.Split(stripANSI(m.View().Content), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \u00a0")
+	}
+	return lines
+}
+
+func TestRender_MultiLineGhostAddsVirtualRows(t *testing.T) {
+	m := New(Config{
+		Text:          "if x\nend",
+		GhostProvider: multiLineGhostProvider(" {\r\n\tcall()\n}"),
+	})
+	m = m.SetSize(12, 5)
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 4})
+	m, _ = m.Update(nil)
+
+	assertLines(t, trimmedViewLines(m), []string{"if x  {", "    call()", "}", "end", ""})
+
+	if _, y, ok := m.DocToScreen(buffer.Pos{Row: 1, GraphemeCol: 0}); !ok || y != 3 {
+		t.Fatalf("row after ghost rows should map to screen row 3, got y=%d ok=%v", y, ok)
+	}
+	if got := m.ScreenToDoc(6, 1); got != (buffer.Pos{Row: 0, GraphemeCol: 4}) {
+		t.Fatalf("ghost row should map to the end of its row, got %v", got)
+	}
+	snap := m.RenderSnapshot()
+	if len(snap.Rows) < 4 || !snap.Rows[1].Ghost || !snap.Rows[2].Ghost || snap.Rows[3].Ghost || snap.Rows[3].DocRow != 1 {
+		t.Fatalf("unexpected snapshot rows: %+v", snap.Rows)
+	}
+
+	m.buf.SetCursor(buffer.Pos{Row: 1, GraphemeCol: 0})
+	m, _ = m.Update(nil)
+	assertLines(t, trimmedViewLines(m), []string{"if x", "end", "", "", ""})
+}
+
+func TestUpdate_GhostPartialAcceptWordAndLine(t *testing.T) {
+	calls := 0
+	provider := multiLineGhostProvider(" {\n\tcall()\n}")
+	m := New(Config{
+		Text: "if x",
+		GhostProvider: func(ctx GhostContext) (Ghost, bool) {
+			calls++
+			return provider(ctx)
+		},
+	})
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 4})
+
+	m, _ = m.Update(testKeyCode(tea.KeyRight, tea.ModCtrl))
+	if got := m.buf.Text(); got != "if x {" {
+		t.Fatalf("accept word: text=%q", got)
+	}
+	callsAfterWord := calls
+
+	m, _ = m.Update(testKeyCode(tea.KeyDown, tea.ModAlt))
+	if got := m.buf.Text(); got != "if x {\n" {
+		t.Fatalf("accept line: text=%q", got)
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyDown, tea.ModAlt))
+	if got := m.buf.Text(); got != "if x {\n\tcall()\n" {
+		t.Fatalf("accept second line: text=%q", got)
+	}
+	if calls != callsAfterWord {
+		t.Fatalf("the rest of an accepted ghost should not query the provider, calls %d -> %d", callsAfterWord, calls)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if got := m.buf.Text(); got != "if x {\n\tcall()\n}" {
+		t.Fatalf("accept all: text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyRight, tea.ModCtrl))
+	if got := m.buf.Text(); got != "if x {\n\tcall()\n}" {
+		t.Fatalf("without a ghost the key should move, text=%q", got)
+	}
+}
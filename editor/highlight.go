@@ -28,6 +28,18 @@ type LineContext struct {
 	// RawCursorGraphemeCol is the grapheme index within RawText (buffer line), if cursor is on this row; otherwise -1.
 	RawCursorGraphemeCol int
 	HasCursor            bool
+
+	rawToVisible []int
+}
+
+// VisibleGraphemeCol maps a grapheme column in RawText to the matching column
+// in Text. Columns hidden by virtual deletions map to the next visible
+// grapheme; out-of-range columns are clamped.
+func (c LineContext) VisibleGraphemeCol(rawCol int) int {
+	if c.rawToVisible == nil {
+		return max(rawCol, 0)
+	}
+	return c.rawToVisible[clampInt(rawCol, 0, len(c.rawToVisible)-1)]
 }
 
 type Highlighter interface {
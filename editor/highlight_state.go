@@ -0,0 +1,219 @@
+package editor
+
+import (
+	"slices"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// HighlightState is an opaque lexer state carried from the end of one line to
+// the start of the next (e.g. "inside a block comment").
+//
+// Equal must report whether two states highlight the following text
+// identically; the editor stops re-highlighting once states converge.
+type HighlightState interface {
+	Equal(other HighlightState) bool
+}
+
+// StatefulHighlighter is an optional Highlighter extension for multi-line
+// constructs such as block comments, heredocs and multi-line strings.
+//
+// HighlightLineState receives the end state of the previous row (nil for row
+// 0) and returns the row's spans and its own end state. When a Highlighter
+// implements it, HighlightLine is not called (except on lazily backed
+// buffers, where rows above the window are never lexed, and with
+// Config.Async.Highlighter).
+type StatefulHighlighter interface {
+	Highlighter
+	HighlightLineState(ctx LineContext, prev HighlightState) ([]HighlightSpan, HighlightState, error)
+}
+
+// highlightStateCache holds per-row end states for a StatefulHighlighter.
+//
+// Rows below valid hold current states. Non-nil entries at or above valid are
+// states from before the last edit, shifted to their new rows; when a
+// recomputed state equals one of them, the rows after it are current again.
+//
+// Value copies of Model share states and spans, so both are replaced rather
+// than modified in place (see own).
+type highlightStateCache struct {
+	states []HighlightState
+	valid  int
+	// spans holds the spans of rows lexed by the last advance, so rendering
+	// them does not lex them again.
+	spans map[int]lexedSpans
+}
+
+// lexedSpans are a row's spans and the input they were computed for.
+type lexedSpans struct {
+	text      string
+	cursorCol int
+	prev      HighlightState
+	spans     []HighlightSpan
+	err       error
+}
+
+func (c *highlightStateCache) reset() {
+	c.states = nil
+	c.valid = 0
+	c.spans = nil
+}
+
+// own gives c its own copy of states and a fresh spans map before rows are
+// recorded, so that other copies of the model keep theirs.
+func (c *highlightStateCache) own() {
+	c.states = slices.Clone(c.states)
+	c.spans = make(map[int]lexedSpans)
+}
+
+// cachedSpans returns the spans recorded for ctx.Row when they were lexed
+// from the same input.
+func (c *highlightStateCache) cachedSpans(ctx LineContext, prev HighlightState) (lexedSpans, bool) {
+	ls, ok := c.spans[ctx.Row]
+	if !ok || ls.text != ctx.Text || ls.cursorCol != ctx.CursorGraphemeCol || !highlightStatesEqual(ls.prev, prev) {
+		return lexedSpans{}, false
+	}
+	return ls, true
+}
+
+// applyEdits splices the cache for sequentially applied edits. Edited rows
+// become unknown; rows after them keep their shifted states.
+func (c *highlightStateCache) applyEdits(edits []buffer.AppliedEdit) {
+	// Entries past valid may predate earlier edits; they cannot be trusted.
+	n := min(c.valid, len(c.states))
+	c.states = c.states[:n:n]
+	c.spans = nil
+	for _, e := range edits {
+		start := e.RangeBefore.Start.Row
+		if start >= len(c.states) {
+			c.valid = min(c.valid, start)
+			continue
+		}
+		oldEnd := min(e.RangeBefore.End.Row+1, len(c.states))
+		newRows := e.RangeAfter.End.Row - start + 1
+		next := make([]HighlightState, 0, start+newRows+len(c.states)-oldEnd)
+		next = append(next, c.states[:start]...)
+		next = append(next, make([]HighlightState, newRows)...)
+		next = append(next, c.states[oldEnd:]...)
+		c.states = next
+		c.valid = min(c.valid, start)
+	}
+}
+
+// record stores the end state of row c.valid. It reports whether the state
+// differs from the cached one, i.e. whether the next row must be re-rendered.
+func (c *highlightStateCache) record(st HighlightState) bool {
+	row := c.valid
+	if row < len(c.states) {
+		if old := c.states[row]; old != nil && highlightStatesEqual(old, st) {
+			// Converged: shifted states after row are current up to the next gap.
+			c.valid = row + 1
+			for c.valid < len(c.states) && c.states[c.valid] != nil {
+				c.valid++
+			}
+			return false
+		}
+		c.states[row] = st
+	} else {
+		c.states = append(c.states, st)
+	}
+	c.valid = row + 1
+	return true
+}
+
+func highlightStatesEqual(a, b HighlightState) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(b)
+}
+
+func (m *Model) statefulHighlighter() (StatefulHighlighter, bool) {
+	if m.windowed() || m.asyncEnabled(asyncHighlight) {
+		return nil, false
+	}
+	sh, ok := m.cfg.Highlighter.(StatefulHighlighter)
+	return sh, ok
+}
+
+// syncHighlightStates updates cached states after a text change. It splices
+// by Change.AppliedEdits when the last change covers everything since
+// prevVersion and starts over otherwise.
+func (m *Model) syncHighlightStates(prevVersion uint64) {
+	if _, ok := m.statefulHighlighter(); !ok {
+		return
+	}
+	ch, ok := m.buf.LastChange()
+	if !ok || ch.VersionBefore != prevVersion || len(ch.AppliedEdits) == 0 {
+		m.highlightStates.reset()
+		return
+	}
+	m.highlightStates.applyEdits(ch.AppliedEdits)
+}
+
+// advanceHighlightStates lexes rows from the first stale row through row
+// (inclusive), stopping early once states converge. Rows whose start state
+// changed are added to dirty when it is non-nil. Spans of lexed rows from the
+// layout window on are kept for rendering.
+func (m *Model) advanceHighlightStates(through int, dirty map[int]struct{}) {
+	sh, ok := m.statefulHighlighter()
+	if !ok {
+		return
+	}
+	lines := m.ensureLines()
+	c := &m.highlightStates
+	if c.valid > through || c.valid >= len(lines) {
+		return
+	}
+	c.own()
+	cursor := m.caretPos()
+	for c.valid <= through && c.valid < len(lines) {
+		row := c.valid
+		rawLine := lines[row]
+		vi := computeVisibleLineInfo(rawLine, m.virtualTextForRow(row, rawLine))
+		prev := m.highlightStateBeforeKnown(row)
+		ctx := m.highlightLineContext(row, rawLine, vi, cursor)
+		spans, st, err := sh.HighlightLineState(ctx, prev)
+		if row >= m.layout.base {
+			c.spans[row] = lexedSpans{text: ctx.Text, cursorCol: ctx.CursorGraphemeCol, prev: prev, spans: spans, err: err}
+		}
+		if err != nil {
+			st = prev
+		}
+		if c.record(st) && dirty != nil {
+			addDirtyRow(dirty, len(lines), row+1)
+		}
+	}
+}
+
+// highlightStateBeforeKnown returns the cached end state of row-1 without
+// lexing. Callers ensure rows above row are current.
+func (m *Model) highlightStateBeforeKnown(row int) HighlightState {
+	if row <= 0 || row-1 >= len(m.highlightStates.states) {
+		return nil
+	}
+	return m.highlightStates.states[row-1]
+}
+
+// lastVisibleLogicalRow returns the document row rendered at the bottom of
+// the viewport according to the current layout.
+func (m *Model) lastVisibleLogicalRow() int {
+	rows := m.layout.rows
+	if len(rows) == 0 {
+		return 0
+	}
+	idx := m.topVisualRow() + m.visibleRowCount() - 1 - m.layout.base
+	return rows[clampInt(idx, 0, len(rows)-1)].logicalRow
+}
+
+// InvalidateHighlight drops cached highlight states and rebuilds rendered
+// content. Use this when highlighter output depends on host-managed state
+// that changed outside editor Update flow.
+func (m Model) InvalidateHighlight() Model {
+	m.highlightStates.reset()
+	if m.async != nil {
+		m.async.highlight.invalidate()
+	}
+	m.rebuildContent()
+	return m
+}
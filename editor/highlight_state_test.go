@@ -0,0 +1,196 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+type blockCommentState bool
+
+func (s blockCommentState) Equal(other HighlightState) bool {
+	o, ok := other.(blockCommentState)
+	return ok && o == s
+}
+
+// blockCommentHighlighter upper-cases text inside /* ... */ comments, which
+// may span rows.
+type blockCommentHighlighter struct {
+	calls map[int]int
+}
+
+func (h *blockCommentHighlighter) HighlightLine(LineContext) ([]HighlightSpan, error) {
+	panic("HighlightLine must not be called for stateful highlighters")
+}
+
+func (h *blockCommentHighlighter) HighlightLineState(ctx LineContext, prev HighlightState) ([]HighlightSpan, HighlightState, error) {
+	if h.calls != nil {
+		h.calls[ctx.Row]++
+	}
+	upper := lipgloss.NewStyle().Transform(strings.ToUpper)
+	inComment := prev != nil && bool(prev.(blockCommentState))
+	var spans []HighlightSpan
+	text := ctx.Text
+	col := 0
+	for {
+		if inComment {
+			end := strings.Index(text[col:], "*/")
+			if end < 0 {
+				if col < len(text) {
+					spans = append(spans, HighlightSpan{StartGraphemeCol: col, EndGraphemeCol: len(text), Style: upper})
+				}
+				break
+			}
+			spans = append(spans, HighlightSpan{StartGraphemeCol: col, EndGraphemeCol: col + end + 2, Style: upper})
+			col += end + 2
+			inComment = false
+			continue
+		}
+		open := strings.Index(text[col:], "/*")
+		if open < 0 {
+			break
+		}
+		col += open
+		inComment = true
+	}
+	return spans, blockCommentState(inComment), nil
+}
+
+func newStatefulTestModel(text string, h *blockCommentHighlighter, height int) Model {
+	return New(Config{Text: text, Highlighter: h}).SetSize(20, height)
+}
+
+func TestStatefulHighlighter_CarriesStateAcrossRows(t *testing.T) {
+	h := &blockCommentHighlighter{}
+	m := newStatefulTestModel("a /* b\nc\nd */ e\nf", h, 4)
+
+	view := stripANSI(m.View().Content)
+	for _, want := range []string{"a /* B", "C", "D */ e", "f"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view missing %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestStatefulHighlighter_EditPropagatesUntilStatesConverge(t *testing.T) {
+	h := &blockCommentHighlighter{}
+	m := newStatefulTestModel("x\ny\nz */ w\nv", h, 4)
+
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 1})
+	m, _ = m.Update(testKeyText("/"))
+	m, _ = m.Update(testKeyText("*"))
+
+	view := stripANSI(m.View().Content)
+	for _, want := range []string{"x/*", "Y", "Z */ w", "v"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view after opening comment missing %q:\n%s", want, view)
+		}
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	view = stripANSI(m.View().Content)
+	for _, want := range []string{"x/", "y", "z */ w"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view after closing comment missing %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestStatefulHighlighter_RelexesOnlyChangedRows(t *testing.T) {
+	h := &blockCommentHighlighter{}
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("row")
+	}
+	m := newStatefulTestModel(sb.String(), h, 5)
+
+	// Scroll far down so rows above the viewport must be lexed once.
+	m.buf.SetCursor(buffer.Pos{Row: 150})
+	m, _ = m.Update(nil)
+	m.buf.SetCursor(buffer.Pos{Row: 152, GraphemeCol: 3})
+	m, _ = m.Update(nil)
+
+	h.calls = map[int]int{}
+	m, _ = m.Update(testKeyText("!"))
+	if h.calls[152] == 0 {
+		t.Fatalf("expected edited row to be re-lexed")
+	}
+	for row, n := range h.calls {
+		if row < 152 {
+			t.Fatalf("row %d above the edit was re-lexed %d times", row, n)
+		}
+		if row > 160 {
+			t.Fatalf("row %d past convergence was re-lexed", row)
+		}
+	}
+}
+
+func TestStatefulHighlighter_LexesEachRowOncePerUpdate(t *testing.T) {
+	h := &blockCommentHighlighter{}
+	m := newStatefulTestModel("x\ny\nz */ w\nv", h, 4)
+	m.buf.SetCursor(buffer.Pos{Row: 0, GraphemeCol: 1})
+
+	h.calls = map[int]int{}
+	m, _ = m.Update(testKeyText("/"))
+	m, _ = m.Update(testKeyText("*"))
+	for row, n := range h.calls {
+		if n > 2 {
+			t.Fatalf("row %d lexed %d times in two updates", row, n)
+		}
+	}
+	if view := stripANSI(m.View().Content); !strings.Contains(view, "Y") {
+		t.Fatalf("expected reused spans to render the comment:\n%s", view)
+	}
+}
+
+func TestStatefulHighlighter_CopiesKeepTheirStates(t *testing.T) {
+	h := &blockCommentHighlighter{}
+	m := newStatefulTestModel("x\ny\nz\nv", h, 4)
+	// Rows 2 and 3 hold shifted states from before an edit.
+	m.highlightStates = highlightStateCache{
+		states: []HighlightState{blockCommentState(false), blockCommentState(false), blockCommentState(true), blockCommentState(true)},
+		valid:  2,
+	}
+	before := m
+
+	m.advanceHighlightStates(3, nil)
+	if st := m.highlightStates.states[2]; st != blockCommentState(false) {
+		t.Fatalf("advanced model: row 2 state %v", st)
+	}
+	if st := before.highlightStates.states[2]; st != blockCommentState(true) {
+		t.Fatalf("earlier copy sees states written by another copy: row 2 state %v", st)
+	}
+}
+
+func TestHighlightStateCache_ApplyEditsShiftsStates(t *testing.T) {
+	s := func(v bool) HighlightState { return blockCommentState(v) }
+	c := highlightStateCache{states: []HighlightState{s(false), s(true), s(true), s(false)}, valid: 4}
+
+	// Row 1 split into two rows.
+	c.applyEdits([]buffer.AppliedEdit{{
+		RangeBefore: buffer.Range{Start: buffer.Pos{Row: 1, GraphemeCol: 1}, End: buffer.Pos{Row: 1, GraphemeCol: 1}},
+		RangeAfter:  buffer.Range{Start: buffer.Pos{Row: 1, GraphemeCol: 1}, End: buffer.Pos{Row: 2, GraphemeCol: 0}},
+		InsertText:  "\n",
+	}})
+	if c.valid != 1 || len(c.states) != 5 || c.states[1] != nil || c.states[2] != nil {
+		t.Fatalf("after split: valid=%d states=%v", c.valid, c.states)
+	}
+	if c.states[3] != s(true) || c.states[4] != s(false) {
+		t.Fatalf("shifted states: %v", c.states)
+	}
+
+	if !c.record(s(true)) || !c.record(s(true)) {
+		t.Fatalf("recording unknown rows should report a change")
+	}
+	// Row 3 matches its shifted state: the rest is current again.
+	if c.record(s(true)) || c.valid != 5 {
+		t.Fatalf("expected convergence, valid=%d", c.valid)
+	}
+}
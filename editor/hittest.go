@@ -24,19 +24,25 @@ func (m *Model) screenToDocPos(x, y int) buffer.Pos {
 		return buffer.Pos{}
 	}
 
-	visualRow := layout.clampVisualRow(m.viewport.YOffset() + y)
+	visualRow := layout.clampVisualRow(m.topVisualRow() + y)
 	row, line, seg, segIdx, ok := layout.lineAndSegmentAt(visualRow)
 	if !ok {
 		return buffer.Pos{}
 	}
+	if ref, _ := layout.rowAt(visualRow); ref.ghostLine > 0 {
+		// Ghost rows are not part of the document; they map to the end of the
+		// row they belong to.
+		return buffer.Pos{Row: row, GraphemeCol: line.visual.RawGraphemeLen}
+	}
 
 	if x < 0 {
 		x = 0
 	}
-	baseGW := m.resolvedBaseGutterWidth(len(lines))
+	lineCount := m.docLineCount(lines)
+	baseGW := m.resolvedBaseGutterWidth(lineCount)
 	gw := baseGW + m.resolvedRowMarkWidth()
 	if x < gw {
-		cell := m.resolveGutterCell(row, segIdx, line.rawLine, len(lines), baseGW, row == m.buf.Cursor().Row)
+		cell := m.resolveGutterCell(row, segIdx, line.rawLine, lineCount, baseGW, row == m.buf.Cursor().Row)
 		return buffer.Pos{Row: row, GraphemeCol: clampInt(cell.ClickCol, 0, line.visual.RawGraphemeLen)}
 	}
 	visualX := x - gw
@@ -83,8 +89,11 @@ func (m *Model) docToScreenPos(pos buffer.Pos) (x int, y int, ok bool) {
 		return 0, 0, false
 	}
 
-	row := clampInt(pos.Row, 0, len(layout.lines)-1)
-	line := layout.lines[row]
+	row := clampInt(pos.Row, layout.base, layout.base+len(layout.lines)-1)
+	if layout.windowed && row != pos.Row {
+		return 0, 0, false
+	}
+	line := layout.lines[row-layout.base]
 	if len(line.segments) == 0 {
 		return 0, 0, false
 	}
@@ -106,7 +115,7 @@ func (m *Model) docToScreenPos(pos buffer.Pos) (x int, y int, ok bool) {
 
 	seg := line.segments[segIdx]
 	visualRow := line.firstVisualRow + segIdx
-	screenY := visualRow - m.viewport.YOffset()
+	screenY := visualRow - m.topVisualRow()
 
 	screenX := 0
 	if m.cfg.WrapMode == WrapNone {
@@ -114,7 +123,7 @@ func (m *Model) docToScreenPos(pos buffer.Pos) (x int, y int, ok bool) {
 	} else {
 		screenX = cell - seg.startCell
 	}
-	screenX += m.resolvedGutterWidth(len(lines))
+	screenX += m.resolvedGutterWidth(m.docLineCount(lines))
 
 	visibleRows := m.visibleRowCount()
 	if screenY < 0 || screenY >= visibleRows {
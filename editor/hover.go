@@ -0,0 +1,235 @@
+package editor
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// Reserved floating window IDs used by the editor.
+const (
+	HoverWindowID         = "editor.hover"
+	SignatureHelpWindowID = "editor.signature"
+)
+
+// Style keys of the editor-owned floating windows, resolved through
+// Config.FloatStyleForKey.
+const (
+	HoverStyleKey         = "float.hover"
+	SignatureHelpStyleKey = "float.signature"
+)
+
+var defaultSignatureHelpTriggerCharacters = []string{"(", ","}
+
+type HoverContext struct {
+	// Pos is the hovered grapheme.
+	Pos      buffer.Pos
+	LineText string
+
+	// Optional host metadata for caching.
+	DocID      string
+	DocVersion uint64
+}
+
+type Hover struct {
+	Contents string
+	Format   CompletionDocFormat
+}
+
+// HoverProvider returns hover contents for a document position, or false when
+// there is nothing to show.
+type HoverProvider func(ctx HoverContext) (Hover, bool)
+
+type SignatureHelpContext struct {
+	Cursor   buffer.Pos
+	LineText string
+	// Trigger is the trigger sequence just typed; empty for re-queries and
+	// ShowSignatureHelp.
+	Trigger string
+	// Active reports that signature help is shown and is being re-queried
+	// after a cursor or text change.
+	Active bool
+
+	// Optional host metadata for caching.
+	DocID      string
+	DocVersion uint64
+}
+
+type SignatureHelp struct {
+	Label string
+	// ActiveStart and ActiveEnd are grapheme offsets in Label of the active
+	// parameter, rendered bold. An empty range highlights nothing.
+	ActiveStart, ActiveEnd int
+	// Documentation is rendered below the label.
+	Documentation       string
+	DocumentationFormat CompletionDocFormat
+}
+
+// SignatureHelpProvider returns signature help at the cursor, or false to
+// close it.
+type SignatureHelpProvider func(ctx SignatureHelpContext) (SignatureHelp, bool)
+
+func normalizeSignatureHelpTriggerCharacters(cfg Config) []string {
+	if cfg.SignatureHelpProvider != nil && cfg.SignatureHelpTriggerCharacters == nil {
+		return defaultSignatureHelpTriggerCharacters
+	}
+	return normalizeCompletionTriggerCharacters(cfg.SignatureHelpTriggerCharacters)
+}
+
+// ShowHover queries Config.HoverProvider at pos and shows the result, or
+// hides the hover window when the provider returns false.
+func (m Model) ShowHover(pos buffer.Pos) Model {
+	m.hoverPos, m.hoverPosOK = pos, true
+	m.queryHover(pos)
+	return m
+}
+
+// ShowSignatureHelp queries Config.SignatureHelpProvider at the cursor and
+// shows the result, or closes signature help when the provider returns false.
+func (m Model) ShowSignatureHelp() Model {
+	m.querySignatureHelp("")
+	return m
+}
+
+func (m *Model) queryHover(pos buffer.Pos) {
+	if m.buf == nil || m.cfg.HoverProvider == nil {
+		m.floats = removeFloatingWindow(m.floats, HoverWindowID)
+		return
+	}
+	lineText, _ := m.docLine(pos.Row)
+	hover, ok := m.cfg.HoverProvider(HoverContext{
+		Pos:        pos,
+		LineText:   lineText,
+		DocID:      m.cfg.DocID,
+		DocVersion: m.buf.Version(),
+	})
+	if !ok || hover.Contents == "" {
+		m.floats = removeFloatingWindow(m.floats, HoverWindowID)
+		return
+	}
+	m.floats = setFloatingWindow(m.floats, FloatingWindow{
+		ID:        HoverWindowID,
+		Anchor:    pos,
+		Content:   hover.Contents,
+		Format:    hover.Format,
+		Placement: FloatAbove,
+		Z:         HoverZ,
+		StyleKey:  HoverStyleKey,
+	})
+}
+
+func (m *Model) hideHover() {
+	m.hoverPosOK = false
+	m.floats = removeFloatingWindow(m.floats, HoverWindowID)
+}
+
+// updateHover follows mouse motion at viewport-local (x, y): it queries the
+// provider when the pointer moves to another grapheme and hides the window
+// when the pointer leaves the text.
+func (m *Model) updateHover(x, y int) {
+	if m.cfg.HoverProvider == nil {
+		return
+	}
+	pos, ok := m.hoverTarget(x, y)
+	if !ok {
+		m.hideHover()
+		return
+	}
+	if m.hoverPosOK && m.hoverPos == pos {
+		return
+	}
+	m.hoverPos, m.hoverPosOK = pos, true
+	m.queryHover(pos)
+}
+
+// hoverTarget maps (x, y) to the grapheme under it; ok is false past the end
+// of a line, below the last line and outside the viewport.
+func (m *Model) hoverTarget(x, y int) (buffer.Pos, bool) {
+	if !m.mouseInBounds(x, y) {
+		return buffer.Pos{}, false
+	}
+	pos := m.screenToDocPos(x, y)
+	if _, py, ok := m.docToScreenPos(pos); !ok || py != y {
+		return buffer.Pos{}, false
+	}
+	lineText, ok := m.docLine(pos.Row)
+	if !ok || pos.GraphemeCol >= graphemeutil.Count(lineText) {
+		return buffer.Pos{}, false
+	}
+	return pos, true
+}
+
+// querySignatureHelp asks the provider for signature help at the cursor and
+// shows or closes the window.
+func (m *Model) querySignatureHelp(trigger string) {
+	if m.buf == nil || m.cfg.SignatureHelpProvider == nil {
+		m.floats = removeFloatingWindow(m.floats, SignatureHelpWindowID)
+		return
+	}
+	cursor := m.buf.Cursor()
+	lineText, _ := m.docLine(cursor.Row)
+	help, ok := m.cfg.SignatureHelpProvider(SignatureHelpContext{
+		Cursor:     cursor,
+		LineText:   lineText,
+		Trigger:    trigger,
+		Active:     m.hasFloatingWindow(SignatureHelpWindowID),
+		DocID:      m.cfg.DocID,
+		DocVersion: m.buf.Version(),
+	})
+	if !ok || help.Label == "" {
+		m.floats = removeFloatingWindow(m.floats, SignatureHelpWindowID)
+		return
+	}
+	m.floats = setFloatingWindow(m.floats, FloatingWindow{
+		ID:        SignatureHelpWindowID,
+		Anchor:    cursor,
+		Content:   help.Label,
+		Placement: FloatAbove,
+		Z:         SignatureHelpZ,
+		StyleKey:  SignatureHelpStyleKey,
+		cells:     signatureHelpCells(help),
+	})
+}
+
+// signatureHelpCells renders the label with the active parameter bold,
+// followed by the documentation lines.
+func signatureHelpCells(help SignatureHelp) [][]completionDocCell {
+	label := completionDocCells(sanitizeSegmentText(help.Label), 0)
+	start := clampInt(help.ActiveStart, 0, len(label))
+	end := clampInt(help.ActiveEnd, start, len(label))
+	for i := start; i < end; i++ {
+		label[i].attr |= completionDocBold
+	}
+	return append([][]completionDocCell{label}, parseCompletionDoc(help.Documentation, help.DocumentationFormat)...)
+}
+
+// updateFloatsAfterKey hides hover on any key, opens signature help after a
+// typed trigger sequence and re-queries it while shown.
+func (m *Model) updateFloatsAfterKey(msg tea.KeyPressMsg, beforeVer, beforeText uint64) {
+	if !m.focused || m.buf == nil {
+		return
+	}
+	m.hideHover()
+	if m.cfg.SignatureHelpProvider == nil {
+		return
+	}
+	if keyText(msg) != "" && !hasAltMod(msg) && m.buf.TextVersion() != beforeText {
+		if trigger, ok := m.triggerBeforeCursor(m.cfg.SignatureHelpTriggerCharacters); ok {
+			m.querySignatureHelp(trigger)
+			return
+		}
+	}
+	if m.hasFloatingWindow(SignatureHelpWindowID) && m.buf.Version() != beforeVer {
+		m.querySignatureHelp("")
+	}
+}
+
+// dismissFloats closes hover and signature help; it reports whether either
+// was shown.
+func (m *Model) dismissFloats() bool {
+	shown := m.hasFloatingWindow(HoverWindowID) || m.hasFloatingWindow(SignatureHelpWindowID)
+	m.hideHover()
+	m.floats = removeFloatingWindow(m.floats, SignatureHelpWindowID)
+	return shown
+}
@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func floatingWindow(m Model, id string) (FloatingWindow, bool) {
+	for _, w := range m.FloatingWindows() {
+		if w.ID == id {
+			return w, true
+		}
+	}
+	return FloatingWindow{}, false
+}
+
+func TestHover_MouseMotionQueriesProvider(t *testing.T) {
+	var calls []HoverContext
+	m := New(Config{
+		Text: "0000\n1234\n2222",
+		HoverProvider: func(ctx HoverContext) (Hover, bool) {
+			calls = append(calls, ctx)
+			return Hover{Contents: "doc"}, true
+		},
+	})
+	m = m.SetSize(8, 3)
+
+	m, _ = m.Update(testMouseMotion(2, 1, tea.MouseNone))
+	w, ok := floatingWindow(m, HoverWindowID)
+	if !ok || w.Anchor != bufferPos(1, 2) || w.Content != "doc" {
+		t.Fatalf("expected hover at (1,2), got %+v %v", w, ok)
+	}
+	if len(calls) != 1 || calls[0].LineText != "1234" {
+		t.Fatalf("calls=%+v", calls)
+	}
+	got := strings.Split(stripANSI(m.View().Content), "\n")
+	assertLines(t, got, []string{"00 doc", "1234", "2222"})
+
+	m, _ = m.Update(testMouseMotion(2, 1, tea.MouseNone))
+	if len(calls) != 1 {
+		t.Fatalf("motion within the same grapheme should not re-query, calls=%d", len(calls))
+	}
+
+	m, _ = m.Update(testMouseMotion(6, 1, tea.MouseNone))
+	if _, ok := floatingWindow(m, HoverWindowID); ok {
+		t.Fatalf("hover past the line end should hide the window")
+	}
+
+	m, _ = m.Update(testMouseMotion(0, 2, tea.MouseNone))
+	if _, ok := floatingWindow(m, HoverWindowID); !ok {
+		t.Fatalf("expected hover over (2,0)")
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyRight))
+	if _, ok := floatingWindow(m, HoverWindowID); ok {
+		t.Fatalf("key press should hide hover")
+	}
+}
+
+func TestSignatureHelp_TriggerRequeryAndClose(t *testing.T) {
+	var contexts []SignatureHelpContext
+	m := New(Config{
+		SignatureHelpProvider: func(ctx SignatureHelpContext) (SignatureHelp, bool) {
+			contexts = append(contexts, ctx)
+			prefix := ctx.LineText[:ctx.Cursor.GraphemeCol]
+			open := strings.LastIndex(prefix, "(")
+			if open < 0 || strings.Contains(prefix[open:], ")") {
+				return SignatureHelp{}, false
+			}
+			help := SignatureHelp{Label: "f(a, b)", ActiveStart: 2, ActiveEnd: 3}
+			if strings.Contains(prefix[open:], ",") {
+				help.ActiveStart, help.ActiveEnd = 5, 6
+			}
+			return help, true
+		},
+	}).Focus()
+	m = m.SetSize(20, 3)
+
+	m, _ = m.Update(testKeyText("f"))
+	if len(contexts) != 0 {
+		t.Fatalf("non-trigger text should not query, got %+v", contexts)
+	}
+	m, _ = m.Update(testKeyText("("))
+	w, ok := floatingWindow(m, SignatureHelpWindowID)
+	if !ok || w.Content != "f(a, b)" || w.Anchor != bufferPos(0, 2) {
+		t.Fatalf("expected signature help at the cursor, got %+v %v", w, ok)
+	}
+	if last := contexts[len(contexts)-1]; last.Trigger != "(" || last.Active {
+		t.Fatalf("context=%+v", last)
+	}
+
+	m, _ = m.Update(testKeyText("x"))
+	if last := contexts[len(contexts)-1]; last.Trigger != "" || !last.Active {
+		t.Fatalf("expected a re-query while shown, context=%+v", last)
+	}
+	m, _ = m.Update(testKeyText(")"))
+	if _, ok := floatingWindow(m, SignatureHelpWindowID); ok {
+		t.Fatalf("provider returning false should close signature help")
+	}
+
+	m, _ = m.Update(testKeyText("("))
+	if _, ok := floatingWindow(m, SignatureHelpWindowID); !ok {
+		t.Fatalf("expected signature help to reopen")
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyEscape))
+	if _, ok := floatingWindow(m, SignatureHelpWindowID); ok {
+		t.Fatalf("esc should close signature help")
+	}
+	if got := m.buf.Text(); got != "f(x)(" {
+		t.Fatalf("text=%q", got)
+	}
+}
+
+func TestSignatureHelpCells_BoldsActiveParameter(t *testing.T) {
+	lines := signatureHelpCells(SignatureHelp{Label: "f(a, b)", ActiveStart: 5, ActiveEnd: 6, Documentation: "doc"})
+	if len(lines) != 2 {
+		t.Fatalf("expected label and documentation lines, got %d", len(lines))
+	}
+	for i, c := range lines[0] {
+		if bold := c.attr&completionDocBold != 0; bold != (i == 5) {
+			t.Fatalf("cell %d (%q) bold=%v", i, c.text, bold)
+		}
+	}
+}
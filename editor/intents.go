@@ -30,6 +30,14 @@ const (
 	IntentSelect
 	IntentUndo
 	IntentRedo
+	// IntentEdit carries deterministic multi-range edits (for example, comment
+	// toggles) that are neither a plain insert nor a delete.
+	IntentEdit
+	// IntentSnippetJump moves between tabstops of the active snippet.
+	IntentSnippetJump
+	// IntentSetCursor places the cursor (and optionally a selection) at
+	// explicit positions, for motions buffer.Move cannot express.
+	IntentSetCursor
 )
 
 // EditorState captures buffer-local state before an intent is executed.
@@ -72,13 +80,21 @@ const (
 type InsertIntentPayload struct {
 	Text string
 	// Edits carries deterministic apply edits for complex insertion sources
-	// (for example, ghost accepts). For regular typing, this is empty.
+	// (for example, ghost accepts, auto-closed pairs, or surround-selection).
+	// For regular typing, this is empty.
 	Edits []buffer.TextEdit
+	// Snippet reports that Edits[0].Text is snippet syntax (see ParseSnippet),
+	// expanded by the editor when applied locally.
+	Snippet bool
 }
 
 // DeleteIntentPayload describes a delete action.
 type DeleteIntentPayload struct {
 	Direction DeleteDirection
+	// Edits carries deterministic apply edits when the delete spans more than
+	// the direction implies (for example, backspace inside an empty auto pair).
+	// For regular deletes, this is empty.
+	Edits []buffer.TextEdit
 }
 
 // MoveIntentPayload describes a cursor move action.
@@ -91,6 +107,34 @@ type SelectIntentPayload struct {
 	Move buffer.Move
 }
 
+// EditIntentPayload describes an IntentEdit action. Edits apply sequentially
+// in order, as with buffer.Apply.
+type EditIntentPayload struct {
+	Edits []buffer.TextEdit
+	// Command names the built-in command that derived Edits from the rows
+	// around the cursor, for example "edit.toggleComment". Macro replay
+	// re-runs it instead of applying Edits. Empty for other edits.
+	Command string
+}
+
+// SnippetJumpIntentPayload describes an IntentSnippetJump action. Reaching
+// the final tabstop ($0) ends the snippet session.
+type SnippetJumpIntentPayload struct {
+	// Tabstop is the target tabstop index.
+	Tabstop int
+	// Range is selected at the target; an empty range places the cursor.
+	Range buffer.Range
+}
+
+// SetCursorIntentPayload describes an IntentSetCursor action.
+type SetCursorIntentPayload struct {
+	Cursor buffer.Pos
+	// Selection is selected when Select is set; otherwise the selection is
+	// cleared. Selection.Start is the anchor, so it may follow End.
+	Selection buffer.Range
+	Select    bool
+}
+
 // UndoIntentPayload marks an undo request.
 type UndoIntentPayload struct{}
 
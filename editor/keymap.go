@@ -18,8 +18,20 @@ type KeyMap struct {
 	Backspace, Delete                 key.Binding
 	DeleteWordBackward, KillLineRight key.Binding
 	Enter                             key.Binding
+	ToggleComment                     key.Binding
 
 	Undo, Redo key.Binding
+
+	// Ghost partial accept bindings apply only while a ghost suggestion is
+	// visible; they accept its next word or line.
+	GhostAcceptWord, GhostAcceptLine key.Binding
+
+	// Snippet bindings apply only while a snippet session is active.
+	SnippetNext, SnippetPrev, SnippetExit key.Binding
+
+	// DismissPopup closes hover and signature help; it applies only while one
+	// is shown and after completion keys.
+	DismissPopup key.Binding
 }
 
 // bindings returns all key bindings as a slice.
@@ -33,7 +45,73 @@ func (km KeyMap) bindings() []key.Binding {
 		km.WordLeft, km.WordRight, km.WordShiftLeft, km.WordShiftRight,
 		km.Home, km.End,
 		km.Backspace, km.Delete, km.DeleteWordBackward, km.KillLineRight, km.Enter,
+		km.ToggleComment,
 		km.Undo, km.Redo,
+		km.GhostAcceptWord, km.GhostAcceptLine,
+		km.SnippetNext, km.SnippetPrev, km.SnippetExit,
+		km.DismissPopup,
+	}
+}
+
+// keyCommand pairs a key map binding with the command it runs.
+type keyCommand struct {
+	binding key.Binding
+	command string
+}
+
+// snippetCommands pairs the snippet bindings with their commands.
+func (km KeyMap) snippetCommands() []keyCommand {
+	return []keyCommand{
+		{km.SnippetNext, "snippet.next"},
+		{km.SnippetPrev, "snippet.prev"},
+		{km.SnippetExit, "snippet.exit"},
+	}
+}
+
+// ghostCommands pairs the ghost partial accept bindings with their commands.
+func (km KeyMap) ghostCommands() []keyCommand {
+	return []keyCommand{
+		{km.GhostAcceptWord, "ghost.acceptWord"},
+		{km.GhostAcceptLine, "ghost.acceptLine"},
+	}
+}
+
+// documentCommands pairs the document bindings with their commands, in
+// match order.
+func (km KeyMap) documentCommands() []keyCommand {
+	return []keyCommand{
+		{km.Left, "cursor.left"},
+		{km.Right, "cursor.right"},
+		{km.ParagraphUp, "cursor.paragraphUp"},
+		{km.ParagraphDown, "cursor.paragraphDown"},
+		{km.Up, "cursor.up"},
+		{km.Down, "cursor.down"},
+		{km.PageUp, "cursor.pageUp"},
+		{km.PageDown, "cursor.pageDown"},
+
+		{km.ShiftLeft, "select.left"},
+		{km.ShiftRight, "select.right"},
+		{km.ParagraphShiftUp, "select.paragraphUp"},
+		{km.ParagraphShiftDown, "select.paragraphDown"},
+		{km.ShiftUp, "select.up"},
+		{km.ShiftDown, "select.down"},
+
+		{km.WordLeft, "cursor.wordLeft"},
+		{km.WordRight, "cursor.wordRight"},
+		{km.WordShiftLeft, "select.wordLeft"},
+		{km.WordShiftRight, "select.wordRight"},
+
+		{km.Home, "cursor.lineStart"},
+		{km.End, "cursor.lineEnd"},
+
+		{km.DeleteWordBackward, "edit.deleteWordBackward"},
+		{km.KillLineRight, "edit.killLineRight"},
+		{km.Backspace, "edit.deleteBackward"},
+		{km.Delete, "edit.deleteForward"},
+		{km.Enter, "edit.newline"},
+		{km.ToggleComment, "edit.toggleComment"},
+		{km.Undo, "edit.undo"},
+		{km.Redo, "edit.redo"},
 	}
 }
 
@@ -95,8 +173,19 @@ func DefaultKeyMap() KeyMap {
 		DeleteWordBackward: key.NewBinding(key.WithKeys("alt+backspace", "ctrl+w"), key.WithHelp("opt+⌫/ctrl+w", "delete word left")),
 		KillLineRight:      key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("ctrl+k", "delete line right")),
 		Enter:              key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "newline")),
+		// Legacy terminals report ctrl+/ as ctrl+_.
+		ToggleComment: key.NewBinding(key.WithKeys("ctrl+/", "ctrl+_"), key.WithHelp("ctrl+/", "toggle comment")),
 
 		Undo: key.NewBinding(key.WithKeys("ctrl+z"), key.WithHelp("ctrl+z", "undo")),
 		Redo: key.NewBinding(key.WithKeys("ctrl+y", "ctrl+shift+z"), key.WithHelp("ctrl+y", "redo")),
+
+		GhostAcceptWord: key.NewBinding(key.WithKeys("alt+right", "ctrl+right"), key.WithHelp("alt/ctrl+→", "accept ghost word")),
+		GhostAcceptLine: key.NewBinding(key.WithKeys("alt+down", "ctrl+down"), key.WithHelp("alt/ctrl+↓", "accept ghost line")),
+
+		SnippetNext: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next tabstop")),
+		SnippetPrev: key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous tabstop")),
+		SnippetExit: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "leave snippet")),
+
+		DismissPopup: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close popup")),
 	}
 }
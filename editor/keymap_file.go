@@ -0,0 +1,141 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iw2rmb/flourish/internal/toml"
+)
+
+// keymapEntry is the table form of a keymap file binding.
+type keymapEntry struct {
+	Command string `json:"command"`
+	When    string `json:"when"`
+}
+
+// ParseKeymapJSON decodes key bindings from JSON. The document maps keys
+// (or chords such as "ctrl+k ctrl+c") to a command name, a
+// {"command": ..., "when": ...} object, or an array of either; array entries
+// are tried in order.
+//
+//	{
+//	  "ctrl+b": "cursor.left",
+//	  "ctrl+n": {"command": "completion.next", "when": "completionVisible"},
+//	  "tab": [{"command": "snippet.next", "when": "snippetActive"}, "edit.tab"]
+//	}
+//
+// Bindings are returned sorted by key. Command names are not checked, as
+// hosts may register their own (see Config.Commands); When expressions are.
+func ParseKeymapJSON(data []byte) ([]Binding, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("editor: keymap: %w", err)
+	}
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []Binding
+	for _, k := range keys {
+		if strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("editor: keymap: empty key")
+		}
+		raw := bytes.TrimSpace(doc[k])
+		items := []json.RawMessage{raw}
+		if len(raw) > 0 && raw[0] == '[' {
+			items = nil
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("editor: keymap %q: %w", k, err)
+			}
+		}
+		for _, item := range items {
+			b, err := parseKeymapEntry(k, item)
+			if err != nil {
+				return nil, fmt.Errorf("editor: keymap %q: %w", k, err)
+			}
+			out = append(out, b)
+		}
+	}
+	return out, nil
+}
+
+func parseKeymapEntry(k string, raw json.RawMessage) (Binding, error) {
+	var e keymapEntry
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) > 0 && raw[0] == '"':
+		if err := json.Unmarshal(raw, &e.Command); err != nil {
+			return Binding{}, err
+		}
+	case len(raw) > 0 && raw[0] == '{':
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return Binding{}, err
+		}
+	default:
+		return Binding{}, fmt.Errorf("binding must be a command name or an object, got %s", raw)
+	}
+	if e.Command == "" {
+		return Binding{}, fmt.Errorf("missing command")
+	}
+	if _, err := parseWhen(e.When); err != nil {
+		return Binding{}, err
+	}
+	return Binding{Key: k, Command: e.Command, When: e.When}, nil
+}
+
+// ParseKeymapTOML decodes key bindings from TOML using the same layout as
+// JSON. Keys containing "+" or spaces must be quoted:
+//
+//	"ctrl+b" = "cursor.left"
+//	"ctrl+n" = { command = "completion.next", when = "completionVisible" }
+func ParseKeymapTOML(data []byte) ([]Binding, error) {
+	doc, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("editor: keymap: %w", err)
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("editor: keymap: %w", err)
+	}
+	return ParseKeymapJSON(js)
+}
+
+// ParseKeymap decodes key bindings by file extension (".json" or ".toml").
+func ParseKeymap(name string, data []byte) ([]Binding, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return ParseKeymapJSON(data)
+	case ".toml":
+		return ParseKeymapTOML(data)
+	}
+	return nil, fmt.Errorf("editor: unsupported keymap file type %q", name)
+}
+
+// LoadKeymapFile reads and decodes a keymap file.
+func LoadKeymapFile(name string) ([]Binding, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeymap(name, data)
+}
+
+// LoadKeymapFS reads and decodes a keymap file from fsys, e.g. an embed.FS.
+func LoadKeymapFS(fsys fs.FS, name string) ([]Binding, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeymap(path.Base(name), data)
+}
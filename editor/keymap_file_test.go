@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+const sampleKeymapJSON = `{
+  "ctrl+b": "cursor.left",
+  "ctrl+n": {"command": "completion.next", "when": "completionVisible"},
+  "tab": [{"command": "snippet.next", "when": "snippetActive"}, "edit.tab"],
+  "ctrl+k ctrl+c": "edit.toggleComment"
+}`
+
+const sampleKeymapTOML = `
+"ctrl+b" = "cursor.left"
+"ctrl+n" = { command = "completion.next", when = "completionVisible" }
+tab = [{ command = "snippet.next", when = "snippetActive" }, "edit.tab"]
+"ctrl+k ctrl+c" = "edit.toggleComment" # chord
+`
+
+func TestParseKeymap_JSONAndTOMLAgree(t *testing.T) {
+	want := []Binding{
+		{Key: "ctrl+b", Command: "cursor.left"},
+		{Key: "ctrl+k ctrl+c", Command: "edit.toggleComment"},
+		{Key: "ctrl+n", Command: "completion.next", When: "completionVisible"},
+		{Key: "tab", Command: "snippet.next", When: "snippetActive"},
+		{Key: "tab", Command: "edit.tab"},
+	}
+
+	j, err := ParseKeymapJSON([]byte(sampleKeymapJSON))
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !reflect.DeepEqual(j, want) {
+		t.Fatalf("json=%+v\nwant %+v", j, want)
+	}
+
+	fsys := fstest.MapFS{"keys/default.toml": {Data: []byte(sampleKeymapTOML)}}
+	tm, err := LoadKeymapFS(fsys, "keys/default.toml")
+	if err != nil {
+		t.Fatalf("toml: %v", err)
+	}
+	if !reflect.DeepEqual(tm, want) {
+		t.Fatalf("toml=%+v\nwant %+v", tm, want)
+	}
+}
+
+func TestParseKeymap_Errors(t *testing.T) {
+	for _, bad := range []string{
+		`{"ctrl+b": 1}`,
+		`{"ctrl+b": {"command": "cursor.left", "typo": true}}`,
+		`{"ctrl+b": {"when": "readOnly"}}`,
+		`{"ctrl+b": {"command": "cursor.left", "when": "readOnly && nope"}}`,
+		`{"": "cursor.left"}`,
+		`[]`,
+	} {
+		if _, err := ParseKeymapJSON([]byte(bad)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+	if _, err := ParseKeymapTOML([]byte("[bindings]\nx = 1")); err == nil {
+		t.Errorf("toml table: expected error")
+	}
+	if _, err := ParseKeymap("keys.yaml", nil); err == nil {
+		t.Errorf("unsupported extension: expected error")
+	}
+}
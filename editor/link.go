@@ -187,7 +187,7 @@ func (m *Model) linksForLine(row int, rawLine string, vi visibleLineInfo, cursor
 		}
 	}
 
-	spans, err := m.cfg.LinkProvider(LinkContext{
+	ctx := LinkContext{
 		Row:                  row,
 		RawText:              rawLine,
 		Text:                 vi.visible,
@@ -196,9 +196,15 @@ func (m *Model) linksForLine(row int, rawLine string, vi visibleLineInfo, cursor
 		HasCursor:            hasCursor,
 		DocID:                m.cfg.DocID,
 		DocVersion:           m.buf.Version(),
-	})
-	if err != nil {
-		return nil
+	}
+	var spans []LinkSpan
+	if m.asyncEnabled(asyncLinks) {
+		spans = m.asyncLinkSpans(ctx)
+	} else {
+		var err error
+		if spans, err = m.cfg.LinkProvider(ctx); err != nil {
+			return nil
+		}
 	}
 
 	return normalizeLinkSpans(spans, vi.rawLen, vi.visLen, vi.rawToVisible, m.cfg.Style.Link)
@@ -212,11 +218,12 @@ func (m *Model) linkAtDocPos(pos buffer.Pos) (LinkHit, bool) {
 
 	lines := m.ensureLines()
 	layout := m.ensureLayoutCache(lines)
-	if pos.Row < 0 || pos.Row >= len(layout.lines) {
+	idx := pos.Row - layout.base
+	if idx < 0 || idx >= len(layout.lines) {
 		return LinkHit{}, false
 	}
 
-	line := &m.layout.lines[pos.Row]
+	line := &m.layout.lines[idx]
 	if !line.linksResolved {
 		if !line.visibleInfoComputed {
 			line.visibleInfo = computeVisibleLineInfo(line.rawLine, line.vt)
@@ -0,0 +1,312 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// Macro is a recorded sequence of intent batches. Macros record what keys
+// did rather than the keys themselves, so they replay the same way under any
+// key map. A Macro marshals to and from JSON for hosts that persist macros
+// between sessions.
+type Macro struct {
+	Batches []IntentBatch
+}
+
+// macroRecording is a macro being recorded.
+type macroRecording struct {
+	slot    string
+	batches []IntentBatch
+}
+
+// StartMacro starts recording a macro into slot, discarding a recording in
+// progress. Every intent batch key handling, Dispatch or RunCommand applies
+// is recorded until StopMacro; batches the host rejects are not. In
+// EmitIntentsOnly mode every emitted batch is recorded.
+func (m Model) StartMacro(slot string) Model {
+	m.recording = &macroRecording{slot: slot}
+	return m
+}
+
+// StopMacro stops recording and stores the macro in its slot, replacing the
+// previous one. It does nothing when no macro is being recorded.
+func (m Model) StopMacro() Model {
+	if m.recording == nil {
+		return m
+	}
+	m = m.SetMacro(m.recording.slot, Macro{Batches: m.recording.batches})
+	m.recording = nil
+	return m
+}
+
+// RecordingMacro returns the slot being recorded into.
+func (m Model) RecordingMacro() (slot string, ok bool) {
+	if m.recording == nil {
+		return "", false
+	}
+	return m.recording.slot, true
+}
+
+// Macro returns the macro stored in slot.
+func (m Model) Macro(slot string) (Macro, bool) {
+	mc, ok := m.macros[slot]
+	return mc, ok
+}
+
+// SetMacro stores mc in slot, for example a macro loaded from disk.
+func (m Model) SetMacro(slot string, mc Macro) Model {
+	macros := maps.Clone(m.macros)
+	if macros == nil {
+		macros = map[string]Macro{}
+	}
+	macros[slot] = mc
+	m.macros = macros
+	return m
+}
+
+// MacroSlots returns the slots holding a macro, sorted.
+func (m Model) MacroSlots() []string {
+	return slices.Sorted(maps.Keys(m.macros))
+}
+
+// PlayMacro replays the macro in slot count times (at least once) as a
+// single undo group. Each batch is reported to Config.OnIntent per
+// Config.MutationMode, like Dispatch. Insertions, deletions and cursor
+// placements are shifted by the distance between the cursor at recording
+// and at replay, so a macro repeats relative to the cursor; other edits
+// shift by rows only, keeping their columns, and edits a command derived
+// from the surrounding rows (see EditIntentPayload.Command) are re-derived
+// by running it again. ok is false when the slot is empty or is being
+// recorded.
+func (m Model) PlayMacro(slot string, count int) (Model, bool) {
+	mc, ok := m.macros[slot]
+	if !ok || m.buf == nil {
+		return m, false
+	}
+	if m.recording != nil && m.recording.slot == slot {
+		return m, false
+	}
+	if count < 1 {
+		count = 1
+	}
+
+	beforeVer, beforeText := m.buf.Version(), m.buf.TextVersion()
+	m.buf.BeginUndoGroup()
+	for range count {
+		for _, recorded := range mc.Batches {
+			(&m).replayBatch(recorded)
+		}
+	}
+	m.buf.EndUndoGroup()
+	(&m).syncAfterChange(beforeVer, beforeText)
+	return m, true
+}
+
+// replayBatch rebases a recorded batch onto the current cursor and runs it.
+func (m *Model) replayBatch(recorded IntentBatch) {
+	before := editorStateFromBuffer(m.buf)
+	if name, ok := replayCommand(recorded); ok {
+		batch, mutations, ok := m.buildCommand(name, before)
+		if ok && len(batch.Intents) > 0 && m.emitDocumentIntentsAndResolveApply(batch) {
+			for _, op := range mutations {
+				op(m)
+			}
+		}
+		return
+	}
+	batch := IntentBatch{Intents: make([]Intent, 0, len(recorded.Intents))}
+	for _, in := range recorded.Intents {
+		if m.cfg.ReadOnly && intentEditsText(in.Kind) {
+			continue
+		}
+		in.Payload = rebasePayload(in.Payload, in.Before.Cursor, before.Cursor)
+		in.Before = before
+		batch.Intents = append(batch.Intents, in)
+	}
+	if len(batch.Intents) == 0 || !m.emitDocumentIntentsAndResolveApply(batch) {
+		return
+	}
+	for _, in := range batch.Intents {
+		m.applyIntent(in)
+	}
+}
+
+// replayCommand returns the command to re-run for a batch whose edits it
+// derived from the rows around the cursor.
+func replayCommand(batch IntentBatch) (string, bool) {
+	for _, in := range batch.Intents {
+		if p, ok := in.Payload.(EditIntentPayload); ok && p.Command != "" {
+			return p.Command, true
+		}
+	}
+	return "", false
+}
+
+// recordMacro adds a batch to the macro being recorded.
+func (m *Model) recordMacro(batch IntentBatch, applied bool) {
+	if m.recording == nil || len(batch.Intents) == 0 {
+		return
+	}
+	if !applied && normalizeMutationMode(m.cfg.MutationMode) != EmitIntentsOnly {
+		return
+	}
+	// The recording is shared by model copies made before this update, so
+	// copy it rather than appending in place.
+	rec := *m.recording
+	rec.batches = append(slices.Clip(rec.batches), IntentBatch{Intents: slices.Clone(batch.Intents)})
+	m.recording = &rec
+}
+
+// rebasePayload shifts the positions of a payload recorded with the cursor
+// at from to a cursor at to. Rows shift by the row distance. Insertions,
+// deletions and cursor placements are made at the cursor, so their columns
+// on the cursor row also shift by the column distance; other edits are
+// anchored to their rows and keep their columns.
+func rebasePayload(payload any, from, to buffer.Pos) any {
+	if from == to {
+		return payload
+	}
+	row := func(p buffer.Pos) buffer.Pos {
+		return buffer.Pos{Row: max(p.Row+to.Row-from.Row, 0), GraphemeCol: p.GraphemeCol}
+	}
+	pos := func(p buffer.Pos) buffer.Pos {
+		out := row(p)
+		if p.Row == from.Row {
+			out.GraphemeCol = max(p.GraphemeCol+to.GraphemeCol-from.GraphemeCol, 0)
+		}
+		return out
+	}
+	rng := func(r buffer.Range) buffer.Range { return buffer.Range{Start: pos(r.Start), End: pos(r.End)} }
+	edits := func(in []buffer.TextEdit, shift func(buffer.Pos) buffer.Pos) []buffer.TextEdit {
+		out := cloneTextEdits(in)
+		for i := range out {
+			out[i].Range = buffer.Range{Start: shift(out[i].Range.Start), End: shift(out[i].Range.End)}
+		}
+		return out
+	}
+
+	switch p := payload.(type) {
+	case InsertIntentPayload:
+		p.Edits = edits(p.Edits, pos)
+		return p
+	case DeleteIntentPayload:
+		p.Edits = edits(p.Edits, pos)
+		return p
+	case EditIntentPayload:
+		p.Edits = edits(p.Edits, row)
+		return p
+	case SetCursorIntentPayload:
+		p.Cursor = pos(p.Cursor)
+		p.Selection = rng(p.Selection)
+		return p
+	}
+	return payload
+}
+
+// intentKindNames names intent kinds in serialized macros.
+var intentKindNames = map[IntentKind]string{
+	IntentInsert:      "insert",
+	IntentDelete:      "delete",
+	IntentMove:        "move",
+	IntentSelect:      "select",
+	IntentUndo:        "undo",
+	IntentRedo:        "redo",
+	IntentEdit:        "edit",
+	IntentSnippetJump: "snippetJump",
+	IntentSetCursor:   "setCursor",
+}
+
+type macroJSON struct {
+	Batches [][]intentJSON `json:"batches"`
+}
+
+type intentJSON struct {
+	Kind    string          `json:"kind"`
+	Before  EditorState     `json:"before"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// MarshalJSON encodes the macro with intent kinds by name.
+func (mc Macro) MarshalJSON() ([]byte, error) {
+	out := macroJSON{Batches: make([][]intentJSON, 0, len(mc.Batches))}
+	for _, batch := range mc.Batches {
+		intents := make([]intentJSON, 0, len(batch.Intents))
+		for _, in := range batch.Intents {
+			name, ok := intentKindNames[in.Kind]
+			if !ok {
+				return nil, fmt.Errorf("editor: macro: unknown intent kind %d", in.Kind)
+			}
+			payload, err := json.Marshal(in.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("editor: macro: %w", err)
+			}
+			intents = append(intents, intentJSON{Kind: name, Before: in.Before, Payload: payload})
+		}
+		out.Batches = append(out.Batches, intents)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a macro encoded by MarshalJSON.
+func (mc *Macro) UnmarshalJSON(data []byte) error {
+	var in macroJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("editor: macro: %w", err)
+	}
+	out := Macro{Batches: make([]IntentBatch, 0, len(in.Batches))}
+	for _, intents := range in.Batches {
+		batch := IntentBatch{Intents: make([]Intent, 0, len(intents))}
+		for _, ij := range intents {
+			intent, err := decodeMacroIntent(ij)
+			if err != nil {
+				return err
+			}
+			batch.Intents = append(batch.Intents, intent)
+		}
+		out.Batches = append(out.Batches, batch)
+	}
+	*mc = out
+	return nil
+}
+
+func decodeMacroIntent(ij intentJSON) (Intent, error) {
+	for kind, name := range intentKindNames {
+		if name != ij.Kind {
+			continue
+		}
+		payload, err := macroPayloadDecoders[kind](ij.Payload)
+		if err != nil {
+			return Intent{}, fmt.Errorf("editor: macro: %s payload: %w", name, err)
+		}
+		return Intent{Kind: kind, Before: ij.Before, Payload: payload}, nil
+	}
+	return Intent{}, fmt.Errorf("editor: macro: unknown intent kind %q", ij.Kind)
+}
+
+// macroPayloadDecoders decode payloads by intent kind. Payloads are stored by
+// value, as key handling emits them.
+var macroPayloadDecoders = map[IntentKind]func(json.RawMessage) (any, error){
+	IntentInsert:      decodePayload[InsertIntentPayload],
+	IntentDelete:      decodePayload[DeleteIntentPayload],
+	IntentMove:        decodePayload[MoveIntentPayload],
+	IntentSelect:      decodePayload[SelectIntentPayload],
+	IntentUndo:        decodePayload[UndoIntentPayload],
+	IntentRedo:        decodePayload[RedoIntentPayload],
+	IntentEdit:        decodePayload[EditIntentPayload],
+	IntentSnippetJump: decodePayload[SnippetJumpIntentPayload],
+	IntentSetCursor:   decodePayload[SetCursorIntentPayload],
+}
+
+func decodePayload[T any](raw json.RawMessage) (any, error) {
+	var p T
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,151 @@
+package editor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func typeKeys(m Model, msgs ...tea.KeyPressMsg) Model {
+	for _, msg := range msgs {
+		m, _ = m.Update(msg)
+	}
+	return m
+}
+
+func TestMacro_RecordAndPlayAsOneUndoGroup(t *testing.T) {
+	m := New(Config{Text: "a\nb\nc\nd"})
+
+	m = m.StartMacro("q")
+	if slot, ok := m.RecordingMacro(); !ok || slot != "q" {
+		t.Fatalf("recording=%q %v", slot, ok)
+	}
+	m = typeKeys(m, testKeyCode(tea.KeyEnd), testKeyText("!"), testKeyCode(tea.KeyDown))
+	m = m.StopMacro()
+	if _, ok := m.RecordingMacro(); ok {
+		t.Fatalf("recording should stop")
+	}
+	if got := m.MacroSlots(); !reflect.DeepEqual(got, []string{"q"}) {
+		t.Fatalf("slots=%v", got)
+	}
+
+	m, ok := m.PlayMacro("q", 2)
+	if !ok {
+		t.Fatalf("play failed")
+	}
+	if got := m.Buffer().Text(); got != "a!\nb!\nc!\nd" {
+		t.Fatalf("text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyCode('z', tea.ModCtrl))
+	if got := m.Buffer().Text(); got != "a!\nb\nc\nd" {
+		t.Fatalf("one undo should revert the whole playback, text=%q", got)
+	}
+
+	if _, ok := m.PlayMacro("missing", 1); ok {
+		t.Fatalf("empty slot should not play")
+	}
+}
+
+func TestMacro_ReplaysRelativeToCursor(t *testing.T) {
+	m := New(Config{Text: "x\n  y", AutoPairs: []AutoPair{{Open: "(", Close: ")"}}})
+
+	m = m.StartMacro("")
+	m = typeKeys(m, testKeyCode(tea.KeyEnd), testKeyText("("))
+	m = m.StopMacro()
+	if got := m.Buffer().Text(); got != "x()\n  y" {
+		t.Fatalf("recorded text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	m, _ = m.PlayMacro("", 1)
+	if got := m.Buffer().Text(); got != "x()\n  y()" {
+		t.Fatalf("pair edit should replay at the cursor, text=%q", got)
+	}
+	if got := m.Buffer().Cursor(); got != (buffer.Pos{Row: 1, GraphemeCol: 4}) {
+		t.Fatalf("cursor=%v", got)
+	}
+}
+
+func TestMacro_RerunsLineCommandsFromAnotherColumn(t *testing.T) {
+	m := New(Config{Text: "\nb\nc", Comment: CommentTokens{Line: "//"}})
+
+	m = m.StartMacro("")
+	m = typeKeys(m, testKeyText("f"), testKeyText("("), toggleCommentKey())
+	m = m.StopMacro()
+	if got := m.Buffer().Text(); got != "// f(\nb\nc" {
+		t.Fatalf("recorded text=%q", got)
+	}
+
+	m.Buffer().SetCursor(buffer.Pos{Row: 1, GraphemeCol: 1})
+	m, _ = m.PlayMacro("", 1)
+	if got := m.Buffer().Text(); got != "// f(\n// bf(\nc" {
+		t.Fatalf("comment should apply to the replay row, text=%q", got)
+	}
+
+	mc, _ := m.Macro("")
+	data, err := json.Marshal(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Macro
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	m = m.SetMacro("", loaded)
+	m.Buffer().SetCursor(buffer.Pos{Row: 2, GraphemeCol: 1})
+	m, _ = m.PlayMacro("", 1)
+	if got := m.Buffer().Text(); got != "// f(\n// bf(\n// cf(" {
+		t.Fatalf("loaded macro, text=%q", got)
+	}
+}
+
+func TestMacro_EditsKeepTheirColumns(t *testing.T) {
+	m := New(Config{Text: "ab\ncd"})
+	m = m.StartMacro("")
+	m = m.Dispatch(Intent{Kind: IntentEdit, Payload: EditIntentPayload{Edits: []buffer.TextEdit{{Text: "\t"}}}})
+	m = m.StopMacro()
+
+	m.Buffer().SetCursor(buffer.Pos{Row: 1, GraphemeCol: 2})
+	m, _ = m.PlayMacro("", 1)
+	if got := m.Buffer().Text(); got != "\tab\n\tcd" {
+		t.Fatalf("line-start edit should stay at the line start, text=%q", got)
+	}
+}
+
+func TestMacro_SerializesAcrossKeyMaps(t *testing.T) {
+	rec := New(Config{
+		Text:     "one two",
+		Bindings: []Binding{{Key: "ctrl+f", Command: "cursor.wordRight"}},
+	})
+	rec = rec.StartMacro("m")
+	rec = typeKeys(rec, testKeyCode('f', tea.ModCtrl), testKeyCode(tea.KeyLeft, tea.ModShift), testKeyCode(tea.KeyBackspace))
+	rec = rec.StopMacro()
+	mc, _ := rec.Macro("m")
+
+	data, err := json.Marshal(mc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var loaded Macro
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, mc) {
+		t.Fatalf("round trip:\n got %+v\nwant %+v", loaded, mc)
+	}
+
+	play := New(Config{Text: "one two"}).SetMacro("m", loaded)
+	play, _ = play.PlayMacro("m", 1)
+	if got := play.Buffer().Text(); got != "on two" {
+		t.Fatalf("text=%q", got)
+	}
+
+	if err := json.Unmarshal([]byte(`{"batches":[[{"kind":"teleport"}]]}`), &loaded); err == nil {
+		t.Fatalf("unknown kind: expected error")
+	}
+}
@@ -18,8 +18,9 @@ type Model struct {
 	focused bool
 
 	completionState       CompletionState
-	completionFilterClean bool     // set by recomputeCompletionQueryFromAnchor to skip redundant filter in syncFromBuffer
-	completionLowerCache  []string // cached lowercased flattened text per completion item
+	completionFilterClean bool        // set by recomputeCompletionQueryFromAnchor to skip redundant filter in syncFromBuffer
+	completionTextCache   []fuzzyText // cached split row text per completion item
+	completionDocScroll   int         // documentation panel offset for the selected item
 
 	viewport viewport.Model
 	// xOffset is the horizontal scroll offset in terminal cells. It is used only
@@ -33,9 +34,59 @@ type Model struct {
 	lastSelectionOK bool
 
 	ghostCache ghostCache
+	// ghostRest is the rest of a partially accepted ghost at the cursor.
+	ghostRest ghostCache
+
+	// autoClosed tracks closers inserted by auto-pairing on the cursor row,
+	// innermost last.
+	autoClosed []autoClosedCloser
+
+	// snippet is the snippet session started by accepting a snippet.
+	snippet snippetSession
+
+	// chord is the partially typed chord, if any.
+	chord ChordTracker
+	// chordTyped is the key, or completed chord, being handled by the
+	// current key press; bindings are matched against it.
+	chordTyped string
+	// bindings are Config.Bindings ready for key matching.
+	bindings []boundCommand
+
+	// macros are the stored macros by slot; recording is the macro being
+	// recorded, if any. Both are replaced rather than mutated, so model copies
+	// keep their own.
+	macros    map[string]Macro
+	recording *macroRecording
+
+	// floats are the shown floating windows, in insertion order.
+	floats []FloatingWindow
+	// hoverPos is the grapheme last queried for hover; valid when hoverPosOK.
+	hoverPos   buffer.Pos
+	hoverPosOK bool
+
+	// bracketMatch is the highlighted bracket pair for the cursor;
+	// lastBracketMatch is the previous one, kept to re-render its rows.
+	bracketMatch     bracketMatch
+	lastBracketMatch bracketMatch
+
+	// highlightStates caches per-row end states for a StatefulHighlighter.
+	highlightStates highlightStateCache
+
+	// async holds results and queued requests for Config.Async providers;
+	// nil when no provider is async.
+	async *asyncState
+	// completionReq tracks Config.CompletionProvider requests; nil without a
+	// provider.
+	completionReq *completionRequests
 
 	mouseDragging bool
 	mouseAnchor   buffer.Pos
+	// click tracks recent clicks for double and triple click selection.
+	click clickState
+	// dragScroll auto-scrolls while a drag selection is past the edges.
+	dragScroll dragScrollState
+	// drop tracks dragging the selection to move or copy it.
+	drop dropState
 
 	scrollbarDragAxis        scrollbarDragAxis
 	scrollbarDragStartCell   int
@@ -54,6 +105,13 @@ type Model struct {
 
 	cachedLines    []string
 	cachedLinesVer uint64
+	// cachedLinesTop/cachedLinesLen key the cached row window for lazy buffers.
+	cachedLinesTop int
+	cachedLinesLen int
+
+	// windowTop is the top visual row when rendering a lazy buffer window. The
+	// viewport then holds only window rows and its YOffset stays 0.
+	windowTop int
 }
 
 type scrollbarDragAxis int
@@ -71,10 +129,13 @@ func New(cfg Config) Model {
 	if cfg.KeyMap.isZero() {
 		cfg.KeyMap = DefaultKeyMap()
 	}
+	cfg.AutoPairs = normalizeAutoPairs(cfg.AutoPairs)
 	cfg.CompletionKeyMap = normalizeCompletionKeyMap(cfg.CompletionKeyMap)
 	cfg.CompletionInputMode = normalizeCompletionInputMode(cfg.CompletionInputMode)
 	cfg.CompletionMaxVisibleRows = normalizeCompletionMaxVisibleRows(cfg.CompletionMaxVisibleRows)
 	cfg.CompletionMaxWidth = normalizeCompletionMaxWidth(cfg.CompletionMaxWidth)
+	cfg.CompletionTriggerCharacters = normalizeCompletionTriggerCharacters(cfg.CompletionTriggerCharacters)
+	cfg.SignatureHelpTriggerCharacters = normalizeSignatureHelpTriggerCharacters(cfg)
 	cfg.RowMarkSymbols = normalizeRowMarkSymbols(cfg.RowMarkSymbols)
 	if cfg.RowMarkProvider != nil && cfg.RowMarkWidth <= 0 {
 		cfg.RowMarkWidth = 2
@@ -86,16 +147,33 @@ func New(cfg Config) Model {
 		cfg.TabWidth = 4
 	}
 
+	buf := cfg.Buffer
+	if buf == nil {
+		buf = buffer.New(cfg.Text, buffer.Options{HistoryLimit: cfg.HistoryLimit})
+	}
+	if buf.IsLazy() {
+		cfg.ReadOnly = true
+		cfg.WrapMode = WrapNone
+	}
+
 	m := Model{
 		cfg:      cfg,
-		buf:      buffer.New(cfg.Text, buffer.Options{HistoryLimit: cfg.HistoryLimit}),
+		buf:      buf,
 		focused:  true,
 		viewport: viewport.New(viewport.WithWidth(0), viewport.WithHeight(0)),
+		bindings: compileBindings(cfg.Bindings),
+	}
+	if cfg.Async.any() {
+		m.async = &asyncState{}
+	}
+	if cfg.CompletionProvider != nil {
+		m.completionReq = &completionRequests{}
 	}
 	m.lastBufVersion = m.buf.Version()
 	m.lastTextVersion = m.buf.TextVersion()
 	m.lastCursor = m.buf.Cursor()
 	m.lastSelection, m.lastSelectionOK = m.buf.Selection()
+	m.bracketMatch = m.computeBracketMatch()
 	m.rebuildContent()
 	return m
 }
@@ -106,12 +184,11 @@ func (m *Model) ghostForCursor() (Ghost, bool) {
 	}
 
 	cur := m.buf.Cursor()
-	lines := m.ensureLines()
-	if cur.Row < 0 || cur.Row >= len(lines) {
+	lineText, ok := m.docLine(cur.Row)
+	if !ok {
 		return Ghost{}, false
 	}
 
-	lineText := lines[cur.Row]
 	rawLen := graphemeutil.Count(lineText)
 	col := clampInt(cur.GraphemeCol, 0, rawLen)
 	return m.ghostFor(cur.Row, col, lineText, rawLen)
@@ -126,7 +203,9 @@ func (m Model) docVersion() uint64 {
 
 func (m Model) Buffer() *buffer.Buffer { return m.buf }
 
-func (m Model) Init() tea.Cmd { return nil }
+// Init returns requests for async providers (see Config.Async) queued while
+// building the initial view.
+func (m Model) Init() tea.Cmd { return m.AsyncCmd() }
 
 func (m Model) SetSize(width, height int) Model {
 	if width < 0 {
@@ -160,6 +239,8 @@ func (m Model) Blur() Model {
 		m.focused = false
 		m.rebuildContent()
 	}
+	m.chord.Reset()
+	m.drop = dropState{}
 	return m
 }
 
@@ -198,47 +279,84 @@ func (m Model) InvalidateStyles() Model {
 }
 
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	mark := m.markCompletionRequest()
+	m, cmd := m.update(msg)
+	m.syncCompletionRequest(mark)
+	if m.async != nil || m.completionReq != nil {
+		cmd = tea.Batch(cmd, m.AsyncCmd(), m.completionCmd())
+	}
+	return m, cmd
+}
+
+func (m Model) update(msg tea.Msg) (Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case AsyncResultMsg:
+		if m.async == nil || msg.owner != m.async {
+			return m, nil
+		}
+		m.syncFromBuffer()
+		m.applyAsyncResult(msg)
+		return m, nil
+	case CompletionResultMsg:
+		if m.completionReq == nil || msg.owner != m.completionReq {
+			return m, nil
+		}
+		m.syncFromBuffer()
+		m.applyCompletionResult(msg)
+		return m, nil
+	case completionDebounceMsg:
+		if r := m.completionReq; r != nil && msg.owner == r && msg.seq == r.seq {
+			r.cmd = m.completionProviderCmd()
+		}
+		return m, nil
+	case chordTimeoutMsg:
+		m.chord.Expire(msg)
+		return m, nil
 	case tea.WindowSizeMsg:
 		return m.SetSize(msg.Width, msg.Height), nil
-	case tea.MouseMsg:
+	case tea.MouseMsg, dragScrollMsg:
 		beforeVer := uint64(0)
 		if m.buf != nil {
 			beforeVer = m.buf.Version()
 		}
-		beforeYOffset := m.viewport.YOffset()
+		beforeYOffset := m.topVisualRow()
 
 		var cmd tea.Cmd
-		m, cmd = m.updateMouse(msg)
+		if tick, ok := msg.(dragScrollMsg); ok {
+			cmd = (&m).stepDragScroll(tick)
+		} else {
+			m, cmd = m.updateMouse(msg.(tea.MouseMsg))
+		}
 		// Rebuild content in case the host mutated the buffer outside of the editor.
 		cursorChanged, versionChanged := m.syncFromBuffer()
 		if cursorChanged || versionChanged {
 			m.followCursorWithForce(false)
 		}
-		if m.cfg.Highlighter != nil && m.viewport.YOffset() != beforeYOffset {
+		if m.rendersVisibleRowsOnly() && m.topVisualRow() != beforeYOffset {
 			m.rebuildContent()
 		}
-		if m.cfg.OnChange != nil && m.buf != nil && m.buf.Version() != beforeVer {
-			if ch, ok := m.buf.LastChange(); ok {
-				m.cfg.OnChange(ch)
-			}
+		if m.buf != nil && m.buf.Version() != beforeVer {
+			m.notifyChange()
 		}
 		// Don't force-follow cursor here; mouse scroll behavior is controlled by
 		// ScrollPolicy.
 		return m, cmd
 	case tea.KeyPressMsg:
-		beforeVer := uint64(0)
+		beforeVer, beforeText := uint64(0), uint64(0)
 		if m.buf != nil {
-			beforeVer = m.buf.Version()
+			beforeVer, beforeText = m.buf.Version(), m.buf.TextVersion()
 		}
 
 		m, cmd := m.updateKey(msg)
 		cursorChanged, versionChanged := m.syncFromBuffer()
-		if m.cfg.OnChange != nil && m.buf != nil && m.buf.Version() != beforeVer {
-			if ch, ok := m.buf.LastChange(); ok {
-				m.cfg.OnChange(ch)
-			}
+		if m.buf != nil && m.buf.Version() != beforeVer {
+			m.notifyChange()
 		}
+		if m.buf != nil && m.buf.TextVersion() != beforeText && (&m).applySnippetMirrors() {
+			m.syncFromBuffer()
+			cursorChanged, versionChanged = true, true
+		}
+		(&m).updateFloatsAfterKey(msg, beforeVer, beforeText)
 		if cursorChanged || versionChanged {
 			m.followCursorWithForce(false)
 		}
@@ -255,12 +373,22 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 func (m Model) View() tea.View {
 	base := m.viewport.View()
 	base = m.renderScrollbarChrome(base)
-	if popup, ok := m.completionPopupRender(base); ok {
-		return tea.NewView(popup.View)
+	if layers := m.overlayLayers(); len(layers) > 0 {
+		base = m.renderOverlays(base, layers)
 	}
 	return tea.NewView(base)
 }
 
+// notifyChange reports the buffer's last change to Config.OnChange.
+func (m *Model) notifyChange() {
+	if m.cfg.OnChange == nil || m.buf == nil {
+		return
+	}
+	if ch, ok := m.buf.LastChange(); ok {
+		m.cfg.OnChange(ch)
+	}
+}
+
 func (m *Model) syncFromBuffer() (cursorChanged bool, versionChanged bool) {
 	if m.buf == nil {
 		return false, false
@@ -270,6 +398,7 @@ func (m *Model) syncFromBuffer() (cursorChanged bool, versionChanged bool) {
 	prevSelection := m.lastSelection
 	prevSelectionOK := m.lastSelectionOK
 	prevTextVersion := m.lastTextVersion
+	prevVersion := m.lastBufVersion
 
 	ver := m.buf.Version()
 	textVer := m.buf.TextVersion()
@@ -283,6 +412,8 @@ func (m *Model) syncFromBuffer() (cursorChanged bool, versionChanged bool) {
 		return false, false
 	}
 
+	m.syncSnippetSession()
+
 	cursorChanged = cur != prevCursor
 	versionChanged = ver != m.lastBufVersion
 	selectionChanged := selOK != prevSelectionOK || (selOK && sel != prevSelection)
@@ -293,6 +424,8 @@ func (m *Model) syncFromBuffer() (cursorChanged bool, versionChanged bool) {
 	m.lastCursor = cur
 	m.lastSelection = sel
 	m.lastSelectionOK = selOK
+	m.lastBracketMatch = m.bracketMatch
+	m.bracketMatch = m.computeBracketMatch()
 
 	if m.completionState.Visible && (cursorChanged || versionChanged) {
 		if m.cursorOutsideCompletionAnchorToken() {
@@ -304,6 +437,8 @@ func (m *Model) syncFromBuffer() (cursorChanged bool, versionChanged bool) {
 	m.completionFilterClean = false
 
 	if textChanged {
+		m.syncHighlightStates(prevVersion)
+		m.syncAsyncRows(prevVersion)
 		if !m.tryIncrementalTextRebuild(prevCursor, cur, prevSelection, prevSelectionOK, sel, selOK) {
 			m.rebuildContent()
 		}
@@ -339,7 +474,7 @@ func (m *Model) rebuildCursorSelectionDirtyRows(
 	nextSel buffer.Range,
 	nextSelOK bool,
 ) bool {
-	if m.buf == nil {
+	if m.buf == nil || m.windowed() {
 		return false
 	}
 
@@ -358,6 +493,7 @@ func (m *Model) rebuildCursorSelectionDirtyRows(
 		nextSel,
 		nextSelOK,
 	)
+	m.addBracketMatchDirtyRows(dirty, len(lines))
 	if len(dirty) == 0 {
 		return true
 	}
@@ -433,12 +569,12 @@ func (m *Model) cursorOutsideCompletionAnchorToken() bool {
 		return true
 	}
 
-	lines := m.ensureLines()
-	if state.Anchor.Row < 0 || state.Anchor.Row >= len(lines) {
+	lineText, ok := m.docLine(state.Anchor.Row)
+	if !ok {
 		return true
 	}
 
-	rowClusters := graphemeutil.Split(lines[state.Anchor.Row])
+	rowClusters := graphemeutil.Split(lineText)
 	startCol, endCol := completionAnchorTokenBounds(rowClusters, state.Anchor.GraphemeCol)
 	return cursor.GraphemeCol < startCol || cursor.GraphemeCol > endCol
 }
@@ -466,7 +602,7 @@ func (m *Model) tryIncrementalTextRebuild(
 	nextSel buffer.Range,
 	nextSelOK bool,
 ) bool {
-	if m.buf == nil || !m.layout.valid {
+	if m.buf == nil || !m.layout.valid || m.windowed() {
 		return false
 	}
 
@@ -500,6 +636,10 @@ func (m *Model) tryIncrementalTextRebuild(
 	addDirtyRow(dirty, len(lines), nextCursor.Row)
 	addDirtyRangeRows(dirty, len(lines), prevSel, prevSelOK)
 	addDirtyRangeRows(dirty, len(lines), nextSel, nextSelOK)
+	m.addBracketMatchDirtyRows(dirty, len(lines))
+	// Re-lex visible rows until stateful highlight states converge; rows whose
+	// start state changed must be re-rendered too.
+	m.advanceHighlightStates(m.lastVisibleLogicalRow(), dirty)
 
 	if len(dirty) == 0 {
 		m.layout.key.textVersion = m.buf.TextVersion()
@@ -574,7 +714,7 @@ func (m *Model) followCursorWithForce(force bool) {
 	layout := m.ensureLayoutCache(lines)
 	metrics := m.resolveScrollbarMetrics(lines, layout)
 
-	beforeYOffset := m.viewport.YOffset()
+	beforeYOffset := m.topVisualRow()
 	newYOffset := metrics.yOffset
 	if metrics.contentHeight > 0 {
 		cursorVisualRow := cur.Row
@@ -593,8 +733,12 @@ func (m *Model) followCursorWithForce(force bool) {
 		newYOffset = clampInt(newYOffset, 0, maxYOffset)
 	}
 	if newYOffset != beforeYOffset {
-		m.viewport.SetYOffset(newYOffset)
-		if m.cfg.Highlighter != nil {
+		m.setTopVisualRow(newYOffset)
+		if m.windowed() {
+			// The window moved; re-measure against the newly loaded rows.
+			lines = m.ensureLines()
+			metrics = m.resolveScrollbarMetrics(lines, m.ensureLayoutCache(lines))
+		} else if m.rendersVisibleRowsOnly() {
 			m.rebuildContent()
 		}
 	}
@@ -607,7 +751,8 @@ func (m *Model) followCursorWithForce(force bool) {
 		return
 	}
 
-	if len(lines) == 0 || cur.Row < 0 || cur.Row >= len(lines) {
+	rawLine, ok := m.docLine(cur.Row)
+	if !ok {
 		if m.xOffset != 0 {
 			m.xOffset = 0
 			m.rebuildContent()
@@ -624,7 +769,6 @@ func (m *Model) followCursorWithForce(force bool) {
 		return
 	}
 
-	rawLine := lines[cur.Row]
 	vt := m.virtualTextForRow(cur.Row, rawLine)
 	vt = m.virtualTextWithGhost(cur.Row, rawLine, vt)
 	vl := BuildVisualLine(rawLine, vt, m.cfg.TabWidth)
@@ -691,7 +835,12 @@ func (m *Model) virtualTextForRow(row int, rawLine string) VirtualText {
 		DocID:      m.cfg.DocID,
 		DocVersion: m.buf.Version(),
 	}
-	vt := m.cfg.VirtualTextProvider(ctx)
+	var vt VirtualText
+	if m.asyncEnabled(asyncVirtualText) {
+		vt = m.asyncVirtualText(ctx)
+	} else {
+		vt = m.cfg.VirtualTextProvider(ctx)
+	}
 	return normalizeVirtualText(vt, rawLen)
 }
 
@@ -736,6 +885,9 @@ func (m *Model) ensureLines() []string {
 	if m.buf == nil {
 		return nil
 	}
+	if m.windowed() {
+		return m.ensureWindowLines()
+	}
 	ver := m.buf.TextVersion()
 	if m.cachedLines != nil && m.cachedLinesVer == ver {
 		return m.cachedLines
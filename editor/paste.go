@@ -0,0 +1,22 @@
+package editor
+
+import "strings"
+
+// Paste inserts text at the cursor, replacing the selection, as one undo
+// step. Hosts call it for tea.PasteMsg; the clipboard package pastes
+// registers through it too.
+//
+// Line endings are normalized to "\n". The insert is reported as an
+// IntentInsert per Config.MutationMode, like Dispatch, and is ignored when
+// the editor is read-only.
+func (m Model) Paste(text string) Model {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if m.buf == nil || text == "" {
+		return m
+	}
+	m.buf.BeginUndoGroup()
+	m = m.Dispatch(Intent{Kind: IntentInsert, Payload: InsertIntentPayload{Text: text}})
+	m.buf.EndUndoGroup()
+	return m
+}
@@ -0,0 +1,32 @@
+package editor
+
+import "testing"
+
+func TestPaste_NormalizesLineEndingsAsOneUndoStep(t *testing.T) {
+	var got []Intent
+	m := New(Config{
+		Text:         "ab",
+		MutationMode: EmitIntentsAndMutate,
+		OnIntent: func(batch IntentBatch) IntentDecision {
+			got = append(got, batch.Intents...)
+			return IntentDecision{ApplyLocally: true}
+		},
+	})
+
+	m = m.Paste("x\r\ny\rz")
+	if text := m.Buffer().Text(); text != "x\ny\nzab" {
+		t.Fatalf("text=%q", text)
+	}
+	if len(got) != 1 || got[0].Kind != IntentInsert || got[0].Payload.(InsertIntentPayload).Text != "x\ny\nz" {
+		t.Fatalf("intents=%+v", got)
+	}
+	m.Buffer().Undo()
+	if text := m.Buffer().Text(); text != "ab" {
+		t.Fatalf("undo text=%q", text)
+	}
+
+	ro := New(Config{Text: "ab", ReadOnly: true}).Paste("x")
+	if text := ro.Buffer().Text(); text != "ab" {
+		t.Fatalf("read-only paste should be ignored, text=%q", text)
+	}
+}
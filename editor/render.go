@@ -72,7 +72,7 @@ func (m Model) renderScrollbarChrome(base string) string {
 			thumbCell := m.cfg.Style.ScrollbarThumb.Render(" ")
 			hRow := renderScrollbarAxisCells(metrics.contentWidth, metrics.hThumbPos, metrics.hThumbLen, trackCell, thumbCell, "")
 			if hRow != "" {
-				gutterWidth := mm.resolvedGutterWidth(len(lines))
+				gutterWidth := mm.resolvedGutterWidth(mm.docLineCount(lines))
 				view = compositeTopLeft(
 					hRow,
 					view,
@@ -127,9 +127,9 @@ func (m *Model) renderRows(
 	dirtyLogicalRows map[int]struct{},
 	useCachedRows bool,
 ) []string {
-	cursor := m.buf.Cursor()
+	cursor := m.caretPos()
 	sel, selOK := m.buf.Selection()
-	lineCount := len(lines)
+	lineCount := m.docLineCount(lines)
 	baseGutterWidth := m.resolvedBaseGutterWidth(lineCount)
 	rowMarkWidth := m.resolvedRowMarkWidth()
 
@@ -155,21 +155,19 @@ func (m *Model) renderRows(
 	m.highlightsByLine = highlightsByLine
 	m.highlightsComputed = highlightsComputed
 
+	visibleStart := clampInt(metrics.yOffset-layout.base, 0, len(layout.rows))
+	visibleEnd := min(visibleStart+max(metrics.contentHeight, 0), len(layout.rows))
 	if m.cfg.Highlighter != nil {
-		h := metrics.contentHeight
-		if h > 0 {
-			start := clampInt(metrics.yOffset, 0, len(layout.rows))
-			end := start + h
-			if end > len(layout.rows) {
-				end = len(layout.rows)
-			}
-			for visualRow := start; visualRow < end; visualRow++ {
-				ref := layout.rows[visualRow]
-				if ref.logicalRow >= 0 && ref.logicalRow < nLines {
-					highlightVisible[ref.logicalRow] = true
-				}
+		for visualRow := visibleStart; visualRow < visibleEnd; visualRow++ {
+			idx := layout.rows[visualRow].logicalRow - layout.base
+			if idx >= 0 && idx < nLines {
+				highlightVisible[idx] = true
 			}
 		}
+		if visibleEnd > visibleStart {
+			// Lex stale rows once up front rather than row by row.
+			m.advanceHighlightStates(layout.rows[visibleEnd-1].logicalRow, nil)
+		}
 	}
 
 	out := make([]string, 0, len(layout.rows))
@@ -189,18 +187,20 @@ func (m *Model) renderRows(
 				continue
 			}
 		}
-		if m.cfg.Highlighter != nil && row >= 0 && row < len(layout.lines) && highlightVisible[row] && !highlightsComputed[row] {
-			line := &layout.lines[row]
+		idx := row - layout.base
+		if m.cfg.Highlighter != nil && idx >= 0 && idx < len(layout.lines) && highlightVisible[idx] && !highlightsComputed[idx] {
+			line := &layout.lines[idx]
 			if !line.visibleInfoComputed {
 				line.visibleInfo = computeVisibleLineInfo(line.rawLine, line.vt)
 				line.visibleInfoComputed = true
 			}
-			highlightsByLine[row] = m.highlightForLine(row, line.rawLine, line.visibleInfo, cursor)
-			highlightsComputed[row] = true
+			highlightsByLine[idx] = m.highlightForLine(row, line.rawLine, line.visibleInfo, cursor)
+			highlightsComputed[idx] = true
 		}
 		highlights := []HighlightSpan(nil)
-		if row >= 0 && row < len(highlightsByLine) {
-			highlights = highlightsByLine[row]
+		if idx >= 0 && idx < len(highlightsByLine) {
+			highlights = highlightsByLine[idx]
+			highlights = m.withBracketMatchSpans(row, &layout.lines[idx], highlights)
 		}
 		rendered, ok := m.renderLayoutRow(
 			layout,
@@ -224,6 +224,9 @@ func (m *Model) renderRows(
 		}
 		out = append(out, rendered)
 	}
+	if visibleStart < visibleEnd {
+		m.requestAsyncRows(layout.rows[visibleStart].logicalRow, layout.rows[visibleEnd-1].logicalRow)
+	}
 	return out
 }
 
@@ -238,10 +241,10 @@ func (m *Model) renderLayoutRow(
 	leftNoWrap, rightNoWrap int,
 ) (string, bool) {
 	row := ref.logicalRow
-	if row < 0 || row >= len(layout.lines) {
+	if row-layout.base < 0 || row-layout.base >= len(layout.lines) {
 		return "", false
 	}
-	line := &layout.lines[row]
+	line := &layout.lines[row-layout.base]
 	needVisibleInfo := m.cfg.RowStyleForRow != nil || m.cfg.TokenStyleForToken != nil || m.cfg.LinkProvider != nil
 	if needVisibleInfo && !line.visibleInfoComputed {
 		line.visibleInfo = computeVisibleLineInfo(line.rawLine, line.vt)
@@ -256,15 +259,25 @@ func (m *Model) renderLayoutRow(
 	}
 	seg := line.segments[ref.segmentIndex]
 
+	// Ghost rows report the segment index they would have as continuations.
+	segmentIndex := ref.segmentIndex + ref.ghostLine
 	var sb strings.Builder
 	if rowMarkWidth > 0 {
-		cell := m.resolveRowMarkCell(row, ref.segmentIndex, line.rawLine, rowMarkWidth, row == cursor.Row)
+		cell := m.resolveRowMarkCell(row, segmentIndex, line.rawLine, rowMarkWidth, row == cursor.Row)
 		sb.WriteString(renderGutterCell(m.cfg.Style.Gutter, nil, cell))
 	}
 	if baseGutterWidth > 0 {
-		cell := m.resolveGutterCell(row, ref.segmentIndex, line.rawLine, lineCount, baseGutterWidth, row == cursor.Row)
+		cell := m.resolveGutterCell(row, segmentIndex, line.rawLine, lineCount, baseGutterWidth, row == cursor.Row)
 		sb.WriteString(renderGutterCell(m.cfg.Style.Gutter, m.cfg.GutterStyleForKey, cell))
 	}
+	if ref.ghostLine > 0 && ref.ghostLine <= len(line.ghostLines) {
+		left, right := leftNoWrap, rightNoWrap
+		if m.cfg.WrapMode != WrapNone {
+			left, right = 0, contentWidth
+		}
+		renderGhostRow(&sb, m.cfg.Style, m.cfg.GhostStyleForKey, line.ghostLines[ref.ghostLine-1], line.ghostStyleKey, m.cfg.TabWidth, left, right)
+		return sb.String(), true
+	}
 
 	left := leftNoWrap
 	right := rightNoWrap
@@ -338,7 +351,7 @@ func (m *Model) renderLayoutRow(
 }
 
 func (m *Model) rebuildGutterRows(rows []int) bool {
-	if m.buf == nil {
+	if m.buf == nil || m.windowed() {
 		return false
 	}
 	lines := m.ensureLines()
@@ -371,6 +384,33 @@ func (m *Model) highlightForLine(row int, rawLine string, vi visibleLineInfo, cu
 		return nil
 	}
 
+	ctx := m.highlightLineContext(row, rawLine, vi, cursor)
+	if m.asyncEnabled(asyncHighlight) {
+		return normalizeHighlightSpans(m.asyncHighlightSpans(ctx), vi.visLen)
+	}
+	var (
+		spans []HighlightSpan
+		err   error
+	)
+	if sh, ok := m.statefulHighlighter(); ok {
+		// Lexing through row records its state and usually its spans.
+		m.advanceHighlightStates(row, nil)
+		prev := m.highlightStateBeforeKnown(row)
+		if ls, ok := m.highlightStates.cachedSpans(ctx, prev); ok {
+			spans, err = ls.spans, ls.err
+		} else {
+			spans, _, err = sh.HighlightLineState(ctx, prev)
+		}
+	} else {
+		spans, err = m.cfg.Highlighter.HighlightLine(ctx)
+	}
+	if err != nil {
+		return nil
+	}
+	return normalizeHighlightSpans(spans, vi.visLen)
+}
+
+func (m *Model) highlightLineContext(row int, rawLine string, vi visibleLineInfo, cursor buffer.Pos) LineContext {
 	hasCursor := cursor.Row == row
 	cursorCol := -1
 	rawCursorCol := -1
@@ -383,18 +423,15 @@ func (m *Model) highlightForLine(row int, rawLine string, vi visibleLineInfo, cu
 		}
 	}
 
-	spans, err := m.cfg.Highlighter.HighlightLine(LineContext{
+	return LineContext{
 		Row:                  row,
 		RawText:              rawLine,
 		Text:                 vi.visible,
 		CursorGraphemeCol:    cursorCol,
 		RawCursorGraphemeCol: rawCursorCol,
 		HasCursor:            hasCursor,
-	})
-	if err != nil {
-		return nil
+		rawToVisible:         vi.rawToVisible,
 	}
-	return normalizeHighlightSpans(spans, vi.visLen)
 }
 
 func renderVisualLine(
@@ -33,7 +33,7 @@ func (m *Model) resolveScrollbarMetrics(lines []string, layout wrapLayoutCache)
 		metrics.innerHeight = 0
 	}
 
-	gutterWidth := m.resolvedGutterWidth(len(lines))
+	gutterWidth := m.resolvedGutterWidth(m.docLineCount(lines))
 	baseContentWidth := metrics.innerWidth - gutterWidth
 	if baseContentWidth < 0 {
 		baseContentWidth = 0
@@ -107,7 +107,7 @@ func (m *Model) resolveScrollbarMetrics(lines []string, layout wrapLayoutCache)
 	if metrics.contentHeight > 0 && metrics.totalRows > metrics.contentHeight {
 		maxYOffset = metrics.totalRows - metrics.contentHeight
 	}
-	metrics.yOffset = clampInt(m.viewport.YOffset(), 0, maxYOffset)
+	metrics.yOffset = clampInt(m.topVisualRow(), 0, maxYOffset)
 
 	metrics.xOffset = 0
 	if m.cfg.WrapMode == WrapNone {
@@ -199,6 +199,9 @@ func (m *Model) measureScrollExtents(lines []string, layout wrapLayoutCache, con
 	if contentWidth < 0 {
 		contentWidth = 0
 	}
+	if m.windowed() {
+		return m.windowScrollExtents(lines)
+	}
 
 	if m.layoutMatchesForExtentMeasurement(layout, len(lines), contentWidth) {
 		totalRows = len(layout.rows)
@@ -224,6 +227,8 @@ func (m *Model) measureScrollExtents(lines []string, layout wrapLayoutCache, con
 				totalCols = w
 			}
 		}
+		ghostLines, _ := m.ghostRowsFor(row, rawLine)
+		totalRows += len(ghostLines)
 		segments := wrapSegmentsForVisualLine(visual, m.cfg.WrapMode, contentWidth)
 		if len(segments) == 0 {
 			totalRows++
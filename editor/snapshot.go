@@ -18,6 +18,9 @@ type RowMap struct {
 	DocStartGrapheme int
 	DocEndGrapheme   int
 	VisibleDocCols   []int
+	// Ghost marks a virtual row of a multi-line ghost suggestion below DocRow.
+	// It maps to no document columns.
+	Ghost bool
 }
 
 type RenderSnapshot struct {
@@ -48,6 +51,7 @@ type snapshotSignature struct {
 	docID                     string
 	gutterInvalidationVersion uint64
 	styleInvalidationVersion  uint64
+	asyncGeneration           uint64
 
 	gutterWidthProvider uintptr
 	gutterCellProvider  uintptr
@@ -95,7 +99,7 @@ func (m *Model) currentSnapshotSignature() snapshotSignature {
 		viewportHeight:            m.viewport.Height(),
 		viewportStyleH:            m.viewport.Style.GetHorizontalFrameSize(),
 		viewportStyleV:            m.viewport.Style.GetVerticalFrameSize(),
-		viewportYOffset:           m.viewport.YOffset(),
+		viewportYOffset:           m.topVisualRow(),
 		xOffset:                   m.xOffset,
 		wrapMode:                  m.cfg.WrapMode,
 		scrollbarVMode:            m.cfg.Scrollbar.Vertical,
@@ -131,6 +135,9 @@ func (m *Model) currentSnapshotSignature() snapshotSignature {
 		linkSet:                   m.cfg.LinkProvider != nil,
 	}
 
+	if m.async != nil {
+		sig.asyncGeneration = m.async.generation
+	}
 	if m.buf != nil {
 		sig.bufVersion = m.buf.Version()
 		sig.cursor = m.buf.Cursor()
@@ -231,10 +238,10 @@ func (m *Model) buildRenderSnapshot(token SnapshotToken) RenderSnapshot {
 		return s
 	}
 
-	start := clampInt(s.Viewport.TopVisualRow, 0, len(layout.rows)-1)
+	start := layout.clampVisualRow(s.Viewport.TopVisualRow)
 	end := start + s.Viewport.VisibleRows
-	if end > len(layout.rows) {
-		end = len(layout.rows)
+	if last := layout.base + len(layout.rows); end > last {
+		end = last
 	}
 	if end < start {
 		end = start
@@ -253,6 +260,14 @@ func (m *Model) buildRenderSnapshot(token SnapshotToken) RenderSnapshot {
 			DocStartGrapheme: seg.StartGraphemeCol,
 			DocEndGrapheme:   seg.EndGraphemeCol,
 		}
+		if ref, _ := layout.rowAt(visualRow); ref.ghostLine > 0 {
+			row.SegmentIndex += ref.ghostLine
+			row.DocStartGrapheme = line.visual.RawGraphemeLen
+			row.DocEndGrapheme = line.visual.RawGraphemeLen
+			row.Ghost = true
+			s.Rows = append(s.Rows, row)
+			continue
+		}
 		if n := seg.endCell - seg.startCell; n > 0 {
 			cols := make([]int, n)
 			for i := range cols {
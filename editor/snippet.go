@@ -0,0 +1,351 @@
+package editor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// Snippet is a parsed LSP/TextMate snippet.
+type Snippet struct {
+	// Text is the snippet with placeholders expanded to their defaults.
+	Text string
+	// Tabstops are ordered by index with the final tabstop ($0) last. When the
+	// source has no $0, one is added at the end of Text.
+	Tabstops []SnippetTabstop
+}
+
+// SnippetTabstop is one tabstop index and every place it appears.
+type SnippetTabstop struct {
+	Index int
+	// Ranges are relative to the start of Snippet.Text: Row 0 is its first
+	// line. More than one range makes mirrors that are kept in sync.
+	Ranges []buffer.Range
+	// Choices lists the alternatives of a ${n|a,b|} choice tabstop.
+	Choices []string
+}
+
+// ParseSnippet parses LSP/TextMate snippet syntax:
+//
+//   - $1 or ${1} tabstops and ${1:default} placeholders, which may nest;
+//   - ${1|one,two|} choices, defaulting to the first one;
+//   - repeated indices as mirrors, all showing the first default given;
+//   - $0 as the final cursor position;
+//   - $NAME and ${NAME:default} variables, which expand to their default;
+//   - backslash escapes for '$', '}' and '\', plus ',' and '|' in choices.
+func ParseSnippet(src string) (Snippet, error) {
+	p := snippetParser{src: src}
+	nodes, err := p.parse(false)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	defaults := make(map[int]string)
+	collectSnippetDefaults(nodes, defaults)
+
+	var r snippetRenderer
+	r.defaults = defaults
+	r.render(nodes)
+
+	indices := make([]int, 0, len(r.marks))
+	for idx := range r.marks {
+		indices = append(indices, idx)
+	}
+	slices.SortFunc(indices, func(a, b int) int {
+		switch {
+		case a == b:
+			return 0
+		case a == 0:
+			return 1
+		case b == 0:
+			return -1
+		}
+		return a - b
+	})
+
+	out := Snippet{Text: r.sb.String()}
+	for _, idx := range indices {
+		stop := SnippetTabstop{Index: idx, Choices: r.choices[idx]}
+		for _, mk := range r.marks[idx] {
+			stop.Ranges = append(stop.Ranges, buffer.Range{
+				Start: snippetPosAt(out.Text, mk[0]),
+				End:   snippetPosAt(out.Text, mk[1]),
+			})
+		}
+		out.Tabstops = append(out.Tabstops, stop)
+	}
+	if len(indices) == 0 || indices[len(indices)-1] != 0 {
+		end := snippetPosAt(out.Text, len(out.Text))
+		out.Tabstops = append(out.Tabstops, SnippetTabstop{Ranges: []buffer.Range{{Start: end, End: end}}})
+	}
+	return out, nil
+}
+
+type snippetNodeKind uint8
+
+const (
+	snippetText snippetNodeKind = iota
+	snippetTabstop
+	snippetVariable
+)
+
+type snippetNode struct {
+	kind snippetNodeKind
+	text string // literal text, or variable name
+	// index is the tabstop index.
+	index int
+	// body holds placeholder or variable default content; hasBody tells
+	// ${1:} from $1.
+	body    []snippetNode
+	hasBody bool
+	choices []string
+}
+
+type snippetParser struct {
+	src string
+	pos int
+}
+
+func (p *snippetParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("snippet: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// parse reads nodes until the end of input or, nested, an unescaped '}'.
+func (p *snippetParser) parse(nested bool) ([]snippetNode, error) {
+	var nodes []snippetNode
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			nodes = append(nodes, snippetNode{kind: snippetText, text: lit.String()})
+			lit.Reset()
+		}
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && strings.IndexByte(`$}\`, p.src[p.pos+1]) >= 0:
+			lit.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '}' && nested:
+			flush()
+			return nodes, nil
+		case c == '$':
+			node, ok, err := p.parseDollar()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				lit.WriteByte('$')
+				p.pos++
+				continue
+			}
+			flush()
+			nodes = append(nodes, node)
+		default:
+			lit.WriteByte(c)
+			p.pos++
+		}
+	}
+	if nested {
+		return nil, p.errorf("missing '}'")
+	}
+	flush()
+	return nodes, nil
+}
+
+// parseDollar parses the construct at a '$'. ok is false when the '$' is
+// literal.
+func (p *snippetParser) parseDollar() (snippetNode, bool, error) {
+	start := p.pos
+	p.pos++ // '$'
+	if n, ok := p.number(); ok {
+		return snippetNode{kind: snippetTabstop, index: n}, true, nil
+	}
+	if name, ok := p.name(); ok {
+		return snippetNode{kind: snippetVariable, text: name}, true, nil
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		p.pos = start
+		return snippetNode{}, false, nil
+	}
+	p.pos++ // '{'
+
+	if n, ok := p.number(); ok {
+		node := snippetNode{kind: snippetTabstop, index: n}
+		switch {
+		case p.consume('}'):
+			return node, true, nil
+		case p.consume(':'):
+			body, err := p.parse(true)
+			if err != nil {
+				return snippetNode{}, false, err
+			}
+			p.pos++ // '}'
+			node.body, node.hasBody = body, true
+			return node, true, nil
+		case p.consume('|'):
+			choices, err := p.choices()
+			if err != nil {
+				return snippetNode{}, false, err
+			}
+			node.choices = choices
+			return node, true, nil
+		}
+		return snippetNode{}, false, p.errorf("unexpected %q in tabstop", p.peek())
+	}
+	if name, ok := p.name(); ok {
+		node := snippetNode{kind: snippetVariable, text: name}
+		switch {
+		case p.consume('}'):
+			return node, true, nil
+		case p.consume(':'):
+			body, err := p.parse(true)
+			if err != nil {
+				return snippetNode{}, false, err
+			}
+			p.pos++ // '}'
+			node.body, node.hasBody = body, true
+			return node, true, nil
+		}
+		return snippetNode{}, false, p.errorf("unsupported variable syntax")
+	}
+	return snippetNode{}, false, p.errorf("expected tabstop or variable after '${'")
+}
+
+// choices reads "a,b|}" after "${n|".
+func (p *snippetParser) choices() ([]string, error) {
+	var out []string
+	var cur strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && strings.IndexByte(`$}\,|`, p.src[p.pos+1]) >= 0:
+			cur.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == ',':
+			out = append(out, cur.String())
+			cur.Reset()
+			p.pos++
+		case c == '|':
+			p.pos++
+			if !p.consume('}') {
+				return nil, p.errorf("expected '}' after choices")
+			}
+			return append(out, cur.String()), nil
+		default:
+			cur.WriteByte(c)
+			p.pos++
+		}
+	}
+	return nil, p.errorf("unterminated choices")
+}
+
+func (p *snippetParser) number() (int, bool) {
+	start := p.pos
+	n := 0
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		n = n*10 + int(p.src[p.pos]-'0')
+		p.pos++
+	}
+	return n, p.pos > start
+}
+
+func (p *snippetParser) name() (string, bool) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || p.pos > start && c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos], p.pos > start
+}
+
+func (p *snippetParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *snippetParser) peek() string {
+	if p.pos >= len(p.src) {
+		return "end of input"
+	}
+	return p.src[p.pos : p.pos+1]
+}
+
+// collectSnippetDefaults records the first default text of each tabstop.
+func collectSnippetDefaults(nodes []snippetNode, defaults map[int]string) {
+	for _, n := range nodes {
+		if n.kind != snippetTabstop {
+			collectSnippetDefaults(n.body, defaults)
+			continue
+		}
+		if _, ok := defaults[n.index]; ok {
+			collectSnippetDefaults(n.body, defaults)
+			continue
+		}
+		switch {
+		case n.hasBody:
+			r := snippetRenderer{defaults: defaults}
+			r.render(n.body)
+			defaults[n.index] = r.sb.String()
+		case len(n.choices) > 0:
+			defaults[n.index] = n.choices[0]
+		}
+		collectSnippetDefaults(n.body, defaults)
+	}
+}
+
+type snippetRenderer struct {
+	sb       strings.Builder
+	defaults map[int]string
+	// marks holds byte ranges of each tabstop occurrence in output order.
+	marks   map[int][][2]int
+	choices map[int][]string
+}
+
+func (r *snippetRenderer) render(nodes []snippetNode) {
+	for _, n := range nodes {
+		switch n.kind {
+		case snippetText:
+			r.sb.WriteString(n.text)
+		case snippetVariable:
+			r.render(n.body)
+		case snippetTabstop:
+			start := r.sb.Len()
+			// Only the first occurrence renders its own body; mirrors show
+			// the first default given.
+			if n.hasBody && len(n.body) > 0 && len(r.marks[n.index]) == 0 {
+				r.render(n.body)
+			} else {
+				r.sb.WriteString(r.defaults[n.index])
+			}
+			if r.marks == nil {
+				r.marks = make(map[int][][2]int)
+				r.choices = make(map[int][]string)
+			}
+			r.marks[n.index] = append(r.marks[n.index], [2]int{start, r.sb.Len()})
+			if len(n.choices) > 0 && r.choices[n.index] == nil {
+				r.choices[n.index] = n.choices
+			}
+		}
+	}
+}
+
+// snippetPosAt converts a byte offset in text to a grapheme position.
+func snippetPosAt(text string, off int) buffer.Pos {
+	before := text[:off]
+	row := strings.Count(before, "\n")
+	if i := strings.LastIndexByte(before, '\n'); i >= 0 {
+		before = before[i+1:]
+	}
+	return buffer.Pos{Row: row, GraphemeCol: len(grapheme.Split(before))}
+}
@@ -0,0 +1,312 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/iw2rmb/flourish/buffer"
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// snippetStop is a tabstop of the active snippet in document coordinates.
+type snippetStop struct {
+	index   int
+	ranges  []buffer.Range
+	choices []string
+}
+
+// snippetSession is the snippet being filled in. It keeps the buffer's undo
+// group open so the insertion and every edit made inside it undo together.
+// stops is nil when no session is active.
+type snippetSession struct {
+	stops  []snippetStop
+	active int
+
+	// version and textVersion are the buffer versions the ranges match.
+	version     uint64
+	textVersion uint64
+}
+
+// SnippetActive reports whether a snippet session is active: Tab and
+// shift+Tab move between its tabstops until the final one is reached, the
+// cursor leaves the snippet, or KeyMap.SnippetExit is pressed.
+func (m Model) SnippetActive() bool { return m.snippet.stops != nil }
+
+// indented prefixes every line after the first with indent so a multi-line
+// snippet lines up with the row it is inserted on.
+func (s Snippet) indented(indent string) Snippet {
+	if indent == "" || !strings.Contains(s.Text, "\n") {
+		return s
+	}
+	cols := graphemeutil.Count(indent)
+	out := Snippet{Text: strings.ReplaceAll(s.Text, "\n", "\n"+indent)}
+	shift := func(p buffer.Pos) buffer.Pos {
+		if p.Row > 0 {
+			p.GraphemeCol += cols
+		}
+		return p
+	}
+	for _, stop := range s.Tabstops {
+		stop.Ranges = slices.Clone(stop.Ranges)
+		for i, r := range stop.Ranges {
+			stop.Ranges[i] = buffer.Range{Start: shift(r.Start), End: shift(r.End)}
+		}
+		out.Tabstops = append(out.Tabstops, stop)
+	}
+	return out
+}
+
+// applyInsertEdits applies accepted completion or ghost edits. With snippet
+// set, the first edit's text is snippet syntax: it is expanded and a
+// snippet session starts at its first tabstop. Text that fails to parse is
+// inserted as is.
+func (m *Model) applyInsertEdits(edits []buffer.TextEdit, snippet bool) {
+	if !snippet || len(edits) == 0 {
+		m.buf.Apply(edits...)
+		return
+	}
+	sn, err := ParseSnippet(edits[0].Text)
+	if err != nil {
+		m.buf.Apply(edits...)
+		return
+	}
+
+	first := buffer.NormalizeRange(edits[0].Range)
+	line, _ := m.docLine(first.Start.Row)
+	sn = sn.indented(line[:len(line)-len(strings.TrimLeft(line, " \t"))])
+	edits = cloneTextEdits(edits)
+	edits[0].Text = sn.Text
+
+	m.endSnippetSession()
+	m.buf.BeginUndoGroup()
+	beforeVer := m.buf.Version()
+	m.buf.Apply(edits...)
+
+	// Tabstops are relative to the first edit; later edits may move them.
+	var later []buffer.AppliedEdit
+	if ch, ok := m.buf.LastChange(); ok && ch.VersionBefore == beforeVer {
+		later = ch.AppliedEdits
+		if len(later) > 0 && later[0].RangeBefore.Start == first.Start && later[0].InsertText == sn.Text {
+			later = later[1:]
+		}
+	}
+	base := first.Start
+	abs := func(p buffer.Pos) buffer.Pos {
+		if p.Row == 0 {
+			p.GraphemeCol += base.GraphemeCol
+		}
+		p.Row += base.Row
+		for _, e := range later {
+			p = snippetShiftPos(p, e, false)
+		}
+		return p
+	}
+
+	stops := make([]snippetStop, 0, len(sn.Tabstops))
+	for _, ts := range sn.Tabstops {
+		stop := snippetStop{index: ts.Index, choices: ts.Choices}
+		for _, r := range ts.Ranges {
+			stop.ranges = append(stop.ranges, buffer.Range{Start: abs(r.Start), End: abs(r.End)})
+		}
+		stops = append(stops, stop)
+	}
+
+	m.snippet = snippetSession{stops: stops, active: -1}
+	m.markSnippetSynced()
+	m.jumpSnippet(0)
+}
+
+// jumpSnippet selects tabstop i, opening completion for its choices. The
+// final tabstop ends the session.
+func (m *Model) jumpSnippet(i int) {
+	s := &m.snippet
+	i = clampInt(i, 0, len(s.stops)-1)
+	s.active = i
+	stop := s.stops[i]
+	r := stop.ranges[0]
+	final := i == len(s.stops)-1
+
+	m.buf.SetCursor(r.End)
+	if r.IsEmpty() {
+		m.buf.ClearSelection()
+	} else {
+		m.buf.SetSelection(r)
+	}
+	if final {
+		m.endSnippetSession()
+		return
+	}
+	m.markSnippetSynced()
+
+	if len(stop.choices) > 0 {
+		items := make([]CompletionItem, 0, len(stop.choices))
+		for _, c := range stop.choices {
+			items = append(items, CompletionItem{
+				ID:         c,
+				InsertText: c,
+				Edits:      []buffer.TextEdit{{Range: r, Text: c}},
+				Label:      []CompletionSegment{{Text: c}},
+			})
+		}
+		m.completionReq.supersede()
+		m.completionTextCache = nil
+		state := CompletionState{Visible: true, Anchor: r.Start, Items: items}
+		m.recomputeCompletionFilter(&state)
+		m.completionState = state
+		m.completionFilterClean = true
+	}
+}
+
+// snippetJumpTarget returns the tabstop reached by moving delta stops from
+// the active one.
+func (m *Model) snippetJumpTarget(delta int) (int, bool) {
+	s := m.snippet
+	if s.stops == nil {
+		return 0, false
+	}
+	next := s.active + delta
+	if next < 0 || next >= len(s.stops) {
+		return 0, false
+	}
+	return next, true
+}
+
+// endSnippetSession leaves the snippet and closes its undo group.
+func (m *Model) endSnippetSession() {
+	if m.snippet.stops == nil {
+		return
+	}
+	m.snippet = snippetSession{}
+	if m.buf != nil {
+		m.buf.EndUndoGroup()
+	}
+}
+
+func (m *Model) markSnippetSynced() {
+	m.snippet.version = m.buf.Version()
+	m.snippet.textVersion = m.buf.TextVersion()
+}
+
+// syncSnippetSession moves tabstop ranges with the last text change and ends
+// the session when the change cannot be followed or the cursor left the
+// snippet.
+func (m *Model) syncSnippetSession() {
+	s := &m.snippet
+	if s.stops == nil || m.buf == nil || m.buf.Version() == s.version {
+		return
+	}
+	if m.buf.TextVersion() != s.textVersion {
+		ch, ok := m.buf.LastChange()
+		if !ok || ch.VersionBefore != s.version || len(ch.AppliedEdits) == 0 {
+			m.endSnippetSession()
+			return
+		}
+		stops := make([]snippetStop, len(s.stops))
+		for i, stop := range s.stops {
+			stop.ranges = slices.Clone(stop.ranges)
+			for j, r := range stop.ranges {
+				// The active tabstop grows with text typed at its edges;
+				// the others keep their extent.
+				active := i == s.active
+				for _, e := range ch.AppliedEdits {
+					r.Start = snippetShiftPos(r.Start, e, active)
+					r.End = snippetShiftPos(r.End, e, !active)
+				}
+				if buffer.ComparePos(r.Start, r.End) > 0 {
+					r.End = r.Start
+				}
+				stop.ranges[j] = r
+			}
+			stops[i] = stop
+		}
+		s.stops = stops
+	}
+	m.markSnippetSynced()
+
+	start, end := s.stops[0].ranges[0].Start, s.stops[0].ranges[0].End
+	for _, stop := range s.stops {
+		for _, r := range stop.ranges {
+			if buffer.ComparePos(r.Start, start) < 0 {
+				start = r.Start
+			}
+			if buffer.ComparePos(r.End, end) > 0 {
+				end = r.End
+			}
+		}
+	}
+	if cur := m.buf.Cursor(); buffer.ComparePos(cur, start) < 0 || buffer.ComparePos(cur, end) > 0 {
+		m.endSnippetSession()
+	}
+}
+
+// applySnippetMirrors copies the text of the active tabstop range holding the
+// cursor to its other ranges. It reports whether the buffer changed.
+func (m *Model) applySnippetMirrors() bool {
+	s := m.snippet
+	if s.stops == nil || s.active < 0 {
+		return false
+	}
+	ranges := s.stops[s.active].ranges
+	if len(ranges) < 2 {
+		return false
+	}
+	cursor := m.buf.Cursor()
+	src := slices.IndexFunc(ranges, func(r buffer.Range) bool {
+		return buffer.ComparePos(r.Start, cursor) <= 0 && buffer.ComparePos(cursor, r.End) <= 0
+	})
+	if src < 0 {
+		return false
+	}
+	text := m.buf.TextInRange(ranges[src])
+
+	// Bottom-up, so each edit applies against the original coordinates.
+	order := make([]int, 0, len(ranges)-1)
+	for i := range ranges {
+		if i != src {
+			order = append(order, i)
+		}
+	}
+	slices.SortFunc(order, func(a, b int) int { return buffer.ComparePos(ranges[b].Start, ranges[a].Start) })
+	edits := make([]buffer.TextEdit, 0, len(order))
+	for _, i := range order {
+		if m.buf.TextInRange(ranges[i]) != text {
+			edits = append(edits, buffer.TextEdit{Range: ranges[i], Text: text})
+		}
+	}
+	if len(edits) == 0 {
+		return false
+	}
+
+	m.buf.Apply(edits...)
+	m.notifyChange()
+	if ch, ok := m.buf.LastChange(); ok {
+		for _, e := range ch.AppliedEdits {
+			cursor = snippetShiftPos(cursor, e, true)
+		}
+	}
+	m.syncSnippetSession()
+	m.buf.SetCursor(cursor)
+	m.notifyChange()
+	m.syncSnippetSession()
+	return true
+}
+
+// snippetShiftPos maps p through one applied edit. A position the edit
+// touches stays before the inserted text when left is set, and moves after
+// it otherwise.
+func snippetShiftPos(p buffer.Pos, e buffer.AppliedEdit, left bool) buffer.Pos {
+	start, end, after := e.RangeBefore.Start, e.RangeBefore.End, e.RangeAfter.End
+	switch {
+	case buffer.ComparePos(p, start) < 0:
+		return p
+	case buffer.ComparePos(p, end) > 0 || (p == end && end != start):
+		if p.Row == end.Row {
+			return buffer.Pos{Row: after.Row, GraphemeCol: after.GraphemeCol + p.GraphemeCol - end.GraphemeCol}
+		}
+		return buffer.Pos{Row: p.Row + after.Row - end.Row, GraphemeCol: p.GraphemeCol}
+	case left:
+		return start
+	default:
+		return after
+	}
+}
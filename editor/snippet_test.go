@@ -0,0 +1,191 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func snippetRange(sr, sc, er, ec int) buffer.Range {
+	return buffer.Range{Start: bufferPos(sr, sc), End: bufferPos(er, ec)}
+}
+
+func TestParseSnippet(t *testing.T) {
+	got, err := ParseSnippet("for ${1:i} := ${2|0,1|}; $1 < ${3:n}; $1++ {\n\t$0\n}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := "for i := 0; i < n; i++ {\n\t\n}"; got.Text != want {
+		t.Fatalf("text=%q, want %q", got.Text, want)
+	}
+	want := []SnippetTabstop{
+		{Index: 1, Ranges: []buffer.Range{snippetRange(0, 4, 0, 5), snippetRange(0, 12, 0, 13), snippetRange(0, 19, 0, 20)}},
+		{Index: 2, Ranges: []buffer.Range{snippetRange(0, 9, 0, 10)}, Choices: []string{"0", "1"}},
+		{Index: 3, Ranges: []buffer.Range{snippetRange(0, 16, 0, 17)}},
+		{Index: 0, Ranges: []buffer.Range{snippetRange(1, 1, 1, 1)}},
+	}
+	if !reflect.DeepEqual(got.Tabstops, want) {
+		t.Fatalf("tabstops:\n got %+v\nwant %+v", got.Tabstops, want)
+	}
+
+	got, err = ParseSnippet("${1:foo} ${1:bar} $1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := "foo foo foo"; got.Text != want {
+		t.Fatalf("mirrors: text=%q, want %q", got.Text, want)
+	}
+}
+
+func TestParseSnippet_EscapesVariablesAndImplicitFinalStop(t *testing.T) {
+	got, err := ParseSnippet(`\$x ${1:a\}b} $TM_NAME ${NAME:def} $ end`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := "$x a}b  def $ end"; got.Text != want {
+		t.Fatalf("text=%q, want %q", got.Text, want)
+	}
+	if n := len(got.Tabstops); n != 2 || got.Tabstops[1].Index != 0 || got.Tabstops[1].Ranges[0] != snippetRange(0, 17, 0, 17) {
+		t.Fatalf("expected implicit $0 at the end, got %+v", got.Tabstops)
+	}
+
+	for _, bad := range []string{"${1:open", "${1|a,b}", "${}"} {
+		if _, err := ParseSnippet(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func acceptSnippetCompletion(t *testing.T, text, snippet string) Model {
+	t.Helper()
+	m := New(Config{Text: text}).Focus()
+	m.buf.SetCursor(bufferPos(0, len(text)))
+	m = m.SetCompletionState(CompletionState{
+		Visible: true,
+		Anchor:  m.buf.Cursor(),
+		Items:   []CompletionItem{{ID: "s", InsertText: snippet, Label: []CompletionSegment{{Text: "s"}}, IsSnippet: true}},
+	})
+	m, _ = m.Update(testKeyCode(tea.KeyEnter))
+	return m
+}
+
+func TestSnippet_TabNavigatesPlaceholdersAndUndoesAsOneStep(t *testing.T) {
+	m := acceptSnippetCompletion(t, "x", "(${1:a}, ${2:b})$0;")
+	if got := m.buf.Text(); got != "x(a, b);" {
+		t.Fatalf("text=%q", got)
+	}
+	if sel, ok := m.buf.Selection(); !ok || sel != snippetRange(0, 2, 0, 3) || !m.SnippetActive() {
+		t.Fatalf("expected first placeholder selected, got %v %v", sel, ok)
+	}
+
+	m, _ = m.Update(testKeyText("q"))
+	m, _ = m.Update(testKeyText("q"))
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if sel, ok := m.buf.Selection(); !ok || sel != snippetRange(0, 6, 0, 7) {
+		t.Fatalf("expected second placeholder selected, got %v %v", sel, ok)
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyTab, tea.ModShift))
+	if sel, ok := m.buf.Selection(); !ok || sel != snippetRange(0, 2, 0, 4) {
+		t.Fatalf("expected edited first placeholder selected, got %v %v", sel, ok)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if m.SnippetActive() || m.buf.Cursor() != bufferPos(0, 8) {
+		t.Fatalf("expected $0 to end the session at col 8, cursor %v", m.buf.Cursor())
+	}
+	if got := m.buf.Text(); got != "x(qq, b);" {
+		t.Fatalf("text=%q", got)
+	}
+
+	m, _ = m.Update(testKeyCode('z', tea.ModCtrl))
+	if got := m.buf.Text(); got != "x" {
+		t.Fatalf("undo should remove the snippet and its edits, got %q", got)
+	}
+}
+
+func TestSnippet_MirrorsFollowEdits(t *testing.T) {
+	var changes int
+	m := acceptSnippetCompletion(t, "", "${1:v} = $1 + $1")
+	m.cfg.OnChange = func(buffer.Change) { changes++ }
+
+	m, _ = m.Update(testKeyText("n"))
+	m, _ = m.Update(testKeyText("m"))
+	if got := m.buf.Text(); got != "nm = nm + nm" {
+		t.Fatalf("text=%q", got)
+	}
+	if m.buf.Cursor() != bufferPos(0, 2) || !m.SnippetActive() {
+		t.Fatalf("cursor=%v active=%v", m.buf.Cursor(), m.SnippetActive())
+	}
+	if changes == 0 {
+		t.Fatalf("expected OnChange for mirror edits")
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyBackspace))
+	if got := m.buf.Text(); got != "n = n + n" {
+		t.Fatalf("text after backspace=%q", got)
+	}
+}
+
+func TestSnippet_LeavingEndsSession(t *testing.T) {
+	m := acceptSnippetCompletion(t, "", "f(${1:a}) $2")
+	m, _ = m.Update(testKeyCode(tea.KeyEscape))
+	if m.SnippetActive() {
+		t.Fatalf("esc should end the session")
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if got := m.buf.Text(); got != "f(\t) " {
+		t.Fatalf("tab after the session should insert a tab, got %q", got)
+	}
+
+	m = acceptSnippetCompletion(t, "", "f(${1:a}) $2\nrest")
+	m.buf.SetCursor(bufferPos(1, 4))
+	m, _ = m.Update(testKeyText("!"))
+	if m.SnippetActive() {
+		t.Fatalf("moving out of the snippet should end the session")
+	}
+}
+
+func TestSnippet_ChoiceOpensCompletion(t *testing.T) {
+	m := acceptSnippetCompletion(t, "", "go ${1|fast,slow|} now")
+	state := m.CompletionState()
+	if !state.Visible || len(state.Items) != 2 || state.Anchor != bufferPos(0, 3) {
+		t.Fatalf("expected choice popup, got %+v", state)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	m, _ = m.Update(testKeyCode(tea.KeyEnter))
+	if got := m.buf.Text(); got != "go slow now" {
+		t.Fatalf("text=%q", got)
+	}
+}
+
+func TestSnippet_GhostIndentsAndStartsSession(t *testing.T) {
+	m := New(Config{
+		Text: "\tif",
+		GhostProvider: func(ctx GhostContext) (Ghost, bool) {
+			at := bufferPos(ctx.Row, ctx.GraphemeCol)
+			return Ghost{
+				Text:    " {…}",
+				Edits:   []buffer.TextEdit{{Range: buffer.Range{Start: at, End: at}, Text: " ${1:cond} {\n\t$0\n}"}},
+				Snippet: true,
+			}, true
+		},
+	}).Focus()
+	m.buf.SetCursor(bufferPos(0, 3))
+
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if got := m.buf.Text(); got != "\tif cond {\n\t\t\n\t}" {
+		t.Fatalf("text=%q", got)
+	}
+	if sel, ok := m.buf.Selection(); !ok || sel != snippetRange(0, 4, 0, 8) {
+		t.Fatalf("expected placeholder selected, got %v %v", sel, ok)
+	}
+	m, _ = m.Update(testKeyCode(tea.KeyTab))
+	if m.SnippetActive() || m.buf.Cursor() != bufferPos(1, 2) {
+		t.Fatalf("expected final stop at (1,2), cursor %v", m.buf.Cursor())
+	}
+}
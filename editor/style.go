@@ -14,6 +14,9 @@ type Style struct {
 	Selection lipgloss.Style
 	Cursor    lipgloss.Style
 	Link      lipgloss.Style
+	// MatchingBracket styles the bracket adjacent to the cursor and its match.
+	// When unset, bracket match highlighting is disabled.
+	MatchingBracket lipgloss.Style
 	// Scrollbar styles are used for editor-owned scrollbar chrome.
 	ScrollbarTrack  lipgloss.Style
 	ScrollbarThumb  lipgloss.Style
@@ -38,6 +41,7 @@ func (s Style) isZero() bool {
 		isLipglossZero(s.Selection) &&
 		isLipglossZero(s.Cursor) &&
 		isLipglossZero(s.Link) &&
+		isLipglossZero(s.MatchingBracket) &&
 		isLipglossZero(s.ScrollbarTrack) &&
 		isLipglossZero(s.ScrollbarThumb) &&
 		isLipglossZero(s.ScrollbarCorner) &&
@@ -61,10 +65,13 @@ func DefaultStyle() Style {
 			Foreground(lipgloss.Color("214")),
 		RowMarkDeleted: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("203")),
-		Text:           lipgloss.NewStyle(),
-		Selection:      lipgloss.NewStyle().Background(lipgloss.Color("237")),
-		Cursor:         lipgloss.NewStyle().Reverse(true),
-		Link:           lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Underline(true),
+		Text:      lipgloss.NewStyle(),
+		Selection: lipgloss.NewStyle().Background(lipgloss.Color("237")),
+		Cursor:    lipgloss.NewStyle().Reverse(true),
+		Link:      lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Underline(true),
+		MatchingBracket: lipgloss.NewStyle().
+			Background(lipgloss.Color("239")).
+			Bold(true),
 		ScrollbarTrack: lipgloss.NewStyle().Background(lipgloss.Color("236")),
 		ScrollbarThumb: lipgloss.NewStyle().Background(lipgloss.Color("241")),
 		ScrollbarCorner: lipgloss.NewStyle().
@@ -23,6 +23,10 @@ func TestStyleIsZero_ScrollbarFieldsAffectZeroCheck(t *testing.T) {
 			name:  "row mark deleted",
 			style: Style{RowMarkDeleted: lipgloss.NewStyle().PaddingLeft(1)},
 		},
+		{
+			name:  "matching bracket",
+			style: Style{MatchingBracket: lipgloss.NewStyle().PaddingLeft(1)},
+		},
 		{
 			name:  "track",
 			style: Style{ScrollbarTrack: lipgloss.NewStyle().PaddingLeft(1)},
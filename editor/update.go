@@ -1,7 +1,6 @@
 package editor
 
 import (
-	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
 
 	"github.com/iw2rmb/flourish/buffer"
@@ -20,36 +19,53 @@ func (m Model) updateKey(msg tea.KeyPressMsg) (Model, tea.Cmd) {
 	if !m.focused || m.buf == nil {
 		return m, nil
 	}
+	cmd, ok := (&m).resolveChord(msg)
+	if !ok {
+		return m, cmd
+	}
+	m, cmd = m.updateTypedKey(msg)
+	m.chordTyped = ""
+	return m, cmd
+}
 
+// updateTypedKey handles a key press, or the last key of a completed chord.
+func (m Model) updateTypedKey(msg tea.KeyPressMsg) (Model, tea.Cmd) {
 	before := editorStateFromBuffer(m.buf)
+	beforeText := m.buf.TextVersion()
+	if cmd, ok := (&m).runBoundCommand(msg, before); ok {
+		return m, cmd
+	}
+
 	if completion, handled := (&m).buildCompletionIntentsFromKey(msg, before); handled {
-		if len(completion.completionBatch.Intents) > 0 && m.cfg.OnCompletionIntent != nil {
-			m.cfg.OnCompletionIntent(completion.completionBatch)
-		}
-		applyDocumentLocally := (&m).emitDocumentIntentsAndResolveApply(completion.documentBatch)
-		for _, op := range completion.completionMutations {
-			op(&m)
-		}
-		if applyDocumentLocally {
-			for _, op := range completion.documentMutations {
-				op(&m)
-			}
+		if (&m).applyCompletionKeyResult(completion) {
+			(&m).maybeTriggerCompletion(msg, before, beforeText)
 		}
 		return m, nil
 	}
 
+	if m.keyMatches(msg, m.cfg.KeyMap.DismissPopup) && (&m).dismissFloats() {
+		return m, nil
+	}
+
 	batch, mutations := (&m).buildIntentsFromKey(msg, before)
 	applyLocally := (&m).emitDocumentIntentsAndResolveApply(batch)
 	if applyLocally {
 		for _, op := range mutations {
 			op(&m)
 		}
+		(&m).maybeTriggerCompletion(msg, before, beforeText)
 	}
 
 	return m, nil
 }
 
 func (m *Model) emitDocumentIntentsAndResolveApply(batch IntentBatch) bool {
+	applied := m.resolveDocumentIntents(batch)
+	m.recordMacro(batch, applied)
+	return applied
+}
+
+func (m *Model) resolveDocumentIntents(batch IntentBatch) bool {
 	mode := normalizeMutationMode(m.cfg.MutationMode)
 
 	switch mode {
@@ -72,176 +88,77 @@ func (m *Model) emitDocumentIntentsAndResolveApply(batch IntentBatch) bool {
 }
 
 func (m *Model) buildIntentsFromKey(msg tea.KeyPressMsg, before EditorState) (IntentBatch, []localMutationOp) {
-	batch := IntentBatch{}
-	mutations := make([]localMutationOp, 0, 1)
-	appendIntent := func(kind IntentKind, payload any) {
-		batch.Intents = append(batch.Intents, Intent{Kind: kind, Before: before, Payload: payload})
-	}
-
 	km := m.cfg.KeyMap
 	ga := normalizeGhostAccept(m.cfg.GhostAccept)
 
-	if m.cfg.GhostProvider != nil && !m.cfg.ReadOnly {
-		if ga.AcceptTab && isTabKey(msg) {
-			if ghost, ok := m.ghostForCursor(); ok && len(ghost.Edits) > 0 {
-				edits := cloneTextEdits(ghost.Edits)
-				appendIntent(IntentInsert, InsertIntentPayload{Text: ghost.Text, Edits: edits})
-				mutations = append(mutations, func(mm *Model) {
-					mm.buf.Apply(edits...)
-				})
+	if m.snippet.stops != nil {
+		for _, kc := range km.snippetCommands() {
+			if m.keyMatches(msg, kc.binding) {
+				batch, mutations, _ := m.buildCommand(kc.command, before)
 				return batch, mutations
 			}
 		}
-		if ga.AcceptRight && key.Matches(msg, km.Right) {
-			if ghost, ok := m.ghostForCursor(); ok && ghostRightAcceptEligible(ghost.Edits, m.buf.Cursor()) {
-				edits := cloneTextEdits(ghost.Edits)
-				appendIntent(IntentInsert, InsertIntentPayload{Text: ghost.Text, Edits: edits})
-				mutations = append(mutations, func(mm *Model) {
-					mm.buf.Apply(edits...)
-				})
+	}
+
+	if ga.AcceptTab && isTabKey(msg) && !m.typedChord() {
+		if batch, mutations, ok := m.buildGhostAccept(before, false); ok {
+			return batch, mutations
+		}
+	}
+	if ga.AcceptRight && m.keyMatches(msg, km.Right) {
+		if batch, mutations, ok := m.buildGhostAccept(before, true); ok {
+			return batch, mutations
+		}
+	}
+	for _, kc := range km.ghostCommands() {
+		if m.keyMatches(msg, kc.binding) {
+			if batch, mutations, ok := m.buildCommand(kc.command, before); ok {
 				return batch, mutations
 			}
 		}
 	}
 
-	appendMove := func(move buffer.Move) {
-		kind := IntentMove
-		var payload any = MoveIntentPayload{Move: move}
-		if move.Extend {
-			kind = IntentSelect
-			payload = SelectIntentPayload{Move: move}
+	for _, kc := range km.documentCommands() {
+		if m.keyMatches(msg, kc.binding) {
+			batch, mutations, _ := m.buildCommand(kc.command, before)
+			return batch, mutations
 		}
-		appendIntent(kind, payload)
-		mutations = append(mutations, func(mm *Model) { mm.buf.Move(move) })
 	}
 
-	switch {
-	case key.Matches(msg, km.Left):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirLeft})
-	case key.Matches(msg, km.Right):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirRight})
-	case key.Matches(msg, km.ParagraphUp):
-		appendMove(buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirUp})
-	case key.Matches(msg, km.ParagraphDown):
-		appendMove(buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirDown})
-	case key.Matches(msg, km.Up):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirUp})
-	case key.Matches(msg, km.Down):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirDown})
-	case key.Matches(msg, km.PageUp):
-		appendMove(buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirUp, Count: m.pageMoveCount()})
-	case key.Matches(msg, km.PageDown):
-		appendMove(buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirDown, Count: m.pageMoveCount()})
-
-	case key.Matches(msg, km.ShiftLeft):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirLeft, Extend: true})
-	case key.Matches(msg, km.ShiftRight):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirRight, Extend: true})
-	case key.Matches(msg, km.ParagraphShiftUp):
-		appendMove(buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirUp, Extend: true})
-	case key.Matches(msg, km.ParagraphShiftDown):
-		appendMove(buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirDown, Extend: true})
-	case key.Matches(msg, km.ShiftUp):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirUp, Extend: true})
-	case key.Matches(msg, km.ShiftDown):
-		appendMove(buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirDown, Extend: true})
-
-	case key.Matches(msg, km.WordLeft):
-		appendMove(buffer.Move{Unit: buffer.MoveWord, Dir: buffer.DirLeft})
-	case key.Matches(msg, km.WordRight):
-		appendMove(buffer.Move{Unit: buffer.MoveWord, Dir: buffer.DirRight})
-	case key.Matches(msg, km.WordShiftLeft):
-		appendMove(buffer.Move{Unit: buffer.MoveWord, Dir: buffer.DirLeft, Extend: true})
-	case key.Matches(msg, km.WordShiftRight):
-		appendMove(buffer.Move{Unit: buffer.MoveWord, Dir: buffer.DirRight, Extend: true})
-
-	case key.Matches(msg, km.Home):
-		appendMove(buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirHome})
-	case key.Matches(msg, km.End):
-		appendMove(buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirEnd})
+	batch := IntentBatch{}
+	mutations := make([]localMutationOp, 0, 1)
+	appendIntent := func(kind IntentKind, payload any) {
+		batch.Intents = append(batch.Intents, Intent{Kind: kind, Before: before, Payload: payload})
+	}
 
-	case key.Matches(msg, km.DeleteWordBackward):
-		if !m.cfg.ReadOnly {
-			dir := DeleteWordBackward
-			if _, ok := m.buf.Selection(); ok {
-				dir = DeleteSelection
-			}
-			appendIntent(IntentDelete, DeleteIntentPayload{Direction: dir})
-			mutations = append(mutations, func(mm *Model) { mm.buf.DeleteWordBackward() })
-		}
-	case key.Matches(msg, km.KillLineRight):
-		if !m.cfg.ReadOnly {
-			dir := DeleteLineRight
-			if _, ok := m.buf.Selection(); ok {
-				dir = DeleteSelection
-			}
-			appendIntent(IntentDelete, DeleteIntentPayload{Direction: dir})
-			mutations = append(mutations, func(mm *Model) { mm.buf.DeleteLineRight() })
-		}
-	case key.Matches(msg, km.Backspace):
-		if !m.cfg.ReadOnly {
-			dir := DeleteBackward
-			if _, ok := m.buf.Selection(); ok {
-				dir = DeleteSelection
-			}
-			appendIntent(IntentDelete, DeleteIntentPayload{Direction: dir})
-			mutations = append(mutations, func(mm *Model) { mm.buf.DeleteBackward() })
-		}
-	case key.Matches(msg, km.Delete):
-		if !m.cfg.ReadOnly {
-			dir := DeleteForward
-			if _, ok := m.buf.Selection(); ok {
-				dir = DeleteSelection
-			}
-			appendIntent(IntentDelete, DeleteIntentPayload{Direction: dir})
-			mutations = append(mutations, func(mm *Model) { mm.buf.DeleteForward() })
-		}
-	case key.Matches(msg, km.Enter):
+	if m.typedChord() {
+		// A chord no binding handles in this context types nothing.
+		return batch, mutations
+	}
+	if isTabKey(msg) {
+		batch, mutations, _ := m.buildCommand("edit.tab", before)
+		return batch, mutations
+	}
+	if isSpaceKey(msg) && !hasAltMod(msg) {
 		if !m.cfg.ReadOnly {
-			appendIntent(IntentInsert, InsertIntentPayload{Text: "\n"})
-			mutations = append(mutations, func(mm *Model) { mm.buf.InsertNewline() })
+			appendIntent(IntentInsert, InsertIntentPayload{Text: " "})
+			mutations = append(mutations, func(mm *Model) { mm.buf.InsertGrapheme(" ") })
 		}
+		return batch, mutations
+	}
 
-	case key.Matches(msg, km.Undo):
+	text := keyText(msg)
+	if text != "" && !hasAltMod(msg) {
 		if !m.cfg.ReadOnly {
-			if m.buf.CanUndo() {
-				appendIntent(IntentUndo, UndoIntentPayload{})
-				mutations = append(mutations, func(mm *Model) { _ = mm.buf.Undo() })
-			}
-		}
-	case key.Matches(msg, km.Redo):
-		if !m.cfg.ReadOnly {
-			if m.buf.CanRedo() {
-				appendIntent(IntentRedo, RedoIntentPayload{})
-				mutations = append(mutations, func(mm *Model) { _ = mm.buf.Redo() })
-			}
-		}
-
-	default:
-		if isTabKey(msg) {
-			if !m.cfg.ReadOnly {
-				appendIntent(IntentInsert, InsertIntentPayload{Text: "\t"})
-				mutations = append(mutations, func(mm *Model) { mm.buf.InsertGrapheme("\t") })
-			}
-			return batch, mutations
-		}
-		if isSpaceKey(msg) && !hasAltMod(msg) {
-			if !m.cfg.ReadOnly {
-				appendIntent(IntentInsert, InsertIntentPayload{Text: " "})
-				mutations = append(mutations, func(mm *Model) { mm.buf.InsertGrapheme(" ") })
-			}
-			return batch, mutations
-		}
-
-		text := keyText(msg)
-		if text != "" && !hasAltMod(msg) {
-			if !m.cfg.ReadOnly {
-				appendIntent(IntentInsert, InsertIntentPayload{Text: text})
-				mutations = append(mutations, func(mm *Model) { mm.buf.InsertText(text) })
+			if action, ok := m.autoPairForText(text); ok {
+				appendIntent(action.kind, action.payload)
+				mutations = append(mutations, action.op)
+				return batch, mutations
 			}
+			appendIntent(IntentInsert, InsertIntentPayload{Text: text})
+			mutations = append(mutations, func(mm *Model) { mm.buf.InsertText(text) })
 		}
 	}
-
 	return batch, mutations
 }
 
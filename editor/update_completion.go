@@ -4,7 +4,6 @@ import (
 	"unicode"
 	"unicode/utf8"
 
-	"charm.land/bubbles/v2/key"
 	tea "charm.land/bubbletea/v2"
 
 	"github.com/iw2rmb/flourish/buffer"
@@ -14,6 +13,22 @@ import (
 func (m *Model) buildCompletionIntentsFromKey(msg tea.KeyPressMsg, before EditorState) (completionKeyResult, bool) {
 	ckm := m.cfg.CompletionKeyMap
 	km := m.cfg.KeyMap
+
+	if m.keyMatches(msg, ckm.Trigger) {
+		return m.buildCompletionCommand("completion.trigger", before)
+	}
+	if !m.completionState.Visible {
+		return completionKeyResult{}, false
+	}
+	for _, kc := range ckm.commands() {
+		if m.keyMatches(msg, kc.binding) {
+			return m.buildCompletionCommand(kc.command, before)
+		}
+	}
+	if isTabKey(msg) && ckm.AcceptTab && !m.typedChord() {
+		return m.buildCompletionCommand("completion.accept", before)
+	}
+
 	result := completionKeyResult{
 		completionMutations: make([]localMutationOp, 0, 2),
 		documentMutations:   make([]localMutationOp, 0, 2),
@@ -32,74 +47,12 @@ func (m *Model) buildCompletionIntentsFromKey(msg tea.KeyPressMsg, before Editor
 			Payload: payload,
 		})
 	}
-	appendNavigate := func(delta int) {
-		payload := m.completionNavigatePayload(delta)
-		appendCompletionIntent(IntentCompletionNavigate, payload)
-		result.completionMutations = append(result.completionMutations, func(mm *Model) {
-			mm.moveCompletionSelection(delta)
-		})
-	}
 
-	if key.Matches(msg, ckm.Trigger) {
-		appendCompletionIntent(IntentCompletionTrigger, CompletionTriggerIntentPayload{Anchor: before.Cursor})
-		result.completionMutations = append(result.completionMutations, func(mm *Model) {
-			mm.openCompletionAtCursor()
-		})
-		return result, true
-	}
-
-	if !m.completionState.Visible {
+	if m.typedChord() {
+		// Document chords still apply while the popup is visible.
 		return result, false
 	}
 
-	switch {
-	case key.Matches(msg, ckm.Dismiss):
-		appendCompletionIntent(IntentCompletionDismiss, CompletionDismissIntentPayload{})
-		result.completionMutations = append(result.completionMutations, func(mm *Model) {
-			*mm = mm.ClearCompletion()
-		})
-		return result, true
-	case key.Matches(msg, ckm.Next):
-		appendNavigate(1)
-		return result, true
-	case key.Matches(msg, ckm.Prev):
-		appendNavigate(-1)
-		return result, true
-	case key.Matches(msg, ckm.PageNext):
-		step := m.cfg.CompletionMaxVisibleRows
-		if step <= 0 {
-			step = defaultCompletionMaxVisibleRows
-		}
-		appendNavigate(step)
-		return result, true
-	case key.Matches(msg, ckm.PagePrev):
-		step := m.cfg.CompletionMaxVisibleRows
-		if step <= 0 {
-			step = defaultCompletionMaxVisibleRows
-		}
-		appendNavigate(-step)
-		return result, true
-	}
-
-	if key.Matches(msg, ckm.Accept) || (isTabKey(msg) && ckm.AcceptTab) {
-		acceptPayload, ok := m.acceptCompletionPayload()
-		if !ok {
-			return result, true
-		}
-		completionPayload := acceptPayload
-		completionPayload.Edits = cloneTextEdits(completionPayload.Edits)
-		appendCompletionIntent(IntentCompletionAccept, completionPayload)
-		if !m.cfg.ReadOnly {
-			documentEdits := cloneTextEdits(acceptPayload.Edits)
-			appendDocumentIntent(IntentInsert, InsertIntentPayload{Text: acceptPayload.InsertText, Edits: cloneTextEdits(documentEdits)})
-			result.documentMutations = append(result.documentMutations, func(mm *Model) {
-				mm.buf.Apply(documentEdits...)
-				*mm = mm.ClearCompletion()
-			})
-		}
-		return result, true
-	}
-
 	mode := normalizeCompletionInputMode(m.cfg.CompletionInputMode)
 	if m.cfg.ReadOnly {
 		mode = CompletionInputQueryOnly
@@ -116,9 +69,9 @@ func (m *Model) buildCompletionIntentsFromKey(msg tea.KeyPressMsg, before Editor
 		return result, false
 	}
 
-	if key.Matches(msg, km.Backspace) ||
-		key.Matches(msg, km.DeleteWordBackward) ||
-		key.Matches(msg, km.KillLineRight) {
+	if m.keyMatches(msg, km.Backspace) ||
+		m.keyMatches(msg, km.DeleteWordBackward) ||
+		m.keyMatches(msg, km.KillLineRight) {
 		query, ok := m.nextCompletionQueryForMutateDocumentKey(msg)
 		if !ok {
 			query = m.completionState.Query
@@ -127,10 +80,10 @@ func (m *Model) buildCompletionIntentsFromKey(msg tea.KeyPressMsg, before Editor
 		dir := DeleteBackward
 		deleteOp := func(mm *Model) { mm.buf.DeleteBackward() }
 		switch {
-		case key.Matches(msg, km.DeleteWordBackward):
+		case m.keyMatches(msg, km.DeleteWordBackward):
 			dir = DeleteWordBackward
 			deleteOp = func(mm *Model) { mm.buf.DeleteWordBackward() }
-		case key.Matches(msg, km.KillLineRight):
+		case m.keyMatches(msg, km.KillLineRight):
 			dir = DeleteLineRight
 			deleteOp = func(mm *Model) { mm.buf.DeleteLineRight() }
 		}
@@ -175,6 +128,124 @@ func (m *Model) buildCompletionIntentsFromKey(msg tea.KeyPressMsg, before Editor
 	return result, false
 }
 
+// buildCompletionCommand builds a built-in completion command. ok is false
+// when name is not one, or when the popup is not visible (completion.trigger
+// aside) or has no documentation to scroll.
+func (m *Model) buildCompletionCommand(name string, before EditorState) (completionKeyResult, bool) {
+	result := completionKeyResult{
+		completionMutations: make([]localMutationOp, 0, 2),
+		documentMutations:   make([]localMutationOp, 0, 2),
+	}
+	appendCompletionIntent := func(kind CompletionIntentKind, payload any) {
+		result.completionBatch.Intents = append(result.completionBatch.Intents, CompletionIntent{
+			Kind:    kind,
+			Before:  before,
+			Payload: payload,
+		})
+	}
+	appendNavigate := func(delta int) {
+		payload := m.completionNavigatePayload(delta)
+		appendCompletionIntent(IntentCompletionNavigate, payload)
+		result.completionMutations = append(result.completionMutations, func(mm *Model) {
+			mm.moveCompletionSelection(delta)
+		})
+	}
+	pageStep := func() int {
+		if m.cfg.CompletionMaxVisibleRows <= 0 {
+			return defaultCompletionMaxVisibleRows
+		}
+		return m.cfg.CompletionMaxVisibleRows
+	}
+
+	if name == "completion.trigger" {
+		appendCompletionIntent(IntentCompletionTrigger, CompletionTriggerIntentPayload{Anchor: before.Cursor})
+		result.completionMutations = append(result.completionMutations, func(mm *Model) {
+			if mm.completionReq != nil {
+				mm.startCompletion(CompletionTriggerInvoked, "")
+				return
+			}
+			mm.openCompletionAtCursor()
+		})
+		return result, true
+	}
+	if !m.completionState.Visible {
+		return result, false
+	}
+
+	switch name {
+	case "completion.dismiss":
+		appendCompletionIntent(IntentCompletionDismiss, CompletionDismissIntentPayload{})
+		result.completionMutations = append(result.completionMutations, func(mm *Model) {
+			*mm = mm.ClearCompletion()
+		})
+	case "completion.next":
+		appendNavigate(1)
+	case "completion.prev":
+		appendNavigate(-1)
+	case "completion.pageNext":
+		appendNavigate(pageStep())
+	case "completion.pagePrev":
+		appendNavigate(-pageStep())
+	case "completion.docScrollDown", "completion.docScrollUp":
+		maxOffset, ok := m.completionDocMaxOffset()
+		if !ok {
+			return result, false
+		}
+		delta := 1
+		if name == "completion.docScrollUp" {
+			delta = -1
+		}
+		offset := clampInt(m.completionDocScroll+delta, 0, maxOffset)
+		appendCompletionIntent(IntentCompletionDocScroll, CompletionDocScrollIntentPayload{Delta: delta, Offset: offset})
+		result.completionMutations = append(result.completionMutations, func(mm *Model) {
+			mm.completionDocScroll = offset
+		})
+	case "completion.accept":
+		acceptPayload, ok := m.acceptCompletionPayload()
+		if !ok {
+			return result, true
+		}
+		completionPayload := acceptPayload
+		completionPayload.Edits = cloneTextEdits(completionPayload.Edits)
+		appendCompletionIntent(IntentCompletionAccept, completionPayload)
+		if !m.cfg.ReadOnly {
+			documentEdits := cloneTextEdits(acceptPayload.Edits)
+			snippet := acceptPayload.Snippet
+			result.documentBatch.Intents = append(result.documentBatch.Intents, Intent{
+				Kind:    IntentInsert,
+				Before:  before,
+				Payload: InsertIntentPayload{Text: acceptPayload.InsertText, Edits: cloneTextEdits(documentEdits), Snippet: snippet},
+			})
+			result.documentMutations = append(result.documentMutations, func(mm *Model) {
+				*mm = mm.ClearCompletion()
+				mm.applyInsertEdits(documentEdits, snippet)
+			})
+		}
+	default:
+		return result, false
+	}
+	return result, true
+}
+
+// applyCompletionKeyResult reports completion and document intents and runs
+// their mutations. It reports whether the document mutations ran.
+func (m *Model) applyCompletionKeyResult(result completionKeyResult) bool {
+	if len(result.completionBatch.Intents) > 0 && m.cfg.OnCompletionIntent != nil {
+		m.cfg.OnCompletionIntent(result.completionBatch)
+	}
+	applyDocumentLocally := m.emitDocumentIntentsAndResolveApply(result.documentBatch)
+	for _, op := range result.completionMutations {
+		op(m)
+	}
+	if !applyDocumentLocally {
+		return false
+	}
+	for _, op := range result.documentMutations {
+		op(m)
+	}
+	return true
+}
+
 func (m *Model) openCompletionAtCursor() {
 	state := m.completionState
 	state.Visible = true
@@ -195,6 +266,7 @@ func (m *Model) moveCompletionSelection(delta int) {
 	}
 	state.Selected = clampInt(state.Selected+delta, 0, len(state.VisibleIndices)-1)
 	m.completionState = state
+	m.completionDocScroll = 0
 }
 
 func (m *Model) completionNavigatePayload(delta int) CompletionNavigateIntentPayload {
@@ -247,26 +319,27 @@ func (m *Model) acceptCompletionPayload() (CompletionAcceptIntentPayload, bool)
 		VisibleIndex: selected,
 		InsertText:   item.InsertText,
 		Edits:        edits,
+		Snippet:      item.IsSnippet,
 	}, true
 }
 
 func (m *Model) nextCompletionQueryFromKey(msg tea.KeyPressMsg) (string, bool) {
 	km := m.cfg.KeyMap
-	if key.Matches(msg, km.DeleteWordBackward) {
+	if m.keyMatches(msg, km.DeleteWordBackward) {
 		parts := grapheme.Split(m.completionState.Query)
 		if len(parts) == 0 {
 			return "", true
 		}
 		return grapheme.Join(parts[:prevCompletionWordBoundary(parts, len(parts))]), true
 	}
-	if key.Matches(msg, km.Backspace) {
+	if m.keyMatches(msg, km.Backspace) {
 		parts := grapheme.Split(m.completionState.Query)
 		if len(parts) == 0 {
 			return "", true
 		}
 		return grapheme.Join(parts[:len(parts)-1]), true
 	}
-	if key.Matches(msg, km.KillLineRight) {
+	if m.keyMatches(msg, km.KillLineRight) {
 		// Query-only input tracks a suffix-at-anchor model with implicit cursor at end.
 		// Deleting to line-right from end is a no-op.
 		return m.completionState.Query, true
@@ -305,7 +378,7 @@ func (m *Model) nextCompletionQueryForMutateDocumentKey(msg tea.KeyPressMsg) (st
 	}
 
 	switch {
-	case key.Matches(msg, m.cfg.KeyMap.DeleteWordBackward):
+	case m.keyMatches(msg, m.cfg.KeyMap.DeleteWordBackward):
 		if hasSel {
 			insertAt := sel.Start
 			if insertAt.Row != anchor.Row || insertAt.GraphemeCol < anchor.GraphemeCol {
@@ -329,7 +402,7 @@ func (m *Model) nextCompletionQueryForMutateDocumentKey(msg tea.KeyPressMsg) (st
 			End:   buffer.Pos{Row: cursor.Row, GraphemeCol: startCol},
 		}), true
 
-	case key.Matches(msg, m.cfg.KeyMap.KillLineRight):
+	case m.keyMatches(msg, m.cfg.KeyMap.KillLineRight):
 		if hasSel {
 			insertAt := sel.Start
 			if insertAt.Row != anchor.Row || insertAt.GraphemeCol < anchor.GraphemeCol {
@@ -343,7 +416,7 @@ func (m *Model) nextCompletionQueryForMutateDocumentKey(msg tea.KeyPressMsg) (st
 		// Delete-right does not change text left of cursor.
 		return m.buf.TextInRange(buffer.Range{Start: anchor, End: cursor}), true
 
-	case key.Matches(msg, m.cfg.KeyMap.Backspace):
+	case m.keyMatches(msg, m.cfg.KeyMap.Backspace):
 		if hasSel {
 			// Selection delete: cursor lands at selection start.
 			insertAt := sel.Start
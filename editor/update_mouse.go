@@ -14,12 +14,18 @@ func (m Model) updateMouse(msg tea.MouseMsg) (Model, tea.Cmd) {
 
 	var cmd tea.Cmd
 	if m.cfg.ScrollPolicy == ScrollAllowManual || !isManualScrollMouse(msg) {
-		m.viewport, cmd = m.viewport.Update(msg)
+		if wheel, ok := msg.(tea.MouseWheelMsg); ok && m.windowed() {
+			m.scrollWindowWheel(wheel)
+		} else {
+			m.viewport, cmd = m.viewport.Update(msg)
+		}
 	}
 
 	if !m.focused || m.buf == nil {
 		if _, ok := msg.(tea.MouseReleaseMsg); ok {
 			m.mouseDragging = false
+			m.dragScroll = dragScrollState{}
+			m.cancelDrop()
 		}
 		return m, cmd
 	}
@@ -43,27 +49,43 @@ func (m Model) updateMouse(msg tea.MouseMsg) (Model, tea.Cmd) {
 			m.mouseAnchor = anchor
 			m.buf.SetCursor(p)
 			m.buf.SetSelection(buffer.Range{Start: anchor, End: p})
+			m.click = clickState{}
 		} else {
 			m.mouseAnchor = p
-			m.buf.SetCursor(p)
-			m.buf.ClearSelection()
+			count := m.registerClick(p)
+			switch {
+			case count == 2:
+				m.selectUnitAt(p, unitWord)
+			case count == 3:
+				m.selectUnitAt(p, unitLine)
+			case m.startDrop(p):
+				m.click.unit = unitGrapheme
+			default:
+				m.click.unit = unitGrapheme
+				m.buf.SetCursor(p)
+				m.buf.ClearSelection()
+			}
 		}
 		m.mouseDragging = true
 
 	case tea.MouseMotionMsg:
 		if !m.mouseDragging {
+			if msg.Button == tea.MouseNone {
+				m.updateHover(msg.X, msg.Y)
+			}
 			return m, cmd
 		}
 
-		x, y := m.clampMouseToBounds(msg.X, msg.Y)
-		p := m.screenToDocPos(x, y)
-		m.buf.SetCursor(p)
-		m.buf.SetSelection(buffer.Range{Start: m.mouseAnchor, End: p})
+		return m, tea.Batch(cmd, m.dragSelectTo(msg.X, msg.Y))
 
 	case tea.MouseReleaseMsg:
 		_ = mouse
 		m.mouseDragging = false
+		m.dragScroll = dragScrollState{}
 		m.clearScrollbarDrag()
+		if m.drop.active {
+			m = m.finishDrop(msg.Mod)
+		}
 	}
 
 	return m, cmd
@@ -92,7 +114,7 @@ func (m *Model) handleScrollbarMouse(msg tea.MouseMsg, allowManual bool) bool {
 	lines := m.ensureLines()
 	layout := m.ensureLayoutCache(lines)
 	metrics := m.resolveScrollbarMetrics(lines, layout)
-	gutterWidth := m.resolvedGutterWidth(len(lines))
+	gutterWidth := m.resolvedGutterWidth(m.docLineCount(lines))
 
 	mouse := msg.Mouse()
 	switch msg := msg.(type) { //nolint:exhaustive
@@ -228,8 +250,8 @@ func (m *Model) clearScrollbarDrag() {
 func (m *Model) pageVerticalScrollbar(delta int, metrics scrollbarMetrics) {
 	maxYOffset := max(metrics.totalRows-metrics.contentHeight, 0)
 	next := clampInt(metrics.yOffset+delta, 0, maxYOffset)
-	if next != m.viewport.YOffset() {
-		m.viewport.SetYOffset(next)
+	if next != m.topVisualRow() {
+		m.setTopVisualRow(next)
 	}
 }
 
@@ -252,8 +274,8 @@ func (m *Model) applyVerticalScrollbarDrag(pointerCell int, metrics scrollbarMet
 		maxYOffset,
 	)
 	next = clampInt(next, 0, maxYOffset)
-	if next != m.viewport.YOffset() {
-		m.viewport.SetYOffset(next)
+	if next != m.topVisualRow() {
+		m.setTopVisualRow(next)
 	}
 }
 
@@ -8,6 +8,8 @@ import (
 
 type wrapLayoutCacheKey struct {
 	textVersion uint64
+	// base is the document row of lines[0] (non-zero only for lazy windows).
+	base int
 
 	wrapMode     WrapMode
 	tabWidth     int
@@ -21,6 +23,9 @@ type wrapLayoutCacheKey struct {
 type wrapLayoutRow struct {
 	logicalRow   int
 	segmentIndex int
+	// ghostLine is the 1-based index into the line's ghostLines of a virtual
+	// ghost row, which follows the last segment; 0 for document rows.
+	ghostLine int
 }
 
 type wrapLayoutLine struct {
@@ -31,6 +36,9 @@ type wrapLayoutLine struct {
 
 	segments       []wrappedSegment
 	firstVisualRow int
+	// ghostLines are ghost text lines rendered as virtual rows after segments.
+	ghostLines    []string
+	ghostStyleKey string
 
 	linksResolved       bool
 	visibleInfo         visibleLineInfo
@@ -40,6 +48,10 @@ type wrapLayoutLine struct {
 type wrapLayoutCache struct {
 	valid bool
 	key   wrapLayoutCacheKey
+	// base is the document row (and visual row) of lines[0] and rows[0].
+	base int
+	// windowed marks a lazy buffer window where visual rows equal document rows.
+	windowed bool
 
 	lines []wrapLayoutLine
 	rows  []wrapLayoutRow
@@ -57,6 +69,7 @@ func (m *Model) layoutKey(lines []string) wrapLayoutCacheKey {
 		focused:      m.focused,
 		linkProvider: providerPtr(m.cfg.LinkProvider),
 		linkSet:      m.cfg.LinkProvider != nil,
+		base:         m.windowBase(),
 	}
 	if m.buf == nil {
 		return key
@@ -73,15 +86,18 @@ func (m *Model) ensureLayoutCache(lines []string) wrapLayoutCache {
 	}
 
 	cache := wrapLayoutCache{
-		valid: true,
-		key:   key,
-		lines: make([]wrapLayoutLine, 0, len(lines)),
-		rows:  make([]wrapLayoutRow, 0, len(lines)),
+		valid:    true,
+		key:      key,
+		base:     key.base,
+		windowed: m.windowed(),
+		lines:    make([]wrapLayoutLine, 0, len(lines)),
+		rows:     make([]wrapLayoutRow, 0, len(lines)),
 	}
 
-	for row, rawLine := range lines {
+	for i, rawLine := range lines {
+		row := key.base + i
 		line := m.buildLayoutLineNoLinks(row, rawLine, key.contentWidth)
-		firstVisualRow := len(cache.rows)
+		firstVisualRow := key.base + len(cache.rows)
 		line.firstVisualRow = firstVisualRow
 		cache.lines = append(cache.lines, line)
 		for segIdx := range line.segments {
@@ -90,6 +106,13 @@ func (m *Model) ensureLayoutCache(lines []string) wrapLayoutCache {
 				segmentIndex: segIdx,
 			})
 		}
+		for i := range line.ghostLines {
+			cache.rows = append(cache.rows, wrapLayoutRow{
+				logicalRow:   row,
+				segmentIndex: len(line.segments) - 1,
+				ghostLine:    i + 1,
+			})
+		}
 	}
 
 	// Keep a stable zero state when the document is unexpectedly empty.
@@ -140,11 +163,14 @@ func (m *Model) buildLayoutLineNoLinks(row int, rawLine string, contentWidth int
 		}}
 	}
 
+	ghostLines, ghostStyleKey := m.ghostRowsFor(row, rawLine)
 	return wrapLayoutLine{
-		rawLine:  rawLine,
-		vt:       vt,
-		visual:   visual,
-		segments: segments,
+		rawLine:       rawLine,
+		vt:            vt,
+		visual:        visual,
+		segments:      segments,
+		ghostLines:    ghostLines,
+		ghostStyleKey: ghostStyleKey,
 	}
 }
 
@@ -161,7 +187,7 @@ func (m *Model) resolveLinksForVisibleRows(lines []string, cache *wrapLayoutCach
 		return
 	}
 	cursor := m.buf.Cursor()
-	start := clampInt(metrics.yOffset, 0, len(cache.rows))
+	start := clampInt(metrics.yOffset-cache.base, 0, len(cache.rows))
 	end := start + h
 	if end > len(cache.rows) {
 		end = len(cache.rows)
@@ -173,7 +199,7 @@ func (m *Model) resolveLinksForVisibleRows(lines []string, cache *wrapLayoutCach
 			continue
 		}
 		seen[row] = true
-		line := &cache.lines[row]
+		line := &cache.lines[row-cache.base]
 		if !line.visibleInfoComputed {
 			line.visibleInfo = computeVisibleLineInfo(line.rawLine, line.vt)
 			line.visibleInfoComputed = true
@@ -190,7 +216,7 @@ func (m *Model) refreshLayoutRows(lines []string, dirtyRows map[int]struct{}) bo
 	if len(dirtyRows) == 0 {
 		return true
 	}
-	if !m.layout.valid || len(lines) != len(m.layout.lines) {
+	if !m.layout.valid || m.layout.windowed || len(lines) != len(m.layout.lines) {
 		return false
 	}
 
@@ -207,7 +233,7 @@ func (m *Model) refreshLayoutRows(lines []string, dirtyRows map[int]struct{}) bo
 		prev := m.layout.lines[row]
 		next := m.buildLayoutLine(row, lines[row], contentWidth)
 		next.firstVisualRow = prev.firstVisualRow
-		if len(prev.segments) != len(next.segments) {
+		if len(prev.segments) != len(next.segments) || len(prev.ghostLines) != len(next.ghostLines) {
 			return false
 		}
 		m.layout.lines[row] = next
@@ -217,9 +243,9 @@ func (m *Model) refreshLayoutRows(lines []string, dirtyRows map[int]struct{}) bo
 
 func (c wrapLayoutCache) clampVisualRow(row int) int {
 	if len(c.rows) == 0 {
-		return 0
+		return c.base
 	}
-	return clampInt(row, 0, len(c.rows)-1)
+	return clampInt(row, c.base, c.base+len(c.rows)-1)
 }
 
 func (c wrapLayoutCache) rowAt(visualRow int) (wrapLayoutRow, bool) {
@@ -227,7 +253,7 @@ func (c wrapLayoutCache) rowAt(visualRow int) (wrapLayoutRow, bool) {
 		return wrapLayoutRow{}, false
 	}
 	visualRow = c.clampVisualRow(visualRow)
-	return c.rows[visualRow], true
+	return c.rows[visualRow-c.base], true
 }
 
 func (c wrapLayoutCache) lineAndSegmentAt(visualRow int) (lineIdx int, line wrapLayoutLine, seg wrappedSegment, segIdx int, ok bool) {
@@ -235,11 +261,12 @@ func (c wrapLayoutCache) lineAndSegmentAt(visualRow int) (lineIdx int, line wrap
 	if !ok {
 		return 0, wrapLayoutLine{}, wrappedSegment{}, 0, false
 	}
-	if ref.logicalRow < 0 || ref.logicalRow >= len(c.lines) {
+	idx := ref.logicalRow - c.base
+	if idx < 0 || idx >= len(c.lines) {
 		return 0, wrapLayoutLine{}, wrappedSegment{}, 0, false
 	}
 
-	line = c.lines[ref.logicalRow]
+	line = c.lines[idx]
 	if ref.segmentIndex < 0 || ref.segmentIndex >= len(line.segments) {
 		return 0, wrapLayoutLine{}, wrappedSegment{}, 0, false
 	}
@@ -251,7 +278,12 @@ func (c wrapLayoutCache) cursorVisualPosition(cursor buffer.Pos) (visualRow int,
 		return 0, 0, false
 	}
 
-	lineIdx := clampInt(cursor.Row, 0, len(c.lines)-1)
+	lineIdx := cursor.Row - c.base
+	if c.windowed && (lineIdx < 0 || lineIdx >= len(c.lines)) {
+		// Rows outside a lazy window are unwrapped; the column is unknown here.
+		return cursor.Row, 0, true
+	}
+	lineIdx = clampInt(lineIdx, 0, len(c.lines)-1)
 	line := c.lines[lineIdx]
 	if len(line.segments) == 0 {
 		return line.firstVisualRow, 0, true
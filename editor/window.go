@@ -0,0 +1,142 @@
+package editor
+
+import tea "charm.land/bubbletea/v2"
+
+// Lazily backed buffers (buffer.NewLazy) are rendered through a row window:
+// ensureLines returns only the rows visible at windowTop, the layout cache is
+// offset by that base, and the viewport holds just the window content. WrapNone
+// is forced in this mode, so visual rows map 1:1 to document rows. Scroll
+// extents cover only the rows indexed so far (see windowLineCount), so the
+// source is read as the window and cursor approach its end rather than up
+// front.
+
+// windowed reports whether the model renders a lazy buffer row window.
+func (m *Model) windowed() bool {
+	return m.buf != nil && m.buf.IsLazy()
+}
+
+// topVisualRow returns the visual row rendered at viewport screen row 0.
+func (m *Model) topVisualRow() int {
+	if m.windowed() {
+		return m.windowTop
+	}
+	return m.viewport.YOffset()
+}
+
+// setTopVisualRow scrolls so row is rendered at viewport screen row 0.
+// Callers clamp row to the scrollable range.
+func (m *Model) setTopVisualRow(row int) {
+	if !m.windowed() {
+		m.viewport.SetYOffset(row)
+		return
+	}
+	row = max(row, 0)
+	if row == m.windowTop {
+		return
+	}
+	m.windowTop = row
+	m.rebuildContent()
+}
+
+// windowHeight is the number of document rows loaded for the window.
+func (m *Model) windowHeight() int {
+	return max(m.viewport.Height()-m.viewport.Style.GetVerticalFrameSize(), 1)
+}
+
+func (m *Model) ensureWindowLines() []string {
+	ver := m.buf.TextVersion()
+	top, n := m.windowTop, m.windowHeight()
+	if m.cachedLines != nil && m.cachedLinesVer == ver && m.cachedLinesTop == top && m.cachedLinesLen == n {
+		return m.cachedLines
+	}
+	lines := m.buf.RawLinesInRange(top, top+n)
+	if lines == nil {
+		lines = []string{}
+	}
+	m.cachedLines = lines
+	m.cachedLinesVer = ver
+	m.cachedLinesTop = top
+	m.cachedLinesLen = n
+	return m.cachedLines
+}
+
+// windowBase returns the document row of lines[0] as returned by ensureLines.
+func (m *Model) windowBase() int {
+	if m.windowed() {
+		return m.windowTop
+	}
+	return 0
+}
+
+// docLineCount returns the document line count for lines returned by
+// ensureLines.
+func (m *Model) docLineCount(lines []string) int {
+	if m.windowed() {
+		return m.windowLineCount()
+	}
+	return len(lines)
+}
+
+// windowLineCount returns the rows of a lazy buffer indexed so far, after
+// growing the index a window height past the window and the cursor, so
+// scrolling and cursor motion always have rows ahead to reach.
+func (m *Model) windowLineCount() int {
+	h := m.windowHeight()
+	m.buf.IndexLines(max(m.windowTop+h, m.buf.Cursor().Row) + h)
+	n, _ := m.buf.IndexedLineCount()
+	return max(n, 1)
+}
+
+// docLine returns the raw text of a document row.
+func (m *Model) docLine(row int) (string, bool) {
+	if m.buf == nil {
+		return "", false
+	}
+	lines := m.ensureLines()
+	if idx := row - m.windowBase(); idx >= 0 && idx < len(lines) {
+		return lines[idx], true
+	}
+	if !m.windowed() {
+		return "", false
+	}
+	out := m.buf.RawLinesInRange(row, row+1)
+	if len(out) == 0 {
+		return "", false
+	}
+	return out[0], true
+}
+
+// windowScrollExtents measures scroll extents for a lazy buffer window.
+// Columns are measured over the loaded window only.
+func (m *Model) windowScrollExtents(lines []string) (totalRows, totalCols int) {
+	base := m.windowBase()
+	for i, rawLine := range lines {
+		vt := m.virtualTextForRow(base+i, rawLine)
+		vt = m.virtualTextWithGhost(base+i, rawLine, vt)
+		if w := BuildVisualLine(rawLine, vt, m.cfg.TabWidth).VisualLen(); w > totalCols {
+			totalCols = w
+		}
+	}
+	return m.windowLineCount(), totalCols
+}
+
+// scrollWindowWheel applies vertical wheel scrolling to a lazy buffer window.
+// The viewport cannot scroll it because it only holds the window rows.
+func (m *Model) scrollWindowWheel(msg tea.MouseWheelMsg) {
+	if !m.viewport.MouseWheelEnabled {
+		return
+	}
+	delta := 0
+	switch msg.Button { //nolint:exhaustive
+	case tea.MouseWheelDown:
+		delta = m.viewport.MouseWheelDelta
+	case tea.MouseWheelUp:
+		delta = -m.viewport.MouseWheelDelta
+	default:
+		return
+	}
+	lines := m.ensureLines()
+	metrics := m.resolveScrollbarMetrics(lines, m.ensureLayoutCache(lines))
+	maxTop := max(metrics.totalRows-metrics.contentHeight, 0)
+	m.setTopVisualRow(clampInt(metrics.yOffset+delta, 0, maxTop))
+}
@@ -0,0 +1,201 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func newLazyTestModel(t *testing.T, lineCount int, cfg Config) Model {
+	t.Helper()
+	var sb strings.Builder
+	for i := 0; i < lineCount; i++ {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "line %d", i)
+	}
+	text := sb.String()
+	cfg.Buffer = buffer.NewLazy(strings.NewReader(text), int64(len(text)), buffer.LazyOptions{CacheLines: 16})
+	return New(cfg)
+}
+
+func TestLazyBuffer_ForcesReadOnlyAndWrapNone(t *testing.T) {
+	m := newLazyTestModel(t, 10, Config{WrapMode: WrapWord})
+	m = m.SetSize(20, 3)
+
+	if !m.cfg.ReadOnly || m.cfg.WrapMode != WrapNone {
+		t.Fatalf("lazy config: ReadOnly=%v WrapMode=%v, want true/WrapNone", m.cfg.ReadOnly, m.cfg.WrapMode)
+	}
+
+	m, _ = m.Update(testKeyText("x"))
+	m, _ = m.Update(testKeyCode(tea.KeyEnter))
+	if got := m.Buffer().TextVersion(); got != 0 {
+		t.Fatalf("text version after typing: got %d, want 0", got)
+	}
+}
+
+func TestLazyBuffer_RendersOnlyWindowRows(t *testing.T) {
+	m := newLazyTestModel(t, 1000, Config{Gutter: LineNumberGutter()})
+	m = m.SetSize(20, 3)
+
+	if got := len(m.layout.lines); got != 3 {
+		t.Fatalf("layout lines: got %d, want 3", got)
+	}
+	view := stripANSI(m.View().Content)
+	if !strings.Contains(view, "   1 line 0") || !strings.Contains(view, "   3 line 2") {
+		t.Fatalf("initial window view:\n%s", view)
+	}
+
+	m, _ = m.Update(testKeyCode(tea.KeyPgDown))
+	m, _ = m.Update(testKeyCode(tea.KeyPgDown))
+	st := m.ViewportState()
+	cur := m.Buffer().Cursor()
+	if cur.Row < st.TopVisualRow || cur.Row >= st.TopVisualRow+st.VisibleRows {
+		t.Fatalf("cursor row %d outside window %+v", cur.Row, st)
+	}
+	if got := len(m.layout.lines); got != 3 {
+		t.Fatalf("layout lines after paging: got %d, want 3", got)
+	}
+}
+
+func TestLazyBuffer_FollowsCursorToDocumentEnd(t *testing.T) {
+	m := newLazyTestModel(t, 500, Config{})
+	m = m.SetSize(20, 4)
+
+	m.Buffer().Move(buffer.Move{Unit: buffer.MoveDoc, Dir: buffer.DirEnd})
+	m, _ = m.Update(nil)
+
+	if got, want := m.ViewportState().TopVisualRow, 496; got != want {
+		t.Fatalf("top row at doc end: got %d, want %d", got, want)
+	}
+	view := stripANSI(m.View().Content)
+	if !strings.Contains(view, "line 499") || strings.Contains(view, "line 495") {
+		t.Fatalf("doc end view:\n%s", view)
+	}
+
+	snap := m.RenderSnapshot()
+	if len(snap.Rows) != 4 {
+		t.Fatalf("snapshot rows: got %d, want 4", len(snap.Rows))
+	}
+	for i, row := range snap.Rows {
+		if row.ScreenRow != i || row.DocRow != 496+i {
+			t.Fatalf("snapshot row %d: got %+v", i, row)
+		}
+	}
+	if pos, ok := m.ScreenToDocWithSnapshot(snap, 2, 1); !ok || pos != (buffer.Pos{Row: 497, GraphemeCol: 2}) {
+		t.Fatalf("ScreenToDocWithSnapshot: got %v ok=%v", pos, ok)
+	}
+}
+
+func TestLazyBuffer_DocToScreenOutsideWindow(t *testing.T) {
+	m := newLazyTestModel(t, 100, Config{})
+	m = m.SetSize(20, 3)
+
+	if x, y, ok := m.DocToScreen(buffer.Pos{Row: 1, GraphemeCol: 2}); !ok || x != 2 || y != 1 {
+		t.Fatalf("DocToScreen in window: got (%d,%d,%v), want (2,1,true)", x, y, ok)
+	}
+	if _, _, ok := m.DocToScreen(buffer.Pos{Row: 50, GraphemeCol: 0}); ok {
+		t.Fatalf("DocToScreen below window should not be visible")
+	}
+}
+
+func TestLazyBuffer_MouseWheelScrollsWindow(t *testing.T) {
+	m := newLazyTestModel(t, 100, Config{})
+	m = m.SetSize(20, 3)
+
+	m, _ = m.Update(testMouseWheel(0, 0, tea.MouseWheelDown))
+	top := m.ViewportState().TopVisualRow
+	if top <= 0 {
+		t.Fatalf("top row after wheel: got %d, want > 0", top)
+	}
+	if got, want := m.ScreenToDoc(0, 0), (buffer.Pos{Row: top}); got != want {
+		t.Fatalf("ScreenToDoc after wheel: got %v, want %v", got, want)
+	}
+	if view := stripANSI(m.View().Content); !strings.Contains(view, fmt.Sprintf("line %d", top)) {
+		t.Fatalf("view after wheel:\n%s", view)
+	}
+}
+
+func TestLazyBuffer_FindScrollsToMatch(t *testing.T) {
+	m := newLazyTestModel(t, 300, Config{})
+	m = m.SetSize(20, 3)
+
+	m, ok := m.Find("line 250", buffer.FindOptions{})
+	if !ok {
+		t.Fatalf("expected match")
+	}
+	sel, selOK := m.Buffer().Selection()
+	want := buffer.Range{Start: buffer.Pos{Row: 250}, End: buffer.Pos{Row: 250, GraphemeCol: 8}}
+	if !selOK || sel != want {
+		t.Fatalf("selection: got %v ok=%v, want %v", sel, selOK, want)
+	}
+	if st := m.ViewportState(); 250 < st.TopVisualRow || 250 >= st.TopVisualRow+st.VisibleRows {
+		t.Fatalf("match row outside viewport: %+v", st)
+	}
+}
+
+func TestLazyBuffer_IndexesRowsAsWindowMoves(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	text := sb.String()
+	buf := buffer.NewLazy(strings.NewReader(text), int64(len(text)), buffer.LazyOptions{ChunkSize: 64})
+	m := New(Config{Buffer: buf, Gutter: LineNumberGutter()}).SetSize(20, 3)
+	_ = m.View()
+	m, _ = m.Update(testKeyCode(tea.KeyDown))
+	if _, ok := m.Find("line 9", buffer.FindOptions{}); !ok {
+		t.Fatalf("expected match")
+	}
+
+	n, complete := buf.IndexedLineCount()
+	if complete || n >= 100 {
+		t.Fatalf("indexed %d rows (complete=%v) before scrolling", n, complete)
+	}
+
+	for range 100 {
+		m, _ = m.Update(testMouseWheel(0, 0, tea.MouseWheelDown))
+	}
+	if top := m.ViewportState().TopVisualRow; top <= n {
+		t.Fatalf("top row %d should scroll past the first %d indexed rows", top, n)
+	}
+	if _, complete = buf.IndexedLineCount(); complete {
+		t.Fatalf("scrolling should not index the whole source")
+	}
+}
+
+func TestFind_StepsThroughMatches(t *testing.T) {
+	m := New(Config{Text: "ab ab\nab"})
+	m = m.SetSize(20, 3)
+
+	var starts []buffer.Pos
+	for i := 0; i < 4; i++ {
+		var ok bool
+		m, ok = m.Find("ab", buffer.FindOptions{Wrap: true})
+		if !ok {
+			t.Fatalf("find %d: expected match", i)
+		}
+		sel, _ := m.Buffer().Selection()
+		starts = append(starts, sel.Start)
+	}
+	want := []buffer.Pos{{Row: 0, GraphemeCol: 0}, {Row: 0, GraphemeCol: 3}, {Row: 1, GraphemeCol: 0}, {Row: 0, GraphemeCol: 0}}
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Fatalf("match %d: got %v, want %v", i, starts[i], want[i])
+		}
+	}
+
+	m, ok := m.Find("ab", buffer.FindOptions{Backward: true, Wrap: true})
+	if sel, _ := m.Buffer().Selection(); !ok || sel.Start != (buffer.Pos{Row: 1, GraphemeCol: 0}) {
+		t.Fatalf("backward wrap: got %v ok=%v", sel, ok)
+	}
+
+	if _, ok := m.Find("zz", buffer.FindOptions{}); ok {
+		t.Fatalf("expected no match")
+	}
+}
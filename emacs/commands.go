@@ -0,0 +1,371 @@
+package emacs
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+var moves = map[command]buffer.Move{
+	cmdForwardChar:       {Unit: buffer.MoveGrapheme, Dir: buffer.DirRight},
+	cmdBackwardChar:      {Unit: buffer.MoveGrapheme, Dir: buffer.DirLeft},
+	cmdNextLine:          {Unit: buffer.MoveGrapheme, Dir: buffer.DirDown},
+	cmdPreviousLine:      {Unit: buffer.MoveGrapheme, Dir: buffer.DirUp},
+	cmdForwardWord:       {Unit: buffer.MoveWord, Dir: buffer.DirRight},
+	cmdBackwardWord:      {Unit: buffer.MoveWord, Dir: buffer.DirLeft},
+	cmdLineStart:         {Unit: buffer.MoveLine, Dir: buffer.DirHome},
+	cmdLineEnd:           {Unit: buffer.MoveLine, Dir: buffer.DirEnd},
+	cmdBufferStart:       {Unit: buffer.MoveDoc, Dir: buffer.DirHome},
+	cmdBufferEnd:         {Unit: buffer.MoveDoc, Dir: buffer.DirEnd},
+	cmdForwardParagraph:  {Unit: buffer.MoveParagraph, Dir: buffer.DirDown},
+	cmdBackwardParagraph: {Unit: buffer.MoveParagraph, Dir: buffer.DirUp},
+	cmdScrollDown:        {Unit: buffer.MoveLine, Dir: buffer.DirDown},
+	cmdScrollUp:          {Unit: buffer.MoveLine, Dir: buffer.DirUp},
+}
+
+// isKill reports whether a command adds to the kill ring, so that a kill
+// following it appends to the same entry.
+func isKill(cmd command) bool {
+	switch cmd {
+	case cmdKillLine, cmdKillWord, cmdBackwardKillWord, cmdKillRegion, cmdCopyRegion:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Model) run(cmd command) {
+	b := m.ed.Buffer()
+	beforeText := b.TextVersion()
+
+	if mv, ok := moves[cmd]; ok {
+		if cmd == cmdScrollDown || cmd == cmdScrollUp {
+			mv.Count = max(m.ed.ViewportState().VisibleRows, 1)
+		}
+		m.move(mv)
+		return
+	}
+
+	switch cmd {
+	case cmdSetMark:
+		if m.last == cmdSetMark && m.markActive {
+			m.deactivateMark()
+			return
+		}
+		m.mark, m.markSet, m.markActive = b.Cursor(), true, true
+		m.dispatch(cursorIntent(b.Cursor()))
+	case cmdExchangePointAndMark:
+		if !m.markSet {
+			return
+		}
+		cur, mark := b.Cursor(), m.clamp(m.mark)
+		m.mark, m.markActive = cur, true
+		m.dispatch(selectIntent(mark, buffer.Range{Start: cur, End: mark}))
+	case cmdMarkWholeBuffer:
+		last := b.LineCount() - 1
+		end := buffer.Pos{Row: last, GraphemeCol: len(m.line(last))}
+		m.mark, m.markSet, m.markActive = end, true, true
+		m.dispatch(selectIntent(buffer.Pos{}, buffer.Range{Start: end}))
+	case cmdQuit:
+		m.deactivateMark()
+
+	case cmdDeleteChar:
+		m.dispatch(editor.Intent{Kind: editor.IntentDelete, Payload: editor.DeleteIntentPayload{Direction: editor.DeleteForward}})
+	case cmdKillLine:
+		cur := b.Cursor()
+		end := buffer.Pos{Row: cur.Row, GraphemeCol: len(m.line(cur.Row))}
+		if cur == end && cur.Row < b.LineCount()-1 {
+			end = buffer.Pos{Row: cur.Row + 1}
+		}
+		m.kill(buffer.Range{Start: cur, End: end}, editor.DeleteLineRight, false)
+	case cmdKillWord:
+		cur := b.Cursor()
+		m.kill(buffer.Range{Start: cur, End: m.wordRight(cur)}, editor.DeleteForward, false)
+	case cmdBackwardKillWord:
+		cur := b.Cursor()
+		m.kill(buffer.Range{Start: m.wordLeft(cur), End: cur}, editor.DeleteWordBackward, true)
+	case cmdKillRegion:
+		if r, ok := m.region(); ok {
+			m.kill(r, editor.DeleteSelection, b.Cursor() == r.Start)
+		}
+	case cmdCopyRegion:
+		if r, ok := m.region(); ok {
+			m.ring.push(b.TextInRange(r), isKill(m.last), b.Cursor() == r.Start)
+			m.deactivateMark()
+		}
+	case cmdYank:
+		m.yank()
+	case cmdYankPop:
+		m.yankPop()
+
+	case cmdTransposeChars:
+		m.transposeChars()
+	case cmdTransposeWords:
+		m.transposeWords()
+	case cmdUpcaseWord:
+		m.caseWord(strings.ToUpper)
+	case cmdDowncaseWord:
+		m.caseWord(strings.ToLower)
+	case cmdCapitalizeWord:
+		m.caseWord(capitalize)
+
+	case cmdUndo:
+		if b.CanUndo() {
+			m.dispatch(editor.Intent{Kind: editor.IntentUndo, Payload: editor.UndoIntentPayload{}})
+		}
+	case cmdRedo:
+		if b.CanRedo() {
+			m.dispatch(editor.Intent{Kind: editor.IntentRedo, Payload: editor.RedoIntentPayload{}})
+		}
+	}
+
+	if m.ed.Buffer().TextVersion() != beforeText {
+		m.markActive = false
+	}
+}
+
+// move moves point, extending the selection from the mark while the region
+// is active.
+func (m *Model) move(mv buffer.Move) {
+	if m.markActive {
+		mv.Extend = true
+		m.dispatch(editor.Intent{Kind: editor.IntentSelect, Payload: editor.SelectIntentPayload{Move: mv}})
+		return
+	}
+	m.dispatch(editor.Intent{Kind: editor.IntentMove, Payload: editor.MoveIntentPayload{Move: mv}})
+}
+
+func (m *Model) deactivateMark() {
+	m.markActive = false
+	if _, ok := m.ed.Buffer().Selection(); ok {
+		m.dispatch(cursorIntent(m.ed.Buffer().Cursor()))
+	}
+}
+
+// region returns the text between mark and point. The mark need not be
+// active.
+func (m *Model) region() (buffer.Range, bool) {
+	if !m.markSet {
+		return buffer.Range{}, false
+	}
+	r := buffer.NormalizeRange(buffer.Range{Start: m.clamp(m.mark), End: m.ed.Buffer().Cursor()})
+	return r, !r.IsEmpty()
+}
+
+// kill deletes r and records its text in the kill ring, appending to the
+// previous entry after another kill.
+func (m *Model) kill(r buffer.Range, dir editor.DeleteDirection, backward bool) {
+	text := m.ed.Buffer().TextInRange(r)
+	if text == "" {
+		return
+	}
+	m.ring.push(text, isKill(m.last), backward)
+	m.dispatch(editor.Intent{Kind: editor.IntentDelete, Payload: editor.DeleteIntentPayload{
+		Direction: dir,
+		Edits:     []buffer.TextEdit{{Range: r}},
+	}})
+}
+
+// yank inserts the latest kill at point and sets the mark at its start.
+func (m *Model) yank() {
+	text, ok := m.ring.at(0)
+	if !ok {
+		return
+	}
+	cur := m.ed.Buffer().Cursor()
+	m.dispatch(editor.Intent{Kind: editor.IntentInsert, Payload: editor.InsertIntentPayload{
+		Text:  text,
+		Edits: []buffer.TextEdit{{Range: buffer.Range{Start: cur, End: cur}, Text: text}},
+	}})
+	m.mark, m.markSet, m.markActive = cur, true, false
+	m.yanked = buffer.Range{Start: cur, End: endOf(cur, text)}
+	m.yankIndex = 0
+}
+
+// yankPop replaces the text just yanked with the previous kill ring entry.
+func (m *Model) yankPop() {
+	if m.last != cmdYank && m.last != cmdYankPop {
+		return
+	}
+	text, ok := m.ring.at(m.yankIndex + 1)
+	if !ok {
+		return
+	}
+	m.dispatch(editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{
+		Edits: []buffer.TextEdit{{Range: m.yanked, Text: text}},
+	}})
+	m.yanked.End = endOf(m.yanked.Start, text)
+	m.yankIndex = (m.yankIndex + 1) % len(m.ring.entries)
+}
+
+// transposeChars swaps the graphemes around point and moves point past
+// them; at the line end it swaps the last two.
+func (m *Model) transposeChars() {
+	cur := m.ed.Buffer().Cursor()
+	line := m.line(cur.Row)
+	if len(line) < 2 || cur.GraphemeCol == 0 {
+		return
+	}
+	col := min(cur.GraphemeCol, len(line)-1)
+	m.edit(buffer.Range{
+		Start: buffer.Pos{Row: cur.Row, GraphemeCol: col - 1},
+		End:   buffer.Pos{Row: cur.Row, GraphemeCol: col + 1},
+	}, line[col]+line[col-1])
+}
+
+// transposeWords swaps the word at or after point with the one before it,
+// and moves point past both. Words are runs of non-blank graphemes on the
+// cursor row, as for buffer.MoveWord.
+func (m *Model) transposeWords() {
+	cur := m.ed.Buffer().Cursor()
+	line := m.line(cur.Row)
+	type word struct{ start, end int }
+	var words []word
+	for i := 0; i < len(line); {
+		if grapheme.IsSpace(line[i]) {
+			i++
+			continue
+		}
+		w := word{start: i}
+		for i < len(line) && !grapheme.IsSpace(line[i]) {
+			i++
+		}
+		w.end = i
+		words = append(words, w)
+	}
+	if len(words) < 2 {
+		return
+	}
+	j := len(words) - 1
+	for i, w := range words {
+		if w.end > cur.GraphemeCol {
+			j = max(i, 1)
+			break
+		}
+	}
+	a, b := words[j-1], words[j]
+	text := grapheme.Join(line[b.start:b.end]) + grapheme.Join(line[a.end:b.start]) + grapheme.Join(line[a.start:a.end])
+	m.edit(buffer.Range{
+		Start: buffer.Pos{Row: cur.Row, GraphemeCol: a.start},
+		End:   buffer.Pos{Row: cur.Row, GraphemeCol: b.end},
+	}, text)
+}
+
+// caseWord converts the text from point to the end of the word and moves
+// point there.
+func (m *Model) caseWord(convert func(string) string) {
+	b := m.ed.Buffer()
+	cur := b.Cursor()
+	r := buffer.Range{Start: cur, End: m.wordRight(cur)}
+	text := b.TextInRange(r)
+	if next := convert(text); next != text {
+		m.edit(r, next)
+		return
+	}
+	m.dispatch(cursorIntent(r.End))
+}
+
+func capitalize(s string) string {
+	start := true
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			start = true
+			return r
+		}
+		if start {
+			start = false
+			return unicode.ToUpper(r)
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// edit replaces r with text; point ends after the replacement.
+func (m *Model) edit(r buffer.Range, text string) {
+	m.dispatch(editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{
+		Edits: []buffer.TextEdit{{Range: r, Text: text}},
+	}})
+}
+
+func (m *Model) dispatch(intents ...editor.Intent) {
+	m.ed = m.ed.Dispatch(intents...)
+}
+
+func (m *Model) line(row int) []string {
+	lines := m.ed.Buffer().RawLinesInRange(row, row+1)
+	if len(lines) == 0 {
+		return nil
+	}
+	return grapheme.Split(lines[0])
+}
+
+func (m *Model) clamp(p buffer.Pos) buffer.Pos {
+	return buffer.ClampPos(p, m.ed.Buffer().LineCount(), func(row int) int { return len(m.line(row)) })
+}
+
+// wordRight returns the end of the word after p, where buffer.MoveWord
+// moves: on the next row when p is past the row's last word.
+func (m *Model) wordRight(p buffer.Pos) buffer.Pos {
+	col := wordEnd(m.line(p.Row), p.GraphemeCol)
+	if col != p.GraphemeCol || p.Row >= m.ed.Buffer().LineCount()-1 {
+		return buffer.Pos{Row: p.Row, GraphemeCol: col}
+	}
+	return buffer.Pos{Row: p.Row + 1, GraphemeCol: wordEnd(m.line(p.Row+1), 0)}
+}
+
+// wordLeft returns the start of the word before p, where buffer.MoveWord
+// moves: on the previous row when p is before the row's first word.
+func (m *Model) wordLeft(p buffer.Pos) buffer.Pos {
+	col := wordStart(m.line(p.Row), p.GraphemeCol)
+	if col != p.GraphemeCol || p.Row == 0 {
+		return buffer.Pos{Row: p.Row, GraphemeCol: col}
+	}
+	prev := m.line(p.Row - 1)
+	return buffer.Pos{Row: p.Row - 1, GraphemeCol: wordStart(prev, len(prev))}
+}
+
+func wordEnd(line []string, col int) int {
+	col = min(max(col, 0), len(line))
+	for col < len(line) && grapheme.IsSpace(line[col]) {
+		col++
+	}
+	for col < len(line) && !grapheme.IsSpace(line[col]) {
+		col++
+	}
+	return col
+}
+
+func wordStart(line []string, col int) int {
+	col = min(max(col, 0), len(line))
+	for col > 0 && grapheme.IsSpace(line[col-1]) {
+		col--
+	}
+	for col > 0 && !grapheme.IsSpace(line[col-1]) {
+		col--
+	}
+	return col
+}
+
+// endOf returns where text inserted at start ends.
+func endOf(start buffer.Pos, text string) buffer.Pos {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return buffer.Pos{Row: start.Row, GraphemeCol: start.GraphemeCol + grapheme.Count(text)}
+	}
+	return buffer.Pos{Row: start.Row + len(lines) - 1, GraphemeCol: grapheme.Count(lines[len(lines)-1])}
+}
+
+func cursorIntent(p buffer.Pos) editor.Intent {
+	return editor.Intent{Kind: editor.IntentSetCursor, Payload: editor.SetCursorIntentPayload{Cursor: p}}
+}
+
+func selectIntent(cursor buffer.Pos, sel buffer.Range) editor.Intent {
+	return editor.Intent{Kind: editor.IntentSetCursor, Payload: editor.SetCursorIntentPayload{
+		Cursor:    cursor,
+		Selection: sel,
+		Select:    true,
+	}}
+}
@@ -0,0 +1,8 @@
+// Package emacs layers emacs-style editing commands over editor.Model.
+//
+// Model adds the mark and region, a kill ring with yank and yank-pop,
+// transpose and case commands, and prefix key sequences such as
+// "ctrl+x ctrl+x". Commands run as editor intents through
+// editor.Model.Dispatch, so hosts using intent modes observe semantic
+// actions rather than raw keys; unbound keys go to the editor.
+package emacs
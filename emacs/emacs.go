@@ -0,0 +1,169 @@
+package emacs
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// Config configures an emacs Model.
+type Config struct {
+	// Editor configures the wrapped editor. Keys the emacs KeyMap does not
+	// bind go to the editor's own KeyMap.
+	Editor editor.Config
+	// KeyMap defaults to DefaultKeyMap when it has no bindings.
+	KeyMap KeyMap
+	// KillRingMax bounds the kill ring. Default is DefaultKillRingMax.
+	KillRingMax int
+}
+
+// Model is a Bubble Tea model that wraps editor.Model with emacs editing
+// commands.
+//
+// Keys bound in the KeyMap run as intents through editor.Model.Dispatch;
+// all other keys, mouse input and messages go to the editor unchanged.
+//
+// Copies of a Model share its kill ring.
+type Model struct {
+	ed   editor.Model
+	cfg  Config
+	ring *killRing
+
+	// chord holds the leading keys of a sequence binding.
+	chord editor.ChordTracker
+
+	// mark is set by set-mark and yank; the region between mark and point is
+	// mirrored as the editor selection while active.
+	mark       buffer.Pos
+	markSet    bool
+	markActive bool
+
+	// last is the previous command, for appending kills and yank-pop.
+	last command
+	// yanked is the text inserted by the last yank or yank-pop, and
+	// yankIndex its kill ring entry.
+	yanked    buffer.Range
+	yankIndex int
+}
+
+// New returns a Model.
+func New(cfg Config) Model {
+	if allBindingsZero(cfg.KeyMap) {
+		cfg.KeyMap = DefaultKeyMap()
+	}
+	if cfg.KillRingMax <= 0 {
+		cfg.KillRingMax = DefaultKillRingMax
+	}
+	return Model{
+		ed:   editor.New(cfg.Editor),
+		cfg:  cfg,
+		ring: &killRing{max: cfg.KillRingMax},
+	}
+}
+
+func allBindingsZero(km KeyMap) bool {
+	for _, bc := range km.commands() {
+		if len(bc.binding.Keys()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Editor returns the wrapped editor.
+func (m Model) Editor() editor.Model { return m.ed }
+
+// SetEditor replaces the wrapped editor, for example after calling editor
+// methods that return an updated model. The mark is deactivated.
+func (m Model) SetEditor(ed editor.Model) Model {
+	m.ed = ed
+	m.markActive = false
+	return m
+}
+
+// SetSize sets the editor size.
+func (m Model) SetSize(width, height int) Model {
+	m.ed = m.ed.SetSize(width, height)
+	return m
+}
+
+// Focus focuses the editor.
+func (m Model) Focus() Model {
+	m.ed = m.ed.Focus()
+	return m
+}
+
+// Blur blurs the editor and cancels a pending key sequence.
+func (m Model) Blur() Model {
+	m.ed = m.ed.Blur()
+	m.chord.Reset()
+	return m
+}
+
+// Pending returns the prefix keys of a sequence still being typed (for
+// example "ctrl+x"), for display in a status line. It includes chords of
+// the wrapped editor's key maps; see editor.Model.PendingChord.
+func (m Model) Pending() string {
+	if p := m.chord.Pending(); p != "" {
+		return p
+	}
+	return m.ed.PendingChord()
+}
+
+// Mark returns the mark and whether the region is active. ok is false until
+// a mark is set.
+func (m Model) Mark() (mark buffer.Pos, active, ok bool) {
+	return m.mark, m.markActive, m.markSet
+}
+
+// KillRing returns the kill ring entries, most recent first.
+func (m Model) KillRing() []string {
+	return append([]string(nil), m.ring.entries...)
+}
+
+func (m Model) Init() tea.Cmd { return m.ed.Init() }
+
+func (m Model) View() tea.View { return m.ed.View() }
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if m.chord.Expire(msg) {
+		return m, nil
+	}
+	k, ok := msg.(tea.KeyPressMsg)
+	if !ok || !m.ed.Focused() || m.ed.Buffer() == nil {
+		return m.forward(msg)
+	}
+
+	// An unbound sequence is dropped whole, so its last key is not typed.
+	typed, tick, ok := m.chord.Resolve(k, m.cfg.Editor.ChordTimeout, m.cfg.KeyMap.bindings())
+	if !ok {
+		return m, tick
+	}
+	if cmd := m.cfg.KeyMap.lookup(typed); cmd != cmdNone {
+		(&m).run(cmd)
+		m.last = cmd
+		return m, nil
+	}
+	return m.forward(msg)
+}
+
+// forward passes msg to the editor. Input that changes the cursor, selection
+// or text deactivates the mark, as the selection no longer mirrors the
+// region.
+func (m Model) forward(msg tea.Msg) (Model, tea.Cmd) {
+	var version uint64
+	if b := m.ed.Buffer(); b != nil {
+		version = b.Version()
+	}
+	var cmd tea.Cmd
+	m.ed, cmd = m.ed.Update(msg)
+	switch msg.(type) {
+	case tea.KeyPressMsg, tea.MouseMsg, tea.PasteMsg:
+		m.last = cmdNone
+	}
+	if b := m.ed.Buffer(); b != nil && b.Version() != version {
+		m.markActive = false
+	}
+	return m, cmd
+}
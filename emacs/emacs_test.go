@@ -0,0 +1,250 @@
+package emacs
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// press builds a key press from its name, for example "a", "ctrl+x",
+// "alt+<" or "alt+backspace".
+func press(t *testing.T, name string) tea.KeyPressMsg {
+	t.Helper()
+	var mod tea.KeyMod
+	for {
+		switch {
+		case strings.HasPrefix(name, "ctrl+") && len(name) > len("ctrl+"):
+			mod |= tea.ModCtrl
+			name = name[len("ctrl+"):]
+			continue
+		case strings.HasPrefix(name, "alt+") && len(name) > len("alt+"):
+			mod |= tea.ModAlt
+			name = name[len("alt+"):]
+			continue
+		}
+		break
+	}
+	switch name {
+	case "space":
+		return tea.KeyPressMsg{Code: tea.KeySpace, Mod: mod}
+	case "backspace":
+		return tea.KeyPressMsg{Code: tea.KeyBackspace, Mod: mod}
+	case "right":
+		return tea.KeyPressMsg{Code: tea.KeyRight, Mod: mod}
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	if size != len(name) {
+		t.Fatalf("unknown key %q", name)
+	}
+	if mod == 0 {
+		return tea.KeyPressMsg{Code: r, Text: name}
+	}
+	return tea.KeyPressMsg{Code: r, Mod: mod}
+}
+
+// feed presses space-separated keys.
+func feed(t *testing.T, m Model, keys string) Model {
+	t.Helper()
+	for _, name := range strings.Fields(keys) {
+		m, _ = m.Update(press(t, name))
+	}
+	return m
+}
+
+func newModel(text string) Model {
+	return New(Config{Editor: editor.Config{Text: text}}).Focus()
+}
+
+func assertState(t *testing.T, m Model, text string, cursor buffer.Pos) {
+	t.Helper()
+	b := m.Editor().Buffer()
+	if got := b.Text(); got != text {
+		t.Fatalf("text=%q, want %q", got, text)
+	}
+	if got := b.Cursor(); got != cursor {
+		t.Fatalf("cursor=%v, want %v", got, cursor)
+	}
+}
+
+func pos(row, col int) buffer.Pos { return buffer.Pos{Row: row, GraphemeCol: col} }
+
+func TestKillLine_AppendsConsecutiveKillsAndYanks(t *testing.T) {
+	m := newModel("one two\nthree")
+
+	m = feed(t, m, "ctrl+k ctrl+k ctrl+k")
+	assertState(t, m, "", pos(0, 0))
+	if ring := m.KillRing(); len(ring) != 1 || ring[0] != "one two\nthree" {
+		t.Fatalf("kill ring=%q", ring)
+	}
+
+	m = feed(t, m, "ctrl+y")
+	assertState(t, m, "one two\nthree", pos(1, 5))
+	if mark, active, ok := m.Mark(); !ok || active || mark != pos(0, 0) {
+		t.Fatalf("yank should set an inactive mark at its start, got %v active=%v ok=%v", mark, active, ok)
+	}
+}
+
+func TestBackwardKillWord_Prepends(t *testing.T) {
+	m := newModel("foo bar baz")
+	m = feed(t, m, "ctrl+e alt+backspace alt+backspace")
+	assertState(t, m, "foo ", pos(0, 4))
+	if ring := m.KillRing(); len(ring) != 1 || ring[0] != "bar baz" {
+		t.Fatalf("kill ring=%q", ring)
+	}
+}
+
+func TestYankPop_CyclesKillRing(t *testing.T) {
+	m := newModel("a b c")
+	m = feed(t, m, "alt+d ctrl+f alt+d ctrl+f alt+d")
+	assertState(t, m, "  ", pos(0, 2))
+	if ring := m.KillRing(); len(ring) != 3 || ring[0] != "c" || ring[2] != "a" {
+		t.Fatalf("kill ring=%q", ring)
+	}
+
+	m = feed(t, m, "ctrl+y")
+	assertState(t, m, "  c", pos(0, 3))
+	m = feed(t, m, "alt+y")
+	assertState(t, m, "  b", pos(0, 3))
+	m = feed(t, m, "alt+y alt+y")
+	assertState(t, m, "  c", pos(0, 3))
+
+	m = feed(t, m, "ctrl+b alt+y")
+	assertState(t, m, "  c", pos(0, 2))
+}
+
+func TestMarkAndRegion(t *testing.T) {
+	m := newModel("one two three")
+
+	m = feed(t, m, "alt+f ctrl+space alt+f alt+f")
+	if r, ok := m.Editor().Buffer().Selection(); !ok || r != (buffer.Range{Start: pos(0, 3), End: pos(0, 13)}) {
+		t.Fatalf("region=%v %v", r, ok)
+	}
+
+	m = feed(t, m, "ctrl+x")
+	if got := m.Pending(); got != "ctrl+x" {
+		t.Fatalf("pending=%q", got)
+	}
+	m = feed(t, m, "ctrl+x")
+	if m.Pending() != "" || m.Editor().Buffer().Cursor() != pos(0, 3) {
+		t.Fatalf("exchange should move point to the mark, cursor=%v pending=%q", m.Editor().Buffer().Cursor(), m.Pending())
+	}
+	if mark, active, _ := m.Mark(); mark != pos(0, 13) || !active {
+		t.Fatalf("mark=%v active=%v", mark, active)
+	}
+
+	m = feed(t, m, "ctrl+w")
+	assertState(t, m, "one", pos(0, 3))
+	if ring := m.KillRing(); len(ring) != 1 || ring[0] != " two three" {
+		t.Fatalf("kill ring=%q", ring)
+	}
+
+	m = newModel("abc")
+	m = feed(t, m, "ctrl+space ctrl+e ctrl+g")
+	if _, ok := m.Editor().Buffer().Selection(); ok {
+		t.Fatalf("ctrl+g should deactivate the region")
+	}
+	m = feed(t, m, "ctrl+x h alt+w")
+	if ring := m.KillRing(); len(ring) != 1 || ring[0] != "abc" {
+		t.Fatalf("kill ring=%q", ring)
+	}
+	if _, active, _ := m.Mark(); active {
+		t.Fatalf("copy should deactivate the region")
+	}
+}
+
+func TestUnboundSequenceIsDropped(t *testing.T) {
+	m := newModel("")
+	m = feed(t, m, "ctrl+x z a")
+	assertState(t, m, "a", pos(0, 1))
+
+	m = feed(t, m, "ctrl+space right")
+	if _, active, _ := m.Mark(); !active {
+		t.Fatalf("mark should be active")
+	}
+	m = feed(t, m, "b")
+	if _, active, _ := m.Mark(); active {
+		t.Fatalf("typing should deactivate the mark")
+	}
+}
+
+func TestSequenceTimeoutAndEditorChords(t *testing.T) {
+	m := New(Config{Editor: editor.Config{
+		Text:         "ab",
+		ChordTimeout: time.Millisecond,
+		Bindings:     []editor.Binding{{Key: "ctrl+o e", Command: "cursor.lineEnd"}},
+	}}).Focus()
+
+	m, cmd := m.Update(press(t, "ctrl+x"))
+	if cmd == nil || m.Pending() != "ctrl+x" {
+		t.Fatalf("expected a timeout for the pending prefix, pending=%q", m.Pending())
+	}
+	m, _ = m.Update(cmd())
+	if m.Pending() != "" {
+		t.Fatalf("the timeout should cancel the prefix, pending=%q", m.Pending())
+	}
+	m = feed(t, m, "h")
+	assertState(t, m, "hab", pos(0, 1))
+
+	m = feed(t, m, "ctrl+o")
+	if got := m.Pending(); got != "ctrl+o" {
+		t.Fatalf("pending=%q, want the editor chord prefix", got)
+	}
+	m = feed(t, m, "e")
+	assertState(t, m, "hab", pos(0, 3))
+}
+
+func TestTransposeAndCase(t *testing.T) {
+	cases := []struct {
+		name, text, keys, want string
+		cursor                 buffer.Pos
+	}{
+		{"transpose chars", "abcd", "ctrl+f ctrl+t", "bacd", pos(0, 2)},
+		{"transpose chars at line end", "abcd", "ctrl+e ctrl+t", "abdc", pos(0, 4)},
+		{"transpose words", "foo bar baz", "alt+f ctrl+f alt+t", "bar foo baz", pos(0, 7)},
+		{"transpose words at line end", "foo bar baz", "ctrl+e alt+t", "foo baz bar", pos(0, 11)},
+		{"upcase word", "foo bar", "alt+u", "FOO bar", pos(0, 3)},
+		{"downcase word from middle", "FOO BAR", "ctrl+f alt+l", "Foo BAR", pos(0, 3)},
+		{"capitalize word", "foo bAR", "alt+f alt+c", "foo Bar", pos(0, 7)},
+		{"undo sequence", "foo", "alt+u ctrl+x u", "foo", pos(0, 0)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := feed(t, newModel(tc.text), tc.keys)
+			assertState(t, m, tc.want, tc.cursor)
+		})
+	}
+}
+
+func TestIntentMode_HostSeesSemanticIntents(t *testing.T) {
+	var kinds []editor.IntentKind
+	m := New(Config{Editor: editor.Config{
+		Text:         "one two",
+		MutationMode: editor.EmitIntentsAndMutate,
+		OnIntent: func(batch editor.IntentBatch) editor.IntentDecision {
+			for _, in := range batch.Intents {
+				kinds = append(kinds, in.Kind)
+			}
+			return editor.IntentDecision{ApplyLocally: true}
+		},
+	}}).Focus()
+
+	m = feed(t, m, "ctrl+space alt+f ctrl+w ctrl+y alt+t")
+	want := []editor.IntentKind{
+		editor.IntentSetCursor, editor.IntentSelect, editor.IntentDelete, editor.IntentInsert, editor.IntentEdit,
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("kinds=%v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("kinds=%v, want %v", kinds, want)
+		}
+	}
+	assertState(t, m, "two one", pos(0, 7))
+}
@@ -0,0 +1,173 @@
+package emacs
+
+import (
+	"charm.land/bubbles/v2/key"
+
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// KeyMap defines the emacs key bindings.
+//
+// A binding key may be a sequence of keys separated by spaces (for example
+// "ctrl+x ctrl+x"); its leading keys act as a prefix until the sequence
+// completes or a key outside it is typed. Config.Editor.ChordTimeout also
+// cancels a pending prefix.
+type KeyMap struct {
+	ForwardChar, BackwardChar     key.Binding
+	NextLine, PreviousLine        key.Binding
+	ForwardWord, BackwardWord     key.Binding
+	LineStart, LineEnd            key.Binding
+	BufferStart, BufferEnd        key.Binding
+	ForwardParagraph              key.Binding
+	BackwardParagraph             key.Binding
+	ScrollDown, ScrollUp          key.Binding
+	SetMark, ExchangePointAndMark key.Binding
+	MarkWholeBuffer               key.Binding
+	Quit                          key.Binding
+
+	DeleteChar                     key.Binding
+	KillLine, KillWord             key.Binding
+	BackwardKillWord               key.Binding
+	KillRegion, CopyRegion         key.Binding
+	Yank, YankPop                  key.Binding
+	TransposeChars, TransposeWords key.Binding
+	UpcaseWord, DowncaseWord       key.Binding
+	CapitalizeWord                 key.Binding
+
+	Undo, Redo key.Binding
+}
+
+// command identifies the action a binding runs.
+type command uint8
+
+const (
+	cmdNone command = iota
+	cmdForwardChar
+	cmdBackwardChar
+	cmdNextLine
+	cmdPreviousLine
+	cmdForwardWord
+	cmdBackwardWord
+	cmdLineStart
+	cmdLineEnd
+	cmdBufferStart
+	cmdBufferEnd
+	cmdForwardParagraph
+	cmdBackwardParagraph
+	cmdScrollDown
+	cmdScrollUp
+	cmdSetMark
+	cmdExchangePointAndMark
+	cmdMarkWholeBuffer
+	cmdQuit
+	cmdDeleteChar
+	cmdKillLine
+	cmdKillWord
+	cmdBackwardKillWord
+	cmdKillRegion
+	cmdCopyRegion
+	cmdYank
+	cmdYankPop
+	cmdTransposeChars
+	cmdTransposeWords
+	cmdUpcaseWord
+	cmdDowncaseWord
+	cmdCapitalizeWord
+	cmdUndo
+	cmdRedo
+)
+
+type boundCommand struct {
+	binding key.Binding
+	cmd     command
+}
+
+// commands pairs every binding with its command.
+func (km KeyMap) commands() []boundCommand {
+	return []boundCommand{
+		{km.ForwardChar, cmdForwardChar}, {km.BackwardChar, cmdBackwardChar},
+		{km.NextLine, cmdNextLine}, {km.PreviousLine, cmdPreviousLine},
+		{km.ForwardWord, cmdForwardWord}, {km.BackwardWord, cmdBackwardWord},
+		{km.LineStart, cmdLineStart}, {km.LineEnd, cmdLineEnd},
+		{km.BufferStart, cmdBufferStart}, {km.BufferEnd, cmdBufferEnd},
+		{km.ForwardParagraph, cmdForwardParagraph}, {km.BackwardParagraph, cmdBackwardParagraph},
+		{km.ScrollDown, cmdScrollDown}, {km.ScrollUp, cmdScrollUp},
+		{km.SetMark, cmdSetMark}, {km.ExchangePointAndMark, cmdExchangePointAndMark},
+		{km.MarkWholeBuffer, cmdMarkWholeBuffer},
+		{km.Quit, cmdQuit},
+		{km.DeleteChar, cmdDeleteChar},
+		{km.KillLine, cmdKillLine}, {km.KillWord, cmdKillWord}, {km.BackwardKillWord, cmdBackwardKillWord},
+		{km.KillRegion, cmdKillRegion}, {km.CopyRegion, cmdCopyRegion},
+		{km.Yank, cmdYank}, {km.YankPop, cmdYankPop},
+		{km.TransposeChars, cmdTransposeChars}, {km.TransposeWords, cmdTransposeWords},
+		{km.UpcaseWord, cmdUpcaseWord}, {km.DowncaseWord, cmdDowncaseWord}, {km.CapitalizeWord, cmdCapitalizeWord},
+		{km.Undo, cmdUndo}, {km.Redo, cmdRedo},
+	}
+}
+
+// bindings lists every binding, for editor.ChordTracker.
+func (km KeyMap) bindings() []key.Binding {
+	cmds := km.commands()
+	out := make([]key.Binding, len(cmds))
+	for i, bc := range cmds {
+		out[i] = bc.binding
+	}
+	return out
+}
+
+// lookup returns the command bound to typed, a key or completed sequence
+// from editor.ChordTracker.Resolve.
+func (km KeyMap) lookup(typed string) command {
+	for _, bc := range km.commands() {
+		if editor.ChordMatches(bc.binding, typed) {
+			return bc.cmd
+		}
+	}
+	return cmdNone
+}
+
+// DefaultKeyMap returns the standard emacs bindings. Arrow and page keys
+// are included so they extend an active region like their emacs
+// counterparts.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		ForwardChar:       key.NewBinding(key.WithKeys("ctrl+f", "right"), key.WithHelp("C-f", "forward char")),
+		BackwardChar:      key.NewBinding(key.WithKeys("ctrl+b", "left"), key.WithHelp("C-b", "backward char")),
+		NextLine:          key.NewBinding(key.WithKeys("ctrl+n", "down"), key.WithHelp("C-n", "next line")),
+		PreviousLine:      key.NewBinding(key.WithKeys("ctrl+p", "up"), key.WithHelp("C-p", "previous line")),
+		ForwardWord:       key.NewBinding(key.WithKeys("alt+f", "alt+right", "ctrl+right"), key.WithHelp("M-f", "forward word")),
+		BackwardWord:      key.NewBinding(key.WithKeys("alt+b", "alt+left", "ctrl+left"), key.WithHelp("M-b", "backward word")),
+		LineStart:         key.NewBinding(key.WithKeys("ctrl+a", "home"), key.WithHelp("C-a", "line start")),
+		LineEnd:           key.NewBinding(key.WithKeys("ctrl+e", "end"), key.WithHelp("C-e", "line end")),
+		BufferStart:       key.NewBinding(key.WithKeys("alt+<"), key.WithHelp("M-<", "buffer start")),
+		BufferEnd:         key.NewBinding(key.WithKeys("alt+>"), key.WithHelp("M->", "buffer end")),
+		ForwardParagraph:  key.NewBinding(key.WithKeys("alt+}"), key.WithHelp("M-}", "forward paragraph")),
+		BackwardParagraph: key.NewBinding(key.WithKeys("alt+{"), key.WithHelp("M-{", "backward paragraph")),
+		ScrollDown:        key.NewBinding(key.WithKeys("ctrl+v", "pgdown"), key.WithHelp("C-v", "page down")),
+		ScrollUp:          key.NewBinding(key.WithKeys("alt+v", "pgup"), key.WithHelp("M-v", "page up")),
+
+		// Legacy terminals report ctrl+space as ctrl+@.
+		SetMark:              key.NewBinding(key.WithKeys("ctrl+space", "ctrl+@"), key.WithHelp("C-SPC", "set mark")),
+		ExchangePointAndMark: key.NewBinding(key.WithKeys("ctrl+x ctrl+x"), key.WithHelp("C-x C-x", "exchange point and mark")),
+		MarkWholeBuffer:      key.NewBinding(key.WithKeys("ctrl+x h"), key.WithHelp("C-x h", "mark whole buffer")),
+		Quit:                 key.NewBinding(key.WithKeys("ctrl+g"), key.WithHelp("C-g", "quit")),
+
+		DeleteChar:       key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("C-d", "delete char")),
+		KillLine:         key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("C-k", "kill line")),
+		KillWord:         key.NewBinding(key.WithKeys("alt+d"), key.WithHelp("M-d", "kill word")),
+		BackwardKillWord: key.NewBinding(key.WithKeys("alt+backspace"), key.WithHelp("M-DEL", "backward kill word")),
+		KillRegion:       key.NewBinding(key.WithKeys("ctrl+w"), key.WithHelp("C-w", "kill region")),
+		CopyRegion:       key.NewBinding(key.WithKeys("alt+w"), key.WithHelp("M-w", "copy region")),
+		Yank:             key.NewBinding(key.WithKeys("ctrl+y"), key.WithHelp("C-y", "yank")),
+		YankPop:          key.NewBinding(key.WithKeys("alt+y"), key.WithHelp("M-y", "yank pop")),
+		TransposeChars:   key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("C-t", "transpose chars")),
+		TransposeWords:   key.NewBinding(key.WithKeys("alt+t"), key.WithHelp("M-t", "transpose words")),
+		UpcaseWord:       key.NewBinding(key.WithKeys("alt+u"), key.WithHelp("M-u", "upcase word")),
+		DowncaseWord:     key.NewBinding(key.WithKeys("alt+l"), key.WithHelp("M-l", "downcase word")),
+		CapitalizeWord:   key.NewBinding(key.WithKeys("alt+c"), key.WithHelp("M-c", "capitalize word")),
+
+		// Legacy terminals report ctrl+/ as ctrl+_.
+		Undo: key.NewBinding(key.WithKeys("ctrl+/", "ctrl+_", "ctrl+x u"), key.WithHelp("C-/", "undo")),
+		Redo: key.NewBinding(key.WithKeys("ctrl+?", "ctrl+alt+_"), key.WithHelp("C-?", "redo")),
+	}
+}
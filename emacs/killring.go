@@ -0,0 +1,43 @@
+package emacs
+
+// DefaultKillRingMax is the default number of kill ring entries.
+const DefaultKillRingMax = 60
+
+// killRing holds killed text, most recent first. It is shared by copies of
+// a Model.
+type killRing struct {
+	entries []string
+	max     int
+}
+
+// push records killed text. When appending to the previous kill, forward
+// kills add text after it and backward kills before it.
+func (r *killRing) push(text string, appendKill, backward bool) {
+	if text == "" {
+		return
+	}
+	if appendKill && len(r.entries) > 0 {
+		if backward {
+			r.entries[0] = text + r.entries[0]
+		} else {
+			r.entries[0] += text
+		}
+		return
+	}
+	r.entries = append([]string{text}, r.entries...)
+	if len(r.entries) > r.max {
+		r.entries = r.entries[:r.max]
+	}
+}
+
+// at returns the entry n kills back, wrapping around the ring.
+func (r *killRing) at(n int) (string, bool) {
+	if len(r.entries) == 0 {
+		return "", false
+	}
+	n %= len(r.entries)
+	if n < 0 {
+		n += len(r.entries)
+	}
+	return r.entries[n], true
+}
@@ -31,7 +31,7 @@ func (s *intentState) handleIntent(batch editor.IntentBatch) editor.IntentDecisi
 	applyLocally := true
 	for _, in := range batch.Intents {
 		switch in.Kind {
-		case editor.IntentInsert, editor.IntentDelete, editor.IntentUndo, editor.IntentRedo:
+		case editor.IntentInsert, editor.IntentDelete, editor.IntentEdit, editor.IntentUndo, editor.IntentRedo:
 			applyLocally = false
 		}
 	}
@@ -74,6 +74,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.editor = m.editor.SetSize(msg.Width, editorHeight(msg.Height))
 		return m, nil
 	case tea.PasteMsg:
+		// Paste bypasses the intent policy: the host inserts it directly.
 		text := strings.ReplaceAll(msg.Content, "\r\n", "\n")
 		text = strings.ReplaceAll(text, "\r", "\n")
 		if text != "" {
@@ -125,6 +126,8 @@ func intentKindString(kind editor.IntentKind) string {
 		return "undo"
 	case editor.IntentRedo:
 		return "redo"
+	case editor.IntentEdit:
+		return "edit"
 	default:
 		return "unknown"
 	}
@@ -140,6 +143,8 @@ func summarizePayload(in editor.Intent) string {
 		return fmt.Sprintf("move=(unit=%d dir=%d extend=%v)", p.Move.Unit, p.Move.Dir, p.Move.Extend)
 	case editor.SelectIntentPayload:
 		return fmt.Sprintf("move=(unit=%d dir=%d extend=%v)", p.Move.Unit, p.Move.Dir, p.Move.Extend)
+	case editor.EditIntentPayload:
+		return fmt.Sprintf("edits=%d", len(p.Edits))
 	default:
 		return "-"
 	}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/highlight"
+	"github.com/iw2rmb/flourish/theme"
+)
+
+type model struct {
+	editor editor.Model
+}
+
+const sample = `package main
+
+/* Bundled grammars carry state across lines,
+   so this block comment stays highlighted. */
+func main() {
+	msg := "hello\tworld"
+	println(len(msg), 0x2A, true)
+}`
+
+// Usage: go run ./examples/syntax [file]
+// The lexer is chosen by file name; without a file a Go sample is shown.
+func newModel(args []string) (model, error) {
+	text, lexer := sample, highlight.Go()
+	if len(args) > 0 {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return model{}, err
+		}
+		text = string(data)
+		if l, ok := highlight.ForFilename(args[0]); ok {
+			lexer = l
+		}
+	}
+
+	cfg := editor.Config{
+		Text:        text,
+		Gutter:      editor.LineNumberGutter(),
+		Highlighter: highlight.New(lexer, nil),
+	}
+	// Apply wires editor styles, keyed gutter styles, and token styles.
+	cfg = theme.Dark().Apply(cfg)
+	return model{editor: editor.New(cfg)}, nil
+}
+
+func (m model) Init() tea.Cmd { return nil }
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.editor = m.editor.SetSize(msg.Width, msg.Height)
+		return m, nil
+	case tea.KeyPressMsg:
+		if msg.String() == "ctrl+q" {
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+func (m model) View() tea.View { return m.editor.View() }
+
+func main() {
+	m, err := newModel(os.Args[1:])
+	if err == nil {
+		_, err = tea.NewProgram(m).Run()
+	}
+	if err != nil {
+		_, _ = os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,13 @@
+// Package highlight provides a declarative, state-machine lexer and an
+// editor.Highlighter built on it.
+//
+// Grammars are sets of named states holding ordered regex rules; a rule emits
+// a token type and may push or pop states, in the spirit of TextMate and
+// Pygments grammars. Lexing is line-based: the state stack at the end of a
+// line is carried to the next one, so multi-line constructs (block comments,
+// fenced code, heredocs) highlight correctly through
+// editor.StatefulHighlighter. Token types are mapped to lipgloss styles by a
+// Theme.
+//
+// Bundled grammars cover Go, JSON, YAML, Markdown, shell and SQL.
+package highlight
@@ -0,0 +1,51 @@
+package highlight
+
+import "github.com/iw2rmb/flourish/editor"
+
+// Highlighter adapts a Lexer and Theme to editor.StatefulHighlighter.
+type Highlighter struct {
+	lexer *Lexer
+	theme Theme
+}
+
+var _ editor.StatefulHighlighter = (*Highlighter)(nil)
+
+// New returns a highlighter for l. A nil theme uses DefaultTheme.
+func New(l *Lexer, theme Theme) *Highlighter {
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	return &Highlighter{lexer: l, theme: theme}
+}
+
+// Lexer returns the highlighter's lexer.
+func (h *Highlighter) Lexer() *Lexer {
+	return h.lexer
+}
+
+// HighlightLine highlights ctx.Text from the root state. The editor calls it
+// only where line states are unavailable.
+func (h *Highlighter) HighlightLine(ctx editor.LineContext) ([]editor.HighlightSpan, error) {
+	spans, _, err := h.HighlightLineState(ctx, nil)
+	return spans, err
+}
+
+// HighlightLineState highlights ctx.Text starting in prev, which is a State
+// returned for the previous row or nil for the root state.
+func (h *Highlighter) HighlightLineState(ctx editor.LineContext, prev editor.HighlightState) ([]editor.HighlightSpan, editor.HighlightState, error) {
+	st, _ := prev.(State)
+	tokens, next := h.lexer.LexLine(ctx.Text, st)
+	var spans []editor.HighlightSpan
+	for _, tok := range tokens {
+		style, ok := h.theme.Style(tok.Type)
+		if !ok {
+			continue
+		}
+		spans = append(spans, editor.HighlightSpan{
+			StartGraphemeCol: tok.Start,
+			EndGraphemeCol:   tok.End,
+			Style:            style,
+		})
+	}
+	return spans, next, nil
+}
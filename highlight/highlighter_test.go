@@ -0,0 +1,53 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/iw2rmb/flourish/editor"
+)
+
+func TestHighlighter_SpansFollowTheme(t *testing.T) {
+	marker := lipgloss.NewStyle().Bold(true)
+	h := New(Go(), Theme{Keyword: marker})
+
+	spans, st, err := h.HighlightLineState(editor.LineContext{Text: "func /* x"}, nil)
+	if err != nil {
+		t.Fatalf("HighlightLineState: %v", err)
+	}
+	// Only keywords are themed; the unterminated comment carries state.
+	if len(spans) != 1 || spans[0].StartGraphemeCol != 0 || spans[0].EndGraphemeCol != 4 {
+		t.Fatalf("spans: %+v", spans)
+	}
+	if st.(State).Depth() != 1 {
+		t.Fatalf("expected comment state to carry to the next line")
+	}
+
+	spans, _, _ = h.HighlightLineState(editor.LineContext{Text: "func */ func"}, st)
+	if len(spans) != 1 || spans[0].StartGraphemeCol != 8 {
+		t.Fatalf("spans inside carried comment: %+v", spans)
+	}
+
+	spans, _ = h.HighlightLine(editor.LineContext{Text: "func */ func"})
+	if len(spans) != 2 {
+		t.Fatalf("stateless spans: %+v", spans)
+	}
+}
+
+func TestHighlighter_RendersInEditor(t *testing.T) {
+	upper := lipgloss.NewStyle().Transform(strings.ToUpper)
+	m := editor.New(editor.Config{
+		Text:        "x := 1 /* a\nb */ y",
+		Highlighter: New(Go(), Theme{Comment: upper}),
+	}).SetSize(20, 2)
+
+	view := ansi.Strip(m.View().Content)
+	for _, want := range []string{"x := 1 /* A", "B */ y"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view missing %q:\n%s", want, view)
+		}
+	}
+}
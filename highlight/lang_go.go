@@ -0,0 +1,57 @@
+package highlight
+
+func goGrammar() Grammar {
+	return Grammar{
+		Name:       "go",
+		Aliases:    []string{"golang"},
+		Extensions: []string{".go"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `\s+`, Token: Text},
+				{Pattern: `//.*`, Token: Comment},
+				{Pattern: `/\*`, Token: Comment, Push: "comment"},
+				{Pattern: "`", Token: String, Push: "rawstring"},
+				{Pattern: `"`, Token: String, Push: "string"},
+				{Pattern: `'(?:[^'\\]|\\[^']+)'`, Token: String},
+				{Pattern: Words(
+					"break", "case", "chan", "const", "continue", "default", "defer",
+					"else", "fallthrough", "for", "func", "go", "goto", "if", "import",
+					"interface", "map", "package", "range", "return", "select", "struct",
+					"switch", "type", "var",
+				), Token: Keyword},
+				{Pattern: Words("true", "false", "nil", "iota"), Token: Constant},
+				{Pattern: Words(
+					"any", "bool", "byte", "comparable", "complex64", "complex128",
+					"error", "float32", "float64", "int", "int8", "int16", "int32",
+					"int64", "rune", "string", "uint", "uint8", "uint16", "uint32",
+					"uint64", "uintptr",
+				), Token: KeywordType},
+				{Pattern: Words(
+					"append", "cap", "clear", "close", "complex", "copy", "delete",
+					"imag", "len", "make", "max", "min", "new", "panic", "print",
+					"println", "real", "recover",
+				), Token: Builtin},
+				{Pattern: `([\pL_][\pL\pN_]*)(\s*)(\()`, Groups: []TokenType{Function, Text, Punctuation}},
+				{Pattern: `[\pL_][\pL\pN_]*`, Token: Name},
+				{Pattern: `(?:0[xX][0-9a-fA-F_]+(?:\.[0-9a-fA-F_]*)?(?:[pP][+-]?\d+)?|0[bB][01_]+|0[oO][0-7_]+|(?:\d[\d_]*(?:\.[\d_]*)?|\.\d[\d_]*)(?:[eE][+-]?\d+)?)i?`, Token: Number},
+				{Pattern: `[-+*/%&|^<>=!:.~]+`, Token: Operator},
+				{Pattern: `[()\[\]{},;]`, Token: Punctuation},
+			},
+			"comment": {
+				{Pattern: `\*/`, Token: Comment, Pop: true},
+				{Pattern: `[^*]+|\*`, Token: Comment},
+			},
+			"string": {
+				{Pattern: `\\(?:[abfnrtv\\'"]|x[0-9a-fA-F]{2}|u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8}|[0-7]{3})`, Token: Escape},
+				{Pattern: `"`, Token: String, Pop: true},
+				{Pattern: `[^"\\]+|\\`, Token: String},
+				// Interpreted strings cannot span lines.
+				{Pattern: `$`, Pop: true},
+			},
+			"rawstring": {
+				{Pattern: "`", Token: String, Pop: true},
+				{Pattern: "[^`]+", Token: String},
+			},
+		},
+	}
+}
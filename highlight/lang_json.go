@@ -0,0 +1,24 @@
+package highlight
+
+func jsonGrammar() Grammar {
+	return Grammar{
+		Name:       "json",
+		Extensions: []string{".json"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `\s+`, Token: Text},
+				{Pattern: `("(?:[^"\\]|\\.)*")(\s*)(:)`, Groups: []TokenType{Key, Text, Punctuation}},
+				{Pattern: `"`, Token: String, Push: "string"},
+				{Pattern: `-?(?:0|[1-9]\d*)(?:\.\d+)?(?:[eE][+-]?\d+)?`, Token: Number},
+				{Pattern: Words("true", "false", "null"), Token: Constant},
+				{Pattern: `[{}\[\],:]`, Token: Punctuation},
+			},
+			"string": {
+				{Pattern: `\\(?:["\\/bfnrt]|u[0-9a-fA-F]{4})`, Token: Escape},
+				{Pattern: `"`, Token: String, Pop: true},
+				{Pattern: `[^"\\]+|\\`, Token: String},
+				{Pattern: `$`, Pop: true},
+			},
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package highlight
+
+func markdownGrammar() Grammar {
+	return Grammar{
+		Name:       "markdown",
+		Aliases:    []string{"md"},
+		Extensions: []string{".md", ".markdown"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: "\\s*(?:`{3,}|~{3,}).*", Token: Code, LineStart: true, Push: "fence"},
+				{Pattern: `#{1,6}(?:\s.*)?$`, Token: Heading, LineStart: true},
+				{Pattern: `\s*>.*`, Token: Quote, LineStart: true},
+				{Pattern: `\s*(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`, Token: Punctuation, LineStart: true},
+				{Pattern: `(\s*)([-*+]|\d+[.)])(\s)`, Groups: []TokenType{Text, List, Text}, LineStart: true},
+				{Pattern: `<!--`, Token: Comment, Push: "comment"},
+				{Pattern: "`[^`]+`", Token: Code},
+				{Pattern: `\*\*[^*]+\*\*|__[^_]+__`, Token: Strong},
+				{Pattern: `\*[^*\s][^*]*\*|_[^_\s][^_]*_`, Token: Emphasis},
+				{Pattern: `!?\[[^\]]*\]\([^)]*\)|<https?://[^>]+>`, Token: Link},
+				{Pattern: `\\.`, Token: Escape},
+				{Pattern: `\w+|\s+`, Token: Text},
+			},
+			"fence": {
+				{Pattern: "\\s*(?:`{3,}|~{3,})\\s*$", Token: Code, LineStart: true, Pop: true},
+				{Pattern: `.+`, Token: Code},
+			},
+			"comment": {
+				{Pattern: `-->`, Token: Comment, Pop: true},
+				{Pattern: `[^-]+|-`, Token: Comment},
+			},
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package highlight
+
+// shellWordEnd matches the end of a reserved word or builtin: whitespace, a
+// control operator, or end of line. ")" is left for the enclosing
+// substitution to close.
+const shellWordEnd = `([\s;&|]|$)`
+
+func shellGrammar() Grammar {
+	return Grammar{
+		Name:       "shell",
+		Aliases:    []string{"sh", "bash", "zsh"},
+		Extensions: []string{".sh", ".bash", ".zsh"},
+		Filenames:  []string{".bashrc", ".bash_profile", ".profile", ".zshrc"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `\s+`, Token: Text},
+				{Pattern: `#.*`, Token: Comment},
+				{Pattern: `<<<`, Token: Operator},
+				// Heredoc bodies end at the first line holding only an upper-case
+				// word (EOF, END, ...); the actual delimiter is not tracked.
+				{Pattern: `<<-?\s*['"]?\w+['"]?`, Token: Operator, Push: "heredoc"},
+				{Pattern: `([\pL_][\pL\pN_]*)(\+?=)`, Groups: []TokenType{Variable, Operator}},
+				{Pattern: `(if|then|else|elif|fi|for|while|until|do|done|case|esac|in|function|select|return|break|continue|local|export|readonly|declare|typeset|unset|shift|exit)` + shellWordEnd, Groups: []TokenType{Keyword, Operator}},
+				{Pattern: `(alias|bg|cd|command|echo|eval|exec|false|fg|getopts|jobs|kill|printf|pwd|read|set|source|test|trap|true|type|ulimit|umask|wait)` + shellWordEnd, Groups: []TokenType{Builtin, Operator}},
+				{Include: "expansion"},
+				{Pattern: `'`, Token: String, Push: "squote"},
+				{Pattern: `"`, Token: String, Push: "dquote"},
+				{Pattern: `\\.`, Token: Escape},
+				{Pattern: `\d+\b`, Token: Number},
+				{Pattern: `&&|\|\||;;|[|&;<>]+`, Token: Operator},
+				{Pattern: `[(){}\[\]]`, Token: Punctuation},
+				{Pattern: `[^\s$"'\x60;&|<>()\\{}#][^\s$"'\x60;&|<>()\\{}]*`, Token: Text},
+			},
+			"expansion": {
+				{Pattern: `\$\{`, Token: Variable, Push: "param"},
+				{Pattern: `\$\(`, Token: Operator, Push: "subst"},
+				{Pattern: "`", Token: Operator, Push: "backtick"},
+				{Pattern: `\$(?:[\pL_][\pL\pN_]*|[@*#?$!0-9-])`, Token: Variable},
+			},
+			"param": {
+				{Pattern: `\}`, Token: Variable, Pop: true},
+				{Pattern: `[^}]+`, Token: Variable},
+			},
+			"subst": {
+				{Pattern: `\)`, Token: Operator, Pop: true},
+				{Include: RootState},
+			},
+			"backtick": {
+				{Pattern: "`", Token: Operator, Pop: true},
+				{Include: RootState},
+			},
+			"squote": {
+				{Pattern: `'`, Token: String, Pop: true},
+				{Pattern: `[^']+`, Token: String},
+			},
+			"dquote": {
+				{Pattern: `"`, Token: String, Pop: true},
+				{Pattern: `\\.`, Token: Escape},
+				{Include: "expansion"},
+				{Pattern: "[^\"\\\\$`]+|[\\\\$]", Token: String},
+			},
+			"heredoc": {
+				{Pattern: `\s*[A-Z_][A-Z0-9_]*\s*$`, Token: Operator, LineStart: true, Pop: true},
+				{Pattern: `.+`, Token: String},
+			},
+		},
+	}
+}
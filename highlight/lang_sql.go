@@ -0,0 +1,56 @@
+package highlight
+
+func sqlGrammar() Grammar {
+	return Grammar{
+		Name:       "sql",
+		Extensions: []string{".sql"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `\s+`, Token: Text},
+				{Pattern: `--.*`, Token: Comment},
+				{Pattern: `/\*`, Token: Comment, Push: "comment"},
+				{Pattern: `'`, Token: String, Push: "string"},
+				{Pattern: `"`, Token: Name, Push: "ident"},
+				{Pattern: `(?i)` + Words(
+					"add", "all", "alter", "and", "as", "asc", "begin", "between", "by",
+					"case", "check", "column", "commit", "constraint", "create", "cross",
+					"default", "delete", "desc", "distinct", "drop", "else", "end",
+					"exists", "foreign", "from", "full", "group", "having", "if", "in",
+					"index", "inner", "insert", "into", "is", "join", "key", "left",
+					"like", "limit", "not", "offset", "on", "or", "order", "outer",
+					"primary", "references", "returning", "right", "rollback", "select",
+					"set", "table", "then", "transaction", "union", "unique", "update",
+					"using", "values", "view", "when", "where", "with",
+				), Token: Keyword},
+				{Pattern: `(?i)` + Words(
+					"bigint", "blob", "bool", "boolean", "char", "date", "decimal",
+					"double", "float", "int", "integer", "interval", "json", "jsonb",
+					"numeric", "real", "serial", "smallint", "text", "time", "timestamp",
+					"timestamptz", "uuid", "varchar",
+				), Token: KeywordType},
+				{Pattern: `(?i)` + Words("null", "true", "false"), Token: Constant},
+				{Pattern: `([\pL_][\pL\pN_$]*)(\s*)(\()`, Groups: []TokenType{Function, Text, Punctuation}},
+				{Pattern: `[\pL_][\pL\pN_$]*`, Token: Name},
+				{Pattern: `(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?`, Token: Number},
+				{Pattern: `::`, Token: Operator},
+				{Pattern: `\$\d+|\?|[:@][\pL_][\pL\pN_]*`, Token: Variable},
+				{Pattern: `[-+*/%<>=!|~^&]+`, Token: Operator},
+				{Pattern: `[(),;.\[\]]`, Token: Punctuation},
+			},
+			"comment": {
+				{Pattern: `\*/`, Token: Comment, Pop: true},
+				{Pattern: `[^*]+|\*`, Token: Comment},
+			},
+			"string": {
+				{Pattern: `''`, Token: Escape},
+				{Pattern: `'`, Token: String, Pop: true},
+				{Pattern: `[^']+`, Token: String},
+			},
+			"ident": {
+				{Pattern: `""`, Token: Escape},
+				{Pattern: `"`, Token: Name, Pop: true},
+				{Pattern: `[^"]+`, Token: Name},
+			},
+		},
+	}
+}
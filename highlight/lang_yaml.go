@@ -0,0 +1,38 @@
+package highlight
+
+func yamlGrammar() Grammar {
+	return Grammar{
+		Name:       "yaml",
+		Aliases:    []string{"yml"},
+		Extensions: []string{".yaml", ".yml"},
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `(?:---|\.\.\.)(?:\s|$)`, Token: Punctuation, LineStart: true},
+				{Pattern: `\s+`, Token: Text},
+				{Pattern: `#.*`, Token: Comment},
+				{Pattern: `-(?:\s|$)`, Token: Punctuation},
+				{Pattern: `("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s#:'"\[\]{},&*!|>-][^#:]*?|-[^\s#:][^#:]*?)(\s*)(:)(?:\s|$)`, Groups: []TokenType{Key, Text, Punctuation}},
+				{Pattern: `"`, Token: String, Push: "dquote"},
+				{Pattern: `'`, Token: String, Push: "squote"},
+				{Pattern: `[&*][^\s,\[\]{}]+`, Token: Variable},
+				{Pattern: `!!?[^\s,\[\]{}]*`, Token: KeywordType},
+				{Pattern: `[|>][-+]?\d*(?:\s|$)`, Token: Operator},
+				{Pattern: `(?:true|false|True|False|TRUE|FALSE|null|Null|NULL)\b|~`, Token: Constant},
+				{Pattern: `[-+]?(?:0x[0-9a-fA-F]+|0o[0-7]+|(?:\d[\d_]*(?:\.\d*)?|\.\d+)(?:[eE][-+]?\d+)?|\.(?:inf|Inf|INF|nan|NaN|NAN))(?:\s|$)`, Token: Number},
+				{Pattern: `[\[\]{},]`, Token: Punctuation},
+				{Pattern: `[^\s#,\[\]{}]+`, Token: String},
+			},
+			// Quoted scalars may span lines.
+			"dquote": {
+				{Pattern: `\\.`, Token: Escape},
+				{Pattern: `"`, Token: String, Pop: true},
+				{Pattern: `[^"\\]+|\\`, Token: String},
+			},
+			"squote": {
+				{Pattern: `''`, Token: Escape},
+				{Pattern: `'`, Token: String, Pop: true},
+				{Pattern: `[^']+`, Token: String},
+			},
+		},
+	}
+}
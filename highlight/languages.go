@@ -0,0 +1,77 @@
+package highlight
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	goLexer       = sync.OnceValue(func() *Lexer { return MustCompile(goGrammar()) })
+	jsonLexer     = sync.OnceValue(func() *Lexer { return MustCompile(jsonGrammar()) })
+	yamlLexer     = sync.OnceValue(func() *Lexer { return MustCompile(yamlGrammar()) })
+	markdownLexer = sync.OnceValue(func() *Lexer { return MustCompile(markdownGrammar()) })
+	shellLexer    = sync.OnceValue(func() *Lexer { return MustCompile(shellGrammar()) })
+	sqlLexer      = sync.OnceValue(func() *Lexer { return MustCompile(sqlGrammar()) })
+)
+
+// Go returns the bundled Go lexer.
+func Go() *Lexer { return goLexer() }
+
+// JSON returns the bundled JSON lexer.
+func JSON() *Lexer { return jsonLexer() }
+
+// YAML returns the bundled YAML lexer.
+func YAML() *Lexer { return yamlLexer() }
+
+// Markdown returns the bundled Markdown lexer.
+func Markdown() *Lexer { return markdownLexer() }
+
+// Shell returns the bundled POSIX/Bash shell lexer.
+func Shell() *Lexer { return shellLexer() }
+
+// SQL returns the bundled SQL lexer.
+func SQL() *Lexer { return sqlLexer() }
+
+// Languages returns the bundled lexers.
+func Languages() []*Lexer {
+	return []*Lexer{Go(), JSON(), YAML(), Markdown(), Shell(), SQL()}
+}
+
+// Lookup returns the bundled lexer whose name or alias equals name, ignoring
+// case.
+func Lookup(name string) (*Lexer, bool) {
+	for _, l := range Languages() {
+		g := l.Grammar()
+		if strings.EqualFold(g.Name, name) || slices.ContainsFunc(g.Aliases, func(a string) bool {
+			return strings.EqualFold(a, name)
+		}) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// ForFilename returns the bundled lexer for path by base name or extension.
+func ForFilename(path string) (*Lexer, bool) {
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	for _, l := range Languages() {
+		g := l.Grammar()
+		if slices.Contains(g.Filenames, base) || (ext != "" && slices.Contains(g.Extensions, ext)) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// Words returns a pattern matching any of words as a whole word.
+func Words(words ...string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	return `\b(?:` + strings.Join(quoted, "|") + `)\b`
+}
@@ -0,0 +1,215 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// typeAt lexes text line by line and returns the token type covering the
+// first grapheme of the first occurrence of sub on row.
+func typeAt(t *testing.T, l *Lexer, text string, row int, sub string) TokenType {
+	t.Helper()
+	var st State
+	for i, line := range strings.Split(text, "\n") {
+		toks, next := l.LexLine(line, st)
+		st = next
+		if i != row {
+			continue
+		}
+		b := strings.Index(line, sub)
+		if b < 0 {
+			t.Fatalf("row %d %q does not contain %q", row, line, sub)
+		}
+		col := graphemeutil.Count(line[:b])
+		for _, tok := range toks {
+			if col >= tok.Start && col < tok.End {
+				return tok.Type
+			}
+		}
+		return Text
+	}
+	t.Fatalf("row %d out of range", row)
+	return Text
+}
+
+type langCase struct {
+	row  int
+	sub  string
+	want TokenType
+}
+
+func checkLang(t *testing.T, l *Lexer, text string, cases []langCase) {
+	t.Helper()
+	for _, c := range cases {
+		if got := typeAt(t, l, text, c.row, c.sub); got != c.want {
+			t.Errorf("%s row %d %q: got %q, want %q", l.Name(), c.row, c.sub, got, c.want)
+		}
+	}
+}
+
+func TestGo(t *testing.T) {
+	src := strings.Join([]string{
+		`package main // pkg`,
+		`/* block`,
+		`   still */ func f(s string) int {`,
+		"\treturn len(`raw`) + 0x1F + 'x' + \"a\\n\"",
+		`}`,
+	}, "\n")
+	checkLang(t, Go(), src, []langCase{
+		{0, "package", Keyword},
+		{0, "main", Name},
+		{0, "// pkg", Comment},
+		{1, "block", Comment},
+		{2, "still", Comment},
+		{2, "func", Keyword},
+		{2, "f(", Function},
+		{2, "string", KeywordType},
+		{3, "len", Builtin},
+		{3, "`raw`", String},
+		{3, "0x1F", Number},
+		{3, "'x'", String},
+		{3, `\n`, Escape},
+		{3, "+", Operator},
+		{4, "}", Punctuation},
+	})
+}
+
+func TestJSON(t *testing.T) {
+	src := `{"name": "a\"b", "n": -1.5e3, "ok": true, "v": null}`
+	checkLang(t, JSON(), src, []langCase{
+		{0, `"name"`, Key},
+		{0, `"a`, String},
+		{0, `\"`, Escape},
+		{0, "-1.5e3", Number},
+		{0, "true", Constant},
+		{0, "null", Constant},
+		{0, "{", Punctuation},
+	})
+}
+
+func TestYAML(t *testing.T) {
+	src := strings.Join([]string{
+		`---`,
+		`name: demo # note`,
+		`items:`,
+		`  - key: "multi`,
+		`    line"`,
+		`  - &anchor 42`,
+		`enabled: true`,
+	}, "\n")
+	checkLang(t, YAML(), src, []langCase{
+		{0, "---", Punctuation},
+		{1, "name", Key},
+		{1, "demo", String},
+		{1, "# note", Comment},
+		{3, "-", Punctuation},
+		{3, "key", Key},
+		{4, "line", String},
+		{5, "&anchor", Variable},
+		{5, "42", Number},
+		{6, "true", Constant},
+	})
+}
+
+func TestMarkdown(t *testing.T) {
+	src := strings.Join([]string{
+		`# Title`,
+		`Some *em* and **strong** with ` + "`code`" + ` and [a link](http://x).`,
+		"```go",
+		`# not a heading`,
+		"```",
+		`- item`,
+		`> quoted`,
+		`snake_case_word`,
+	}, "\n")
+	checkLang(t, Markdown(), src, []langCase{
+		{0, "Title", Heading},
+		{1, "*em*", Emphasis},
+		{1, "**strong**", Strong},
+		{1, "`code`", Code},
+		{1, "[a link]", Link},
+		{1, "Some", Text},
+		{3, "not", Code},
+		{5, "-", List},
+		{5, "item", Text},
+		{6, "quoted", Quote},
+		{7, "case", Text},
+	})
+}
+
+func TestShell(t *testing.T) {
+	src := strings.Join([]string{
+		`#!/bin/sh`,
+		`NAME="x $HOME ${USER}" # c`,
+		`if [ -n "$1" ]; then echo 'a`,
+		`b' | grep x 2>&1; fi`,
+		`cat <<EOF`,
+		`if body`,
+		`EOF`,
+		`out=$(pwd)`,
+	}, "\n")
+	checkLang(t, Shell(), src, []langCase{
+		{0, "#!", Comment},
+		{1, "NAME", Variable},
+		{1, `"x`, String},
+		{1, "$HOME", Variable},
+		{1, "${USER}", Variable},
+		{1, "# c", Comment},
+		{2, "if", Keyword},
+		{2, "then", Keyword},
+		{2, "echo", Builtin},
+		{2, "'a", String},
+		{3, "b'", String},
+		{3, "|", Operator},
+		{3, "grep", Text},
+		{3, "fi", Keyword},
+		{5, "if", String},
+		{6, "EOF", Operator},
+		{7, "$(", Operator},
+		{7, "pwd", Text},
+	})
+}
+
+func TestSQL(t *testing.T) {
+	src := strings.Join([]string{
+		`SELECT count(*), "Col" FROM t -- all`,
+		`where x = 'it''s' AND y::int > 3.5 /* c`,
+		`*/ limit $1;`,
+	}, "\n")
+	checkLang(t, SQL(), src, []langCase{
+		{0, "SELECT", Keyword},
+		{0, "count", Function},
+		{0, `"Col"`, Name},
+		{0, "FROM", Keyword},
+		{0, "-- all", Comment},
+		{1, "where", Keyword},
+		{1, "'it", String},
+		{1, "''", Escape},
+		{1, "::", Operator},
+		{1, "int", KeywordType},
+		{1, "3.5", Number},
+		{2, "*/", Comment},
+		{2, "limit", Keyword},
+		{2, "$1", Variable},
+	})
+}
+
+func TestLookupAndForFilename(t *testing.T) {
+	for name, want := range map[string]string{"Go": "go", "golang": "go", "yml": "yaml", "bash": "shell", "MD": "markdown"} {
+		l, ok := Lookup(name)
+		if !ok || l.Name() != want {
+			t.Errorf("Lookup(%q): got %v ok=%v, want %s", name, l, ok, want)
+		}
+	}
+	for path, want := range map[string]string{"a/b/main.go": "go", "x.JSON": "json", "/home/u/.bashrc": "shell", "q.sql": "sql"} {
+		l, ok := ForFilename(path)
+		if !ok || l.Name() != want {
+			t.Errorf("ForFilename(%q): got %v ok=%v, want %s", path, l, ok, want)
+		}
+	}
+	if _, ok := ForFilename("README"); ok {
+		t.Errorf("ForFilename(README): expected no lexer")
+	}
+}
@@ -0,0 +1,355 @@
+package highlight
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/iw2rmb/flourish/editor"
+	graphemeutil "github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// RootState is the state every line of a document starts in before any push.
+const RootState = "root"
+
+// maxStackDepth bounds nested pushes so a runaway grammar cannot grow state
+// without limit. Pushes past the bound are ignored.
+const maxStackDepth = 32
+
+// maxEmptyMatches bounds consecutive zero-width matches at one position.
+const maxEmptyMatches = maxStackDepth * 2
+
+// Rule matches Pattern at the current position of the current state.
+//
+// Patterns use RE2 syntax and are anchored at the current position; `$`
+// matches the end of the line. A rule that matches the empty string is used
+// only when it changes state (e.g. `{Pattern: "$", Pop: true}` to leave a
+// state at end of line).
+type Rule struct {
+	Pattern string
+	// Token is the type of the matched text outside Groups.
+	Token TokenType
+	// Groups optionally assigns types to non-nested capture groups, in order.
+	// Unmatched groups are skipped.
+	Groups []TokenType
+
+	// LineStart restricts the rule to the first column of a line.
+	LineStart bool
+
+	// Pop leaves the current state; the root state is never popped. Push then
+	// enters the named state, so setting both replaces the current state.
+	Pop  bool
+	Push string
+
+	// Include splices the rules of the named state in place of this rule.
+	// Other fields are ignored.
+	Include string
+}
+
+// Grammar declares a language as named states of ordered rules. The first
+// matching rule wins. States must include RootState.
+type Grammar struct {
+	Name string
+	// Aliases are extra lookup names (e.g. "golang").
+	Aliases []string
+	// Extensions are file extensions including the dot (e.g. ".go").
+	Extensions []string
+	// Filenames are exact base names (e.g. "Dockerfile").
+	Filenames []string
+
+	States map[string][]Rule
+}
+
+// Token is a lexed range of a line in grapheme columns, half-open
+// [Start, End).
+type Token struct {
+	Start int
+	End   int
+	Type  TokenType
+}
+
+// State is the lexer state stack at a line boundary. The zero value is the
+// root state. State implements editor.HighlightState.
+type State struct {
+	stack []int
+}
+
+// Equal reports whether other is a State with the same stack.
+func (s State) Equal(other editor.HighlightState) bool {
+	o, ok := other.(State)
+	return ok && slices.Equal(s.stack, o.stack)
+}
+
+// Depth returns the number of states pushed above the root state.
+func (s State) Depth() int {
+	return len(s.stack)
+}
+
+type compiledRule struct {
+	re        *regexp.Regexp
+	token     TokenType
+	groups    []TokenType
+	lineStart bool
+	pop       bool
+	push      int // state index, or -1
+}
+
+func (r compiledRule) changesState() bool {
+	return r.pop || r.push >= 0
+}
+
+// Lexer is a compiled Grammar. It is immutable and safe for concurrent use.
+type Lexer struct {
+	grammar Grammar
+	names   []string
+	states  [][]compiledRule
+}
+
+// Compile validates g and compiles its rules.
+func Compile(g Grammar) (*Lexer, error) {
+	if _, ok := g.States[RootState]; !ok {
+		return nil, fmt.Errorf("highlight: grammar %q has no %q state", g.Name, RootState)
+	}
+
+	names := make([]string, 0, len(g.States))
+	names = append(names, RootState)
+	for name := range g.States {
+		if name != RootState {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names[1:])
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+
+	l := &Lexer{grammar: g, names: names, states: make([][]compiledRule, len(names))}
+	for i, name := range names {
+		rules, err := expandIncludes(g, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		compiled := make([]compiledRule, 0, len(rules))
+		for j, r := range rules {
+			re, err := regexp.Compile(`^(?:` + r.Pattern + `)`)
+			if err != nil {
+				return nil, fmt.Errorf("highlight: grammar %q state %q rule %d: %w", g.Name, name, j, err)
+			}
+			if len(r.Groups) > re.NumSubexp() {
+				return nil, fmt.Errorf("highlight: grammar %q state %q rule %d: %d group types for %d groups", g.Name, name, j, len(r.Groups), re.NumSubexp())
+			}
+			push := -1
+			if r.Push != "" {
+				p, ok := index[r.Push]
+				if !ok {
+					return nil, fmt.Errorf("highlight: grammar %q state %q rule %d: unknown state %q", g.Name, name, j, r.Push)
+				}
+				push = p
+			}
+			compiled = append(compiled, compiledRule{
+				re:        re,
+				token:     r.Token,
+				groups:    slices.Clone(r.Groups),
+				lineStart: r.LineStart,
+				pop:       r.Pop,
+				push:      push,
+			})
+		}
+		l.states[i] = compiled
+	}
+	return l, nil
+}
+
+// MustCompile is like Compile but panics on error. It is intended for
+// package-level grammars.
+func MustCompile(g Grammar) *Lexer {
+	l, err := Compile(g)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func expandIncludes(g Grammar, name string, seen []string) ([]Rule, error) {
+	if slices.Contains(seen, name) {
+		return nil, fmt.Errorf("highlight: grammar %q includes state %q recursively", g.Name, name)
+	}
+	rules, ok := g.States[name]
+	if !ok {
+		return nil, fmt.Errorf("highlight: grammar %q includes unknown state %q", g.Name, name)
+	}
+	seen = append(seen, name)
+	out := make([]Rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Include == "" {
+			out = append(out, r)
+			continue
+		}
+		inc, err := expandIncludes(g, r.Include, seen)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, inc...)
+	}
+	return out, nil
+}
+
+// Name returns the grammar name.
+func (l *Lexer) Name() string {
+	return l.grammar.Name
+}
+
+// Grammar returns the grammar l was compiled from.
+func (l *Lexer) Grammar() Grammar {
+	return l.grammar
+}
+
+// StateName returns the name of the innermost state in st.
+func (l *Lexer) StateName(st State) string {
+	if len(st.stack) == 0 {
+		return RootState
+	}
+	return l.names[st.stack[len(st.stack)-1]]
+}
+
+// LexLine tokenizes one line (without its line terminator) starting in st,
+// and returns the tokens and the state at the end of the line. Text no rule
+// matches is emitted as Text; adjacent tokens of one type are merged.
+func (l *Lexer) LexLine(line string, st State) ([]Token, State) {
+	stack := st.stack
+	shared := true // stack aliases st until the first change
+	setStack := func(r compiledRule) {
+		if shared {
+			stack = slices.Clone(stack)
+			shared = false
+		}
+		if r.pop && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+		if r.push >= 0 && len(stack) < maxStackDepth {
+			stack = append(stack, r.push)
+		}
+	}
+
+	var out byteTokens
+	pos, empties := 0, 0
+	for pos <= len(line) {
+		top := 0
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		matched := false
+		for _, r := range l.states[top] {
+			if r.lineStart && pos != 0 {
+				continue
+			}
+			loc := r.re.FindStringSubmatchIndex(line[pos:])
+			if loc == nil {
+				continue
+			}
+			if loc[1] == 0 && (!r.changesState() || empties >= maxEmptyMatches) {
+				continue
+			}
+			out.emitMatch(pos, loc, r)
+			setStack(r)
+			if loc[1] == 0 {
+				empties++
+			} else {
+				empties = 0
+			}
+			pos += loc[1]
+			matched = true
+			break
+		}
+		if matched {
+			continue
+		}
+		if pos == len(line) {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		out.emit(pos, pos+size, Text)
+		pos += size
+		empties = 0
+	}
+
+	return out.toColumns(line), State{stack: stack}
+}
+
+// byteTokens accumulates tokens in byte offsets, merging adjacent tokens of
+// one type.
+type byteTokens []Token
+
+func (t *byteTokens) emit(start, end int, typ TokenType) {
+	if end <= start {
+		return
+	}
+	if n := len(*t); n > 0 && (*t)[n-1].Type == typ && (*t)[n-1].End == start {
+		(*t)[n-1].End = end
+		return
+	}
+	*t = append(*t, Token{Start: start, End: end, Type: typ})
+}
+
+func (t *byteTokens) emitMatch(pos int, loc []int, r compiledRule) {
+	at := 0
+	for i, typ := range r.groups {
+		gs, ge := loc[2*(i+1)], loc[2*(i+1)+1]
+		if gs < at {
+			continue
+		}
+		t.emit(pos+at, pos+gs, r.token)
+		t.emit(pos+gs, pos+ge, typ)
+		at = ge
+	}
+	t.emit(pos+at, pos+loc[1], r.token)
+}
+
+// toColumns converts byte offsets to grapheme columns. Offsets inside a
+// cluster round up to the next cluster boundary.
+func (t byteTokens) toColumns(line string) []Token {
+	if len(t) == 0 {
+		return nil
+	}
+	ascii := true
+	for i := 0; i < len(line); i++ {
+		if line[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return t
+	}
+
+	// colAt[b] is the column of the first cluster starting at or after byte b.
+	colAt := make([]int, len(line)+1)
+	b, col := 0, 0
+	for _, g := range graphemeutil.Split(line) {
+		for end := b + len(g); b < end; b++ {
+			colAt[b] = col
+			if b > end-len(g) {
+				colAt[b] = col + 1
+			}
+		}
+		col++
+	}
+	colAt[len(line)] = col
+
+	out := make([]Token, 0, len(t))
+	for _, tok := range t {
+		start, end := colAt[tok.Start], colAt[tok.End]
+		if end <= start {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].Type == tok.Type && out[n-1].End == start {
+			out[n-1].End = end
+			continue
+		}
+		out = append(out, Token{Start: start, End: end, Type: tok.Type})
+	}
+	return out
+}
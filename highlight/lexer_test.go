@@ -0,0 +1,155 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testGrammar() Grammar {
+	return Grammar{
+		Name: "test",
+		States: map[string][]Rule{
+			RootState: {
+				{Pattern: `#.*`, Token: Comment, LineStart: true},
+				{Pattern: `\(\*`, Token: Comment, Push: "comment"},
+				{Pattern: `(let)(\s+)(\w+)`, Groups: []TokenType{Keyword, Text, Name}},
+				{Pattern: `"`, Token: String, Push: "string"},
+				{Pattern: `\d+`, Token: Number},
+			},
+			"comment": {
+				{Pattern: `\(\*`, Token: Comment, Push: "comment"},
+				{Pattern: `\*\)`, Token: Comment, Pop: true},
+				{Pattern: `[^*(]+|[*(]`, Token: Comment},
+			},
+			"string": {
+				{Pattern: `"`, Token: String, Pop: true},
+				{Pattern: `[^"]+`, Token: String},
+				{Pattern: `$`, Pop: true},
+			},
+		},
+	}
+}
+
+func TestLexLine_GroupsAndFallback(t *testing.T) {
+	l := MustCompile(testGrammar())
+	toks, st := l.LexLine("let x = 42", State{})
+	want := []Token{
+		{Start: 0, End: 3, Type: Keyword},
+		{Start: 3, End: 4, Type: Text},
+		{Start: 4, End: 5, Type: Name},
+		{Start: 5, End: 8, Type: Text},
+		{Start: 8, End: 10, Type: Number},
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Fatalf("tokens: got %+v, want %+v", toks, want)
+	}
+	if st.Depth() != 0 || !st.Equal(State{}) {
+		t.Fatalf("end state: got depth %d", st.Depth())
+	}
+}
+
+func TestLexLine_StateCarriesAcrossLines(t *testing.T) {
+	l := MustCompile(testGrammar())
+
+	toks, st := l.LexLine("1 (* a (* b", State{})
+	if st.Depth() != 2 || l.StateName(st) != "comment" {
+		t.Fatalf("after line 1: depth=%d state=%q", st.Depth(), l.StateName(st))
+	}
+	if got := toks[len(toks)-1]; got.Type != Comment || got.End != 11 {
+		t.Fatalf("line 1 tail: %+v", got)
+	}
+
+	toks, st = l.LexLine("c *) d *) 7", st)
+	if st.Depth() != 0 {
+		t.Fatalf("after line 2: depth=%d", st.Depth())
+	}
+	want := []Token{{Start: 0, End: 9, Type: Comment}, {Start: 9, End: 10, Type: Text}, {Start: 10, End: 11, Type: Number}}
+	if !reflect.DeepEqual(toks, want) {
+		t.Fatalf("line 2: got %+v, want %+v", toks, want)
+	}
+}
+
+func TestLexLine_EmptyMatchPopsAtEndOfLine(t *testing.T) {
+	l := MustCompile(testGrammar())
+	toks, st := l.LexLine(`1 "open`, State{})
+	if st.Depth() != 0 {
+		t.Fatalf("unterminated string should pop at end of line, depth=%d", st.Depth())
+	}
+	if got := toks[len(toks)-1]; got != (Token{Start: 2, End: 7, Type: String}) {
+		t.Fatalf("string token: %+v", got)
+	}
+}
+
+func TestLexLine_LineStart(t *testing.T) {
+	l := MustCompile(testGrammar())
+	toks, _ := l.LexLine("# c", State{})
+	if len(toks) != 1 || toks[0].Type != Comment {
+		t.Fatalf("line-start comment: %+v", toks)
+	}
+	toks, _ = l.LexLine("1 # c", State{})
+	for _, tok := range toks {
+		if tok.Type == Comment {
+			t.Fatalf("mid-line # must not match a LineStart rule: %+v", toks)
+		}
+	}
+}
+
+func TestLexLine_GraphemeColumns(t *testing.T) {
+	l := MustCompile(testGrammar())
+	toks, _ := l.LexLine("é🇺🇸 12", State{})
+	want := []Token{{Start: 0, End: 3, Type: Text}, {Start: 3, End: 5, Type: Number}}
+	if !reflect.DeepEqual(toks, want) {
+		t.Fatalf("tokens: got %+v, want %+v", toks, want)
+	}
+}
+
+func TestLexLine_StackDepthIsBounded(t *testing.T) {
+	l := MustCompile(testGrammar())
+	_, st := l.LexLine(strings.Repeat("(*", maxStackDepth+10), State{})
+	if st.Depth() != maxStackDepth {
+		t.Fatalf("depth: got %d, want %d", st.Depth(), maxStackDepth)
+	}
+}
+
+func TestLexLine_EmptyStateLoopTerminates(t *testing.T) {
+	l := MustCompile(Grammar{States: map[string][]Rule{
+		RootState: {{Pattern: ``, Push: "a"}},
+		"a":       {{Pattern: ``, Pop: true}},
+	}})
+	toks, _ := l.LexLine("xy", State{})
+	if want := []Token{{Start: 0, End: 2, Type: Text}}; !reflect.DeepEqual(toks, want) {
+		t.Fatalf("tokens: got %+v, want %+v", toks, want)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	cases := map[string]Grammar{
+		"no root":       {States: map[string][]Rule{"x": nil}},
+		"bad regex":     {States: map[string][]Rule{RootState: {{Pattern: `(`}}}},
+		"unknown push":  {States: map[string][]Rule{RootState: {{Pattern: `a`, Push: "nope"}}}},
+		"extra groups":  {States: map[string][]Rule{RootState: {{Pattern: `a`, Groups: []TokenType{Name}}}}},
+		"bad include":   {States: map[string][]Rule{RootState: {{Include: "nope"}}}},
+		"include cycle": {States: map[string][]Rule{RootState: {{Include: "a"}}, "a": {{Include: RootState}}}},
+	}
+	for name, g := range cases {
+		if _, err := Compile(g); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTheme_StyleFallsBackToParent(t *testing.T) {
+	th := DefaultTheme()
+	delete(th, Escape)
+	got, ok := th.Style(Escape)
+	if !ok || !reflect.DeepEqual(got, th[String]) {
+		t.Fatalf("escape should inherit string style")
+	}
+	if _, ok := th.Style(Text); ok {
+		t.Fatalf("text must not be styled")
+	}
+	if _, ok := th.Style(Name); ok {
+		t.Fatalf("name is unstyled in the default theme")
+	}
+}
@@ -0,0 +1,282 @@
+package highlight
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+// SemanticLegend is the server's semantic tokens legend: token type and
+// modifier names indexed by the integers in token data.
+type SemanticLegend struct {
+	TokenTypes     []string
+	TokenModifiers []string
+}
+
+// SemanticToken is a decoded semantic token on one row, in raw grapheme
+// columns, half-open [StartGraphemeCol, EndGraphemeCol).
+type SemanticToken struct {
+	Row              int
+	StartGraphemeCol int
+	EndGraphemeCol   int
+	Type             string
+	Modifiers        []string
+}
+
+// HasModifier reports whether t carries the named modifier.
+func (t SemanticToken) HasModifier(name string) bool {
+	return slices.Contains(t.Modifiers, name)
+}
+
+// SemanticTokensEdit is one edit of a semanticTokens/full/delta response:
+// replace DeleteCount integers at Start of the previous data with Data.
+type SemanticTokensEdit struct {
+	Start       int
+	DeleteCount int
+	Data        []uint32
+}
+
+// DecodeSemanticTokens decodes LSP relative token data (five integers per
+// token: deltaLine, deltaStartChar, length, tokenType, tokenModifiers) against
+// b. Character offsets are UTF-16 code units within a line and are converted
+// through the buffer's UTF-16 index. Offsets inside a grapheme cluster widen
+// the token to cover the cluster; tokens past the end of a line are clamped to
+// it and tokens past the last line are dropped.
+func DecodeSemanticTokens(b *buffer.Buffer, legend SemanticLegend, data []uint32) ([]SemanticToken, error) {
+	if len(data)%5 != 0 {
+		return nil, fmt.Errorf("highlight: semantic token data length %d is not a multiple of 5", len(data))
+	}
+
+	var out []SemanticToken
+	lineCount := b.LineCount()
+	line, char := 0, 0
+	cachedRow, lineStart, lineEnd := -1, 0, 0
+	for i := 0; i < len(data); i += 5 {
+		if data[i] > 0 {
+			line += int(data[i])
+			char = int(data[i+1])
+		} else {
+			char += int(data[i+1])
+		}
+		length, typ, mods := int(data[i+2]), int(data[i+3]), data[i+4]
+		if typ >= len(legend.TokenTypes) {
+			return nil, fmt.Errorf("highlight: semantic token %d has unknown type index %d", i/5, typ)
+		}
+		if line >= lineCount {
+			continue
+		}
+
+		if line != cachedRow {
+			cachedRow = line
+			lineStart, _ = b.UTF16OffsetFromPos(buffer.Pos{Row: line}, buffer.ConvertPolicy{ClampMode: buffer.OffsetError})
+			lineEnd, _ = b.UTF16OffsetFromPos(buffer.Pos{Row: line, GraphemeCol: math.MaxInt}, buffer.ConvertPolicy{ClampMode: buffer.OffsetClamp})
+		}
+		start := min(lineStart+char, lineEnd)
+		end := min(start+length, lineEnd)
+		startPos := posAtUTF16(b, start, lineStart, -1)
+		endPos := posAtUTF16(b, end, lineEnd, +1)
+		if endPos.GraphemeCol <= startPos.GraphemeCol {
+			continue
+		}
+
+		out = append(out, SemanticToken{
+			Row:              line,
+			StartGraphemeCol: startPos.GraphemeCol,
+			EndGraphemeCol:   endPos.GraphemeCol,
+			Type:             legend.TokenTypes[typ],
+			Modifiers:        semanticModifiers(legend, mods),
+		})
+	}
+	return out, nil
+}
+
+// posAtUTF16 maps a UTF-16 offset within one line to a position, stepping
+// toward bound (dir -1 or +1) out of a grapheme cluster. bound is a valid
+// line boundary offset, so the walk always terminates.
+func posAtUTF16(b *buffer.Buffer, off, bound, dir int) buffer.Pos {
+	policy := buffer.ConvertPolicy{ClampMode: buffer.OffsetError}
+	for {
+		if p, ok := b.PosFromUTF16Offset(off, policy); ok {
+			return p
+		}
+		if off == bound {
+			return buffer.Pos{}
+		}
+		off += dir
+	}
+}
+
+func semanticModifiers(legend SemanticLegend, bits uint32) []string {
+	if bits == 0 {
+		return nil
+	}
+	var out []string
+	for i, name := range legend.TokenModifiers {
+		if i < 32 && bits&(1<<i) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// SemanticTokens holds one document's semantic tokens: the raw data of the
+// last result (for delta updates) and its decoding indexed by row.
+//
+// Decoded rows follow local edits until the server sends fresh tokens: tokens
+// overlapping an edit are dropped and tokens after it move with the text. When
+// an edit cannot be followed (several text changes between reads), decoded
+// tokens are dropped. After SetFull or ApplyDelta, call editor
+// Model.InvalidateHighlight so the new tokens render.
+type SemanticTokens struct {
+	buf      *buffer.Buffer
+	legend   SemanticLegend
+	resultID string
+	data     []uint32
+
+	rows        [][]SemanticToken
+	textVersion uint64
+}
+
+// NewSemanticTokens returns an empty token set for the document in b, e.g.
+// the editor's Model.Buffer().
+func NewSemanticTokens(b *buffer.Buffer, legend SemanticLegend) *SemanticTokens {
+	return &SemanticTokens{buf: b, legend: legend, textVersion: b.TextVersion()}
+}
+
+// ResultID returns the result id of the last full or delta response, for the
+// next semanticTokens/full/delta request.
+func (s *SemanticTokens) ResultID() string {
+	return s.resultID
+}
+
+// SetFull replaces all tokens with a semanticTokens/full response. The
+// response must describe the current buffer text.
+func (s *SemanticTokens) SetFull(resultID string, data []uint32) error {
+	toks, err := DecodeSemanticTokens(s.buf, s.legend, data)
+	if err != nil {
+		return err
+	}
+	s.resultID = resultID
+	s.data = slices.Clone(data)
+	s.setRows(toks)
+	return nil
+}
+
+// ApplyDelta applies a semanticTokens/full/delta response to the data of the
+// previous result and re-decodes it. Edits refer to the previous data and
+// must not overlap. On error the previous tokens are kept.
+func (s *SemanticTokens) ApplyDelta(resultID string, edits []SemanticTokensEdit) error {
+	sorted := slices.Clone(edits)
+	slices.SortStableFunc(sorted, func(x, y SemanticTokensEdit) int { return x.Start - y.Start })
+
+	data := make([]uint32, 0, len(s.data))
+	at := 0
+	for _, e := range sorted {
+		if e.Start < at || e.DeleteCount < 0 || e.Start+e.DeleteCount > len(s.data) {
+			return fmt.Errorf("highlight: invalid semantic tokens edit at %d (delete %d) for data length %d", e.Start, e.DeleteCount, len(s.data))
+		}
+		data = append(data, s.data[at:e.Start]...)
+		data = append(data, e.Data...)
+		at = e.Start + e.DeleteCount
+	}
+	data = append(data, s.data[at:]...)
+
+	toks, err := DecodeSemanticTokens(s.buf, s.legend, data)
+	if err != nil {
+		return err
+	}
+	s.resultID = resultID
+	s.data = data
+	s.setRows(toks)
+	return nil
+}
+
+// Clear drops all tokens and the result id.
+func (s *SemanticTokens) Clear() {
+	s.resultID = ""
+	s.data = nil
+	s.rows = nil
+	s.textVersion = s.buf.TextVersion()
+}
+
+// Row returns the tokens on row, ordered by column.
+func (s *SemanticTokens) Row(row int) []SemanticToken {
+	s.sync()
+	if row < 0 || row >= len(s.rows) {
+		return nil
+	}
+	return s.rows[row]
+}
+
+func (s *SemanticTokens) setRows(toks []SemanticToken) {
+	s.rows = make([][]SemanticToken, s.buf.LineCount())
+	for _, t := range toks {
+		s.rows[t.Row] = append(s.rows[t.Row], t)
+	}
+	s.textVersion = s.buf.TextVersion()
+}
+
+// sync moves decoded rows through the buffer's last change when it is the
+// only text change since the last read.
+func (s *SemanticTokens) sync() {
+	tv := s.buf.TextVersion()
+	if tv == s.textVersion {
+		return
+	}
+	ch, ok := s.buf.LastChange()
+	if tv == s.textVersion+1 && ok && len(ch.AppliedEdits) > 0 {
+		for _, e := range ch.AppliedEdits {
+			s.applyEdit(e)
+		}
+	} else {
+		s.rows = nil
+	}
+	s.textVersion = tv
+}
+
+func (s *SemanticTokens) applyEdit(e buffer.AppliedEdit) {
+	before, after := e.RangeBefore, e.RangeAfter
+	if before.Start.Row >= len(s.rows) {
+		return
+	}
+	endRow := min(before.End.Row, len(s.rows)-1)
+
+	// Tokens on the first edited row before the edit stay; tokens on the last
+	// edited row after the edit move to the new end row.
+	var head, tail []SemanticToken
+	for _, t := range s.rows[before.Start.Row] {
+		if t.EndGraphemeCol <= before.Start.GraphemeCol {
+			head = append(head, t)
+		}
+	}
+	colDelta := after.End.GraphemeCol - before.End.GraphemeCol
+	for _, t := range s.rows[endRow] {
+		if t.StartGraphemeCol >= before.End.GraphemeCol && endRow == before.End.Row {
+			t.StartGraphemeCol += colDelta
+			t.EndGraphemeCol += colDelta
+			tail = append(tail, t)
+		}
+	}
+
+	newRows := after.End.Row - before.Start.Row + 1
+	mid := make([][]SemanticToken, newRows)
+	mid[0] = head
+	mid[newRows-1] = append(mid[newRows-1], tail...)
+
+	rows := make([][]SemanticToken, 0, len(s.rows)-(endRow-before.Start.Row+1)+newRows)
+	rows = append(rows, s.rows[:before.Start.Row]...)
+	rows = append(rows, mid...)
+	rows = append(rows, s.rows[endRow+1:]...)
+	renumberTo := before.Start.Row + newRows
+	if newRows != endRow-before.Start.Row+1 {
+		renumberTo = len(rows)
+	}
+	for r := before.Start.Row; r < renumberTo; r++ {
+		for i := range rows[r] {
+			rows[r][i].Row = r
+		}
+	}
+	s.rows = rows
+}
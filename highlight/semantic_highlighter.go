@@ -0,0 +1,227 @@
+package highlight
+
+import (
+	"slices"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// SemanticPrecedence controls how a semantic token style combines with a base
+// highlighter span where they overlap.
+type SemanticPrecedence int
+
+const (
+	// SemanticOver applies the semantic style over the base style: attributes
+	// the semantic style sets win, others come from the base span.
+	SemanticOver SemanticPrecedence = iota
+	// SemanticReplace uses the semantic style alone.
+	SemanticReplace
+	// SemanticUnder applies the base style over the semantic style, so
+	// semantic tokens only fill attributes the base span leaves unset.
+	SemanticUnder
+)
+
+// SemanticHighlighter layers semantic token spans over a base highlighter.
+//
+// Outside semantic tokens, base spans render unchanged; inside them, styles
+// combine according to Precedence. When Base implements
+// editor.StatefulHighlighter, line states are passed through.
+type SemanticHighlighter struct {
+	// Base optionally supplies syntax spans, e.g. a *Highlighter.
+	Base   editor.Highlighter
+	Tokens *SemanticTokens
+	// Style resolves a token's style. When nil, SemanticStyle(DefaultTheme())
+	// is used.
+	Style      func(SemanticToken) (lipgloss.Style, bool)
+	Precedence SemanticPrecedence
+}
+
+var _ editor.StatefulHighlighter = (*SemanticHighlighter)(nil)
+
+// HighlightLine layers semantic spans over Base.HighlightLine.
+func (h *SemanticHighlighter) HighlightLine(ctx editor.LineContext) ([]editor.HighlightSpan, error) {
+	var base []editor.HighlightSpan
+	if h.Base != nil {
+		var err error
+		if base, err = h.Base.HighlightLine(ctx); err != nil {
+			base = nil
+		}
+	}
+	return h.layer(ctx, base), nil
+}
+
+// HighlightLineState layers semantic spans over a stateful Base, carrying its
+// state. For other bases it behaves like HighlightLine with a nil state.
+func (h *SemanticHighlighter) HighlightLineState(ctx editor.LineContext, prev editor.HighlightState) ([]editor.HighlightSpan, editor.HighlightState, error) {
+	sh, ok := h.Base.(editor.StatefulHighlighter)
+	if !ok {
+		spans, err := h.HighlightLine(ctx)
+		return spans, nil, err
+	}
+	base, next, err := sh.HighlightLineState(ctx, prev)
+	if err != nil {
+		base, next = nil, prev
+	}
+	return h.layer(ctx, base), next, nil
+}
+
+func (h *SemanticHighlighter) layer(ctx editor.LineContext, base []editor.HighlightSpan) []editor.HighlightSpan {
+	var toks []SemanticToken
+	if h.Tokens != nil {
+		toks = h.Tokens.Row(ctx.Row)
+	}
+	if len(toks) == 0 {
+		return base
+	}
+
+	styleFor := h.Style
+	if styleFor == nil {
+		styleFor = SemanticStyle(DefaultTheme())
+	}
+	sem := make([]editor.HighlightSpan, 0, len(toks))
+	for _, t := range toks {
+		st, ok := styleFor(t)
+		if !ok {
+			continue
+		}
+		sem = append(sem, editor.HighlightSpan{
+			StartGraphemeCol: ctx.VisibleGraphemeCol(t.StartGraphemeCol),
+			EndGraphemeCol:   ctx.VisibleGraphemeCol(t.EndGraphemeCol),
+			Style:            st,
+		})
+	}
+	sem = disjointSpans(sem)
+	if len(sem) == 0 {
+		return base
+	}
+	return layerSpans(disjointSpans(base), sem, h.Precedence)
+}
+
+// layerSpans combines two sorted, disjoint span lists into one, splitting
+// spans at every boundary of the other list.
+func layerSpans(base, sem []editor.HighlightSpan, prec SemanticPrecedence) []editor.HighlightSpan {
+	bounds := make([]int, 0, 2*(len(base)+len(sem)))
+	for _, sp := range base {
+		bounds = append(bounds, sp.StartGraphemeCol, sp.EndGraphemeCol)
+	}
+	for _, sp := range sem {
+		bounds = append(bounds, sp.StartGraphemeCol, sp.EndGraphemeCol)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var out []editor.HighlightSpan
+	bi, si := 0, 0
+	prevB, prevS := -1, -1
+	for k := 0; k+1 < len(bounds); k++ {
+		from, to := bounds[k], bounds[k+1]
+		for bi < len(base) && base[bi].EndGraphemeCol <= from {
+			bi++
+		}
+		for si < len(sem) && sem[si].EndGraphemeCol <= from {
+			si++
+		}
+		b, s := -1, -1
+		if bi < len(base) && base[bi].StartGraphemeCol <= from {
+			b = bi
+		}
+		if si < len(sem) && sem[si].StartGraphemeCol <= from {
+			s = si
+		}
+		if b < 0 && s < 0 {
+			continue
+		}
+		if s >= 0 && prec == SemanticReplace {
+			b = -1
+		}
+		if n := len(out); n > 0 && b == prevB && s == prevS && out[n-1].EndGraphemeCol == from {
+			out[n-1].EndGraphemeCol = to
+			continue
+		}
+		prevB, prevS = b, s
+
+		var st lipgloss.Style
+		switch {
+		case s < 0:
+			st = base[b].Style
+		case b < 0:
+			st = sem[s].Style
+		case prec == SemanticUnder:
+			st = base[b].Style.Inherit(sem[s].Style)
+		default:
+			st = sem[s].Style.Inherit(base[b].Style)
+		}
+		out = append(out, editor.HighlightSpan{StartGraphemeCol: from, EndGraphemeCol: to, Style: st})
+	}
+	return out
+}
+
+// disjointSpans sorts spans and drops empty and overlapping ones, keeping
+// the earliest, matching how the editor resolves overlaps.
+func disjointSpans(spans []editor.HighlightSpan) []editor.HighlightSpan {
+	out := make([]editor.HighlightSpan, 0, len(spans))
+	for _, sp := range spans {
+		if sp.EndGraphemeCol > sp.StartGraphemeCol {
+			out = append(out, sp)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b editor.HighlightSpan) int {
+		if a.StartGraphemeCol != b.StartGraphemeCol {
+			return a.StartGraphemeCol - b.StartGraphemeCol
+		}
+		return a.EndGraphemeCol - b.EndGraphemeCol
+	})
+	n := 0
+	for _, sp := range out {
+		if n > 0 && sp.StartGraphemeCol < out[n-1].EndGraphemeCol {
+			continue
+		}
+		out[n] = sp
+		n++
+	}
+	return out[:n]
+}
+
+// SemanticTokenType maps a standard LSP semantic token to a TokenType.
+// Unknown types map to Name.
+func SemanticTokenType(t SemanticToken) TokenType {
+	switch t.Type {
+	case "function", "method", "decorator":
+		if t.HasModifier("defaultLibrary") {
+			return Builtin
+		}
+		return Function
+	case "macro":
+		return Builtin
+	case "variable", "parameter", "property":
+		if t.HasModifier("readonly") {
+			return Constant
+		}
+		return Variable
+	case "enumMember":
+		return Constant
+	case "type", "class", "enum", "interface", "struct", "typeParameter":
+		return KeywordType
+	case "keyword", "modifier":
+		return Keyword
+	case "comment":
+		return Comment
+	case "string", "regexp":
+		return String
+	case "number":
+		return Number
+	case "operator":
+		return Operator
+	}
+	return Name
+}
+
+// SemanticStyle returns a token style resolver that maps tokens through
+// SemanticTokenType and theme.
+func SemanticStyle(theme Theme) func(SemanticToken) (lipgloss.Style, bool) {
+	return func(t SemanticToken) (lipgloss.Style, bool) {
+		return theme.Style(SemanticTokenType(t))
+	}
+}
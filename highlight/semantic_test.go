@@ -0,0 +1,197 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+var testLegend = SemanticLegend{
+	TokenTypes:     []string{"variable", "function", "type"},
+	TokenModifiers: []string{"declaration", "readonly"},
+}
+
+type span struct{ row, start, end int }
+
+func tokenSpans(toks []SemanticToken) []span {
+	var out []span
+	for _, t := range toks {
+		out = append(out, span{t.Row, t.StartGraphemeCol, t.EndGraphemeCol})
+	}
+	return out
+}
+
+func TestDecodeSemanticTokens_UTF16(t *testing.T) {
+	b := buffer.New("a😀bc := x\n  fn()", buffer.Options{})
+	toks, err := DecodeSemanticTokens(b, testLegend, []uint32{
+		0, 3, 2, 0, 3, // "bc" after a surrogate pair
+		0, 6, 1, 0, 0, // "x"
+		1, 2, 2, 1, 0, // "fn"
+		0, 0, 99, 1, 0, // clamped to the line end
+		5, 0, 1, 0, 0, // past the last line
+	})
+	if err != nil {
+		t.Fatalf("DecodeSemanticTokens: %v", err)
+	}
+	want := []span{{0, 2, 4}, {0, 8, 9}, {1, 2, 4}, {1, 2, 6}}
+	if got := tokenSpans(toks); !reflect.DeepEqual(got, want) {
+		t.Fatalf("spans: got %v, want %v", got, want)
+	}
+	if toks[0].Type != "variable" || !reflect.DeepEqual(toks[0].Modifiers, []string{"declaration", "readonly"}) {
+		t.Fatalf("type/modifiers: %+v", toks[0])
+	}
+	if !toks[0].HasModifier("readonly") || toks[1].HasModifier("readonly") {
+		t.Fatalf("HasModifier mismatch")
+	}
+
+	// An offset inside the surrogate pair widens the token to the grapheme.
+	toks, _ = DecodeSemanticTokens(b, testLegend, []uint32{0, 2, 1, 0, 0})
+	if got := tokenSpans(toks); !reflect.DeepEqual(got, []span{{0, 1, 2}}) {
+		t.Fatalf("split grapheme: %v", got)
+	}
+
+	if _, err := DecodeSemanticTokens(b, testLegend, []uint32{0, 0, 1}); err == nil {
+		t.Fatalf("short data: expected error")
+	}
+	if _, err := DecodeSemanticTokens(b, testLegend, []uint32{0, 0, 1, 7, 0}); err == nil {
+		t.Fatalf("unknown type: expected error")
+	}
+}
+
+func TestSemanticTokens_ApplyDelta(t *testing.T) {
+	b := buffer.New("aa bb cc", buffer.Options{})
+	s := NewSemanticTokens(b, testLegend)
+	if err := s.SetFull("1", []uint32{0, 0, 2, 0, 0, 0, 3, 2, 0, 0, 0, 3, 2, 0, 0}); err != nil {
+		t.Fatalf("SetFull: %v", err)
+	}
+
+	// Drop "bb" and retype "cc" as a function.
+	err := s.ApplyDelta("2", []SemanticTokensEdit{
+		{Start: 10, DeleteCount: 5, Data: []uint32{0, 6, 2, 1, 0}},
+		{Start: 5, DeleteCount: 5},
+	})
+	if err != nil {
+		t.Fatalf("ApplyDelta: %v", err)
+	}
+	if s.ResultID() != "2" {
+		t.Fatalf("ResultID: %q", s.ResultID())
+	}
+	row := s.Row(0)
+	if got := tokenSpans(row); !reflect.DeepEqual(got, []span{{0, 0, 2}, {0, 6, 8}}) || row[1].Type != "function" {
+		t.Fatalf("after delta: %+v", row)
+	}
+
+	if err := s.ApplyDelta("3", []SemanticTokensEdit{{Start: 8, DeleteCount: 5}}); err == nil {
+		t.Fatalf("out of range edit: expected error")
+	}
+	if s.ResultID() != "2" || len(s.Row(0)) != 2 {
+		t.Fatalf("failed delta must keep previous tokens")
+	}
+}
+
+func TestSemanticTokens_FollowEdits(t *testing.T) {
+	b := buffer.New("aa bb\ncc dd", buffer.Options{})
+	s := NewSemanticTokens(b, testLegend)
+	if err := s.SetFull("1", []uint32{0, 0, 2, 0, 0, 0, 3, 2, 0, 0, 1, 0, 2, 0, 0, 0, 3, 2, 0, 0}); err != nil {
+		t.Fatalf("SetFull: %v", err)
+	}
+
+	// Split the first line inside "bb": "aa" stays, "bb" is dropped.
+	b.Apply(buffer.TextEdit{Range: buffer.Range{Start: buffer.Pos{Row: 0, GraphemeCol: 4}, End: buffer.Pos{Row: 0, GraphemeCol: 4}}, Text: "\nx"})
+	got := append(tokenSpans(s.Row(0)), append(tokenSpans(s.Row(1)), tokenSpans(s.Row(2))...)...)
+	if want := []span{{0, 0, 2}, {2, 0, 2}, {2, 3, 5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after newline: got %v, want %v", got, want)
+	}
+
+	// Insert before "dd": it shifts right.
+	b.Apply(buffer.TextEdit{Range: buffer.Range{Start: buffer.Pos{Row: 2, GraphemeCol: 3}, End: buffer.Pos{Row: 2, GraphemeCol: 3}}, Text: "zz"})
+	if got := tokenSpans(s.Row(2)); !reflect.DeepEqual(got, []span{{2, 0, 2}, {2, 5, 7}}) {
+		t.Fatalf("after insert: %v", got)
+	}
+
+	// Two changes between reads cannot be followed.
+	b.Apply(buffer.TextEdit{Range: buffer.Range{Start: buffer.Pos{Row: 0}, End: buffer.Pos{Row: 0}}, Text: "q"})
+	b.Apply(buffer.TextEdit{Range: buffer.Range{Start: buffer.Pos{Row: 0}, End: buffer.Pos{Row: 0}}, Text: "q"})
+	if len(s.Row(0)) != 0 || len(s.Row(2)) != 0 {
+		t.Fatalf("expected tokens to be dropped")
+	}
+}
+
+func TestSemanticHighlighter_Precedence(t *testing.T) {
+	b := buffer.New("func f() x", buffer.Options{})
+	s := NewSemanticTokens(b, testLegend)
+	// "func f" as one function token overlapping the keyword span.
+	if err := s.SetFull("1", []uint32{0, 2, 4, 1, 0}); err != nil {
+		t.Fatalf("SetFull: %v", err)
+	}
+
+	kw := lipgloss.NewStyle().Bold(true)
+	fn := lipgloss.NewStyle().Italic(true)
+	h := &SemanticHighlighter{
+		Base:   New(Go(), Theme{Keyword: kw}),
+		Tokens: s,
+		Style:  SemanticStyle(Theme{Function: fn}),
+	}
+	ctx := editor.LineContext{Row: 0, Text: "func f() x"}
+
+	type result struct {
+		start, end int
+		bold, ital bool
+	}
+	run := func(p SemanticPrecedence) []result {
+		h.Precedence = p
+		spans, st, err := h.HighlightLineState(ctx, nil)
+		if err != nil || st == nil {
+			t.Fatalf("HighlightLineState: err=%v state=%v", err, st)
+		}
+		var out []result
+		for _, sp := range spans {
+			out = append(out, result{sp.StartGraphemeCol, sp.EndGraphemeCol, sp.Style.GetBold(), sp.Style.GetItalic()})
+		}
+		return out
+	}
+
+	if got, want := run(SemanticOver), []result{{0, 2, true, false}, {2, 4, true, true}, {4, 6, false, true}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("over: got %v, want %v", got, want)
+	}
+	if got, want := run(SemanticReplace), []result{{0, 2, true, false}, {2, 6, false, true}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("replace: got %v, want %v", got, want)
+	}
+
+	kw = kw.Italic(false)
+	h.Base = New(Go(), Theme{Keyword: kw})
+	if got, want := run(SemanticUnder), []result{{0, 2, true, false}, {2, 4, true, false}, {4, 6, false, true}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("under: got %v, want %v", got, want)
+	}
+}
+
+func TestSemanticHighlighter_VisibleColumns(t *testing.T) {
+	b := buffer.New("abcdef", buffer.Options{})
+	s := NewSemanticTokens(b, testLegend)
+	if err := s.SetFull("1", []uint32{0, 4, 2, 0, 0}); err != nil {
+		t.Fatalf("SetFull: %v", err)
+	}
+	m := editor.New(editor.Config{
+		Text: "abcdef",
+		Highlighter: &SemanticHighlighter{
+			Tokens: s,
+			Style: func(SemanticToken) (lipgloss.Style, bool) {
+				return lipgloss.NewStyle().Transform(strings.ToUpper), true
+			},
+		},
+		VirtualTextProvider: func(editor.VirtualTextContext) editor.VirtualText {
+			return editor.VirtualText{Deletions: []editor.VirtualDeletion{{StartGraphemeCol: 0, EndGraphemeCol: 2}}}
+		},
+	}).SetSize(10, 1)
+
+	// "ef" is raw 4..6 and visible 2..4 once "ab" is hidden.
+	if got := ansi.Strip(m.View().Content); !strings.Contains(got, "cdEF") {
+		t.Fatalf("semantic span not mapped to visible columns: %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package highlight
+
+import "charm.land/lipgloss/v2"
+
+// Theme maps token types to styles. Lookups fall back through parent types,
+// so a theme may style "string" and let "string.escape" inherit it.
+type Theme map[TokenType]lipgloss.Style
+
+// Style returns the style for t or its nearest styled parent. Text is never
+// styled.
+func (th Theme) Style(t TokenType) (lipgloss.Style, bool) {
+	for ; t != Text; t = t.Parent() {
+		if s, ok := th[t]; ok {
+			return s, true
+		}
+	}
+	return lipgloss.Style{}, false
+}
+
+// DefaultTheme returns a 256-color theme for dark terminal backgrounds.
+func DefaultTheme() Theme {
+	fg := func(c string) lipgloss.Style { return lipgloss.NewStyle().Foreground(lipgloss.Color(c)) }
+	return Theme{
+		Comment:     fg("244").Italic(true),
+		Keyword:     fg("170"),
+		KeywordType: fg("81"),
+		Constant:    fg("209"),
+		Function:    fg("75"),
+		Builtin:     fg("81"),
+		Variable:    fg("180"),
+		Key:         fg("75"),
+		String:      fg("114"),
+		Escape:      fg("180"),
+		Number:      fg("209"),
+		Operator:    fg("250"),
+
+		Heading:  fg("75").Bold(true),
+		Emphasis: lipgloss.NewStyle().Italic(true),
+		Strong:   lipgloss.NewStyle().Bold(true),
+		Code:     fg("114"),
+		Link:     fg("39").Underline(true),
+		List:     fg("170"),
+		Quote:    fg("244").Italic(true),
+	}
+}
@@ -0,0 +1,42 @@
+package highlight
+
+import "strings"
+
+// TokenType classifies lexed text. Types are dot-separated from general to
+// specific (e.g. "string.escape"); a Theme without a style for a type falls
+// back to its parent ("string").
+type TokenType string
+
+const (
+	Text        TokenType = ""
+	Comment     TokenType = "comment"
+	Keyword     TokenType = "keyword"
+	KeywordType TokenType = "keyword.type"
+	Constant    TokenType = "constant"
+	Name        TokenType = "name"
+	Function    TokenType = "name.function"
+	Builtin     TokenType = "name.builtin"
+	Variable    TokenType = "name.variable"
+	Key         TokenType = "name.key"
+	String      TokenType = "string"
+	Escape      TokenType = "string.escape"
+	Number      TokenType = "number"
+	Operator    TokenType = "operator"
+	Punctuation TokenType = "punctuation"
+
+	Heading  TokenType = "markup.heading"
+	Emphasis TokenType = "markup.emphasis"
+	Strong   TokenType = "markup.strong"
+	Code     TokenType = "markup.code"
+	Link     TokenType = "markup.link"
+	List     TokenType = "markup.list"
+	Quote    TokenType = "markup.quote"
+)
+
+// Parent returns the next more general type, or Text for top-level types.
+func (t TokenType) Parent() TokenType {
+	if i := strings.LastIndexByte(string(t), '.'); i >= 0 {
+		return t[:i]
+	}
+	return Text
+}
@@ -0,0 +1,474 @@
+// Package toml decodes the TOML subset used by theme and keymap files.
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse decodes the TOML subset used by theme and keymap files into nested
+// maps: comments, [table] headers with dotted keys, key = value pairs (bare,
+// quoted and dotted keys), basic and literal strings, booleans, integers,
+// floats, arrays (which may span lines) and single-line inline tables. A table
+// header may appear only once. Multi-line strings, dates and arrays of tables
+// are not supported.
+func Parse(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	current := root
+	defined := map[string]bool{}
+	p := &parser{lines: strings.Split(string(data), "\n")}
+	for p.nextLine() {
+		p.skipSpace()
+		if p.done() || p.peek() == '#' {
+			continue
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			if !p.done() && p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			path, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume(']') {
+				return nil, p.errorf("expected ']'")
+			}
+			if err := p.end(); err != nil {
+				return nil, err
+			}
+			name := strings.Join(path, "\x00")
+			if defined[name] {
+				return nil, p.errorf("table %q defined twice", strings.Join(path, "."))
+			}
+			defined[name] = true
+			if current, err = p.table(root, path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := p.keyValue(current); err != nil {
+			return nil, err
+		}
+		if err := p.end(); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// parser reads one line at a time; s is the current line and line its
+// 1-based number. Only arrays read past the end of a line.
+type parser struct {
+	lines []string
+	s     string
+	pos   int
+	line  int
+}
+
+// nextLine moves to the start of the next line, reporting false at the end
+// of the input.
+func (p *parser) nextLine() bool {
+	if p.line >= len(p.lines) {
+		return false
+	}
+	p.s = strings.TrimSuffix(p.lines[p.line], "\r")
+	p.pos = 0
+	p.line++
+	return true
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("toml line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.s) }
+
+func (p *parser) peek() byte { return p.s[p.pos] }
+
+func (p *parser) skipSpace() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipArraySpace skips whitespace, comments and line breaks inside an array,
+// reporting false at the end of the input.
+func (p *parser) skipArraySpace() bool {
+	for {
+		p.skipSpace()
+		if !p.done() && p.peek() != '#' {
+			return true
+		}
+		if !p.nextLine() {
+			return false
+		}
+	}
+}
+
+func (p *parser) consume(c byte) bool {
+	if !p.done() && p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// end accepts trailing whitespace and an optional comment.
+func (p *parser) end() error {
+	p.skipSpace()
+	if !p.done() && p.peek() != '#' {
+		return p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return nil
+}
+
+// table returns the table at path, creating intermediate tables.
+func (p *parser) table(root map[string]any, path []string) (map[string]any, error) {
+	t := root
+	for _, k := range path {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			t = v
+		default:
+			return nil, p.errorf("key %q is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+func (p *parser) keyValue(t map[string]any) error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if !p.consume('=') {
+		return p.errorf("expected '=' after key")
+	}
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.table(t, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, dup := parent[last]; dup {
+		return p.errorf("duplicate key %q", last)
+	}
+	parent[last] = v
+	return nil
+}
+
+// key parses a dotted key of bare or quoted parts.
+func (p *parser) key() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace()
+		if p.done() {
+			return nil, p.errorf("expected key")
+		}
+		var part string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.done() && isBareKeyByte(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("invalid key character %q", c)
+			}
+			part = p.s[start:p.pos]
+		}
+		path = append(path, part)
+		p.skipSpace()
+		if !p.consume('.') {
+			return path, nil
+		}
+	}
+}
+
+func isBareKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) value() (any, error) {
+	if p.done() {
+		return nil, p.errorf("expected value")
+	}
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '{':
+		return p.inlineTable()
+	case c == '[':
+		return p.array()
+	}
+
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(" \t,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if v, ok := number(word); ok {
+		return v, nil
+	}
+	return nil, p.errorf("invalid value %q", word)
+}
+
+// number decodes a TOML integer (int64) or float. Integers are decimal
+// without leading zeros, or unsigned with an explicit 0x, 0o or 0b prefix;
+// underscores must sit between digits.
+func number(word string) (any, bool) {
+	sign, body := "", word
+	if body != "" && (body[0] == '+' || body[0] == '-') {
+		sign, body = body[:1], body[1:]
+	}
+
+	if len(body) > 2 && body[0] == '0' {
+		base, isDigit := 0, isDecDigit
+		switch body[1] {
+		case 'x':
+			base, isDigit = 16, isHexDigit
+		case 'o':
+			base, isDigit = 8, func(c byte) bool { return c >= '0' && c <= '7' }
+		case 'b':
+			base, isDigit = 2, func(c byte) bool { return c == '0' || c == '1' }
+		}
+		if base != 0 {
+			digits, ok := stripUnderscores(body[2:], isDigit)
+			if !ok || sign != "" {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(digits, base, 64)
+			return n, err == nil
+		}
+	}
+
+	switch body {
+	case "inf", "nan":
+		f, _ := strconv.ParseFloat(sign+body, 64)
+		return f, true
+	}
+	clean, ok := stripUnderscores(body, isDecDigit)
+	if !ok {
+		return nil, false
+	}
+	intLen := 0
+	for intLen < len(clean) && isDecDigit(clean[intLen]) {
+		intLen++
+	}
+	if intLen == 0 || intLen > 1 && clean[0] == '0' {
+		return nil, false
+	}
+	if intLen == len(clean) {
+		n, err := strconv.ParseInt(sign+clean, 10, 64)
+		return n, err == nil
+	}
+	if !isDecimalFloatTail(clean[intLen:]) {
+		return nil, false
+	}
+	f, err := strconv.ParseFloat(sign+clean, 64)
+	return f, err == nil
+}
+
+// isDecimalFloatTail reports whether s is a TOML float fraction and/or
+// exponent: ["." digits] [("e" | "E") [sign] digits], not empty.
+func isDecimalFloatTail(s string) bool {
+	digits := func(s string) (rest string, ok bool) {
+		i := 0
+		for i < len(s) && isDecDigit(s[i]) {
+			i++
+		}
+		return s[i:], i > 0
+	}
+	ok := true
+	if s != "" && s[0] == '.' {
+		if s, ok = digits(s[1:]); !ok {
+			return false
+		}
+	}
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if s, ok = digits(s); !ok {
+			return false
+		}
+	}
+	return s == ""
+}
+
+// stripUnderscores removes digit separators from s, reporting false when an
+// underscore is not between two digits.
+func stripUnderscores(s string, isDigit func(byte) bool) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i == 0 || i+1 == len(s) || !isDigit(s[i-1]) || !isDigit(s[i+1]) {
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+func isDecDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHexDigit(c byte) bool {
+	return isDecDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func (p *parser) inlineTable() (map[string]any, error) {
+	p.pos++ // {
+	t := map[string]any{}
+	p.skipSpace()
+	if p.consume('}') {
+		return t, nil
+	}
+	for {
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.consume('}') {
+			return t, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *parser) array() ([]any, error) {
+	p.pos++ // [
+	out := []any{}
+	for {
+		if !p.skipArraySpace() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.consume(']') {
+			return out, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if !p.skipArraySpace() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.consume(']') {
+			return out, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// str parses a basic ("...") or literal ('...') single-line string.
+func (p *parser) str() (string, error) {
+	quote := p.peek()
+	p.pos++
+	if quote == '\'' {
+		end := strings.IndexByte(p.s[p.pos:], '\'')
+		if end < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		s := p.s[p.pos : p.pos+end]
+		p.pos += end + 1
+		return s, nil
+	}
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			r, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) escape() (rune, error) {
+	p.pos++ // backslash
+	if p.done() {
+		return 0, p.errorf("unterminated escape")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case '"':
+		return '"', nil
+	case '\\':
+		return '\\', nil
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.s) {
+			return 0, p.errorf("short unicode escape")
+		}
+		v, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(v)) {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		p.pos += n
+		return rune(v), nil
+	}
+	return 0, p.errorf("invalid escape \\%c", c)
+}
@@ -0,0 +1,93 @@
+package toml
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_Subset(t *testing.T) {
+	doc, err := Parse([]byte(strings.Join([]string{
+		`# comment`,
+		`a = "x\tyé"`,
+		`b.c = [1, 2.5, -3, true, 'lit']`,
+		`"quoted key" = {}`,
+		`[t.u]`,
+		`n = 1_000`,
+		`keys = [`,
+		`  "ctrl+a", # first`,
+		``,
+		`  "ctrl+b",`,
+		`]`,
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := map[string]any{
+		"a":          "x\tyé",
+		"b":          map[string]any{"c": []any{int64(1), 2.5, int64(-3), true, "lit"}},
+		"quoted key": map[string]any{},
+		"t":          map[string]any{"u": map[string]any{"n": int64(1000), "keys": []any{"ctrl+a", "ctrl+b"}}},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("got %#v\nwant %#v", doc, want)
+	}
+
+	for _, bad := range []string{
+		"a = ",
+		"a = 1\na = 2",
+		"a = \"open",
+		"[a\nx = 1",
+		"[[a]]",
+		"[a]\nx = 1\n[a]\ny = 2",
+		"[a.b]\n[a]\n[a.b]",
+		"a = [1,\n2",
+		"a = [1\n2]",
+		"a = 1 b",
+		"a = 1\n[a]",
+		"a = 010",
+		"a = 1__0",
+		"a = _1",
+		"a = 1_",
+		"a = 1_.5",
+		"a = 0x_1",
+		"a = -0x1",
+		"a = 0X1",
+		"a = .5",
+		"a = 1.",
+		"a = 1e",
+		"a = Infinity",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestParse_Numbers(t *testing.T) {
+	for in, want := range map[string]any{
+		"0":           int64(0),
+		"-0":          int64(0),
+		"+17":         int64(17),
+		"1_000_000":   int64(1000000),
+		"0xdead_BEEF": int64(0xdeadbeef),
+		"0o755":       int64(0o755),
+		"0b1010":      int64(10),
+		"3.14":        3.14,
+		"-0.5":        -0.5,
+		"1e3":         1000.0,
+		"6.25E-2":     0.0625,
+		"1_0.2_5":     10.25,
+		"-inf":        math.Inf(-1),
+	} {
+		doc, err := Parse([]byte("a = " + in))
+		if err != nil {
+			t.Errorf("%q: %v", in, err)
+			continue
+		}
+		if got := doc["a"]; got != want {
+			t.Errorf("%q: got %#v, want %#v", in, got, want)
+		}
+	}
+}
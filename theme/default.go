@@ -0,0 +1,27 @@
+package theme
+
+import _ "embed"
+
+//go:embed default.toml
+var defaultTOML []byte
+
+// Default returns the bundled theme: a dark base matching editor.DefaultStyle
+// and highlight.DefaultTheme, with a Light override. Each call returns a
+// fresh copy the caller may modify.
+func Default() Theme {
+	t, err := ParseTOML(defaultTOML)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Dark returns the bundled theme resolved for dark backgrounds.
+func Dark() Theme {
+	return Default().Variant(true)
+}
+
+// Light returns the bundled theme resolved for light backgrounds.
+func Light() Theme {
+	return Default().Variant(false)
+}
@@ -0,0 +1,7 @@
+// Package theme provides serializable color schemes for the editor.
+//
+// A Theme covers every editor.Style field, highlight token types, keyed
+// gutter/completion/ghost/overlay styles and row-mark symbols. Themes load
+// from JSON or TOML files (including from an fs.FS), may carry light and dark
+// variants, and Apply wires a theme into an editor.Config.
+package theme
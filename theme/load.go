@@ -0,0 +1,68 @@
+package theme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/iw2rmb/flourish/internal/toml"
+)
+
+// ParseJSON decodes a theme from JSON. Unknown fields are rejected so typos
+// in style names surface as errors.
+func ParseJSON(data []byte) (Theme, error) {
+	var t Theme
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return Theme{}, fmt.Errorf("theme: %w", err)
+	}
+	return t, nil
+}
+
+// ParseTOML decodes a theme from TOML using the same field names as JSON.
+func ParseTOML(data []byte) (Theme, error) {
+	doc, err := toml.Parse(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme: %w", err)
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return Theme{}, fmt.Errorf("theme: %w", err)
+	}
+	return ParseJSON(js)
+}
+
+// Parse decodes data by file extension (".json" or ".toml").
+func Parse(name string, data []byte) (Theme, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return ParseJSON(data)
+	case ".toml":
+		return ParseTOML(data)
+	}
+	return Theme{}, fmt.Errorf("theme: unsupported file type %q", name)
+}
+
+// LoadFile reads and decodes a theme file.
+func LoadFile(name string) (Theme, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return Theme{}, err
+	}
+	return Parse(name, data)
+}
+
+// LoadFS reads and decodes a theme file from fsys, e.g. an embed.FS.
+func LoadFS(fsys fs.FS, name string) (Theme, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Theme{}, err
+	}
+	return Parse(path.Base(name), data)
+}
@@ -0,0 +1,97 @@
+package theme
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+const sampleJSON = `{
+  "name": "sample",
+  "editor": {"selection": {"bg": "#334"}, "cursor": {"reverse": true}},
+  "tokens": {"keyword": {"fg": 170, "bold": true}},
+  "gutter": {"line_num": {"fg": "240"}},
+  "row_marks": {"inserted": "+"},
+  "light": {"editor": {"selection": {"bg": "#ddeeff"}}}
+}`
+
+const sampleTOML = `
+name = "sample" # trailing comment
+
+[editor]
+selection = { bg = "#334" }
+cursor.reverse = true
+
+[tokens]
+keyword = { fg = 170, bold = true }
+
+[gutter.line_num]
+fg = "240"
+
+[row_marks]
+inserted = "+"
+
+[light.editor.selection]
+bg = '#ddeeff'
+`
+
+func TestParse_JSONAndTOMLAgree(t *testing.T) {
+	j, err := ParseJSON([]byte(sampleJSON))
+	if err != nil {
+		t.Fatalf("ParseJSON: %v", err)
+	}
+	tm, err := ParseTOML([]byte(sampleTOML))
+	if err != nil {
+		t.Fatalf("ParseTOML: %v", err)
+	}
+	if !reflect.DeepEqual(j, tm) {
+		t.Fatalf("JSON and TOML differ:\njson: %+v\ntoml: %+v", j, tm)
+	}
+	if j.Tokens["keyword"] != (StyleSpec{Foreground: "170", Bold: true}) {
+		t.Fatalf("numeric color: %+v", j.Tokens["keyword"])
+	}
+	if got := j.Variant(false).Editor.Selection.Background; got != "#ddeeff" {
+		t.Fatalf("light selection: %q", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown field": `{"editor": {"selectoin": {}}}`,
+		"bad color":     `{"editor": {"text": {"fg": "blue"}}}`,
+		"color range":   `{"editor": {"text": {"fg": 256}}}`,
+		"bad hex":       `{"editor": {"text": {"fg": "#12345"}}}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseJSON([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := ParseTOML([]byte("[editor]\ntext = { fg = \"nope\" }")); err == nil {
+		t.Errorf("toml bad color: expected error")
+	}
+	if _, err := Parse("theme.yaml", nil); err == nil {
+		t.Errorf("unsupported extension: expected error")
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"themes/a.json": {Data: []byte(sampleJSON)},
+		"themes/b.TOML": {Data: []byte(sampleTOML)},
+	}
+	a, err := LoadFS(fsys, "themes/a.json")
+	if err != nil {
+		t.Fatalf("LoadFS json: %v", err)
+	}
+	b, err := LoadFS(fsys, "themes/b.TOML")
+	if err != nil {
+		t.Fatalf("LoadFS toml: %v", err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("loaded themes differ")
+	}
+	if _, err := LoadFS(fsys, "themes/missing.json"); err == nil {
+		t.Fatalf("missing file: expected error")
+	}
+}
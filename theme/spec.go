@@ -0,0 +1,96 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Color is a terminal color: an ANSI 256 index ("0".."255") or a hex RGB
+// value ("#rgb" or "#rrggbb"). Theme files may write indices as numbers.
+type Color string
+
+// Valid reports whether c is empty or a supported color value.
+func (c Color) Valid() bool {
+	s := string(c)
+	if s == "" {
+		return true
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n json.Number
+		if json.Unmarshal(data, &n) != nil {
+			return fmt.Errorf("theme: color must be a string or number, got %s", data)
+		}
+		s = n.String()
+	}
+	if !Color(s).Valid() {
+		return fmt.Errorf("theme: invalid color %q", s)
+	}
+	*c = Color(s)
+	return nil
+}
+
+// StyleSpec is a serializable subset of lipgloss.Style: colors and text
+// attributes. Layout options are deliberately absent so themed styles keep
+// render mapping deterministic.
+type StyleSpec struct {
+	Foreground    Color `json:"fg,omitempty"`
+	Background    Color `json:"bg,omitempty"`
+	Bold          bool  `json:"bold,omitempty"`
+	Italic        bool  `json:"italic,omitempty"`
+	Underline     bool  `json:"underline,omitempty"`
+	Faint         bool  `json:"faint,omitempty"`
+	Reverse       bool  `json:"reverse,omitempty"`
+	Strikethrough bool  `json:"strikethrough,omitempty"`
+}
+
+// IsZero reports whether s sets nothing.
+func (s StyleSpec) IsZero() bool {
+	return s == StyleSpec{}
+}
+
+// Style converts s to a lipgloss style.
+func (s StyleSpec) Style() lipgloss.Style {
+	st := lipgloss.NewStyle()
+	if s.Foreground != "" {
+		st = st.Foreground(lipgloss.Color(string(s.Foreground)))
+	}
+	if s.Background != "" {
+		st = st.Background(lipgloss.Color(string(s.Background)))
+	}
+	if s.Bold {
+		st = st.Bold(true)
+	}
+	if s.Italic {
+		st = st.Italic(true)
+	}
+	if s.Underline {
+		st = st.Underline(true)
+	}
+	if s.Faint {
+		st = st.Faint(true)
+	}
+	if s.Reverse {
+		st = st.Reverse(true)
+	}
+	if s.Strikethrough {
+		st = st.Strikethrough(true)
+	}
+	return st
+}
@@ -0,0 +1,209 @@
+package theme
+
+import (
+	"maps"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/highlight"
+)
+
+// EditorStyles mirrors editor.Style with one StyleSpec per field.
+type EditorStyles struct {
+	Gutter          StyleSpec `json:"gutter"`
+	RowMarkInserted StyleSpec `json:"row_mark_inserted"`
+	RowMarkUpdated  StyleSpec `json:"row_mark_updated"`
+	RowMarkDeleted  StyleSpec `json:"row_mark_deleted"`
+
+	Text            StyleSpec `json:"text"`
+	Selection       StyleSpec `json:"selection"`
+	Cursor          StyleSpec `json:"cursor"`
+	Link            StyleSpec `json:"link"`
+	MatchingBracket StyleSpec `json:"matching_bracket"`
+
+	ScrollbarTrack  StyleSpec `json:"scrollbar_track"`
+	ScrollbarThumb  StyleSpec `json:"scrollbar_thumb"`
+	ScrollbarCorner StyleSpec `json:"scrollbar_corner"`
+
+	CompletionItem     StyleSpec `json:"completion_item"`
+	CompletionSelected StyleSpec `json:"completion_selected"`
+
+	Ghost          StyleSpec `json:"ghost"`
+	VirtualOverlay StyleSpec `json:"virtual_overlay"`
+}
+
+// Style converts s to an editor.Style.
+func (s EditorStyles) Style() editor.Style {
+	return editor.Style{
+		Gutter:             s.Gutter.Style(),
+		RowMarkInserted:    s.RowMarkInserted.Style(),
+		RowMarkUpdated:     s.RowMarkUpdated.Style(),
+		RowMarkDeleted:     s.RowMarkDeleted.Style(),
+		Text:               s.Text.Style(),
+		Selection:          s.Selection.Style(),
+		Cursor:             s.Cursor.Style(),
+		Link:               s.Link.Style(),
+		MatchingBracket:    s.MatchingBracket.Style(),
+		ScrollbarTrack:     s.ScrollbarTrack.Style(),
+		ScrollbarThumb:     s.ScrollbarThumb.Style(),
+		ScrollbarCorner:    s.ScrollbarCorner.Style(),
+		CompletionItem:     s.CompletionItem.Style(),
+		CompletionSelected: s.CompletionSelected.Style(),
+		Ghost:              s.Ghost.Style(),
+		VirtualOverlay:     s.VirtualOverlay.Style(),
+	}
+}
+
+// fields returns pointers to every spec, for merging.
+func (s *EditorStyles) fields() []*StyleSpec {
+	return []*StyleSpec{
+		&s.Gutter, &s.RowMarkInserted, &s.RowMarkUpdated, &s.RowMarkDeleted,
+		&s.Text, &s.Selection, &s.Cursor, &s.Link, &s.MatchingBracket,
+		&s.ScrollbarTrack, &s.ScrollbarThumb, &s.ScrollbarCorner,
+		&s.CompletionItem, &s.CompletionSelected,
+		&s.Ghost, &s.VirtualOverlay,
+	}
+}
+
+// RowMarkSymbols mirrors editor.RowMarkSymbols. Empty fields keep editor
+// defaults.
+type RowMarkSymbols struct {
+	Inserted     string `json:"inserted,omitempty"`
+	Updated      string `json:"updated,omitempty"`
+	DeletedAbove string `json:"deleted_above,omitempty"`
+	DeletedBelow string `json:"deleted_below,omitempty"`
+}
+
+// Theme is a complete, serializable editor color scheme.
+//
+// Keyed sections resolve the editor's *StyleForKey callbacks: Gutter by
+// GutterSegment.StyleKey (e.g. "line_num", "line_num_active"), Completion by
+// completion item/segment StyleKey, Ghost and Overlay by virtual insertion
+// StyleKey, Float by floating window StyleKey (e.g. "float", "float.code",
+// "float.hover", "float.signature"). Tokens maps highlight token types (e.g. "string.escape").
+type Theme struct {
+	Name string `json:"name,omitempty"`
+
+	Editor     EditorStyles         `json:"editor"`
+	Tokens     map[string]StyleSpec `json:"tokens,omitempty"`
+	Gutter     map[string]StyleSpec `json:"gutter,omitempty"`
+	Completion map[string]StyleSpec `json:"completion,omitempty"`
+	Ghost      map[string]StyleSpec `json:"ghost,omitempty"`
+	Overlay    map[string]StyleSpec `json:"overlay,omitempty"`
+	Float      map[string]StyleSpec `json:"float,omitempty"`
+	RowMarks   RowMarkSymbols       `json:"row_marks"`
+
+	// Light and Dark optionally override the base theme for light and dark
+	// terminal backgrounds. Use Variant to resolve one.
+	Light *Theme `json:"light,omitempty"`
+	Dark  *Theme `json:"dark,omitempty"`
+}
+
+// Variant returns the base theme with the Dark (dark=true) or Light override
+// merged in: set style specs and symbols replace base values, and keyed
+// sections merge per key. The result has no variants.
+func (t Theme) Variant(dark bool) Theme {
+	over := t.Light
+	if dark {
+		over = t.Dark
+	}
+	out := t
+	out.Light, out.Dark = nil, nil
+	out.Tokens = maps.Clone(t.Tokens)
+	out.Gutter = maps.Clone(t.Gutter)
+	out.Completion = maps.Clone(t.Completion)
+	out.Ghost = maps.Clone(t.Ghost)
+	out.Overlay = maps.Clone(t.Overlay)
+	out.Float = maps.Clone(t.Float)
+	if over == nil {
+		return out
+	}
+
+	if over.Name != "" {
+		out.Name = over.Name
+	}
+	dst, src := out.Editor.fields(), over.Editor.fields()
+	for i := range dst {
+		if !src[i].IsZero() {
+			*dst[i] = *src[i]
+		}
+	}
+	out.Tokens = mergeSpecs(out.Tokens, over.Tokens)
+	out.Gutter = mergeSpecs(out.Gutter, over.Gutter)
+	out.Completion = mergeSpecs(out.Completion, over.Completion)
+	out.Ghost = mergeSpecs(out.Ghost, over.Ghost)
+	out.Overlay = mergeSpecs(out.Overlay, over.Overlay)
+	out.Float = mergeSpecs(out.Float, over.Float)
+	setIfSet(&out.RowMarks.Inserted, over.RowMarks.Inserted)
+	setIfSet(&out.RowMarks.Updated, over.RowMarks.Updated)
+	setIfSet(&out.RowMarks.DeletedAbove, over.RowMarks.DeletedAbove)
+	setIfSet(&out.RowMarks.DeletedBelow, over.RowMarks.DeletedBelow)
+	return out
+}
+
+func setIfSet(dst *string, src string) {
+	if src != "" {
+		*dst = src
+	}
+}
+
+func mergeSpecs(dst, src map[string]StyleSpec) map[string]StyleSpec {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]StyleSpec, len(src))
+	}
+	maps.Copy(dst, src)
+	return dst
+}
+
+// Highlight returns the Tokens section as a highlight.Theme.
+func (t Theme) Highlight() highlight.Theme {
+	out := make(highlight.Theme, len(t.Tokens))
+	for k, spec := range t.Tokens {
+		out[highlight.TokenType(k)] = spec.Style()
+	}
+	return out
+}
+
+// Apply returns cfg with the theme's styles wired in: Style, RowMarkSymbols,
+// and the Gutter, Completion, Ghost, VirtualOverlay and Float StyleForKey
+// callbacks.
+// A *highlight.Highlighter in cfg.Highlighter is rebuilt with the theme's
+// token styles. Variants are not resolved; call Variant first.
+func (t Theme) Apply(cfg editor.Config) editor.Config {
+	cfg.Style = t.Editor.Style()
+	cfg.RowMarkSymbols = editor.RowMarkSymbols{
+		Inserted:     t.RowMarks.Inserted,
+		Updated:      t.RowMarks.Updated,
+		DeletedAbove: t.RowMarks.DeletedAbove,
+		DeletedBelow: t.RowMarks.DeletedBelow,
+	}
+	cfg.GutterStyleForKey = styleForKey(t.Gutter)
+	cfg.CompletionStyleForKey = styleForKey(t.Completion)
+	cfg.GhostStyleForKey = styleForKey(t.Ghost)
+	cfg.VirtualOverlayStyleForKey = styleForKey(t.Overlay)
+	cfg.FloatStyleForKey = styleForKey(t.Float)
+	if h, ok := cfg.Highlighter.(*highlight.Highlighter); ok && h != nil {
+		cfg.Highlighter = highlight.New(h.Lexer(), t.Highlight())
+	}
+	return cfg
+}
+
+// styleForKey returns a lookup over specs, or nil when specs is empty so the
+// editor keeps its unkeyed fallbacks.
+func styleForKey(specs map[string]StyleSpec) func(string) (lipgloss.Style, bool) {
+	if len(specs) == 0 {
+		return nil
+	}
+	styles := make(map[string]lipgloss.Style, len(specs))
+	for k, spec := range specs {
+		styles[k] = spec.Style()
+	}
+	return func(key string) (lipgloss.Style, bool) {
+		st, ok := styles[key]
+		return st, ok
+	}
+}
@@ -0,0 +1,139 @@
+package theme
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/highlight"
+)
+
+func TestEditorStyles_CoversEveryStyleField(t *testing.T) {
+	st := reflect.TypeFor[editor.Style]()
+	es := reflect.TypeFor[EditorStyles]()
+	if st.NumField() != es.NumField() {
+		t.Fatalf("editor.Style has %d fields, EditorStyles has %d", st.NumField(), es.NumField())
+	}
+	for i := 0; i < st.NumField(); i++ {
+		if _, ok := es.FieldByName(st.Field(i).Name); !ok {
+			t.Errorf("EditorStyles is missing %s", st.Field(i).Name)
+		}
+	}
+	if got := len((&EditorStyles{}).fields()); got != es.NumField() {
+		t.Errorf("fields() returns %d specs, want %d", got, es.NumField())
+	}
+}
+
+func TestDark_MatchesBuiltInDefaults(t *testing.T) {
+	got := reflect.ValueOf(Dark().Editor.Style())
+	want := reflect.ValueOf(editor.DefaultStyle())
+	for i := 0; i < got.NumField(); i++ {
+		g := got.Field(i).Interface().(lipgloss.Style).Render("x")
+		w := want.Field(i).Interface().(lipgloss.Style).Render("x")
+		if g != w {
+			t.Errorf("%s: got %q, want %q", got.Type().Field(i).Name, g, w)
+		}
+	}
+
+	completion := Dark().Apply(editor.Config{}).CompletionStyleForKey
+	for _, key := range []string{editor.CompletionMatchStyleKey, editor.CompletionDocStyleKey, editor.CompletionDocCodeStyleKey, editor.CompletionLoadingStyleKey} {
+		if _, ok := completion(key); !ok {
+			t.Errorf("completion key %q not covered", key)
+		}
+	}
+	floats := Dark().Apply(editor.Config{}).FloatStyleForKey
+	for _, key := range []string{editor.FloatStyleKey, editor.FloatCodeStyleKey, editor.HoverStyleKey, editor.SignatureHelpStyleKey} {
+		if _, ok := floats(key); !ok {
+			t.Errorf("float key %q not covered", key)
+		}
+	}
+
+	tokens := Dark().Highlight()
+	for typ, w := range highlight.DefaultTheme() {
+		g, ok := tokens[typ]
+		if !ok || g.Render("x") != w.Render("x") {
+			t.Errorf("token %q: got %q, want %q", typ, g.Render("x"), w.Render("x"))
+		}
+	}
+}
+
+func TestVariant_MergesOverrides(t *testing.T) {
+	base := Theme{
+		Name:   "base",
+		Editor: EditorStyles{Text: StyleSpec{Foreground: "1"}, Cursor: StyleSpec{Reverse: true}},
+		Tokens: map[string]StyleSpec{"string": {Foreground: "2"}, "comment": {Italic: true}},
+		Light: &Theme{
+			Editor:   EditorStyles{Text: StyleSpec{Foreground: "3"}},
+			Tokens:   map[string]StyleSpec{"string": {Foreground: "4"}},
+			RowMarks: RowMarkSymbols{Inserted: "+"},
+		},
+	}
+
+	light := base.Variant(false)
+	if light.Light != nil || light.Dark != nil {
+		t.Fatalf("resolved variant must not carry variants")
+	}
+	if light.Editor.Text.Foreground != "3" || !light.Editor.Cursor.Reverse {
+		t.Fatalf("editor merge: %+v", light.Editor)
+	}
+	if light.Tokens["string"].Foreground != "4" || !light.Tokens["comment"].Italic {
+		t.Fatalf("token merge: %+v", light.Tokens)
+	}
+	if light.RowMarks.Inserted != "+" || light.Name != "base" {
+		t.Fatalf("row marks/name: %+v %q", light.RowMarks, light.Name)
+	}
+	if base.Tokens["string"].Foreground != "2" {
+		t.Fatalf("Variant must not modify the base theme")
+	}
+
+	if dark := base.Variant(true); dark.Editor.Text.Foreground != "1" {
+		t.Fatalf("missing dark override should keep base: %+v", dark.Editor.Text)
+	}
+}
+
+func TestApply_WiresConfig(t *testing.T) {
+	bold := StyleSpec{Bold: true}
+	th := Theme{
+		Editor:     EditorStyles{Gutter: StyleSpec{Foreground: "240"}},
+		Tokens:     map[string]StyleSpec{"keyword": bold},
+		Gutter:     map[string]StyleSpec{"line_num_active": {Foreground: "250"}},
+		Completion: map[string]StyleSpec{"kind.type": {Foreground: "81"}},
+		Float:      map[string]StyleSpec{"float.hover": {Foreground: "252"}},
+		RowMarks:   RowMarkSymbols{DeletedAbove: "v"},
+	}
+	cfg := th.Apply(editor.Config{Highlighter: highlight.New(highlight.Go(), nil)})
+
+	if cfg.Style.Gutter.Render("x") != th.Editor.Gutter.Style().Render("x") {
+		t.Fatalf("Style not applied")
+	}
+	if cfg.RowMarkSymbols.DeletedAbove != "v" {
+		t.Fatalf("RowMarkSymbols not applied: %+v", cfg.RowMarkSymbols)
+	}
+	if _, ok := cfg.GutterStyleForKey("line_num_active"); !ok {
+		t.Fatalf("gutter key not resolved")
+	}
+	if _, ok := cfg.GutterStyleForKey("line_num"); ok {
+		t.Fatalf("unknown gutter key must fall back to Style.Gutter")
+	}
+	if _, ok := cfg.CompletionStyleForKey("kind.type"); !ok {
+		t.Fatalf("completion key not resolved")
+	}
+	if _, ok := cfg.FloatStyleForKey(editor.HoverStyleKey); !ok {
+		t.Fatalf("float key not resolved")
+	}
+	if cfg.GhostStyleForKey != nil || cfg.VirtualOverlayStyleForKey != nil {
+		t.Fatalf("empty sections must leave callbacks nil")
+	}
+
+	h := cfg.Highlighter.(*highlight.Highlighter)
+	if h.Lexer() != highlight.Go() {
+		t.Fatalf("highlighter lexer not preserved")
+	}
+	spans, err := h.HighlightLine(editor.LineContext{Text: "func f() x"})
+	if err != nil || len(spans) != 1 || !strings.Contains(spans[0].Style.Render("x"), "\x1b[1m") {
+		t.Fatalf("highlighter should use theme tokens only: %+v err=%v", spans, err)
+	}
+}
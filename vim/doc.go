@@ -0,0 +1,11 @@
+// Package vim layers vim-style modal editing over editor.Model.
+//
+// Model routes key presses through normal, insert, visual, visual-line and
+// visual-block modes. Normal-mode commands (counts, registers, operators
+// combined with motions or text objects, and . repeat) run as editor intents
+// through editor.Model.Dispatch, so hosts using intent modes observe semantic
+// actions rather than raw keys.
+//
+// Visual-block mode supports d, y, > and <, and I, A and c, which copy the
+// text typed on the top row to the other rows; p and P only leave it.
+package vim
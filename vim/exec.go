@@ -0,0 +1,525 @@
+package vim
+
+import (
+	"strconv"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// aliases are normal-mode commands that are shorthands for an operator.
+var aliases = map[string]struct {
+	op       string
+	motion   string
+	linewise bool
+}{
+	"x": {op: "d", motion: "l"},
+	"X": {op: "d", motion: "h"},
+	"D": {op: "d", motion: "$"},
+	"C": {op: "c", motion: "$"},
+	"s": {op: "c", motion: "l"},
+	"S": {op: "c", linewise: true},
+	"Y": {op: "y", linewise: true},
+}
+
+// changes lists the commands "." repeats.
+var changes = map[string]bool{
+	"d": true, "c": true, ">": true, "<": true,
+	"p": true, "P": true, "J": true, "r": true,
+	"i": true, "a": true, "I": true, "A": true, "o": true, "O": true,
+}
+
+func (m *Model) runNormal(cmd command) {
+	if a, ok := aliases[cmd.action]; ok {
+		cmd.op, cmd.motion, cmd.linewise = a.op, motion{key: a.motion}, a.linewise
+		cmd.action = ""
+	}
+	dot := &dotRecord{count: cmd.count, keys: cmd.keys}
+	if !changes[cmd.op] && !changes[cmd.action] {
+		dot = nil
+	}
+
+	switch {
+	case cmd.op != "":
+		r, ok := m.operatorRegion(cmd)
+		if !ok {
+			return
+		}
+		m.operate(cmd.op, cmd.reg, r, 1, dot)
+	case cmd.motion.key != "":
+		m.moveNormal(cmd)
+	default:
+		m.normalAction(cmd, dot)
+	}
+	if dot != nil && m.mode != ModeInsert {
+		m.dot = *dot
+	}
+}
+
+// moveNormal moves the cursor. Motions buffer.Move can express are
+// dispatched as IntentMove so hosts see them semantically.
+func (m *Model) moveNormal(cmd command) {
+	env := m.env()
+	cur := m.cursor()
+	target, _, ok := env.target(cur, cmd.motion, cmd.count)
+	if !ok {
+		return
+	}
+	m.rememberFind(cmd.motion)
+
+	n := max(cmd.count, 1)
+	var move *buffer.Move
+	switch cmd.motion.key {
+	case "j", "down":
+		move = &buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirDown, Count: n}
+	case "k", "up":
+		move = &buffer.Move{Unit: buffer.MoveGrapheme, Dir: buffer.DirUp, Count: n}
+	case "{":
+		move = &buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirUp, Count: n}
+	case "}":
+		move = &buffer.Move{Unit: buffer.MoveParagraph, Dir: buffer.DirDown, Count: n}
+	case "0", "home":
+		move = &buffer.Move{Unit: buffer.MoveLine, Dir: buffer.DirHome}
+	}
+	if move != nil {
+		m.dispatch(editor.Intent{Kind: editor.IntentMove, Payload: editor.MoveIntentPayload{Move: *move}})
+		m.clampCursor()
+		return
+	}
+	m.dispatch(cursorIntent(env.t.clampNormal(target)))
+}
+
+func (m *Model) rememberFind(mo motion) {
+	if takesChar(mo.key) {
+		m.find = findState{key: mo.key, char: mo.char}
+	}
+}
+
+// operatorRegion resolves the text a normal-mode operator acts on.
+func (m *Model) operatorRegion(cmd command) (region, bool) {
+	env := m.env()
+	t := env.t
+	cur := m.cursor()
+	n := max(cmd.count, 1)
+
+	switch {
+	case cmd.linewise:
+		return region{
+			start: buffer.Pos{Row: cur.Row},
+			end:   buffer.Pos{Row: t.clampRow(cur.Row + n - 1)},
+			kind:  linewiseRegion,
+		}, true
+	case cmd.object != "":
+		return env.object(cur, cmd.object, cmd.count)
+	}
+
+	mo := cmd.motion
+	big := mo.key == "W"
+	if cmd.op == "c" && (mo.key == "w" || big) && t.class(cur, big) != classBlank {
+		// cw changes to the end of the word, like ce, but never past it.
+		p := buffer.Pos{Row: cur.Row, GraphemeCol: t.runEnd(cur.Row, cur.GraphemeCol, big) - 1}
+		for i := 1; i < n; i++ {
+			p = t.wordEnd(p, big)
+		}
+		return region{start: cur, end: t.after(p)}, true
+	}
+
+	target, kind, ok := env.target(cur, mo, cmd.count)
+	if !ok {
+		return region{}, false
+	}
+	m.rememberFind(mo)
+	if (mo.key == "w" || big) && target.Row > cur.Row {
+		// The last word moved over ends its row: stop there instead of at
+		// the next row's first word.
+		target = buffer.Pos{Row: target.Row - 1, GraphemeCol: t.lineLen(target.Row - 1)}
+		if posLess(target, cur) {
+			target = cur
+		}
+		return region{start: cur, end: target}, true
+	}
+	return motionRegion(t, cur, target, kind), true
+}
+
+// motionRegion turns a motion from cur to target into a region.
+func motionRegion(t text, cur, target buffer.Pos, kind motionKind) region {
+	start, end := orderPos(cur, target)
+	switch kind {
+	case linewise:
+		return region{start: buffer.Pos{Row: start.Row}, end: buffer.Pos{Row: end.Row}, kind: linewiseRegion}
+	case inclusive:
+		return region{start: start, end: t.after(end)}
+	}
+	if end.GraphemeCol == 0 && end.Row > start.Row {
+		// An exclusive motion ending at a row start stops at the end of the
+		// previous row, and covers whole rows when it started in the
+		// indentation.
+		end = buffer.Pos{Row: end.Row - 1, GraphemeCol: t.lineLen(end.Row - 1)}
+		if start.GraphemeCol <= t.firstNonBlank(start.Row) {
+			return region{start: buffer.Pos{Row: start.Row}, end: buffer.Pos{Row: end.Row}, kind: linewiseRegion}
+		}
+	}
+	return region{start: start, end: end}
+}
+
+// regionText returns the text of r as stored in a register.
+func regionText(t text, r region) string {
+	var sb strings.Builder
+	for row := r.start.Row; row <= r.end.Row; row++ {
+		if row > r.start.Row {
+			sb.WriteByte('\n')
+		}
+		line := t.row(row)
+		from, to := 0, len(line)
+		switch r.kind {
+		case charwise:
+			if row == r.start.Row {
+				from = min(r.start.GraphemeCol, len(line))
+			}
+			if row == r.end.Row {
+				to = min(r.end.GraphemeCol, len(line))
+			}
+		case blockwise:
+			from, to = min(r.start.GraphemeCol, len(line)), min(r.end.GraphemeCol, len(line))
+		}
+		sb.WriteString(grapheme.Join(line[from:max(from, to)]))
+	}
+	return sb.String()
+}
+
+// deleteEdits returns the edits that delete r. Deleting rows also removes a
+// line break: the one after them, or before them at the document end.
+func deleteEdits(t text, r region) []buffer.TextEdit {
+	switch r.kind {
+	case linewiseRegion:
+		start, end := buffer.Pos{Row: r.start.Row}, buffer.Pos{Row: r.end.Row + 1}
+		if t.isLastRow(r.end.Row) {
+			end = buffer.Pos{Row: r.end.Row, GraphemeCol: t.lineLen(r.end.Row)}
+			if r.start.Row > 0 {
+				start = buffer.Pos{Row: r.start.Row - 1, GraphemeCol: t.lineLen(r.start.Row - 1)}
+			}
+		}
+		return []buffer.TextEdit{{Range: buffer.Range{Start: start, End: end}}}
+	case blockwise:
+		var edits []buffer.TextEdit
+		for row := r.start.Row; row <= r.end.Row; row++ {
+			from, to := min(r.start.GraphemeCol, t.lineLen(row)), min(r.end.GraphemeCol, t.lineLen(row))
+			if from < to {
+				edits = append(edits, buffer.TextEdit{Range: buffer.Range{
+					Start: buffer.Pos{Row: row, GraphemeCol: from},
+					End:   buffer.Pos{Row: row, GraphemeCol: to},
+				}})
+			}
+		}
+		return edits
+	default:
+		return []buffer.TextEdit{{Range: buffer.Range{Start: r.start, End: r.end}}}
+	}
+}
+
+// afterDelete returns where the cursor lands once r is deleted.
+func afterDelete(t text, r region) buffer.Pos {
+	switch r.kind {
+	case linewiseRegion:
+		if !t.isLastRow(r.end.Row) {
+			return buffer.Pos{Row: r.start.Row, GraphemeCol: t.firstNonBlank(r.end.Row + 1)}
+		}
+		if r.start.Row > 0 {
+			return buffer.Pos{Row: r.start.Row - 1, GraphemeCol: t.firstNonBlank(r.start.Row - 1)}
+		}
+		return buffer.Pos{}
+	case blockwise:
+		return r.start
+	}
+	rest := t.lineLen(r.end.Row) - min(r.end.GraphemeCol, t.lineLen(r.end.Row))
+	col := min(r.start.GraphemeCol, max(r.start.GraphemeCol+rest-1, 0))
+	return buffer.Pos{Row: r.start.Row, GraphemeCol: col}
+}
+
+// operate applies op to r. levels is how many indents > and < shift by.
+// dot, when set, is recorded for "." once a change enters insert mode.
+func (m *Model) operate(op string, reg rune, r region, levels int, dot *dotRecord) {
+	t := m.env().t
+	cur := m.cursor()
+	stored := Register{Text: regionText(t, r), Linewise: r.kind == linewiseRegion}
+
+	switch op {
+	case "y":
+		m.regs.store(reg, stored, true)
+		p := r.start
+		if r.kind == linewiseRegion {
+			p = buffer.Pos{Row: r.start.Row, GraphemeCol: cur.GraphemeCol}
+			if cur.Row != r.start.Row {
+				p.GraphemeCol = t.firstNonBlank(r.start.Row)
+			}
+		}
+		m.dispatch(cursorIntent(t.clampNormal(p)))
+	case "d":
+		m.regs.store(reg, stored, false)
+		m.dispatch(deleteIntent(cur, r, deleteEdits(t, r)), cursorIntent(afterDelete(t, r)))
+	case "c":
+		m.regs.store(reg, stored, false)
+		edits := deleteEdits(t, r)
+		p := r.start
+		if r.kind == linewiseRegion {
+			// Keep the first row, and its indentation.
+			indent := leadingBlanks(t.row(r.start.Row))
+			p = buffer.Pos{Row: r.start.Row, GraphemeCol: indent}
+			edits = []buffer.TextEdit{{Range: buffer.Range{
+				Start: p,
+				End:   buffer.Pos{Row: r.end.Row, GraphemeCol: t.lineLen(r.end.Row)},
+			}}}
+		}
+		m.enterInsert(dot)
+		m.dispatch(deleteIntent(cur, r, edits), cursorIntent(p))
+	case ">", "<":
+		m.shift(t, r.start.Row, r.end.Row, op == ">", max(levels, 1))
+	}
+}
+
+func deleteIntent(cur buffer.Pos, r region, edits []buffer.TextEdit) editor.Intent {
+	dir := editor.DeleteForward
+	if r.kind == charwise && !posLess(cur, r.end) {
+		dir = editor.DeleteBackward
+	}
+	return editor.Intent{Kind: editor.IntentDelete, Payload: editor.DeleteIntentPayload{Direction: dir, Edits: edits}}
+}
+
+func leadingBlanks(line []string) int {
+	n := 0
+	for n < len(line) && grapheme.IsSpace(line[n]) {
+		n++
+	}
+	return n
+}
+
+// shift indents (or dedents) rows top..bottom by levels indents. Empty rows
+// are not indented.
+func (m *Model) shift(t text, top, bottom int, right bool, levels int) {
+	indent := m.cfg.Indent
+	unit := grapheme.Split(indent)
+	var edits []buffer.TextEdit
+	for row := top; row <= bottom; row++ {
+		line := t.row(row)
+		if right {
+			if len(line) > 0 {
+				edits = append(edits, buffer.TextEdit{
+					Range: buffer.Range{Start: buffer.Pos{Row: row}, End: buffer.Pos{Row: row}},
+					Text:  strings.Repeat(indent, levels),
+				})
+			}
+			continue
+		}
+		n := 0
+		for range levels {
+			switch {
+			case hasPrefix(line[n:], unit):
+				n += len(unit)
+			case n < len(line) && line[n] == "\t":
+				n++
+			default:
+				for k := 0; k < len(unit) && n < len(line) && line[n] == " "; k++ {
+					n++
+				}
+			}
+		}
+		if n > 0 {
+			edits = append(edits, buffer.TextEdit{Range: buffer.Range{
+				Start: buffer.Pos{Row: row},
+				End:   buffer.Pos{Row: row, GraphemeCol: n},
+			}})
+		}
+	}
+	if len(edits) == 0 {
+		return
+	}
+
+	col := leadingBlanks(t.row(top))
+	if right && len(t.row(top)) > 0 {
+		col += levels * len(unit)
+	} else if !right && len(edits) > 0 && edits[0].Range.Start.Row == top {
+		col -= edits[0].Range.End.GraphemeCol
+	}
+	m.dispatch(
+		editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{Edits: edits}},
+		cursorIntent(buffer.Pos{Row: top, GraphemeCol: max(col, 0)}),
+	)
+}
+
+func hasPrefix(line, prefix []string) bool {
+	if len(prefix) == 0 || len(line) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if line[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Model) normalAction(cmd command, dot *dotRecord) {
+	t := m.env().t
+	cur := m.cursor()
+	n := max(cmd.count, 1)
+
+	switch cmd.action {
+	case "p", "P":
+		m.put(t, cur, cmd.reg, n, cmd.action == "p")
+	case "J":
+		m.join(t, cur.Row, cur.Row+max(n, 2)-1)
+	case "r":
+		if cur.GraphemeCol+n > t.lineLen(cur.Row) {
+			return
+		}
+		edit := buffer.TextEdit{
+			Range: buffer.Range{Start: cur, End: buffer.Pos{Row: cur.Row, GraphemeCol: cur.GraphemeCol + n}},
+			Text:  strings.Repeat(cmd.char, n),
+		}
+		m.dispatch(
+			editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{Edits: []buffer.TextEdit{edit}}},
+			cursorIntent(buffer.Pos{Row: cur.Row, GraphemeCol: cur.GraphemeCol + n - 1}),
+		)
+	case ".":
+		m.repeat(cmd.count)
+	case "u", "ctrl+r":
+		kind, payload := editor.IntentUndo, any(editor.UndoIntentPayload{})
+		if cmd.action == "ctrl+r" {
+			kind, payload = editor.IntentRedo, editor.RedoIntentPayload{}
+		}
+		intents := make([]editor.Intent, n)
+		for i := range intents {
+			intents[i] = editor.Intent{Kind: kind, Payload: payload}
+		}
+		m.dispatch(intents...)
+		m.clampCursor()
+	case "i":
+		m.enterInsert(dot)
+	case "a":
+		if t.lineLen(cur.Row) > 0 {
+			m.dispatch(cursorIntent(buffer.Pos{Row: cur.Row, GraphemeCol: cur.GraphemeCol + 1}))
+		}
+		m.enterInsert(dot)
+	case "I":
+		m.dispatch(cursorIntent(buffer.Pos{Row: cur.Row, GraphemeCol: leadingBlanks(t.row(cur.Row))}))
+		m.enterInsert(dot)
+	case "A":
+		m.dispatch(cursorIntent(buffer.Pos{Row: cur.Row, GraphemeCol: t.lineLen(cur.Row)}))
+		m.enterInsert(dot)
+	case "o", "O":
+		// New rows copy the indentation of the cursor row.
+		line := t.row(cur.Row)
+		indent := grapheme.Join(line[:leadingBlanks(line)])
+		at, text := buffer.Pos{Row: cur.Row, GraphemeCol: len(line)}, "\n"+indent
+		p := buffer.Pos{Row: cur.Row + 1, GraphemeCol: leadingBlanks(line)}
+		if cmd.action == "O" {
+			at, text = buffer.Pos{Row: cur.Row}, indent+"\n"
+			p.Row = cur.Row
+		}
+		m.enterInsert(dot)
+		m.dispatch(insertIntent(at, text), cursorIntent(p))
+	case "v":
+		m.enterVisual(ModeVisual)
+	case "V":
+		m.enterVisual(ModeVisualLine)
+	case "ctrl+v":
+		m.enterVisual(ModeVisualBlock)
+	}
+}
+
+func insertIntent(at buffer.Pos, text string) editor.Intent {
+	return editor.Intent{Kind: editor.IntentInsert, Payload: editor.InsertIntentPayload{
+		Text:  text,
+		Edits: []buffer.TextEdit{{Range: buffer.Range{Start: at, End: at}, Text: text}},
+	}}
+}
+
+// put inserts a register n times after (p) or before (P) the cursor.
+// Linewise content goes on rows of its own.
+func (m *Model) put(t text, cur buffer.Pos, name rune, n int, after bool) {
+	reg, ok := m.regs.get(name)
+	if !ok || (reg.Text == "" && !reg.Linewise) {
+		return
+	}
+
+	if reg.Linewise {
+		body := strings.TrimSuffix(strings.Repeat(reg.Text+"\n", n), "\n")
+		at, text := buffer.Pos{Row: cur.Row}, body+"\n"
+		row := cur.Row
+		if after {
+			at, text = buffer.Pos{Row: cur.Row, GraphemeCol: t.lineLen(cur.Row)}, "\n"+body
+			row++
+		}
+		col := leadingBlanks(grapheme.Split(strings.SplitN(reg.Text, "\n", 2)[0]))
+		m.dispatch(insertIntent(at, text), cursorIntent(buffer.Pos{Row: row, GraphemeCol: col}))
+		return
+	}
+
+	body := strings.Repeat(reg.Text, n)
+	at := cur
+	if after && t.lineLen(cur.Row) > 0 {
+		at.GraphemeCol++
+	}
+	p := at
+	if !strings.Contains(body, "\n") {
+		p.GraphemeCol += grapheme.Count(body) - 1
+	}
+	m.dispatch(insertIntent(at, body), cursorIntent(p))
+}
+
+// join joins rows top..bottom into one, replacing each line break and the
+// indentation after it with a space.
+func (m *Model) join(t text, top, bottom int) {
+	bottom = t.clampRow(bottom)
+	if bottom <= top {
+		return
+	}
+	width := t.lineLen(top)
+	var edits []buffer.TextEdit
+	col := width
+	for row := top + 1; row <= bottom; row++ {
+		line := t.row(row)
+		lead := leadingBlanks(line)
+		sep := " "
+		prev := t.row(row - 1)
+		if lead == len(line) || width == 0 || line[lead] == ")" ||
+			(len(prev) > 0 && grapheme.IsSpace(prev[len(prev)-1])) {
+			sep = ""
+		}
+		edits = append(edits, buffer.TextEdit{
+			Range: buffer.Range{Start: buffer.Pos{Row: top, GraphemeCol: width}, End: buffer.Pos{Row: top + 1, GraphemeCol: lead}},
+			Text:  sep,
+		})
+		col = width
+		width += len(sep) + len(line) - lead
+	}
+	m.dispatch(
+		editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{Edits: edits}},
+		cursorIntent(buffer.Pos{Row: top, GraphemeCol: col}),
+	)
+}
+
+// repeat replays the last change. A count replaces the change's count.
+func (m *Model) repeat(count int) {
+	dot := m.dot
+	if len(dot.keys) == 0 {
+		return
+	}
+	if count > 0 {
+		dot.count = count
+	}
+	keys := make([]tea.KeyPressMsg, 0, len(dot.keys)+3)
+	if dot.count > 0 {
+		for _, r := range strconv.Itoa(dot.count) {
+			keys = append(keys, tea.KeyPressMsg{Code: r, Text: string(r)})
+		}
+	}
+	keys = append(keys, dot.keys...)
+	for _, k := range keys {
+		*m, _ = m.Update(k)
+	}
+}
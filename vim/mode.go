@@ -0,0 +1,34 @@
+package vim
+
+// Mode is the active vim mode.
+type Mode uint8
+
+const (
+	ModeNormal Mode = iota
+	ModeInsert
+	ModeVisual
+	ModeVisualLine
+	ModeVisualBlock
+)
+
+// String returns the mode name as vim shows it in the mode indicator, for
+// example "VISUAL LINE".
+func (m Mode) String() string {
+	switch m {
+	case ModeInsert:
+		return "INSERT"
+	case ModeVisual:
+		return "VISUAL"
+	case ModeVisualLine:
+		return "VISUAL LINE"
+	case ModeVisualBlock:
+		return "VISUAL BLOCK"
+	default:
+		return "NORMAL"
+	}
+}
+
+// Visual reports whether m is one of the visual modes.
+func (m Mode) Visual() bool {
+	return m == ModeVisual || m == ModeVisualLine || m == ModeVisualBlock
+}
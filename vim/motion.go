@@ -0,0 +1,288 @@
+package vim
+
+import "github.com/iw2rmb/flourish/buffer"
+
+// motionKind says how a motion's target bounds the text an operator acts on.
+type motionKind uint8
+
+const (
+	// exclusive motions cover [start, target).
+	exclusive motionKind = iota
+	// inclusive motions also cover the grapheme at the target.
+	inclusive
+	// linewise motions cover whole rows from start to target.
+	linewise
+)
+
+// motionKinds lists the supported motion keys. f, F, t and T take a
+// character argument.
+var motionKinds = map[string]motionKind{
+	"h": exclusive, "left": exclusive, "backspace": exclusive,
+	"l": exclusive, "right": exclusive, "space": exclusive,
+	"j": linewise, "down": linewise,
+	"k": linewise, "up": linewise,
+	"w": exclusive, "W": exclusive,
+	"b": exclusive, "B": exclusive,
+	"e": inclusive, "E": inclusive,
+	"0": exclusive, "home": exclusive,
+	"^": exclusive,
+	"$": inclusive, "end": inclusive,
+	"G": linewise, "gg": linewise,
+	"f": inclusive, "t": inclusive,
+	"F": exclusive, "T": exclusive,
+	";": inclusive, ",": inclusive,
+	"{": exclusive, "}": exclusive,
+	"%": inclusive,
+}
+
+func takesChar(key string) bool {
+	return key == "f" || key == "F" || key == "t" || key == "T"
+}
+
+// findState is the last f, F, t or T search, repeated by ; and ,.
+type findState struct {
+	key  string
+	char string
+}
+
+// motion is a parsed motion with its character argument.
+type motion struct {
+	key  string
+	char string
+}
+
+// motionEnv is what motions are computed against.
+type motionEnv struct {
+	t    text
+	buf  *buffer.Buffer
+	find findState
+}
+
+// target returns where mo moves from p, repeated count times (count <= 0
+// means 1), and the kind that applies to it. ok is false when the motion
+// fails, which aborts the command. The kind of ; and , follows the search
+// they repeat.
+func (e motionEnv) target(p buffer.Pos, mo motion, count int) (buffer.Pos, motionKind, bool) {
+	t := e.t
+	kind := motionKinds[mo.key]
+	n := max(count, 1)
+
+	switch mo.key {
+	case "h", "left", "backspace":
+		if p.GraphemeCol == 0 {
+			return p, kind, false
+		}
+		p.GraphemeCol = max(p.GraphemeCol-n, 0)
+	case "l", "right", "space":
+		if p.GraphemeCol >= t.lineLen(p.Row) {
+			return p, kind, false
+		}
+		p.GraphemeCol = min(p.GraphemeCol+n, t.lineLen(p.Row))
+	case "j", "down":
+		if t.isLastRow(p.Row) {
+			return p, kind, false
+		}
+		p.Row = t.clampRow(p.Row + n)
+	case "k", "up":
+		if p.Row == 0 {
+			return p, kind, false
+		}
+		p.Row = max(p.Row-n, 0)
+	case "w", "W":
+		for range n {
+			p = t.wordStart(p, mo.key == "W")
+		}
+	case "b", "B":
+		for range n {
+			p = t.wordBack(p, mo.key == "B")
+		}
+	case "e", "E":
+		for range n {
+			p = t.wordEnd(p, mo.key == "E")
+		}
+	case "0", "home":
+		p.GraphemeCol = 0
+	case "^":
+		p.GraphemeCol = t.firstNonBlank(p.Row)
+	case "$", "end":
+		p.Row = t.clampRow(p.Row + n - 1)
+		p.GraphemeCol = t.maxCol(p.Row)
+	case "G", "gg":
+		row := 0
+		switch {
+		case count > 0:
+			row = t.clampRow(count - 1)
+		case mo.key == "G":
+			row = t.lastRow()
+		}
+		p = buffer.Pos{Row: row, GraphemeCol: t.firstNonBlank(row)}
+	case "f", "F", "t", "T":
+		return t.findInLine(p, mo.key, mo.char, n, false)
+	case ";", ",":
+		if e.find.key == "" {
+			return p, kind, false
+		}
+		key := e.find.key
+		if mo.key == "," {
+			key = reverseFind[key]
+		}
+		return t.findInLine(p, key, e.find.char, n, true)
+	case "{", "}":
+		for range n {
+			p = t.paragraph(p, mo.key == "}")
+		}
+	case "%":
+		if e.buf == nil {
+			return p, kind, false
+		}
+		// The bracket under the cursor, or the next one on its row.
+		for p.GraphemeCol < t.lineLen(p.Row) && !isBracket(t.at(p)) {
+			p.GraphemeCol++
+		}
+		_, match, ok := e.buf.MatchBracket(p, buffer.BracketOptions{})
+		if !ok || !isBracket(t.at(p)) {
+			return p, kind, false
+		}
+		return match, kind, true
+	default:
+		return p, kind, false
+	}
+	return p, kind, true
+}
+
+func isBracket(g string) bool {
+	for _, pair := range buffer.DefaultBracketPairs() {
+		if g == pair.Open || g == pair.Close {
+			return true
+		}
+	}
+	return false
+}
+
+var reverseFind = map[string]string{"f": "F", "F": "f", "t": "T", "T": "t"}
+
+// findInLine searches the cursor row for the n-th occurrence of char: f and
+// t forward, F and T backward. t and T stop one grapheme short of it.
+func (t text) findInLine(p buffer.Pos, key, char string, n int, repeat bool) (buffer.Pos, motionKind, bool) {
+	line := t.row(p.Row)
+	forward := key == "f" || key == "t"
+	col := p.GraphemeCol
+	// Repeating t or T must not find the character it is already next to.
+	if repeat && key == "t" && col+1 < len(line) && line[col+1] == char {
+		col++
+	}
+	if repeat && key == "T" && col > 0 && line[col-1] == char {
+		col--
+	}
+	for found := 0; found < n; {
+		if forward {
+			col++
+		} else {
+			col--
+		}
+		if col < 0 || col >= len(line) {
+			return p, motionKinds[key], false
+		}
+		if line[col] == char {
+			found++
+		}
+	}
+	switch key {
+	case "t":
+		col--
+	case "T":
+		col++
+	}
+	return buffer.Pos{Row: p.Row, GraphemeCol: col}, motionKinds[key], true
+}
+
+// wordStart implements w: it skips the rest of the current word, then
+// blanks and line ends. An empty line counts as a word.
+func (t text) wordStart(p buffer.Pos, big bool) buffer.Pos {
+	start := p
+	if c := t.class(p, big); c != classBlank {
+		for t.class(p, big) == c {
+			q, ok := t.next(p)
+			if !ok {
+				return p
+			}
+			p = q
+		}
+	}
+	for t.class(p, big) == classBlank {
+		if p != start && t.emptyLine(p.Row) {
+			return p
+		}
+		q, ok := t.next(p)
+		if !ok {
+			return p
+		}
+		p = q
+	}
+	return p
+}
+
+// wordBack implements b: it moves to the start of the word before p,
+// stopping on empty lines.
+func (t text) wordBack(p buffer.Pos, big bool) buffer.Pos {
+	q, ok := t.prev(p)
+	if !ok {
+		return p
+	}
+	p = q
+	for t.class(p, big) == classBlank {
+		if t.emptyLine(p.Row) {
+			return p
+		}
+		q, ok := t.prev(p)
+		if !ok {
+			return p
+		}
+		p = q
+	}
+	c := t.class(p, big)
+	for p.GraphemeCol > 0 && t.class(buffer.Pos{Row: p.Row, GraphemeCol: p.GraphemeCol - 1}, big) == c {
+		p.GraphemeCol--
+	}
+	return p
+}
+
+// wordEnd implements e: it moves to the last grapheme of the word after p.
+func (t text) wordEnd(p buffer.Pos, big bool) buffer.Pos {
+	q, ok := t.next(p)
+	if !ok {
+		return p
+	}
+	p = q
+	for t.class(p, big) == classBlank {
+		q, ok := t.next(p)
+		if !ok {
+			return p
+		}
+		p = q
+	}
+	c := t.class(p, big)
+	for p.GraphemeCol+1 < t.lineLen(p.Row) && t.class(buffer.Pos{Row: p.Row, GraphemeCol: p.GraphemeCol + 1}, big) == c {
+		p.GraphemeCol++
+	}
+	return p
+}
+
+// paragraph implements { and }: the next empty row in that direction, or
+// the document start or end.
+func (t text) paragraph(p buffer.Pos, down bool) buffer.Pos {
+	if down {
+		for row := p.Row + 1; t.hasRow(row); row++ {
+			if t.emptyLine(row) {
+				return buffer.Pos{Row: row}
+			}
+		}
+		return buffer.Pos{Row: t.lastRow(), GraphemeCol: t.lineLen(t.lastRow())}
+	}
+	for row := p.Row - 1; row >= 0; row-- {
+		if t.emptyLine(row) {
+			return buffer.Pos{Row: row}
+		}
+	}
+	return buffer.Pos{}
+}
@@ -0,0 +1,68 @@
+package vim
+
+import (
+	"testing"
+
+	"github.com/iw2rmb/flourish/buffer"
+)
+
+func textFromString(s string) text {
+	return textFromBuffer(buffer.New(s, buffer.Options{}))
+}
+
+func TestWordMotions(t *testing.T) {
+	txt := textFromString("foo.bar  baz\n\n  qux")
+	cases := []struct {
+		key      string
+		from     buffer.Pos
+		count    int
+		want     buffer.Pos
+		wantKind motionKind
+	}{
+		{"w", pos(0, 0), 1, pos(0, 3), exclusive},
+		{"w", pos(0, 3), 1, pos(0, 4), exclusive},
+		{"W", pos(0, 0), 1, pos(0, 9), exclusive},
+		{"w", pos(0, 9), 1, pos(1, 0), exclusive},
+		{"w", pos(1, 0), 1, pos(2, 2), exclusive},
+		{"w", pos(0, 0), 3, pos(0, 9), exclusive},
+		{"b", pos(2, 2), 1, pos(1, 0), exclusive},
+		{"b", pos(0, 9), 1, pos(0, 4), exclusive},
+		{"B", pos(0, 9), 1, pos(0, 0), exclusive},
+		{"e", pos(0, 0), 1, pos(0, 2), inclusive},
+		{"e", pos(0, 2), 1, pos(0, 3), inclusive},
+		{"E", pos(0, 0), 1, pos(0, 6), inclusive},
+		{"e", pos(0, 11), 1, pos(2, 4), inclusive},
+		{"$", pos(0, 0), 2, pos(1, 0), inclusive},
+		{"^", pos(2, 4), 1, pos(2, 2), exclusive},
+		{"}", pos(0, 5), 1, pos(1, 0), exclusive},
+		{"G", pos(0, 0), 0, pos(2, 2), linewise},
+		{"gg", pos(2, 0), 2, pos(1, 0), linewise},
+	}
+	env := motionEnv{t: txt}
+	for _, tc := range cases {
+		got, kind, ok := env.target(tc.from, motion{key: tc.key}, tc.count)
+		if !ok || got != tc.want || kind != tc.wantKind {
+			t.Fatalf("%d%s from %v: got %v kind=%d ok=%v, want %v kind=%d", tc.count, tc.key, tc.from, got, kind, ok, tc.want, tc.wantKind)
+		}
+	}
+}
+
+func TestFindInLine(t *testing.T) {
+	env := motionEnv{t: textFromString("a,b,c,d")}
+	got, _, ok := env.target(pos(0, 0), motion{key: "t", char: ","}, 2)
+	if !ok || got != pos(0, 2) {
+		t.Fatalf("2t, got %v %v", got, ok)
+	}
+	env.find = findState{key: "t", char: ","}
+	got, _, ok = env.target(pos(0, 2), motion{key: ";"}, 1)
+	if !ok || got != pos(0, 4) {
+		t.Fatalf("; after t should skip the adjacent match, got %v %v", got, ok)
+	}
+	got, kind, ok := env.target(pos(0, 4), motion{key: ","}, 1)
+	if !ok || got != pos(0, 2) || kind != exclusive {
+		t.Fatalf(", reverses t into T, got %v kind=%d %v", got, kind, ok)
+	}
+	if _, _, ok := env.target(pos(0, 0), motion{key: "f", char: "x"}, 1); ok {
+		t.Fatalf("f without a match should fail")
+	}
+}
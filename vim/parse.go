@@ -0,0 +1,186 @@
+package vim
+
+import (
+	"unicode/utf8"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+type parseState uint8
+
+const (
+	parseDone parseState = iota
+	parseIncomplete
+	parseInvalid
+)
+
+// command is a parsed normal- or visual-mode command:
+//
+//	["x][count] (operator [count] (operator | motion | text object) | motion | action)
+type command struct {
+	// count is the product of the counts typed, 0 when none was.
+	count int
+	reg   rune
+
+	op       string
+	linewise bool // doubled operator, as in dd
+	motion   motion
+	object   string
+	action   string
+	// char is the argument of r.
+	char string
+
+	// keys are the command's keys without counts, for "." to replay.
+	keys []tea.KeyPressMsg
+}
+
+var operators = map[string]bool{"d": true, "c": true, "y": true, ">": true, "<": true}
+
+var normalActions = map[string]bool{
+	"x": true, "X": true, "D": true, "C": true, "Y": true, "s": true, "S": true,
+	"p": true, "P": true, "J": true, "r": true, ".": true,
+	"u": true, "ctrl+r": true,
+	"i": true, "a": true, "I": true, "A": true, "o": true, "O": true,
+	"v": true, "V": true, "ctrl+v": true,
+	"esc": true, "ctrl+[": true,
+}
+
+var visualActions = map[string]bool{
+	"d": true, "x": true, "c": true, "s": true, "y": true, ">": true, "<": true,
+	"J": true, "p": true, "P": true, "o": true, "I": true, "A": true,
+	"v": true, "V": true, "ctrl+v": true,
+	"esc": true, "ctrl+[": true,
+}
+
+// parseCommand parses the keys typed so far in a non-insert mode.
+func parseCommand(keys []tea.KeyPressMsg, mode Mode) (command, parseState) {
+	var cmd command
+	i := 0
+	readCount := func() int {
+		n := 0
+		for ; i < len(keys); i++ {
+			s := keys[i].String()
+			if len(s) != 1 || s[0] < '0' || s[0] > '9' || (n == 0 && s == "0") {
+				break
+			}
+			n = n*10 + int(s[0]-'0')
+		}
+		return n
+	}
+	// next consumes the next key; ok is false when it has not been typed yet.
+	next := func() (string, bool) {
+		if i >= len(keys) {
+			return "", false
+		}
+		cmd.keys = append(cmd.keys, keys[i])
+		i++
+		return keys[i-1].String(), true
+	}
+	// char consumes a character argument such as the target of f.
+	char := func() (string, parseState) {
+		if i >= len(keys) {
+			return "", parseIncomplete
+		}
+		text := keys[i].Key().Text
+		cmd.keys = append(cmd.keys, keys[i])
+		i++
+		if text == "" {
+			return "", parseInvalid
+		}
+		return text, parseDone
+	}
+
+	cmd.count = readCount()
+	if i < len(keys) && keys[i].String() == `"` {
+		next()
+		name, state := char()
+		if state != parseDone {
+			return cmd, state
+		}
+		r, size := utf8.DecodeRuneInString(name)
+		if size != len(name) || !validRegister(r) {
+			return cmd, parseInvalid
+		}
+		cmd.reg = r
+	}
+	cmd.count = mulCount(cmd.count, readCount())
+
+	k, ok := next()
+	if !ok {
+		return cmd, parseIncomplete
+	}
+
+	if mode == ModeNormal && operators[k] {
+		cmd.op = k
+		cmd.count = mulCount(cmd.count, readCount())
+		if k, ok = next(); !ok {
+			return cmd, parseIncomplete
+		}
+		if k == cmd.op {
+			cmd.linewise = true
+			return cmd, parseDone
+		}
+		return parseTarget(&cmd, k, char, next)
+	}
+
+	actions := normalActions
+	if mode.Visual() {
+		actions = visualActions
+	}
+	if actions[k] {
+		cmd.action = k
+		if k == "r" {
+			c, state := char()
+			cmd.char = c
+			return cmd, state
+		}
+		return cmd, parseDone
+	}
+	return parseTarget(&cmd, k, char, next)
+}
+
+// parseTarget parses the motion or text object starting with key k.
+func parseTarget(cmd *command, k string, char func() (string, parseState), next func() (string, bool)) (command, parseState) {
+	switch {
+	case k == "i" || k == "a":
+		name, ok := next()
+		if !ok {
+			return *cmd, parseIncomplete
+		}
+		if !objectKeys[name] {
+			return *cmd, parseInvalid
+		}
+		cmd.object = k + name
+		return *cmd, parseDone
+	case k == "g":
+		k2, ok := next()
+		if !ok {
+			return *cmd, parseIncomplete
+		}
+		if k2 != "g" {
+			return *cmd, parseInvalid
+		}
+		cmd.motion = motion{key: "gg"}
+		return *cmd, parseDone
+	case takesChar(k):
+		c, state := char()
+		cmd.motion = motion{key: k, char: c}
+		return *cmd, state
+	}
+	if _, ok := motionKinds[k]; ok {
+		cmd.motion = motion{key: k}
+		return *cmd, parseDone
+	}
+	return *cmd, parseInvalid
+}
+
+func mulCount(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return a * b
+	}
+}
@@ -0,0 +1,65 @@
+package vim
+
+import "unicode"
+
+// Register is the content of a vim register.
+type Register struct {
+	Text string
+	// Linewise marks whole-row content: it is put on rows of its own, and
+	// Text has no trailing newline.
+	Linewise bool
+}
+
+// Register names with special meaning.
+const (
+	// UnnamedRegister receives every yank and delete and is the default
+	// source for p and P.
+	UnnamedRegister = '"'
+	// YankRegister holds the last yank made without a register name.
+	YankRegister = '0'
+	// BlackHoleRegister discards what is written to it.
+	BlackHoleRegister = '_'
+)
+
+func validRegister(name rune) bool {
+	return name == UnnamedRegister || name == BlackHoleRegister ||
+		(name >= '0' && name <= '9') || (name < unicode.MaxASCII && unicode.IsLetter(name))
+}
+
+// registers stores register contents. Uppercase names address the
+// lowercase register.
+type registers map[rune]Register
+
+func (r registers) get(name rune) (Register, bool) {
+	if name == 0 {
+		name = UnnamedRegister
+	}
+	reg, ok := r[unicode.ToLower(name)]
+	return reg, ok
+}
+
+// store records yanked (yank set) or deleted text. Writing to an uppercase
+// name appends to its lowercase register.
+func (r registers) store(name rune, reg Register, yank bool) {
+	switch {
+	case name == BlackHoleRegister:
+		return
+	case name == 0 || name == UnnamedRegister:
+		if yank {
+			r[YankRegister] = reg
+		}
+	case unicode.IsUpper(name):
+		lower := unicode.ToLower(name)
+		if prev, ok := r[lower]; ok {
+			sep := ""
+			if prev.Linewise || reg.Linewise {
+				sep = "\n"
+			}
+			reg = Register{Text: prev.Text + sep + reg.Text, Linewise: prev.Linewise || reg.Linewise}
+		}
+		r[lower] = reg
+	default:
+		r[name] = reg
+	}
+	r[UnnamedRegister] = reg
+}
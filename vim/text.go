@@ -0,0 +1,182 @@
+package vim
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+// text reads the document as graphemes for motions and text objects. Rows
+// are split on first use, so a motion touches only the rows it visits.
+type text struct {
+	buf     *buffer.Buffer
+	version uint64
+	rows    map[int][]string
+}
+
+func textFromBuffer(b *buffer.Buffer) text {
+	return text{buf: b, version: b.TextVersion(), rows: map[int][]string{}}
+}
+
+// row returns the graphemes of row, or nil outside the document.
+func (t text) row(row int) []string {
+	if !t.hasRow(row) {
+		return nil
+	}
+	if g, ok := t.rows[row]; ok {
+		return g
+	}
+	var g []string
+	if raw := t.buf.RawLinesInRange(row, row+1); len(raw) == 1 {
+		g = grapheme.Split(raw[0])
+	}
+	t.rows[row] = g
+	return g
+}
+
+// rowsThrough returns the number of rows known once row is indexed, which
+// for lazy buffers reads no further than row.
+func (t text) rowsThrough(row int) int {
+	t.buf.IndexLines(row)
+	n, _ := t.buf.IndexedLineCount()
+	return max(n, 1)
+}
+
+func (t text) hasRow(row int) bool { return row >= 0 && row < t.rowsThrough(row) }
+
+func (t text) isLastRow(row int) bool { return !t.hasRow(row + 1) }
+
+// clampRow clamps row to the document.
+func (t text) clampRow(row int) int {
+	row = max(row, 0)
+	return min(row, t.rowsThrough(row)-1)
+}
+
+// lastRow returns the last row, indexing the whole document.
+func (t text) lastRow() int { return max(t.buf.LineCount(), 1) - 1 }
+
+func (t text) lineLen(row int) int { return len(t.row(row)) }
+
+func (t text) line(row int) string { return grapheme.Join(t.row(row)) }
+
+// at returns the grapheme at p, or "" at (or past) the line end.
+func (t text) at(p buffer.Pos) string {
+	line := t.row(p.Row)
+	if p.GraphemeCol < 0 || p.GraphemeCol >= len(line) {
+		return ""
+	}
+	return line[p.GraphemeCol]
+}
+
+// maxCol is the last column the normal-mode cursor may rest on.
+func (t text) maxCol(row int) int {
+	return max(t.lineLen(row)-1, 0)
+}
+
+func (t text) clamp(p buffer.Pos) buffer.Pos {
+	p.Row = t.clampRow(p.Row)
+	p.GraphemeCol = min(max(p.GraphemeCol, 0), t.lineLen(p.Row))
+	return p
+}
+
+// clampNormal keeps p on a grapheme, as the normal-mode cursor never rests
+// past the last one.
+func (t text) clampNormal(p buffer.Pos) buffer.Pos {
+	p = t.clamp(p)
+	p.GraphemeCol = min(p.GraphemeCol, t.maxCol(p.Row))
+	return p
+}
+
+func (t text) firstNonBlank(row int) int {
+	for i, g := range t.row(row) {
+		if !grapheme.IsSpace(g) {
+			return i
+		}
+	}
+	return t.maxCol(row)
+}
+
+// next advances p by one grapheme. The line end (GraphemeCol == lineLen) is
+// a position of its own, followed by the next row's start. ok is false at
+// the document end.
+func (t text) next(p buffer.Pos) (buffer.Pos, bool) {
+	if p.GraphemeCol < t.lineLen(p.Row) {
+		return buffer.Pos{Row: p.Row, GraphemeCol: p.GraphemeCol + 1}, true
+	}
+	if t.isLastRow(p.Row) {
+		return p, false
+	}
+	return buffer.Pos{Row: p.Row + 1}, true
+}
+
+// prev is the inverse of next.
+func (t text) prev(p buffer.Pos) (buffer.Pos, bool) {
+	if p.GraphemeCol > 0 {
+		return buffer.Pos{Row: p.Row, GraphemeCol: p.GraphemeCol - 1}, true
+	}
+	if p.Row == 0 {
+		return p, false
+	}
+	return buffer.Pos{Row: p.Row - 1, GraphemeCol: t.lineLen(p.Row - 1)}, true
+}
+
+// after returns the position one grapheme past p on its row, for turning an
+// inclusive end into an exclusive one.
+func (t text) after(p buffer.Pos) buffer.Pos {
+	p.GraphemeCol = min(p.GraphemeCol+1, t.lineLen(p.Row))
+	return p
+}
+
+const (
+	classBlank = iota
+	classPunct
+	classWord
+)
+
+// class returns the vim character class of the grapheme at p: blanks (and
+// line ends), punctuation, or keyword characters. With big set every
+// non-blank is a keyword character, as for WORD motions.
+func (t text) class(p buffer.Pos, big bool) int {
+	return graphemeClass(t.at(p), big)
+}
+
+func graphemeClass(g string, big bool) int {
+	if g == "" || grapheme.IsSpace(g) {
+		return classBlank
+	}
+	if big {
+		return classWord
+	}
+	r, _ := utf8.DecodeRuneInString(g)
+	if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+		return classWord
+	}
+	return classPunct
+}
+
+func (t text) emptyLine(row int) bool { return t.lineLen(row) == 0 }
+
+func (t text) blankLine(row int) bool {
+	for _, g := range t.row(row) {
+		if !grapheme.IsSpace(g) {
+			return false
+		}
+	}
+	return true
+}
+
+func posLess(a, b buffer.Pos) bool {
+	if a.Row != b.Row {
+		return a.Row < b.Row
+	}
+	return a.GraphemeCol < b.GraphemeCol
+}
+
+func orderPos(a, b buffer.Pos) (buffer.Pos, buffer.Pos) {
+	if posLess(b, a) {
+		return b, a
+	}
+	return a, b
+}
@@ -0,0 +1,226 @@
+package vim
+
+import "github.com/iw2rmb/flourish/buffer"
+
+// regionKind is the shape of the text an operator acts on.
+type regionKind uint8
+
+const (
+	charwise regionKind = iota
+	linewiseRegion
+	blockwise
+)
+
+// region is the text an operator acts on. Charwise regions are the
+// half-open range [start, end). Linewise regions cover rows start.Row
+// through end.Row. Blockwise regions cover columns
+// [start.GraphemeCol, end.GraphemeCol) of rows start.Row through end.Row.
+type region struct {
+	start, end buffer.Pos
+	kind       regionKind
+}
+
+// objectKeys lists the supported text objects by the key after i or a.
+var objectKeys = map[string]bool{
+	"w": true, "W": true, "p": true,
+	`"`: true, "'": true, "`": true,
+	"(": true, ")": true, "b": true,
+	"[": true, "]": true,
+	"{": true, "}": true, "B": true,
+	"<": true, ">": true,
+}
+
+var objectBrackets = map[string]buffer.BracketPair{
+	"(": {Open: "(", Close: ")"}, ")": {Open: "(", Close: ")"}, "b": {Open: "(", Close: ")"},
+	"[": {Open: "[", Close: "]"}, "]": {Open: "[", Close: "]"},
+	"{": {Open: "{", Close: "}"}, "}": {Open: "{", Close: "}"}, "B": {Open: "{", Close: "}"},
+	"<": {Open: "<", Close: ">"}, ">": {Open: "<", Close: ">"},
+}
+
+// object returns the region of the text object key ("iw", `a"`, "ip", ...)
+// around p. count widens word, bracket and paragraph objects.
+func (e motionEnv) object(p buffer.Pos, key string, count int) (region, bool) {
+	if len(key) < 2 || (key[0] != 'i' && key[0] != 'a') {
+		return region{}, false
+	}
+	inner, name := key[0] == 'i', key[1:]
+	n := max(count, 1)
+	t := e.t
+
+	switch name {
+	case "w", "W":
+		return t.wordObject(p, inner, name == "W", n)
+	case "p":
+		return t.paragraphObject(p.Row, inner, n)
+	case `"`, "'", "`":
+		return t.quoteObject(p, name, inner)
+	}
+	pair, ok := objectBrackets[name]
+	if !ok || e.buf == nil {
+		return region{}, false
+	}
+	return e.bracketObject(p, pair, inner, n)
+}
+
+// runEnd returns the column after the run of graphemes sharing the class of
+// col on row.
+func (t text) runEnd(row, col int, big bool) int {
+	c := t.class(buffer.Pos{Row: row, GraphemeCol: col}, big)
+	for col < t.lineLen(row) && t.class(buffer.Pos{Row: row, GraphemeCol: col}, big) == c {
+		col++
+	}
+	return col
+}
+
+func (t text) runStart(row, col int, big bool) int {
+	c := t.class(buffer.Pos{Row: row, GraphemeCol: col}, big)
+	for col > 0 && t.class(buffer.Pos{Row: row, GraphemeCol: col - 1}, big) == c {
+		col--
+	}
+	return col
+}
+
+// wordObject implements iw and aw (and the WORD variants). iw covers runs
+// of words or blanks; aw covers words with their trailing blanks, or leading
+// ones when there are none after the word.
+func (t text) wordObject(p buffer.Pos, inner, big bool, n int) (region, bool) {
+	row := p.Row
+	if t.lineLen(row) == 0 {
+		return region{}, false
+	}
+	col := min(p.GraphemeCol, t.lineLen(row)-1)
+	start := t.runStart(row, col, big)
+	end := t.runEnd(row, col, big)
+	onBlank := t.class(buffer.Pos{Row: row, GraphemeCol: col}, big) == classBlank
+
+	if inner {
+		for i := 1; i < n && end < t.lineLen(row); i++ {
+			end = t.runEnd(row, end, big)
+		}
+		return region{start: buffer.Pos{Row: row, GraphemeCol: start}, end: buffer.Pos{Row: row, GraphemeCol: end}}, true
+	}
+
+	if onBlank {
+		// Blanks followed by a word.
+		if end < t.lineLen(row) {
+			end = t.runEnd(row, end, big)
+		}
+	}
+	trailing := false
+	for i := 0; i < n && end < t.lineLen(row); i++ {
+		if i > 0 || !onBlank {
+			if t.class(buffer.Pos{Row: row, GraphemeCol: end}, big) == classBlank {
+				end = t.runEnd(row, end, big)
+				trailing = true
+			}
+		}
+		if i+1 < n && end < t.lineLen(row) {
+			end = t.runEnd(row, end, big)
+		}
+	}
+	if !onBlank && !trailing && start > 0 && t.class(buffer.Pos{Row: row, GraphemeCol: start - 1}, big) == classBlank {
+		start = t.runStart(row, start-1, big)
+	}
+	return region{start: buffer.Pos{Row: row, GraphemeCol: start}, end: buffer.Pos{Row: row, GraphemeCol: end}}, true
+}
+
+// paragraphObject implements ip and ap over runs of blank and non-blank
+// rows. ap adds the blank rows after the paragraph, or before it when it
+// ends the document.
+func (t text) paragraphObject(row int, inner bool, n int) (region, bool) {
+	runStart := func(r int) int {
+		blank := t.blankLine(r)
+		for r > 0 && t.blankLine(r-1) == blank {
+			r--
+		}
+		return r
+	}
+	runEnd := func(r int) int {
+		blank := t.blankLine(r)
+		for !t.isLastRow(r) && t.blankLine(r+1) == blank {
+			r++
+		}
+		return r
+	}
+
+	start, end := runStart(row), runEnd(row)
+	runs := 1
+	if !inner {
+		runs = 2
+	}
+	for i := 1; i < n*runs && !t.isLastRow(end); i++ {
+		end = runEnd(end + 1)
+	}
+	if !inner && !t.blankLine(row) && t.blankLine(end) == t.blankLine(row) && start > 0 {
+		// No blank rows follow: take the ones before the paragraph.
+		start = runStart(start - 1)
+	}
+	return region{start: buffer.Pos{Row: start}, end: buffer.Pos{Row: end}, kind: linewiseRegion}, true
+}
+
+// quoteObject implements i" and a" (and the ' and ` variants) within the
+// cursor row. Quotes pair up from the line start; escaped quotes are
+// skipped. With the cursor outside every pair, the next pair is used.
+func (t text) quoteObject(p buffer.Pos, quote string, inner bool) (region, bool) {
+	line := t.row(p.Row)
+	var quotes []int
+	for i, g := range line {
+		if g == quote && (i == 0 || line[i-1] != `\`) {
+			quotes = append(quotes, i)
+		}
+	}
+	open, close := -1, -1
+	for i := 0; i+1 < len(quotes); i += 2 {
+		if p.GraphemeCol <= quotes[i+1] {
+			open, close = quotes[i], quotes[i+1]
+			break
+		}
+	}
+	if open < 0 {
+		return region{}, false
+	}
+	if inner {
+		return region{start: buffer.Pos{Row: p.Row, GraphemeCol: open + 1}, end: buffer.Pos{Row: p.Row, GraphemeCol: close}}, true
+	}
+	start, end := open, close+1
+	if end < len(line) && graphemeClass(line[end], false) == classBlank {
+		end = t.runEnd(p.Row, end, false)
+	} else if start > 0 && graphemeClass(line[start-1], false) == classBlank {
+		start = t.runStart(p.Row, start-1, false)
+	}
+	return region{start: buffer.Pos{Row: p.Row, GraphemeCol: start}, end: buffer.Pos{Row: p.Row, GraphemeCol: end}}, true
+}
+
+// bracketObject implements i( and a( (and the other bracket pairs). The
+// bracket under the cursor counts as enclosing it; count selects outer
+// pairs. An inner object spanning whole rows becomes linewise, so di{ keeps
+// the braces on their own rows.
+func (e motionEnv) bracketObject(p buffer.Pos, pair buffer.BracketPair, inner bool, n int) (region, bool) {
+	t := e.t
+	opt := buffer.BracketOptions{Pairs: []buffer.BracketPair{pair}}
+	var open, close buffer.Pos
+	var ok bool
+	if g := t.at(p); g == pair.Open || g == pair.Close {
+		var at, match buffer.Pos
+		at, match, ok = e.buf.MatchBracket(p, opt)
+		open, close = orderPos(at, match)
+	} else {
+		open, close, ok = e.buf.EnclosingBrackets(p, opt)
+	}
+	for i := 1; ok && i < n; i++ {
+		open, close, ok = e.buf.EnclosingBrackets(open, opt)
+	}
+	if !ok {
+		return region{}, false
+	}
+
+	if !inner {
+		return region{start: open, end: t.after(close)}, true
+	}
+	start, end := t.after(open), close
+	if start.GraphemeCol == t.lineLen(start.Row) && start.Row < end.Row &&
+		end.GraphemeCol <= t.firstNonBlank(end.Row) && end.Row-start.Row >= 2 {
+		return region{start: buffer.Pos{Row: start.Row + 1}, end: buffer.Pos{Row: end.Row - 1}, kind: linewiseRegion}, true
+	}
+	return region{start: start, end: end}, true
+}
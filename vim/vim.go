@@ -0,0 +1,321 @@
+package vim
+
+import (
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// Config configures a vim Model.
+type Config struct {
+	// Editor configures the wrapped editor. Its TokenStyleForToken is wrapped
+	// to paint visual-block selections with Style.Selection.
+	Editor editor.Config
+	// Indent is the text > adds to and < removes from each row. Default is a
+	// tab.
+	Indent string
+	// OnModeChange is called after the mode changes.
+	OnModeChange func(Mode)
+}
+
+// Model is a Bubble Tea model that wraps editor.Model with vim modes.
+//
+// Insert mode forwards keys to the editor, so its key map, completion and
+// snippets work as usual; esc returns to normal mode. All other modes parse
+// keys as vim commands and run them as intents through editor.Model.Dispatch.
+// Mouse and other messages go to the editor unchanged.
+//
+// Copies of a Model share its registers.
+type Model struct {
+	ed    editor.Model
+	cfg   Config
+	mode  Mode
+	regs  registers
+	block *blockState
+
+	// pending holds the keys of a command still being typed.
+	pending []tea.KeyPressMsg
+	// anchor is where the visual selection started.
+	anchor buffer.Pos
+	// blockIns is the visual-block insert in progress, if any.
+	blockIns *blockInsertState
+	find     findState
+	// txt caches document rows while one key runs; see env.
+	txt *text
+
+	// dot is the last change, replayed by "."; rec collects an insert-mode
+	// change until esc.
+	dot       dotRecord
+	rec       dotRecord
+	recording bool
+}
+
+// dotRecord is a change as the keys that made it, without their counts.
+type dotRecord struct {
+	count int
+	keys  []tea.KeyPressMsg
+}
+
+// blockState is the visual-block rectangle painted by the token style hook.
+// It is shared by copies of a Model because the hook is installed once.
+type blockState struct {
+	active      bool
+	top, bottom int
+	// left and right bound grapheme columns [left, right).
+	left, right int
+}
+
+// blockInsertState is a visual-block insert: text typed at col on the top
+// row is copied to rows when insert mode ends.
+type blockInsertState struct {
+	top, col int
+	// before is the top row when typing started.
+	before []string
+	rows   []rowInsert
+}
+
+// rowInsert is where a block insert copies the typed text on one row, after
+// pad spaces.
+type rowInsert struct {
+	row, col, pad int
+}
+
+func (b *blockState) contains(row int, tok editor.VisualToken) bool {
+	return b.active && tok.Kind == editor.VisualTokenDoc &&
+		row >= b.top && row <= b.bottom &&
+		tok.DocStartGraphemeCol >= b.left && tok.DocStartGraphemeCol < b.right
+}
+
+// New returns a Model in normal mode.
+func New(cfg Config) Model {
+	if cfg.Indent == "" {
+		cfg.Indent = "\t"
+	}
+
+	block := &blockState{}
+	selection := cfg.Editor.Style.Selection
+	if selection.Render("x") == "x" {
+		selection = editor.DefaultStyle().Selection
+	}
+	hostStyle := cfg.Editor.TokenStyleForToken
+	cfg.Editor.TokenStyleForToken = func(ctx editor.TokenStyleContext) (lipgloss.Style, bool) {
+		style, ok := lipgloss.Style{}, false
+		if hostStyle != nil {
+			style, ok = hostStyle(ctx)
+		}
+		if block.contains(ctx.Row, ctx.Token) {
+			return selection.Inherit(style), true
+		}
+		return style, ok
+	}
+
+	return Model{
+		ed:    editor.New(cfg.Editor),
+		cfg:   cfg,
+		regs:  registers{},
+		block: block,
+	}
+}
+
+// Editor returns the wrapped editor.
+func (m Model) Editor() editor.Model { return m.ed }
+
+// SetEditor replaces the wrapped editor, for example after calling editor
+// methods that return an updated model.
+func (m Model) SetEditor(ed editor.Model) Model {
+	m.ed = ed
+	return m
+}
+
+// SetSize sets the editor size.
+func (m Model) SetSize(width, height int) Model {
+	m.ed = m.ed.SetSize(width, height)
+	return m
+}
+
+// Focus focuses the editor.
+func (m Model) Focus() Model {
+	m.ed = m.ed.Focus()
+	return m
+}
+
+// Blur blurs the editor.
+func (m Model) Blur() Model {
+	m.ed = m.ed.Blur()
+	return m
+}
+
+// Mode returns the active mode.
+func (m Model) Mode() Mode { return m.mode }
+
+// Pending returns the keys of a command still being typed (for example
+// `2d` or `"a`), for display next to the mode indicator.
+func (m Model) Pending() string {
+	var sb strings.Builder
+	for _, k := range m.pending {
+		sb.WriteString(k.String())
+	}
+	return sb.String()
+}
+
+// Register returns the content of a register. Uppercase names read the
+// lowercase register.
+func (m Model) Register(name rune) (Register, bool) {
+	return m.regs.get(name)
+}
+
+// SetRegister sets the content of a register, as yanking into it would.
+func (m Model) SetRegister(name rune, r Register) Model {
+	if validRegister(name) {
+		m.regs.store(name, r, false)
+	}
+	return m
+}
+
+func (m Model) Init() tea.Cmd { return m.ed.Init() }
+
+func (m Model) View() tea.View { return m.ed.View() }
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyPressMsg)
+	if !ok || !m.ed.Focused() || m.ed.Buffer() == nil {
+		var cmd tea.Cmd
+		m.ed, cmd = m.ed.Update(msg)
+		return m, cmd
+	}
+	if m.mode == ModeInsert {
+		return m.insertKey(key)
+	}
+
+	m.pending = append(m.pending, key)
+	cmd, state := parseCommand(m.pending, m.mode)
+	switch state {
+	case parseIncomplete:
+		return m, nil
+	case parseInvalid:
+		m.pending = nil
+		return m, nil
+	}
+	m.pending = nil
+	if m.mode.Visual() {
+		(&m).runVisual(cmd)
+	} else {
+		(&m).runNormal(cmd)
+	}
+	m.txt = nil
+	return m, nil
+}
+
+func (m Model) insertKey(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if isEscape(msg) {
+		// The editor closes completion, popups and snippets on esc.
+		m.ed, cmd = m.ed.Update(msg)
+		(&m).leaveInsert(msg)
+		return m, cmd
+	}
+	if m.recording {
+		m.rec.keys = append(m.rec.keys, msg)
+	}
+	m.ed, cmd = m.ed.Update(msg)
+	return m, cmd
+}
+
+func isEscape(msg tea.KeyPressMsg) bool {
+	s := msg.String()
+	return s == "esc" || s == "ctrl+["
+}
+
+func (m *Model) setMode(mode Mode) {
+	prev := m.mode
+	m.mode = mode
+	wasBlock := m.block.active
+	m.block.active = mode == ModeVisualBlock
+	if wasBlock && !m.block.active {
+		m.ed = m.ed.InvalidateStyles()
+	}
+	if prev != mode && m.cfg.OnModeChange != nil {
+		m.cfg.OnModeChange(mode)
+	}
+}
+
+// enterInsert switches to insert mode. Edits made until esc undo as one
+// step; a change started by an operator or insert command is recorded for
+// ".".
+func (m *Model) enterInsert(rec *dotRecord) {
+	m.ed.Buffer().BeginUndoGroup()
+	if rec != nil {
+		m.rec = *rec
+		m.recording = true
+	}
+	m.setMode(ModeInsert)
+}
+
+func (m *Model) leaveInsert(esc tea.KeyPressMsg) {
+	if m.recording {
+		m.rec.keys = append(m.rec.keys, esc)
+		m.dot = m.rec
+		m.rec = dotRecord{}
+		m.recording = false
+	}
+	if ins := m.blockIns; ins != nil {
+		m.finishBlockInsert()
+		m.ed.Buffer().EndUndoGroup()
+		m.setMode(ModeNormal)
+		m.dispatch(cursorIntent(buffer.Pos{Row: ins.top, GraphemeCol: ins.col}))
+		m.clampCursor()
+		return
+	}
+	m.ed.Buffer().EndUndoGroup()
+	m.setMode(ModeNormal)
+	if cur := m.cursor(); cur.GraphemeCol > 0 {
+		cur.GraphemeCol--
+		m.dispatch(cursorIntent(cur))
+	}
+}
+
+func (m *Model) cursor() buffer.Pos { return m.ed.Buffer().Cursor() }
+
+func (m *Model) env() motionEnv {
+	return motionEnv{t: m.text(), buf: m.ed.Buffer(), find: m.find}
+}
+
+// text returns the rows read so far for the running key, starting over
+// once an edit changes the text.
+func (m *Model) text() text {
+	b := m.ed.Buffer()
+	if m.txt == nil || m.txt.buf != b || m.txt.version != b.TextVersion() {
+		t := textFromBuffer(b)
+		m.txt = &t
+	}
+	return *m.txt
+}
+
+func (m *Model) dispatch(intents ...editor.Intent) {
+	m.ed = m.ed.Dispatch(intents...)
+}
+
+// clampCursor keeps the normal-mode cursor on a grapheme.
+func (m *Model) clampCursor() {
+	t := m.text()
+	cur := m.cursor()
+	if p := t.clampNormal(cur); p != cur {
+		m.dispatch(cursorIntent(p))
+	}
+}
+
+func cursorIntent(p buffer.Pos) editor.Intent {
+	return editor.Intent{Kind: editor.IntentSetCursor, Payload: editor.SetCursorIntentPayload{Cursor: p}}
+}
+
+func selectIntent(cursor buffer.Pos, sel buffer.Range) editor.Intent {
+	return editor.Intent{Kind: editor.IntentSetCursor, Payload: editor.SetCursorIntentPayload{
+		Cursor:    cursor,
+		Selection: sel,
+		Select:    true,
+	}}
+}
@@ -0,0 +1,316 @@
+package vim
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+)
+
+// feed types keys: characters are typed as text, and <esc>, <c-r>, <c-v>
+// name special keys.
+func feed(t *testing.T, m Model, keys string) Model {
+	t.Helper()
+	for keys != "" {
+		var msg tea.KeyPressMsg
+		if strings.HasPrefix(keys, "<") {
+			end := strings.Index(keys, ">")
+			if end < 0 {
+				t.Fatalf("unterminated key in %q", keys)
+			}
+			switch name := keys[1:end]; name {
+			case "esc":
+				msg = tea.KeyPressMsg{Code: tea.KeyEscape}
+			case "c-r":
+				msg = tea.KeyPressMsg{Code: 'r', Mod: tea.ModCtrl}
+			case "c-v":
+				msg = tea.KeyPressMsg{Code: 'v', Mod: tea.ModCtrl}
+			case "lt":
+				msg = tea.KeyPressMsg{Code: '<', Text: "<"}
+			default:
+				t.Fatalf("unknown key <%s>", name)
+			}
+			keys = keys[end+1:]
+		} else {
+			r, size := utf8.DecodeRuneInString(keys)
+			msg = tea.KeyPressMsg{Code: r, Text: keys[:size]}
+			keys = keys[size:]
+		}
+		m, _ = m.Update(msg)
+	}
+	return m
+}
+
+func newModel(text string) Model {
+	return New(Config{Editor: editor.Config{Text: text}}).Focus()
+}
+
+func assertState(t *testing.T, m Model, text string, cursor buffer.Pos) {
+	t.Helper()
+	b := m.Editor().Buffer()
+	if got := b.Text(); got != text {
+		t.Fatalf("text=%q, want %q", got, text)
+	}
+	if got := b.Cursor(); got != cursor {
+		t.Fatalf("cursor=%v, want %v", got, cursor)
+	}
+}
+
+func pos(row, col int) buffer.Pos { return buffer.Pos{Row: row, GraphemeCol: col} }
+
+func TestNormal_OperatorsWithMotionsAndCounts(t *testing.T) {
+	cases := []struct {
+		name, text, keys, want string
+		cursor                 buffer.Pos
+	}{
+		{"dw", "one two three", "dw", "two three", pos(0, 0)},
+		{"count before and after operator", "a b c d e f", "2d2w", "e f", pos(0, 0)},
+		{"dw stops at row end", "one\n  two", "dw", "\n  two", pos(0, 0)},
+		{"de", "one two", "de", " two", pos(0, 0)},
+		{"d$ from middle", "hello world", "wd$", "hello ", pos(0, 5)},
+		{"dt", "call(a, b)", "dt,", ", b)", pos(0, 0)},
+		{"df then ;", "a.b.c.d", "f.;D", "a.b", pos(0, 2)},
+		{"dd with count", "1\n2\n3\n4", "j2dd", "1\n4", pos(1, 0)},
+		{"dd last row", "1\n2", "jdd", "1", pos(0, 0)},
+		{"dj", "1\n2\n3", "dj", "3", pos(0, 0)},
+		{"dG", "1\n2\n3", "jdG", "1", pos(0, 0)},
+		{"x with count", "abcdef", "l3x", "aef", pos(0, 1)},
+		{"X", "abc", "$X", "ac", pos(0, 1)},
+		{"indent", "a\nb", ">j", "\ta\n\tb", pos(0, 1)},
+		{"dedent", "\t\ta", "<lt><lt>", "\ta", pos(0, 1)},
+		{"J", "a\n  b\nc", "3J", "a b c", pos(0, 3)},
+		{"r with count", "abcd", "2rx", "xxcd", pos(0, 1)},
+		{"%", "f(a(b))", "%x", "f(a(b)", pos(0, 5)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := feed(t, newModel(tc.text), tc.keys)
+			assertState(t, m, tc.want, tc.cursor)
+		})
+	}
+}
+
+func TestNormal_TextObjects(t *testing.T) {
+	cases := []struct {
+		name, text, keys, want string
+	}{
+		{"diw", "foo bar baz", "wdiw", "foo  baz"},
+		{"daw", "foo bar baz", "wdaw", "foo baz"},
+		{"di quote", `x = "a b" + y`, `fadi"`, `x = "" + y`},
+		{"da quote", `x = "a b" + y`, `fada"`, `x = + y`},
+		{"di paren", "f(a, (b), c)", "fadi(", "f()"},
+		{"da paren count", "f(a, (b), c)", "fbd2a(", "f"},
+		{"di brace multiline", "if {\n\ta\n\tb\n}", "jdi{", "if {\n}"},
+		{"dip", "a\nb\n\nc", "dip", "\nc"},
+		{"dap", "a\nb\n\nc", "dap", "c"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := feed(t, newModel(tc.text), tc.keys)
+			if got := m.Editor().Buffer().Text(); got != tc.want {
+				t.Fatalf("text=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInsertAndDotRepeat(t *testing.T) {
+	var modes []Mode
+	m := New(Config{
+		Editor:       editor.Config{Text: "one two three"},
+		OnModeChange: func(mode Mode) { modes = append(modes, mode) },
+	}).Focus()
+
+	m = feed(t, m, "cwONE")
+	if m.Mode() != ModeInsert {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	m = feed(t, m, "<esc>")
+	assertState(t, m, "ONE two three", pos(0, 2))
+
+	m = feed(t, m, "w.")
+	assertState(t, m, "ONE ONE three", pos(0, 6))
+
+	m = feed(t, m, "u")
+	if got := m.Editor().Buffer().Text(); got != "ONE two three" {
+		t.Fatalf("undo should revert the whole change, text=%q", got)
+	}
+
+	m = feed(t, m, "0dw2.")
+	if got := m.Editor().Buffer().Text(); got != "" {
+		t.Fatalf("dot with a count, text=%q", got)
+	}
+	if want := []Mode{ModeInsert, ModeNormal, ModeInsert, ModeNormal}; len(modes) != len(want) {
+		t.Fatalf("modes=%v, want %v", modes, want)
+	}
+
+	m = newModel("a\nb")
+	m = feed(t, m, "ox<esc>j.")
+	assertState(t, m, "a\nx\nb\nx", pos(3, 0))
+}
+
+func TestRegisters(t *testing.T) {
+	m := newModel("alpha beta")
+
+	m = feed(t, m, `"ayiww"Ayiw`)
+	if r, _ := m.Register('a'); r.Text != "alphabeta" || r.Linewise {
+		t.Fatalf("append register=%+v", r)
+	}
+	m = feed(t, m, `"ap`)
+	assertState(t, m, "alpha balphabetaeta", pos(0, 15))
+
+	m = newModel("one\ntwo")
+	m = feed(t, m, "yyjp")
+	assertState(t, m, "one\ntwo\none", pos(2, 0))
+	if r, _ := m.Register(YankRegister); r.Text != "one" || !r.Linewise {
+		t.Fatalf("yank register=%+v", r)
+	}
+	m = feed(t, m, `"_ddk"0P`)
+	assertState(t, m, "one\none\ntwo", pos(0, 0))
+
+	m = m.SetRegister('z', Register{Text: "Z"})
+	m = feed(t, m, `"zP`)
+	assertState(t, m, "Zone\none\ntwo", pos(0, 0))
+}
+
+func TestVisualModes(t *testing.T) {
+	m := newModel("abc\ndef\nghi")
+
+	m = feed(t, m, "vl")
+	if m.Mode() != ModeVisual || m.Mode().String() != "VISUAL" {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	if r, ok := m.Editor().Buffer().Selection(); !ok || r != (buffer.Range{Start: pos(0, 0), End: pos(0, 2)}) {
+		t.Fatalf("selection=%v %v", r, ok)
+	}
+	m = feed(t, m, "jd")
+	assertState(t, m, "f\nghi", pos(0, 0))
+	if m.Mode() != ModeNormal {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	if _, ok := m.Editor().Buffer().Selection(); ok {
+		t.Fatalf("selection should clear after the operator")
+	}
+
+	m = newModel("abc\ndef\nghi")
+	m = feed(t, m, "jVky")
+	if r, _ := m.Register(UnnamedRegister); r.Text != "abc\ndef" || !r.Linewise {
+		t.Fatalf("linewise yank=%+v", r)
+	}
+
+	m = newModel("abcd\nefgh\nijkl")
+	m = feed(t, m, "l<c-v>jl")
+	if m.Mode().String() != "VISUAL BLOCK" {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	m = feed(t, m, "d")
+	assertState(t, m, "ad\neh\nijkl", pos(0, 1))
+
+	m = newModel("foo(bar baz)")
+	m = feed(t, m, "fbvi(c")
+	if m.Mode() != ModeInsert {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	m = feed(t, m, "x<esc>")
+	assertState(t, m, "foo(x)", pos(0, 4))
+}
+
+func TestVisualBlock_StylesRectangle(t *testing.T) {
+	m := newModel("abcd\nefgh")
+	m = m.SetSize(10, 2)
+	m = feed(t, m, "l<c-v>jl")
+
+	want := m.Editor().View().Content
+	m = feed(t, m, "<esc>")
+	if m.Editor().View().Content == want {
+		t.Fatalf("leaving block mode should repaint the block")
+	}
+	if m.block.active {
+		t.Fatalf("block should be inactive in normal mode")
+	}
+	if !(&blockState{active: true, top: 0, bottom: 1, left: 1, right: 3}).contains(1, editor.VisualToken{Kind: editor.VisualTokenDoc, DocStartGraphemeCol: 2}) {
+		t.Fatalf("expected block to contain (1,2)")
+	}
+}
+
+func TestIntentMode_HostSeesSemanticIntents(t *testing.T) {
+	var kinds []editor.IntentKind
+	m := New(Config{Editor: editor.Config{
+		Text:         "one two\nthree",
+		MutationMode: editor.EmitIntentsAndMutate,
+		OnIntent: func(batch editor.IntentBatch) editor.IntentDecision {
+			for _, in := range batch.Intents {
+				kinds = append(kinds, in.Kind)
+			}
+			return editor.IntentDecision{ApplyLocally: true}
+		},
+	}}).Focus()
+
+	m = feed(t, m, "j")
+	if len(kinds) != 1 || kinds[0] != editor.IntentMove {
+		t.Fatalf("j kinds=%v", kinds)
+	}
+
+	kinds = nil
+	m = feed(t, m, "kdw")
+	want := []editor.IntentKind{editor.IntentMove, editor.IntentDelete, editor.IntentSetCursor}
+	if len(kinds) != len(want) {
+		t.Fatalf("kinds=%v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("kinds=%v, want %v", kinds, want)
+		}
+	}
+	assertState(t, m, "two\nthree", pos(0, 0))
+}
+
+func TestPending(t *testing.T) {
+	m := newModel("abc")
+	m = feed(t, m, `"a2d`)
+	if got := m.Pending(); got != `"a2d` {
+		t.Fatalf("pending=%q", got)
+	}
+	m = feed(t, m, "<esc>")
+	if got := m.Pending(); got != "" {
+		t.Fatalf("esc should cancel the pending command, got %q", got)
+	}
+	assertState(t, m, "abc", pos(0, 0))
+}
+
+func TestVisualBlock_InsertAppendChange(t *testing.T) {
+	m := newModel("abcd\nefgh\nij\nklmn")
+	m = feed(t, m, "l<c-v>3jIX<esc>")
+	assertState(t, m, "aXbcd\neXfgh\niXj\nkXlmn", pos(0, 1))
+	if m.Mode() != ModeNormal {
+		t.Fatalf("mode=%v", m.Mode())
+	}
+	m = feed(t, m, "u")
+	assertState(t, m, "abcd\nefgh\nij\nklmn", pos(0, 1))
+
+	// I skips rows ending before the block; A pads them.
+	m = newModel("abcd\ne\nfghi")
+	m = feed(t, m, "ll<c-v>2jIX<esc>")
+	assertState(t, m, "abXcd\ne\nfgXhi", pos(0, 2))
+	m = newModel("abcd\ne\nfghi")
+	m = feed(t, m, "l<c-v>2jlAYZ<esc>")
+	assertState(t, m, "abcYZd\ne  YZ\nfghYZi", pos(0, 3))
+
+	m = newModel("abcd\nefgh\nijkl")
+	m = feed(t, m, "l<c-v>jlc-<esc>")
+	assertState(t, m, "a-d\ne-h\nijkl", pos(0, 1))
+	if r, _ := m.Register(UnnamedRegister); r.Text != "bc\nfg" {
+		t.Fatalf("register=%+v", r)
+	}
+
+	// A line break typed on the top row is not copied.
+	m = newModel("ab\ncd")
+	m = feed(t, m, "<c-v>jIx\n<esc>")
+	if got := m.Editor().Buffer().Text(); got != "x\nab\ncd" {
+		t.Fatalf("text=%q", got)
+	}
+}
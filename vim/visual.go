@@ -0,0 +1,257 @@
+package vim
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/iw2rmb/flourish/buffer"
+	"github.com/iw2rmb/flourish/editor"
+	"github.com/iw2rmb/flourish/internal/grapheme"
+)
+
+func (m *Model) enterVisual(mode Mode) {
+	m.anchor = m.cursor()
+	m.setMode(mode)
+	m.showVisual()
+}
+
+func (m *Model) leaveVisual() {
+	m.setMode(ModeNormal)
+	m.dispatch(cursorIntent(m.cursor()))
+}
+
+// visualRegion returns the selected text. Charwise selections include the
+// grapheme under the cursor.
+func (m *Model) visualRegion(t text) region {
+	cur := m.cursor()
+	start, end := orderPos(m.anchor, cur)
+	switch m.mode {
+	case ModeVisualLine:
+		return region{start: buffer.Pos{Row: start.Row}, end: buffer.Pos{Row: end.Row}, kind: linewiseRegion}
+	case ModeVisualBlock:
+		left := min(m.anchor.GraphemeCol, cur.GraphemeCol)
+		right := max(m.anchor.GraphemeCol, cur.GraphemeCol) + 1
+		return region{
+			start: buffer.Pos{Row: start.Row, GraphemeCol: left},
+			end:   buffer.Pos{Row: end.Row, GraphemeCol: right},
+			kind:  blockwise,
+		}
+	default:
+		return region{start: start, end: t.after(end)}
+	}
+}
+
+// showVisual mirrors the visual selection into the editor: as its
+// selection for charwise and linewise modes, and through the token style
+// hook for blockwise mode. The selection starts at the anchor.
+func (m *Model) showVisual() {
+	t := m.env().t
+	cur := m.cursor()
+	r := m.visualRegion(t)
+
+	if m.mode == ModeVisualBlock {
+		*m.block = blockState{
+			active: true,
+			top:    r.start.Row, bottom: r.end.Row,
+			left: r.start.GraphemeCol, right: r.end.GraphemeCol,
+		}
+		m.dispatch(cursorIntent(cur))
+		m.ed = m.ed.InvalidateStyles()
+		return
+	}
+
+	start, end := r.start, r.end
+	if r.kind == linewiseRegion {
+		end = buffer.Pos{Row: end.Row, GraphemeCol: t.lineLen(end.Row)}
+		if !t.isLastRow(end.Row) {
+			end = buffer.Pos{Row: end.Row + 1}
+		}
+	}
+	sel := buffer.Range{Start: start, End: end}
+	if posLess(cur, m.anchor) || (r.kind == linewiseRegion && cur.Row < m.anchor.Row) {
+		sel = buffer.Range{Start: end, End: start}
+	}
+	m.dispatch(selectIntent(cur, sel))
+}
+
+func (m *Model) runVisual(cmd command) {
+	env := m.env()
+	t := env.t
+
+	switch {
+	case cmd.motion.key != "":
+		target, _, ok := env.target(m.cursor(), cmd.motion, cmd.count)
+		if !ok {
+			return
+		}
+		m.rememberFind(cmd.motion)
+		m.dispatch(cursorIntent(t.clampNormal(target)))
+		m.showVisual()
+		return
+	case cmd.object != "":
+		r, ok := env.object(m.cursor(), cmd.object, cmd.count)
+		if !ok {
+			return
+		}
+		last := r.end
+		if r.kind == linewiseRegion {
+			m.setMode(ModeVisualLine)
+		} else if p, ok := t.prev(r.end); ok && posLess(r.start, r.end) {
+			last = p
+		}
+		m.anchor = r.start
+		m.dispatch(cursorIntent(last))
+		m.showVisual()
+		return
+	}
+
+	switch cmd.action {
+	case "esc", "ctrl+[":
+		m.leaveVisual()
+	case "v", "V", "ctrl+v":
+		mode := map[string]Mode{"v": ModeVisual, "V": ModeVisualLine, "ctrl+v": ModeVisualBlock}[cmd.action]
+		if mode == m.mode {
+			m.leaveVisual()
+			return
+		}
+		m.setMode(mode)
+		m.showVisual()
+	case "o":
+		cur := m.cursor()
+		m.dispatch(cursorIntent(m.anchor))
+		m.anchor = cur
+		m.showVisual()
+	case "I", "A":
+		if m.mode == ModeVisualBlock {
+			m.blockInsert(t, m.visualRegion(t), cmd.action, cmd.reg)
+		}
+	case "y", "d", "x", "c", "s", ">", "<":
+		op := cmd.action
+		switch op {
+		case "x":
+			op = "d"
+		case "s":
+			op = "c"
+		}
+		r := m.visualRegion(t)
+		if op == "c" && r.kind == blockwise {
+			m.blockInsert(t, r, op, cmd.reg)
+			return
+		}
+		m.setMode(ModeNormal)
+		m.operate(op, cmd.reg, r, cmd.count, nil)
+		if m.mode == ModeNormal {
+			m.clampCursor()
+		}
+	case "J":
+		r := m.visualRegion(t)
+		m.setMode(ModeNormal)
+		m.join(t, r.start.Row, max(r.end.Row, r.start.Row+1))
+	case "p", "P":
+		m.replaceVisual(t, cmd.reg)
+	}
+}
+
+// replaceVisual replaces the selection with a register and stores the
+// replaced text in the unnamed register.
+func (m *Model) replaceVisual(t text, name rune) {
+	r := m.visualRegion(t)
+	reg, ok := m.regs.get(name)
+	if !ok || r.kind == blockwise {
+		m.leaveVisual()
+		return
+	}
+	replaced := Register{Text: regionText(t, r), Linewise: r.kind == linewiseRegion}
+
+	rng := buffer.Range{Start: r.start, End: r.end}
+	text := reg.Text
+	switch {
+	case r.kind == linewiseRegion:
+		rng.End = buffer.Pos{Row: r.end.Row, GraphemeCol: t.lineLen(r.end.Row)}
+	case reg.Linewise:
+		text = "\n" + text + "\n"
+	}
+	p := rng.Start
+	if reg.Linewise && r.kind != linewiseRegion {
+		p = buffer.Pos{Row: rng.Start.Row + 1}
+	}
+	if !strings.Contains(text, "\n") && text != "" {
+		p.GraphemeCol += grapheme.Count(text) - 1
+	}
+
+	m.setMode(ModeNormal)
+	m.dispatch(
+		editor.Intent{Kind: editor.IntentEdit, Payload: editor.EditIntentPayload{Edits: []buffer.TextEdit{{Range: rng, Text: text}}}},
+		cursorIntent(p),
+	)
+	m.clampCursor()
+	m.regs.store(UnnamedRegister, replaced, false)
+}
+
+// blockInsert starts a visual-block insert: I inserts before the block, A
+// appends after it and c replaces it. Text typed on the top row is copied to
+// the other rows on esc (see finishBlockInsert). I and c skip rows that end
+// before the block; A pads them with spaces.
+func (m *Model) blockInsert(t text, r region, op string, reg rune) {
+	left, right := r.start.GraphemeCol, r.end.GraphemeCol
+	col := left
+	if op == "A" {
+		col = right
+	}
+	ins := &blockInsertState{top: r.start.Row, col: min(col, t.lineLen(r.start.Row))}
+	for row := r.start.Row + 1; row <= r.end.Row; row++ {
+		n := t.lineLen(row)
+		switch {
+		case op == "A":
+			ins.rows = append(ins.rows, rowInsert{row: row, col: min(col, n), pad: max(col-n, 0)})
+		case n > left:
+			ins.rows = append(ins.rows, rowInsert{row: row, col: left})
+		}
+	}
+
+	var intents []editor.Intent
+	switch {
+	case op == "c":
+		m.regs.store(reg, Register{Text: regionText(t, r)}, false)
+		intents = append(intents, deleteIntent(m.cursor(), r, deleteEdits(t, r)))
+	case ins.col < col:
+		// A past the end of the top row pads it first.
+		at := buffer.Pos{Row: ins.top, GraphemeCol: ins.col}
+		intents = append(intents, insertIntent(at, strings.Repeat(" ", col-ins.col)))
+		ins.col = col
+	}
+	intents = append(intents, cursorIntent(buffer.Pos{Row: ins.top, GraphemeCol: ins.col}))
+
+	m.setMode(ModeNormal)
+	m.enterInsert(nil)
+	m.dispatch(intents...)
+	ins.before = m.text().row(ins.top)
+	m.blockIns = ins
+}
+
+// finishBlockInsert copies the text typed on the top row of a block insert
+// to its other rows. Nothing is copied when the top row changed other than
+// by inserting at the block column, for example when a line break was typed.
+func (m *Model) finishBlockInsert() {
+	ins := m.blockIns
+	m.blockIns = nil
+	after := m.text().row(ins.top)
+	n := len(after) - len(ins.before)
+	if n <= 0 || !slices.Equal(after[:ins.col], ins.before[:ins.col]) ||
+		!slices.Equal(after[ins.col+n:], ins.before[ins.col:]) {
+		return
+	}
+	typed := grapheme.Join(after[ins.col : ins.col+n])
+
+	edits := make([]buffer.TextEdit, 0, len(ins.rows))
+	for _, ri := range ins.rows {
+		at := buffer.Pos{Row: ri.row, GraphemeCol: ri.col}
+		edits = append(edits, buffer.TextEdit{
+			Range: buffer.Range{Start: at, End: at},
+			Text:  strings.Repeat(" ", ri.pad) + typed,
+		})
+	}
+	if len(edits) > 0 {
+		m.dispatch(editor.Intent{Kind: editor.IntentInsert, Payload: editor.InsertIntentPayload{Text: typed, Edits: edits}})
+	}
+}